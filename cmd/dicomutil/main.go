@@ -2,6 +2,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -10,7 +11,6 @@ import (
 	"io"
 	"log"
 	"os"
-	"sync"
 
 	"github.com/wybaby168/dicom"
 	"github.com/wybaby168/dicom/pkg/frame"
@@ -25,7 +25,9 @@ var (
 	filepath                 = flag.String("path", "", "path")
 	extractImagesStream      = flag.Bool("extract-images-stream", false, "Extract images using frame streaming capability")
 	printJSON                = flag.Bool("json", false, "Print dataset as JSON")
+	printXML                 = flag.Bool("xml", false, "Print dataset as Native DICOM Model XML (PS3.19)")
 	allowPixelDataVLMismatch = flag.Bool("allow-pixel-data-mismatch", false, "Allows the pixel data mismatch")
+	dictPath                 = flag.String("dict", "", "path to an extra data dictionary to load, in dcmtk format or pydicom format (.py)")
 )
 
 // FrameBufferSize represents the size of the *Frame buffered channel for streaming calls
@@ -39,6 +41,19 @@ func main() {
 		os.Exit(0)
 	}
 
+	if len(*dictPath) > 0 {
+		if err := tag.LoadDictionaryFile(*dictPath, true); err != nil {
+			log.Fatalf("error loading dictionary %s: %v", *dictPath, err)
+		}
+	}
+
+	switch flag.Arg(0) {
+	case "diff":
+		os.Exit(runDiff(flag.Args()[1:]))
+	case "dump":
+		os.Exit(runDump(flag.Args()[1:]))
+	}
+
 	if len(*filepath) > 0 {
 
 		f, err := os.Open(*filepath)
@@ -82,6 +97,12 @@ func main() {
 				return
 			}
 			fmt.Println(string(j))
+		} else if *printXML {
+			log.Println("Printing DICOM dataset serialized as Native DICOM Model XML to stdout")
+			if err := dicom.EncodeXML(os.Stdout, *ds); err != nil {
+				log.Printf("error marshaling dataset to xml: %v", err)
+				return
+			}
 		} else {
 			log.Println("Printing DICOM dataset parsed elements to stdout:")
 			fmt.Print(ds)
@@ -108,84 +129,156 @@ func main() {
 
 }
 
+// runDiff prints the elements added, removed and changed from the first to the
+// second DICOM file in args, and returns the exit status: like diff(1), 0 if
+// the files are the same, 1 if they differ and 2 on errors.
+func runDiff(args []string) int {
+	if len(args) != 2 {
+		log.Println("usage: dicomutil diff a.dcm b.dcm")
+		return 2
+	}
+	var opts []dicom.ParseOption
+	if *allowPixelDataVLMismatch {
+		opts = append(opts, dicom.AllowMismatchPixelDataLength())
+	}
+	var datasets [2]dicom.Dataset
+	for i, path := range args {
+		ds, err := dicom.ParseFile(path, nil, opts...)
+		if err != nil {
+			log.Printf("error parsing %s: %v", path, err)
+			return 2
+		}
+		datasets[i] = ds
+	}
+
+	changes := dicom.Diff(datasets[0], datasets[1])
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	if len(changes) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runDump prints the DICOM file in args in the format of dcmtk's dcmdump, and
+// returns the exit status.
+func runDump(args []string) int {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	maxLength := fs.Int("max-length", 64, "shorten values to this many characters, or 0 to print them in full")
+	offsets := fs.Bool("offsets", false, "print the byte offset of each element in the file")
+	hidePrivate := fs.Bool("hide-private", false, "hide private elements")
+	rawVR := fs.Bool("raw-vr", false, "print VRs as read, rather than resolving UN from the data dictionary")
+	color := fs.Bool("color", false, "highlight the output for terminals")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: dicomutil dump [flags] file.dcm")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	var parseOpts []dicom.ParseOption
+	if *allowPixelDataVLMismatch {
+		parseOpts = append(parseOpts, dicom.AllowMismatchPixelDataLength())
+	}
+	if *offsets {
+		parseOpts = append(parseOpts, dicom.RecordOffsets())
+	}
+	ds, err := dicom.ParseFile(fs.Arg(0), nil, parseOpts...)
+	if err != nil {
+		log.Printf("error parsing %s: %v", fs.Arg(0), err)
+		return 1
+	}
+
+	dumpOpts := []dicom.DumpOption{dicom.MaxValueLength(*maxLength)}
+	if *offsets {
+		dumpOpts = append(dumpOpts, dicom.ShowOffsets())
+	}
+	if *hidePrivate {
+		dumpOpts = append(dumpOpts, dicom.HidePrivateTags())
+	}
+	if *rawVR {
+		dumpOpts = append(dumpOpts, dicom.ShowRawVR())
+	}
+	if *color {
+		dumpOpts = append(dumpOpts, dicom.ColorOutput())
+	}
+	if err := dicom.Dump(os.Stdout, ds, dumpOpts...); err != nil {
+		log.Printf("error dumping %s: %v", fs.Arg(0), err)
+		return 1
+	}
+	return 0
+}
+
 func parseWithStreaming(in io.Reader, size int64) (*dicom.Dataset, error) {
 	fc := make(chan *frame.Frame, FrameBufferSize)
 
 	// Go routine to process frames as they are sent to frameChannel
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go writeStreamingFrames(fc, &wg)
+	done := make(chan error, 1)
+	go func() {
+		done <- dicom.DecodeFrames(context.Background(), fc, nil, dicom.RenderFrames(writeStreamingFrame))
+	}()
 
 	ds, err := dicom.Parse(in, size, fc)
 	if err != nil {
+		// Parse only closes the frame channel once it is done with the DICOM.
+		close(fc)
+		<-done
 		return &ds, err
 	}
-	wg.Wait()
+	if err := <-done; err != nil {
+		log.Printf("error writing frames: %v", err)
+	}
 
 	return &ds, nil
 
 }
 
-func writeStreamingFrames(frameChan chan *frame.Frame, doneWG *sync.WaitGroup) {
-	count := 0 // may not correspond to frame number
-	var wg sync.WaitGroup
-	for fr := range frameChan {
-		count++
-		wg.Add(1)
-		go generateImage(fr, count, "", &wg)
-	}
-	wg.Wait()
-	doneWG.Done()
+func writeStreamingFrame(_ context.Context, f dicom.DecodedFrame) error {
+	// The index of the frames received may not correspond to frame number.
+	return writeImage(f, f.Index+1, "")
 }
 
-func generateImage(fr *frame.Frame, frameIndex int, frameSuffix string, wg *sync.WaitGroup) {
-	i, err := fr.GetImage()
-	if err != nil {
-		fmt.Printf("Error while getting image: %v\n", err)
-		return
-	}
-
+// writeImage writes the image of f to a file, in PNG format for native frames
+// to exactly preserve the pixel values, and in JPEG format otherwise.
+func writeImage(f dicom.DecodedFrame, frameIndex int, frameSuffix string) error {
 	ext := ".jpg"
-	if !fr.IsEncapsulated() {
+	if !f.Frame.IsEncapsulated() {
 		ext = ".png"
 	}
 
 	name := fmt.Sprintf("image_%d%s%s", frameIndex, frameSuffix, ext)
-	f, err := os.Create(name)
+	out, err := os.Create(name)
 	if err != nil {
-		fmt.Printf("Error while creating file: %s", err.Error())
-		return
+		return fmt.Errorf("error creating file: %w", err)
 	}
+	defer out.Close()
 
-	if !fr.IsEncapsulated() {
-		// Native (non-encapsulated) frames are written as PNGs to exactly
-		// preserve the pixel values.
-		err := png.Encode(f, i)
-		if err != nil {
-			log.Println(err)
-			return
-		}
+	if !f.Frame.IsEncapsulated() {
+		err = png.Encode(out, f.Image)
 	} else {
-		err = jpeg.Encode(f, i, &jpeg.Options{Quality: 100})
-		if err != nil {
-			log.Println(err)
-			return
-		}
+		err = jpeg.Encode(out, f.Image, &jpeg.Options{Quality: 100})
 	}
-
-	if err = f.Close(); err != nil {
-		log.Println("ERROR: unable to properly close file: ", f.Name())
+	if err != nil {
+		return err
 	}
-	log.Printf("Image %s written\n", name)
 
-	if wg != nil {
-		wg.Done()
+	if err = out.Close(); err != nil {
+		return fmt.Errorf("unable to properly close file %s: %w", name, err)
 	}
+	log.Printf("Image %s written\n", name)
+	return nil
 }
 
 func writePixelDataElement(e *dicom.Element, suffix string) {
 	imageInfo := e.Value.GetValue().(dicom.PixelDataInfo)
-	for idx, f := range imageInfo.Frames {
-		generateImage(f, idx, suffix, nil)
+	err := dicom.DecodePixelData(context.Background(), imageInfo, nil, dicom.RenderFrames(func(_ context.Context, f dicom.DecodedFrame) error {
+		return writeImage(f, f.Index, suffix)
+	}))
+	if err != nil {
+		log.Printf("error writing frames: %v", err)
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/wybaby168/dicom/pkg/tag"
@@ -36,6 +38,19 @@ func (d *Dataset) FindElementByTag(tag tag.Tag) (*Element, error) {
 	return nil, fmt.Errorf("unable to find %v element: %w", tag, ErrorElementNotFound)
 }
 
+// SetElement replaces the element with the same tag as elem, or inserts elem
+// keeping the Dataset sorted by tag. It DOES NOT search within Sequences.
+func (d *Dataset) SetElement(elem *Element) {
+	if i := slices.IndexFunc(d.Elements, func(e *Element) bool { return e.Tag == elem.Tag }); i >= 0 {
+		d.Elements[i] = elem
+		return
+	}
+	idx := sort.Search(len(d.Elements), func(i int) bool {
+		return d.Elements[i].Tag.Compare(elem.Tag) >= 0
+	})
+	d.Elements = slices.Insert(d.Elements, idx, elem)
+}
+
 func (d *Dataset) transferSyntax() (binary.ByteOrder, bool, error) {
 	elem, err := d.FindElementByTag(tag.TransferSyntaxUID)
 	if err != nil {
@@ -106,7 +121,7 @@ func ExhaustElementChannel(c <-chan *Element) {
 
 func flatElementsIterator(elems []*Element, elemChan chan<- *Element) {
 	for _, elem := range elems {
-		if elem.Value.ValueType() == Sequences {
+		if elem.Value != nil && elem.Value.ValueType() == Sequences {
 			elemChan <- elem
 			for _, seqItem := range elem.Value.GetValue().([]*SequenceItemValue) {
 				flatElementsIterator(seqItem.elements, elemChan)
@@ -154,7 +169,7 @@ func (d *Dataset) FlatStatefulIterator() *FlatDatasetIterator {
 func flatSliceBuilder(datasetElems []*Element) []*Element {
 	var current []*Element
 	for _, elem := range datasetElems {
-		if elem.Value.ValueType() == Sequences {
+		if elem.Value != nil && elem.Value.ValueType() == Sequences {
 			current = append(current, elem)
 			for _, seqItem := range elem.Value.GetValue().([]*SequenceItemValue) {
 				current = append(current, flatSliceBuilder(seqItem.elements)...)
@@ -172,6 +187,9 @@ func (d *Dataset) String() string {
 	var b strings.Builder
 	b.Grow(len(d.Elements) * 100) // Underestimate of the size of the final string in an attempt to limit buffer copying
 	for elem := range d.flatIteratorWithLevel() {
+		if elem.e == nil {
+			continue
+		}
 		tabs := buildTabs(elem.l)
 		var tagName string
 		if tagInfo, err := tag.Find(elem.e.Tag); err == nil {
@@ -204,10 +222,20 @@ func (d *Dataset) Equals(target *Dataset) bool {
 	return true
 }
 
+// Clone returns a deep copy of this Dataset, including the items of sequences
+// and the frames of PixelData, so that modifying either Dataset does not affect
+// the other.
+func (d *Dataset) Clone() Dataset {
+	return Dataset{Elements: cloneElements(d.Elements)}
+}
+
 type elementWithLevel struct {
 	e *Element
 	// l represents the nesting level of the Element
 	l uint
+	// item is set, and e is nil, at the start of each sequence item, which is
+	// at the level of its elements.
+	item *SequenceItemValue
 }
 
 func (d *Dataset) flatIteratorWithLevel() <-chan *elementWithLevel {
@@ -221,14 +249,15 @@ func (d *Dataset) flatIteratorWithLevel() <-chan *elementWithLevel {
 
 func flatElementsIteratorWithLevel(elems []*Element, level uint, eWithLevelChan chan<- *elementWithLevel) {
 	for _, elem := range elems {
-		if elem.Value.ValueType() == Sequences {
-			eWithLevelChan <- &elementWithLevel{elem, level}
+		if elem.Value != nil && elem.Value.ValueType() == Sequences {
+			eWithLevelChan <- &elementWithLevel{e: elem, l: level}
 			for _, seqItem := range elem.Value.GetValue().([]*SequenceItemValue) {
+				eWithLevelChan <- &elementWithLevel{l: level + 1, item: seqItem}
 				flatElementsIteratorWithLevel(seqItem.elements, level+1, eWithLevelChan)
 			}
 			continue
 		}
-		eWithLevelChan <- &elementWithLevel{elem, level}
+		eWithLevelChan <- &elementWithLevel{e: elem, l: level}
 	}
 }
 
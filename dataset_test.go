@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/wybaby168/dicom/pkg/frame"
 	"github.com/wybaby168/dicom/pkg/tag"
 )
 
@@ -71,6 +72,70 @@ func TestDataset_FindElementByTag(t *testing.T) {
 	}
 }
 
+func TestDataset_SetElement(t *testing.T) {
+	ds := Dataset{Elements: []*Element{
+		mustNewElement(tag.SOPInstanceUID, []string{"1.2.3"}),
+		mustNewElement(tag.PatientName, []string{"Bob"}),
+	}}
+	ds.SetElement(mustNewElement(tag.Modality, []string{"CT"}))
+	ds.SetElement(mustNewElement(tag.PatientName, []string{"Alice"}))
+	ds.SetElement(mustNewElement(tag.SpecificCharacterSet, []string{"ISO_IR 100"}))
+	ds.SetElement(mustNewElement(tag.Rows, []int{128}))
+
+	want := Dataset{Elements: []*Element{
+		mustNewElement(tag.SpecificCharacterSet, []string{"ISO_IR 100"}),
+		mustNewElement(tag.SOPInstanceUID, []string{"1.2.3"}),
+		mustNewElement(tag.Modality, []string{"CT"}),
+		mustNewElement(tag.PatientName, []string{"Alice"}),
+		mustNewElement(tag.Rows, []int{128}),
+	}}
+	if diff := cmp.Diff(want, ds, cmp.AllowUnexported(allValues...)); diff != "" {
+		t.Errorf("SetElement() unexpected diff (-want +got): %v", diff)
+	}
+
+	// An element out of order is replaced where it is rather than duplicated.
+	unsorted := Dataset{Elements: []*Element{
+		mustNewElement(tag.Rows, []int{128}),
+		mustNewElement(tag.PatientName, []string{"Bob"}),
+	}}
+	unsorted.SetElement(mustNewElement(tag.PatientName, []string{"Alice"}))
+	if len(unsorted.Elements) != 2 || MustGetStrings(unsorted.Elements[1].Value)[0] != "Alice" {
+		t.Errorf("SetElement() on an unsorted Dataset = %v, want PatientName replaced", unsorted.Elements)
+	}
+}
+
+func TestDataset_Clone(t *testing.T) {
+	nativeFrame := frame.NewNativeFrame[uint8](8, 1, 2, 2, 1)
+	ds := Dataset{Elements: []*Element{
+		mustNewElement(tag.PatientName, []string{"Bob"}),
+		makeSequenceElement(tag.ReferencedPatientSequence, [][]*Element{
+			{mustNewElement(tag.ReferencedSOPInstanceUID, []string{"1.2.3"})},
+		}),
+		mustNewElement(tag.PixelData, PixelDataInfo{Frames: []*frame.Frame{{NativeData: nativeFrame}}}),
+	}}
+
+	c := ds.Clone()
+	if diff := cmp.Diff(ds, c, cmp.AllowUnexported(allValues...)); diff != "" {
+		t.Fatalf("Clone() unexpected diff (-want +got): %v", diff)
+	}
+
+	MustGetStrings(ds.Elements[0].Value)[0] = "Alice"
+	item := ds.Elements[1].Value.GetValue().([]*SequenceItemValue)[0]
+	MustGetStrings(item.elements[0].Value)[0] = "4.5.6"
+	nativeFrame.RawData[0] = 1
+
+	want := Dataset{Elements: []*Element{
+		mustNewElement(tag.PatientName, []string{"Bob"}),
+		makeSequenceElement(tag.ReferencedPatientSequence, [][]*Element{
+			{mustNewElement(tag.ReferencedSOPInstanceUID, []string{"1.2.3"})},
+		}),
+		mustNewElement(tag.PixelData, PixelDataInfo{Frames: []*frame.Frame{{NativeData: frame.NewNativeFrame[uint8](8, 1, 2, 2, 1)}}}),
+	}}
+	if diff := cmp.Diff(want, c, cmp.AllowUnexported(allValues...)); diff != "" {
+		t.Errorf("Clone() changed with the original Dataset, unexpected diff (-want +got): %v", diff)
+	}
+}
+
 func TestDataset_FlatStatefulIterator(t *testing.T) {
 	cases := []struct {
 		name                 string
@@ -80,12 +145,12 @@ func TestDataset_FlatStatefulIterator(t *testing.T) {
 		{
 			name: "flat dataset",
 			dataset: Dataset{Elements: []*Element{
-				mustNewElement(tag.PatientName, []string{"Bob", "Smith"}),
-				mustNewElement(tag.PatientName, []string{"Bob", "Jones"}),
+				mustNewElement(tag.PatientName, []string{"Bob^Smith"}),
+				mustNewElement(tag.PatientName, []string{"Bob^Jones"}),
 			}},
 			expectedFlatElements: []*Element{
-				mustNewElement(tag.PatientName, []string{"Bob", "Smith"}),
-				mustNewElement(tag.PatientName, []string{"Bob", "Jones"}),
+				mustNewElement(tag.PatientName, []string{"Bob^Smith"}),
+				mustNewElement(tag.PatientName, []string{"Bob^Jones"}),
 			},
 		},
 		{
@@ -94,11 +159,11 @@ func TestDataset_FlatStatefulIterator(t *testing.T) {
 				makeSequenceElement(tag.AddOtherSequence, [][]*Element{
 					// Item 1
 					{
-						mustNewElement(tag.PatientName, []string{"Bob", "Jones"}),
+						mustNewElement(tag.PatientName, []string{"Bob^Jones"}),
 						// Nested Sequence.
 						makeSequenceElement(tag.AnatomicRegionSequence, [][]*Element{
 							{
-								mustNewElement(tag.PatientName, []string{"Bob", "Smith"}),
+								mustNewElement(tag.PatientName, []string{"Bob^Smith"}),
 							},
 						}),
 					},
@@ -109,25 +174,25 @@ func TestDataset_FlatStatefulIterator(t *testing.T) {
 				makeSequenceElement(tag.AddOtherSequence, [][]*Element{
 					// Item 1
 					{
-						mustNewElement(tag.PatientName, []string{"Bob", "Jones"}),
+						mustNewElement(tag.PatientName, []string{"Bob^Jones"}),
 						// Nested Sequence.
 						makeSequenceElement(tag.AnatomicRegionSequence, [][]*Element{
 							{
-								mustNewElement(tag.PatientName, []string{"Bob", "Smith"}),
+								mustNewElement(tag.PatientName, []string{"Bob^Smith"}),
 							},
 						}),
 					},
 				}),
 				// Then expect the inner elements
-				mustNewElement(tag.PatientName, []string{"Bob", "Jones"}),
+				mustNewElement(tag.PatientName, []string{"Bob^Jones"}),
 				// Inner SQ element
 				makeSequenceElement(tag.AnatomicRegionSequence, [][]*Element{
 					{
-						mustNewElement(tag.PatientName, []string{"Bob", "Smith"}),
+						mustNewElement(tag.PatientName, []string{"Bob^Smith"}),
 					},
 				}),
 				// Inner element of the inner SQ
-				mustNewElement(tag.PatientName, []string{"Bob", "Smith"}),
+				mustNewElement(tag.PatientName, []string{"Bob^Smith"}),
 			},
 		},
 	}
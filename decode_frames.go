@@ -0,0 +1,180 @@
+package dicom
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"runtime"
+	"sync"
+
+	"github.com/wybaby168/dicom/pkg/frame"
+)
+
+// DecodedFrame is a frame decoded by DecodeFrames or DecodePixelData.
+type DecodedFrame struct {
+	// Index is the index of the frame in the PixelData, or among the frames
+	// received, counting from 0.
+	Index int
+	Frame *frame.Frame
+	// Image is the frame decoded with GetImage.
+	Image image.Image
+}
+
+// DecodeOption represents an option that can be passed to DecodeFrames and
+// DecodePixelData.
+type DecodeOption func(*decodeOptSet)
+
+type decodeOptSet struct {
+	workers int
+	render  func(context.Context, DecodedFrame) error
+}
+
+// DecodeWorkers sets the maximum number of frames decoded at once, which
+// defaults to runtime.GOMAXPROCS(0).
+func DecodeWorkers(n int) DecodeOption {
+	return func(set *decodeOptSet) {
+		set.workers = n
+	}
+}
+
+// RenderFrames sets a function that the workers call with each frame right
+// after decoding it, concurrently and in no particular order, e.g. to encode
+// it to a file. An error stops decoding like an error decoding the frame.
+func RenderFrames(render func(ctx context.Context, f DecodedFrame) error) DecodeOption {
+	return func(set *decodeOptSet) {
+		set.render = render
+	}
+}
+
+// DecodeFrames decodes the frames received from frames, like the frame
+// channel of NewParser, with up to DecodeWorkers frames decoded at once, and
+// calls fn, if not nil, with each frame in the order they were received.
+//
+// It returns the first error decoding or rendering a frame or returned by fn,
+// in the order of the frames, or the error of ctx once it is done. The frames
+// that are still received after that are discarded until frames is closed, so
+// that the sender, e.g. Parse, does not block.
+func DecodeFrames(ctx context.Context, frames <-chan *frame.Frame, fn func(DecodedFrame) error, opts ...DecodeOption) error {
+	optSet := decodeOptSet{workers: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(&optSet)
+	}
+	if optSet.workers < 1 {
+		optSet.workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type decodeResult struct {
+		frame DecodedFrame
+		err   error
+	}
+	type decodeJob struct {
+		frame  DecodedFrame
+		result chan decodeResult
+	}
+	jobs := make(chan decodeJob)
+	// pending holds the results of the frames being decoded in the order of
+	// the frames, which also bounds how far decoding gets ahead of fn.
+	pending := make(chan chan decodeResult, optSet.workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < optSet.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				job.result <- decodeResult{frame: job.frame, err: decodeFrame(ctx, &job.frame, optSet.render)}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		defer close(pending)
+		for index := 0; ; index++ {
+			var f *frame.Frame
+			var ok bool
+			select {
+			case f, ok = <-frames:
+			case <-ctx.Done():
+			}
+			if !ok {
+				if ctx.Err() != nil {
+					go func() {
+						for range frames {
+						}
+					}()
+				}
+				return
+			}
+			result := make(chan decodeResult, 1)
+			select {
+			case pending <- result:
+			case <-ctx.Done():
+				continue
+			}
+			// The result is buffered, so the workers never block on it.
+			jobs <- decodeJob{frame: DecodedFrame{Index: index, Frame: f}, result: result}
+		}
+	}()
+
+	err := func() error {
+		for result := range pending {
+			var r decodeResult
+			select {
+			case r = <-result:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if r.err != nil {
+				return r.err
+			}
+			if fn != nil {
+				if err := fn(r.frame); err != nil {
+					return err
+				}
+			}
+		}
+		return ctx.Err()
+	}()
+	cancel()
+	for range pending {
+	}
+	wg.Wait()
+	return err
+}
+
+// decodeFrame decodes f.Frame into f.Image, and renders it if render is set.
+func decodeFrame(ctx context.Context, f *DecodedFrame, render func(context.Context, DecodedFrame) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	img, err := f.Frame.GetImage()
+	if err != nil {
+		return fmt.Errorf("error decoding frame %d: %w", f.Index, err)
+	}
+	f.Image = img
+	if render != nil {
+		if err := render(ctx, *f); err != nil {
+			return fmt.Errorf("error rendering frame %d: %w", f.Index, err)
+		}
+	}
+	return nil
+}
+
+// DecodePixelData decodes the frames of info like DecodeFrames.
+func DecodePixelData(ctx context.Context, info PixelDataInfo, fn func(DecodedFrame) error, opts ...DecodeOption) error {
+	frames := make(chan *frame.Frame)
+	go func() {
+		defer close(frames)
+		for _, f := range info.Frames {
+			select {
+			case frames <- f:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return DecodeFrames(ctx, frames, fn, opts...)
+}
@@ -0,0 +1,167 @@
+package dicom
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wybaby168/dicom/pkg/frame"
+)
+
+func TestDecodeFrames_Order(t *testing.T) {
+	frames := nativeTestFrames(20, 3, 5)
+	ch := make(chan *frame.Frame)
+	go func() {
+		defer close(ch)
+		for _, f := range frames {
+			ch <- f
+		}
+	}()
+	var running, maxRunning int32
+	render := func(ctx context.Context, f DecodedFrame) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		// Later frames finish first.
+		time.Sleep(time.Duration(len(frames)-f.Index) * time.Millisecond)
+		return nil
+	}
+	var got []int
+	err := DecodeFrames(context.Background(), ch, func(f DecodedFrame) error {
+		if f.Frame != frames[f.Index] {
+			t.Errorf("frame %d is not the frame received at that index", f.Index)
+		}
+		if f.Image == nil {
+			t.Errorf("frame %d has no image", f.Index)
+		}
+		got = append(got, f.Index)
+		return nil
+	}, DecodeWorkers(4), RenderFrames(render))
+	if err != nil {
+		t.Fatalf("DecodeFrames() unexpected error: %v", err)
+	}
+	var want []int
+	for i := range frames {
+		want = append(want, i)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("DecodeFrames() unexpected order (-want +got): %v", diff)
+	}
+	if maxRunning > 4 {
+		t.Errorf("DecodeFrames() ran %d renders at once, want at most 4", maxRunning)
+	}
+}
+
+func TestDecodeFrames_Error(t *testing.T) {
+	errRender := errors.New("render error")
+	cases := []struct {
+		name   string
+		render func(context.Context, DecodedFrame) error
+		fn     func(DecodedFrame) error
+	}{
+		{
+			name: "render error",
+			render: func(_ context.Context, f DecodedFrame) error {
+				if f.Index == 3 {
+					return errRender
+				}
+				return nil
+			},
+		},
+		{
+			name: "fn error",
+			fn: func(f DecodedFrame) error {
+				if f.Index == 3 {
+					return errRender
+				}
+				return nil
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The sender must not block once DecodeFrames returns.
+			ch := make(chan *frame.Frame)
+			sent := make(chan struct{})
+			go func() {
+				defer close(sent)
+				defer close(ch)
+				for _, f := range nativeTestFrames(50, 3, 5) {
+					ch <- f
+				}
+			}()
+			var last int
+			err := DecodeFrames(context.Background(), ch, func(f DecodedFrame) error {
+				last = f.Index
+				if tc.fn != nil {
+					return tc.fn(f)
+				}
+				return nil
+			}, DecodeWorkers(2), RenderFrames(tc.render))
+			if !errors.Is(err, errRender) {
+				t.Errorf("DecodeFrames() error = %v, want %v", err, errRender)
+			}
+			if want := 2; tc.fn == nil && last != want {
+				t.Errorf("DecodeFrames() last called fn with frame %d, want %d", last, want)
+			}
+			select {
+			case <-sent:
+			case <-time.After(5 * time.Second):
+				t.Errorf("DecodeFrames() blocked the sender of the frames")
+			}
+		})
+	}
+}
+
+func TestDecodeFrames_DecodeError(t *testing.T) {
+	ch := make(chan *frame.Frame, 1)
+	ch <- &frame.Frame{Encapsulated: true, EncapsulatedData: frame.EncapsulatedFrame{Data: []byte{1, 2, 3}}}
+	close(ch)
+	if err := DecodeFrames(context.Background(), ch, nil); err == nil {
+		t.Errorf("DecodeFrames() of an invalid JPEG frame expected error, got nil")
+	}
+}
+
+func TestDecodeFrames_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The channel is never closed, so only cancellation can stop DecodeFrames.
+	ch := make(chan *frame.Frame)
+	go func() {
+		for _, f := range nativeTestFrames(3, 3, 5) {
+			ch <- f
+		}
+	}()
+	err := DecodeFrames(ctx, ch, func(f DecodedFrame) error {
+		if f.Index == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("DecodeFrames() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestDecodePixelData(t *testing.T) {
+	info := PixelDataInfo{Frames: nativeTestFrames(5, 3, 5)}
+	var got []int
+	err := DecodePixelData(context.Background(), info, func(f DecodedFrame) error {
+		got = append(got, f.Index)
+		return nil
+	}, DecodeWorkers(3))
+	if err != nil {
+		t.Fatalf("DecodePixelData() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]int{0, 1, 2, 3, 4}, got); diff != "" {
+		t.Errorf("DecodePixelData() unexpected order (-want +got): %v", diff)
+	}
+}
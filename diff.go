@@ -0,0 +1,240 @@
+package dicom
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/wybaby168/dicom/pkg/tag"
+)
+
+// ChangeKind is the kind of a Change between two Datasets.
+type ChangeKind int
+
+const (
+	// ElementAdded is an element only present in the second Dataset.
+	ElementAdded ChangeKind = iota
+	// ElementRemoved is an element only present in the first Dataset.
+	ElementRemoved
+	// ElementChanged is an element present in both Datasets with a different
+	// VR or value.
+	ElementChanged
+)
+
+// String returns "added", "removed" or "changed".
+func (k ChangeKind) String() string {
+	switch k {
+	case ElementAdded:
+		return "added"
+	case ElementRemoved:
+		return "removed"
+	case ElementChanged:
+		return "changed"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// Change is a difference between two Datasets, see Diff.
+type Change struct {
+	Kind ChangeKind
+	// Path is the location of the element in both Datasets.
+	Path Path
+	// Old is the element in the first Dataset, or nil if Kind is ElementAdded.
+	Old *Element
+	// New is the element in the second Dataset, or nil if Kind is
+	// ElementRemoved.
+	New *Element
+}
+
+// String returns the Change formatted like
+// "~ (0010,0010) PatientName: PN [Alice] -> PN [Bob]", with a "+" for added
+// and a "-" for removed elements.
+func (c Change) String() string {
+	name := c.Path.String()
+	if info, err := tag.Find(c.Path.Tag()); err == nil {
+		name += " " + info.Keyword
+	}
+	switch c.Kind {
+	case ElementAdded:
+		return fmt.Sprintf("+ %s: %s", name, describeElement(c.New))
+	case ElementRemoved:
+		return fmt.Sprintf("- %s: %s", name, describeElement(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", name, describeElement(c.Old), describeElement(c.New))
+	}
+}
+
+// describeElement returns the VR and value of elem, summarizing sequences by
+// their number of items.
+func describeElement(elem *Element) string {
+	switch {
+	case elem == nil || elem.Value == nil:
+		return "<nil>"
+	case elem.Value.ValueType() == Sequences:
+		return fmt.Sprintf("%s %d items", elem.RawValueRepresentation, len(elem.Value.GetValue().([]*SequenceItemValue)))
+	default:
+		return fmt.Sprintf("%s %s", elem.RawValueRepresentation, elem.Value)
+	}
+}
+
+// Diff returns the elements added, removed and changed from a to b, ordered by
+// tag. Elements are compared by VR and value, not by ValueLength, which
+// depends on how an element was read. Sequences with the same number of items
+// in a and b are compared item by item, so that changes point to the nested
+// elements; other changed sequences are a single ElementChanged Change.
+func Diff(a, b Dataset) []Change {
+	var changes []Change
+	diffElements(a.Elements, b.Elements, nil, &changes)
+	return changes
+}
+
+func diffElements(a, b []*Element, parent Path, changes *[]Change) {
+	a, b = sortedByTag(a), sortedByTag(b)
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i].Tag.Compare(b[j].Tag) < 0):
+			*changes = append(*changes, Change{Kind: ElementRemoved, Path: parent.with(PathStep{Tag: a[i].Tag}), Old: a[i]})
+			i++
+		case i == len(a) || a[i].Tag.Compare(b[j].Tag) > 0:
+			*changes = append(*changes, Change{Kind: ElementAdded, Path: parent.with(PathStep{Tag: b[j].Tag}), New: b[j]})
+			j++
+		default:
+			diffElement(a[i], b[j], parent, changes)
+			i++
+			j++
+		}
+	}
+}
+
+func diffElement(a, b *Element, parent Path, changes *[]Change) {
+	path := parent.with(PathStep{Tag: a.Tag})
+	if a.RawValueRepresentation == b.RawValueRepresentation && a.Value != nil && b.Value != nil &&
+		a.Value.ValueType() == Sequences && b.Value.ValueType() == Sequences {
+		aItems := a.Value.GetValue().([]*SequenceItemValue)
+		bItems := b.Value.GetValue().([]*SequenceItemValue)
+		if len(aItems) == len(bItems) {
+			for i := range aItems {
+				itemPath := slices.Clone(path)
+				itemPath[len(itemPath)-1].Item = i
+				diffElements(aItems[i].elements, bItems[i].elements, itemPath, changes)
+			}
+			return
+		}
+	}
+	if !sameElement(a, b) {
+		*changes = append(*changes, Change{Kind: ElementChanged, Path: path, Old: a, New: b})
+	}
+}
+
+// sortedByTag returns elems sorted by tag, keeping the order of elements with
+// the same tag.
+func sortedByTag(elems []*Element) []*Element {
+	if slices.IsSortedFunc(elems, compareElementTags) {
+		return elems
+	}
+	sorted := slices.Clone(elems)
+	slices.SortStableFunc(sorted, compareElementTags)
+	return sorted
+}
+
+func compareElementTags(a, b *Element) int {
+	return a.Tag.Compare(b.Tag)
+}
+
+// sameElement is like Element.Equals, but ignores the ValueLength.
+func sameElement(a, b *Element) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Tag != b.Tag || a.RawValueRepresentation != b.RawValueRepresentation {
+		return false
+	}
+	if a.Value == nil || b.Value == nil {
+		return a.Value == b.Value
+	}
+	return a.Value.Equals(b.Value)
+}
+
+// Conflict is a Change that Merge did not apply, because the element at its
+// Path matches neither its Old nor its New element, e.g. because both sides of
+// a merge changed it.
+type Conflict struct {
+	Change Change
+	// Current is the element at Change.Path in the Dataset merged into, or nil
+	// if there is none.
+	Current *Element
+}
+
+// Error returns a description of the conflict, so that a Conflict can be used
+// as an error.
+func (c Conflict) Error() string {
+	return fmt.Sprintf("%v: conflicting %v element, found %s", c.Change.Path, c.Change.Kind, describeElement(c.Current))
+}
+
+// Merge applies changes to a Clone of ds and returns it. For a three-way
+// merge, ds is one side and changes is the Diff from the common base to the
+// other side:
+//
+//	merged, conflicts := dicom.Merge(ours, dicom.Diff(base, theirs))
+//
+// A Change is applied if the element at its Path still equals its Old
+// element, and skipped if it already equals its New element. All other
+// changes, including those nested in sequences that no longer have the item
+// of the Path, are returned as Conflicts and leave the element untouched.
+func Merge(ds Dataset, changes []Change) (Dataset, []Conflict) {
+	merged := ds.Clone()
+	var conflicts []Conflict
+	for _, change := range changes {
+		if current, ok := merged.applyChange(change); !ok {
+			conflicts = append(conflicts, Conflict{Change: change, Current: current})
+		}
+	}
+	return merged, conflicts
+}
+
+// applyChange applies change to d, returning false and the conflicting
+// element if it does not apply.
+func (d *Dataset) applyChange(change Change) (*Element, bool) {
+	if len(change.Path) == 0 {
+		return nil, false
+	}
+	elems := &d.Elements
+	for _, step := range change.Path[:len(change.Path)-1] {
+		parent := findElement(*elems, step.Tag)
+		if parent == nil || parent.Value == nil || parent.Value.ValueType() != Sequences {
+			return parent, false
+		}
+		items := parent.Value.GetValue().([]*SequenceItemValue)
+		if step.Item < 0 || step.Item >= len(items) {
+			return parent, false
+		}
+		elems = &items[step.Item].elements
+	}
+
+	t := change.Path.Tag()
+	current := findElement(*elems, t)
+	switch {
+	case sameElement(current, change.New):
+		// Already applied.
+	case !sameElement(current, change.Old):
+		return current, false
+	case change.New == nil:
+		*elems = slices.DeleteFunc(*elems, func(e *Element) bool { return e.Tag == t })
+	default:
+		item := Dataset{Elements: *elems}
+		item.SetElement(change.New.Clone())
+		*elems = item.Elements
+	}
+	return current, true
+}
+
+// findElement returns the first element of elems with tag t, or nil.
+func findElement(elems []*Element, t tag.Tag) *Element {
+	for _, elem := range elems {
+		if elem.Tag == t {
+			return elem
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package dicom
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wybaby168/dicom/pkg/tag"
+)
+
+func TestDiff(t *testing.T) {
+	a := Dataset{Elements: []*Element{
+		mustNewElement(tag.PatientName, []string{"Bob"}),
+		mustNewElement(tag.PatientID, []string{"123"}),
+		makeSequenceElement(tag.ReferencedPatientSequence, [][]*Element{
+			{mustNewElement(tag.ReferencedSOPInstanceUID, []string{"1.2.3"})},
+			{mustNewElement(tag.ReferencedSOPInstanceUID, []string{"1.2.4"})},
+		}),
+		makeSequenceElement(tag.ReferencedImageSequence, [][]*Element{
+			{mustNewElement(tag.ReferencedSOPInstanceUID, []string{"1.2.5"})},
+		}),
+		mustNewElement(tag.Rows, []int{128}),
+	}}
+	b := Dataset{Elements: []*Element{
+		mustNewElement(tag.Rows, []int{256}),
+		mustNewElement(tag.PatientName, []string{"Bob"}),
+		mustNewElement(tag.PatientBirthDate, []string{"20000101"}),
+		makeSequenceElement(tag.ReferencedPatientSequence, [][]*Element{
+			{mustNewElement(tag.ReferencedSOPInstanceUID, []string{"1.2.3"})},
+			{mustNewElement(tag.ReferencedSOPInstanceUID, []string{"1.2.9"})},
+		}),
+		makeSequenceElement(tag.ReferencedImageSequence, [][]*Element{}),
+	}}
+	b.Elements[1].ValueLength = 4 // Only differs in ValueLength.
+
+	want := []Change{
+		{
+			Kind: ElementChanged,
+			Path: Path{{Tag: tag.ReferencedPatientSequence, Item: 1}, {Tag: tag.ReferencedSOPInstanceUID}},
+			Old:  a.Elements[2].Value.GetValue().([]*SequenceItemValue)[1].elements[0],
+			New:  b.Elements[3].Value.GetValue().([]*SequenceItemValue)[1].elements[0],
+		},
+		{
+			Kind: ElementChanged,
+			Path: Path{{Tag: tag.ReferencedImageSequence}},
+			Old:  a.Elements[3],
+			New:  b.Elements[4],
+		},
+		{Kind: ElementRemoved, Path: Path{{Tag: tag.PatientID}}, Old: a.Elements[1]},
+		{Kind: ElementAdded, Path: Path{{Tag: tag.PatientBirthDate}}, New: b.Elements[2]},
+		{Kind: ElementChanged, Path: Path{{Tag: tag.Rows}}, Old: a.Elements[4], New: b.Elements[0]},
+	}
+	got := Diff(a, b)
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(allValues...)); diff != "" {
+		t.Errorf("Diff() unexpected diff (-want +got): %v", diff)
+	}
+	if got := Diff(a, a.Clone()); got != nil {
+		t.Errorf("Diff(a, a.Clone()) = %v, want no changes", got)
+	}
+}
+
+func TestChange_String(t *testing.T) {
+	cases := []struct {
+		change Change
+		want   string
+	}{
+		{
+			change: Change{Kind: ElementAdded, Path: Path{{Tag: tag.PatientName}}, New: mustNewElement(tag.PatientName, []string{"Bob"})},
+			want:   "+ (0010,0010) PatientName: PN [Bob]",
+		},
+		{
+			change: Change{Kind: ElementRemoved, Path: Path{{Tag: tag.Rows}}, Old: mustNewElement(tag.Rows, []int{128})},
+			want:   "- (0028,0010) Rows: US [128]",
+		},
+		{
+			change: Change{
+				Kind: ElementChanged,
+				Path: Path{{Tag: tag.ReferencedPatientSequence}},
+				Old:  makeSequenceElement(tag.ReferencedPatientSequence, [][]*Element{{}}),
+				New:  makeSequenceElement(tag.ReferencedPatientSequence, nil),
+			},
+			want: "~ (0008,1120) ReferencedPatientSequence: SQ 1 items -> SQ 0 items",
+		},
+	}
+	for _, tc := range cases {
+		if got := tc.change.String(); got != tc.want {
+			t.Errorf("Change.String() = %q, want %q", got, tc.want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := Dataset{Elements: []*Element{
+		mustNewElement(tag.PatientName, []string{"Bob"}),
+		mustNewElement(tag.PatientID, []string{"123"}),
+		mustNewElement(tag.PatientSex, []string{"M"}),
+		makeSequenceElement(tag.ReferencedPatientSequence, [][]*Element{
+			{mustNewElement(tag.ReferencedSOPInstanceUID, []string{"1.2.3"})},
+		}),
+		mustNewElement(tag.Rows, []int{128}),
+	}}
+
+	// Ours changes PatientName and removes the sequence item.
+	ours := base.Clone()
+	ours.Elements[0] = mustNewElement(tag.PatientName, []string{"Alice"})
+	ours.Elements[3] = makeSequenceElement(tag.ReferencedPatientSequence, nil)
+
+	// Theirs changes PatientName differently, changes the sequence item,
+	// removes PatientSex, adds PatientBirthDate and changes Rows.
+	theirs := Dataset{Elements: []*Element{
+		mustNewElement(tag.PatientName, []string{"Carol"}),
+		mustNewElement(tag.PatientID, []string{"123"}),
+		mustNewElement(tag.PatientBirthDate, []string{"20000101"}),
+		makeSequenceElement(tag.ReferencedPatientSequence, [][]*Element{
+			{mustNewElement(tag.ReferencedSOPInstanceUID, []string{"1.2.4"})},
+		}),
+		mustNewElement(tag.Rows, []int{256}),
+	}}
+
+	changes := Diff(base, theirs)
+	merged, conflicts := Merge(ours, changes)
+
+	want := Dataset{Elements: []*Element{
+		mustNewElement(tag.PatientName, []string{"Alice"}),
+		mustNewElement(tag.PatientID, []string{"123"}),
+		mustNewElement(tag.PatientBirthDate, []string{"20000101"}),
+		makeSequenceElement(tag.ReferencedPatientSequence, nil),
+		mustNewElement(tag.Rows, []int{256}),
+	}}
+	if diff := cmp.Diff(want, merged, cmp.AllowUnexported(allValues...)); diff != "" {
+		t.Errorf("Merge() unexpected dataset diff (-want +got): %v", diff)
+	}
+
+	var conflictPaths []string
+	for _, c := range conflicts {
+		conflictPaths = append(conflictPaths, c.Change.Path.String())
+	}
+	wantConflictPaths := []string{"(0008,1120)[0].(0008,1155)", "(0010,0010)"}
+	if diff := cmp.Diff(wantConflictPaths, conflictPaths); diff != "" {
+		t.Errorf("Merge() unexpected conflicts (-want +got): %v", diff)
+	}
+	if got := conflicts[1].Current; !sameElement(got, ours.Elements[0]) {
+		t.Errorf("Merge() conflict Current = %v, want %v", got, ours.Elements[0])
+	}
+
+	if _, conflicts := Merge(merged, changes); len(conflicts) != 2 {
+		t.Errorf("Merge() of already merged changes returned %d conflicts, want the same 2", len(conflicts))
+	}
+	if got := MustGetInts(ours.Elements[4].Value); got[0] != 128 {
+		t.Errorf("Merge() modified its input Dataset, Rows = %v, want [128]", got)
+	}
+}
@@ -0,0 +1,650 @@
+package dicom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/wybaby168/dicom/pkg/dicomio"
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/uid"
+)
+
+// DumpOption represents an option that can be passed to Dump.
+type DumpOption func(*dumpOptSet)
+
+// dumpOptSet represents the flattened option set after all DumpOptions have
+// been applied.
+type dumpOptSet struct {
+	maxValueLength  int
+	showOffsets     bool
+	hidePrivateTags bool
+	showRawVR       bool
+	color           bool
+}
+
+func toDumpOptSet(opts ...DumpOption) dumpOptSet {
+	optSet := dumpOptSet{maxValueLength: defaultDumpMaxValueLength}
+	for _, opt := range opts {
+		opt(&optSet)
+	}
+	return optSet
+}
+
+// defaultDumpMaxValueLength is the number of characters values are shortened
+// to by default, like dcmdump does.
+const defaultDumpMaxValueLength = 64
+
+// MaxValueLength shortens the values printed by Dump to n characters followed
+// by "...". Values are not shortened if n is 0 or less. The default is 64.
+func MaxValueLength(n int) DumpOption {
+	return func(set *dumpOptSet) {
+		set.maxValueLength = n
+	}
+}
+
+// ShowOffsets prefixes each line printed by Dump with the hexadecimal byte
+// offset of the element in the file Write writes for the Dataset. If the
+// Dataset was parsed with RecordOffsets, the offsets in the parsed file are
+// printed instead, and elements added since have no offset.
+func ShowOffsets() DumpOption {
+	return func(set *dumpOptSet) {
+		set.showOffsets = true
+	}
+}
+
+// HidePrivateTags leaves out private elements, and any elements nested in
+// them, when dumping.
+func HidePrivateTags() DumpOption {
+	return func(set *dumpOptSet) {
+		set.hidePrivateTags = true
+	}
+}
+
+// ShowRawVR prints the VR elements were read or created with, e.g. UN for
+// elements of unknown VR in explicit VR files. By default, Dump prints the VR
+// from the data dictionary for UN elements of tags with a single VR.
+func ShowRawVR() DumpOption {
+	return func(set *dumpOptSet) {
+		set.showRawVR = true
+	}
+}
+
+// ColorOutput highlights tags, VRs, values and comments with ANSI escape
+// sequences, for terminals.
+func ColorOutput() DumpOption {
+	return func(set *dumpOptSet) {
+		set.color = true
+	}
+}
+
+// ANSI escape sequences for ColorOutput.
+const (
+	colorTag     = "\x1b[36m"
+	colorVR      = "\x1b[33m"
+	colorValue   = "\x1b[32m"
+	colorComment = "\x1b[90m"
+	colorReset   = "\x1b[0m"
+)
+
+// Dump writes a human readable representation of ds to w, in the format of
+// dcmtk's dcmdump: one line per element, including elements nested in
+// sequences, with the tag, VR and value followed by a comment with the value
+// length, the number of values and the keyword of the element:
+//
+//	(0010,0010) PN [Doe^John]                               #   8, 1 PatientName
+//
+// Sequences, items and encapsulated PixelData are framed by their items and
+// delimitation items, and undefined lengths are printed as "u/l".
+func Dump(w io.Writer, ds Dataset, opts ...DumpOption) error {
+	d := &dumper{w: w, opts: toDumpOptSet(opts...)}
+	if d.opts.showOffsets {
+		d.offsets = computeOffsets(ds)
+		if slices.ContainsFunc(ds.Elements, func(e *Element) bool { return e.Position != nil }) {
+			d.offsets = recordedOffsets(ds)
+		}
+	}
+
+	hasMeta := false
+	for _, elem := range ds.Elements {
+		hasMeta = hasMeta || elem.Tag.Group == tag.MetadataGroup
+	}
+	if hasMeta {
+		d.printf("# Dicom-File Format\n")
+	}
+
+	elems := ds.flatIteratorWithLevel()
+	defer func() {
+		for range elems {
+		}
+	}()
+	section := -1
+	skipBelow := -1
+	for elem := range elems {
+		level := int(elem.l)
+		if skipBelow >= 0 {
+			if level > skipBelow {
+				continue
+			}
+			skipBelow = -1
+		}
+		d.closeOpen(level, elem.item != nil)
+
+		if elem.item != nil {
+			d.printItem(elem.item, level)
+			continue
+		}
+		if d.opts.hidePrivateTags && tag.IsPrivate(elem.e.Tag.Group) {
+			skipBelow = level
+			continue
+		}
+		if level == 0 {
+			isMeta := elem.e.Tag.Group == tag.MetadataGroup
+			if isMeta && section != 0 {
+				section = 0
+				d.printf("\n# Dicom-Meta-Information-Header\n# Used TransferSyntax: %s\n", uid.MustLookup(uid.ExplicitVRLittleEndian).Name)
+			} else if !isMeta && section != 1 {
+				section = 1
+				if hasMeta {
+					d.printf("\n")
+				}
+				d.printf("# Dicom-Data-Set\n# Used TransferSyntax: %s\n", transferSyntaxName(ds))
+			}
+		}
+		d.printElement(elem.e, level)
+		if d.err != nil {
+			return d.err
+		}
+	}
+	d.closeOpen(0, false)
+	return d.err
+}
+
+// transferSyntaxName returns the name of the transfer syntax of ds.
+func transferSyntaxName(ds Dataset) string {
+	elem, err := ds.FindElementByTag(tag.TransferSyntaxUID)
+	if err != nil || elem.Value == nil || elem.Value.ValueType() != Strings || len(MustGetStrings(elem.Value)) == 0 {
+		return "Unknown Transfer Syntax"
+	}
+	ts := MustGetStrings(elem.Value)[0]
+	if info, err := uid.Lookup(ts); err == nil {
+		return info.Name
+	}
+	return ts
+}
+
+// dumper holds the state of Dump.
+type dumper struct {
+	w       io.Writer
+	opts    dumpOptSet
+	offsets *dumpOffsets
+	// open are the sequences and items that still need their delimitation
+	// items printed.
+	open []openStructure
+	err  error
+}
+
+// openStructure is a sequence, or if item is set, a sequence item, at level.
+type openStructure struct {
+	level int
+	seq   *Element
+	item  *SequenceItemValue
+}
+
+func (d *dumper) printf(format string, args ...any) {
+	if d.err == nil {
+		_, d.err = fmt.Fprintf(d.w, format, args...)
+	}
+}
+
+// closeOpen prints the delimitation items of the items and sequences that end
+// before an element at level, or before an item at level if isItem is true.
+func (d *dumper) closeOpen(level int, isItem bool) {
+	for len(d.open) > 0 {
+		top := d.open[len(d.open)-1]
+		if top.item != nil && (top.level > level || (isItem && top.level == level)) {
+			value := "(ItemDelimitationItem)"
+			if pos := top.item.position; pos != nil && !pos.UndefinedLength() {
+				value = "(ItemDelimitationItem for re-encoding)"
+			}
+			d.line(2*top.level-1, d.offsets.itemEnd(top.item), tag.ItemDelimitationItem, "na", value, "0", 0)
+		} else if top.item == nil && top.level >= level {
+			value := "(SequenceDelimitationItem)"
+			if top.seq.ValueLength != tag.VLUndefinedLength {
+				value = "(SequenceDelimitationItem for re-encod.)"
+			}
+			d.line(2*top.level, d.offsets.sequenceEnd(top.seq), tag.SequenceDelimitationItem, "na", value, "0", 0)
+		} else {
+			return
+		}
+		d.open = d.open[:len(d.open)-1]
+	}
+}
+
+func (d *dumper) printItem(item *SequenceItemValue, level int) {
+	form, length := "undefined", "u/l"
+	if pos := item.position; pos != nil && !pos.UndefinedLength() {
+		form, length = "explicit", formatValueLength(pos.ValueLength)
+	}
+	value := fmt.Sprintf("(Item with %s length #=%d)", form, len(item.elements))
+	d.line(2*level-1, d.offsets.item(item), tag.Item, "na", value, length, 1)
+	d.open = append(d.open, openStructure{level: level, item: item})
+}
+
+func (d *dumper) printElement(elem *Element, level int) {
+	vr := d.vr(elem)
+	length := formatValueLength(elem.ValueLength)
+	offset := d.offsets.element(elem)
+	if elem.Value == nil {
+		d.line(2*level, offset, elem.Tag, vr, "(no value available)", length, 0)
+		return
+	}
+
+	switch elem.Value.ValueType() {
+	case Sequences:
+		items := elem.Value.GetValue().([]*SequenceItemValue)
+		form := "undefined"
+		if elem.ValueLength != tag.VLUndefinedLength {
+			form = "explicit"
+		}
+		d.line(2*level, offset, elem.Tag, vr, fmt.Sprintf("(Sequence with %s length #=%d)", form, len(items)), length, 1)
+		d.open = append(d.open, openStructure{level: level, seq: elem})
+	case PixelData:
+		d.printPixelData(elem, vr, level, offset)
+	default:
+		value, vm := d.formatValue(elem, vr)
+		d.line(2*level, offset, elem.Tag, vr, value, length, vm)
+	}
+}
+
+func (d *dumper) printPixelData(elem *Element, vr string, level int, offset int64) {
+	info := MustGetPixelDataInfo(elem.Value)
+	length := formatValueLength(elem.ValueLength)
+	switch {
+	case info.IntentionallySkipped:
+		d.line(2*level, offset, elem.Tag, vr, "(not loaded)", length, 1)
+	case info.IntentionallyUnprocessed:
+		d.line(2*level, offset, elem.Tag, vr, d.formatBytes(info.UnprocessedValueData, vr), length, 1)
+	case info.IsEncapsulated:
+		d.line(2*level, offset, elem.Tag, vr, fmt.Sprintf("(PixelSequence #=%d)", len(info.Frames)+1), "u/l", 1)
+		table := make([]byte, 0, 4*len(info.Offsets))
+		for _, o := range info.Offsets {
+			table = binary.LittleEndian.AppendUint32(table, o)
+		}
+		itemOffset := d.offsets.value(elem)
+		fragments := [][]byte{table}
+		for _, f := range info.Frames {
+			fragments = append(fragments, f.EncapsulatedData.Data)
+		}
+		for _, data := range fragments {
+			value := "(no value available)"
+			if len(data) > 0 {
+				value = d.formatBytes(data, "OB")
+			}
+			d.line(2*level+1, itemOffset, tag.Item, "pi", value, strconv.Itoa(len(data)), 1)
+			if itemOffset >= 0 {
+				itemOffset += 8 + int64(len(data))
+			}
+		}
+		d.line(2*level, itemOffset, tag.SequenceDelimitationItem, "na", "(SequenceDelimitationItem)", "0", 0)
+	case len(info.Frames) == 0:
+		d.line(2*level, offset, elem.Tag, vr, "(no value available)", length, 0)
+	case info.Frames[0].Encapsulated:
+		// Fallback of unparsable native PixelData, see ParseErr.
+		d.line(2*level, offset, elem.Tag, vr, d.formatBytes(info.Frames[0].EncapsulatedData.Data, vr), length, 1)
+	default:
+		var samples []string
+		for _, f := range info.Frames {
+			native := f.NativeData
+			width := (native.BitsPerSample() + 3) / 4
+			for p := 0; p < native.Rows()*native.Cols() && !d.exceedsLimit(samples); p++ {
+				pixel, err := native.GetPixel(p%native.Cols(), p/native.Cols())
+				if err != nil {
+					break
+				}
+				for _, sample := range pixel {
+					samples = append(samples, fmt.Sprintf("%0*x", width, sample))
+				}
+			}
+		}
+		d.line(2*level, offset, elem.Tag, vr, strings.Join(samples, "\\"), length, 1)
+	}
+}
+
+// exceedsLimit returns true if joining values already exceeds the maximum
+// value length, so that no more need to be formatted.
+func (d *dumper) exceedsLimit(values []string) bool {
+	return d.opts.maxValueLength > 0 && len(values)*2 > d.opts.maxValueLength
+}
+
+// vr returns the VR to print for elem.
+func (d *dumper) vr(elem *Element) string {
+	vr := elem.RawValueRepresentation
+	if d.opts.showRawVR || (vr != "" && vr != "UN") {
+		return vr
+	}
+	if info, err := tag.Find(elem.Tag); err == nil && len(info.VRs) == 1 {
+		return info.VRs[0]
+	}
+	if vr == "" {
+		return "??"
+	}
+	return vr
+}
+
+// formatValue returns the value of elem formatted like dcmdump, and the
+// number of values.
+func (d *dumper) formatValue(elem *Element, vr string) (string, int) {
+	var values []string
+	switch elem.Value.ValueType() {
+	case Strings:
+		strs := MustGetStrings(elem.Value)
+		if len(strs) == 0 || (len(strs) == 1 && strs[0] == "") {
+			return "(no value available)", 0
+		}
+		if vr == "UI" && len(strs) == 1 {
+			// Only print keywords that identify the UID, so that ParseDump can
+			// read them back.
+			if info, err := uid.Lookup(strs[0]); err == nil {
+				if found, err := uid.LookupKeyword(info.Keyword()); err == nil && found.UID == info.UID {
+					return "=" + info.Keyword(), 1
+				}
+			}
+		}
+		return "[" + strings.Join(strs, "\\") + "]", len(strs)
+	case Bytes:
+		data := MustGetBytes(elem.Value)
+		if len(data) == 0 {
+			return "(no value available)", 0
+		}
+		return d.formatBytes(data, vr), 1
+	case Ints:
+		for _, v := range MustGetInts(elem.Value) {
+			values = append(values, strconv.Itoa(v))
+		}
+	case Int64s:
+		for _, v := range MustGetInt64s(elem.Value) {
+			values = append(values, strconv.FormatInt(v, 10))
+		}
+	case Uint64s:
+		for _, v := range MustGetUint64s(elem.Value) {
+			values = append(values, strconv.FormatUint(v, 10))
+		}
+	case Floats:
+		bitSize := 64
+		if vr == "FL" || vr == "OF" {
+			bitSize = 32
+		}
+		for _, v := range MustGetFloats(elem.Value) {
+			values = append(values, strconv.FormatFloat(v, 'g', -1, bitSize))
+		}
+	case Tags:
+		for _, t := range MustGetTags(elem.Value) {
+			values = append(values, fmt.Sprintf("(%04x,%04x)", t.Group, t.Element))
+		}
+	default:
+		return elem.Value.String(), 1
+	}
+	if len(values) == 0 {
+		return "(no value available)", 0
+	}
+	return strings.Join(values, "\\"), len(values)
+}
+
+// formatBytes returns data as backslash separated hexadecimal words of the
+// word size of vr, e.g. "0000\ffff" for OW.
+func (d *dumper) formatBytes(data []byte, vr string) string {
+	wordSize := 1
+	if parsed, err := tag.ParseVR(vr); err == nil && parsed.WordSize() > 1 && len(data)%parsed.WordSize() == 0 {
+		wordSize = parsed.WordSize()
+	}
+	var words []string
+	for i := 0; i < len(data) && !d.exceedsLimit(words); i += wordSize {
+		var word uint64
+		for j := wordSize - 1; j >= 0; j-- {
+			word = word<<8 | uint64(data[i+j])
+		}
+		words = append(words, fmt.Sprintf("%0*x", 2*wordSize, word))
+	}
+	return strings.Join(words, "\\")
+}
+
+func formatValueLength(vl uint32) string {
+	if vl == tag.VLUndefinedLength {
+		return "u/l"
+	}
+	return strconv.FormatUint(uint64(vl), 10)
+}
+
+// line prints a line at depth, in the format of dcmdump.
+func (d *dumper) line(depth int, offset int64, t tag.Tag, vr, value, length string, vm int) {
+	if limit := d.opts.maxValueLength; limit > 0 && utf8.RuneCountInString(value) > limit {
+		value = string([]rune(value)[:limit]) + "..."
+	}
+	name := "Unknown Tag & Data"
+	if info, err := tag.Find(t); err == nil {
+		name = info.Keyword
+	} else if tag.IsPrivateCreator(t) {
+		name = "PrivateCreator"
+	}
+
+	var prefix string
+	if d.opts.showOffsets {
+		prefix = "          "
+		if offset >= 0 {
+			prefix = fmt.Sprintf("%08x: ", offset)
+		}
+	}
+	tagString := fmt.Sprintf("(%04x,%04x)", t.Group, t.Element)
+	padding := strings.Repeat(" ", max(0, 40-utf8.RuneCountInString(value)))
+	comment := fmt.Sprintf("# %3s,%2d %s", length, vm, name)
+	if d.opts.color {
+		tagString = colorTag + tagString + colorReset
+		vr = colorVR + vr + colorReset
+		value = colorValue + value + colorReset
+		comment = colorComment + comment + colorReset
+	}
+	d.printf("%s%s%s %s %s%s %s\n", prefix, strings.Repeat("  ", depth), tagString, vr, value, padding, comment)
+}
+
+// dumpOffsets are the byte offsets of elements, items and delimitation items
+// in the file Write writes for a Dataset, or in the file it was parsed from.
+// A nil *dumpOffsets has no offsets.
+type dumpOffsets struct {
+	elements     map[*Element]int64
+	values       map[*Element]int64
+	items        map[*SequenceItemValue]int64
+	itemEnds     map[*SequenceItemValue]int64
+	sequenceEnds map[*Element]int64
+}
+
+func (o *dumpOffsets) element(elem *Element) int64 {
+	if o == nil {
+		return -1
+	}
+	return lookupOffset(o.elements, elem)
+}
+
+func (o *dumpOffsets) value(elem *Element) int64 {
+	if o == nil {
+		return -1
+	}
+	return lookupOffset(o.values, elem)
+}
+
+func (o *dumpOffsets) sequenceEnd(elem *Element) int64 {
+	if o == nil {
+		return -1
+	}
+	return lookupOffset(o.sequenceEnds, elem)
+}
+
+func (o *dumpOffsets) item(item *SequenceItemValue) int64 {
+	if o == nil {
+		return -1
+	}
+	return lookupOffset(o.items, item)
+}
+
+func (o *dumpOffsets) itemEnd(item *SequenceItemValue) int64 {
+	if o == nil {
+		return -1
+	}
+	return lookupOffset(o.itemEnds, item)
+}
+
+func newDumpOffsets() *dumpOffsets {
+	return &dumpOffsets{
+		elements:     map[*Element]int64{},
+		values:       map[*Element]int64{},
+		items:        map[*SequenceItemValue]int64{},
+		itemEnds:     map[*SequenceItemValue]int64{},
+		sequenceEnds: map[*Element]int64{},
+	}
+}
+
+func lookupOffset[K comparable](m map[K]int64, key K) int64 {
+	if offset, ok := m[key]; ok {
+		return offset
+	}
+	return -1
+}
+
+// offsetCounter is an io.Writer that counts the bytes written to it.
+type offsetCounter struct {
+	n int64
+}
+
+func (c *offsetCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// computeOffsets lays out ds the way Write does: the preamble, the file meta
+// elements in explicit VR little endian, and the other elements in the
+// transfer syntax of ds, with sequences and items of undefined length.
+func computeOffsets(ds Dataset) *dumpOffsets {
+	o := newDumpOffsets()
+	var counter offsetCounter
+	w := &Writer{
+		writer: dicomio.NewWriter(&counter, binary.LittleEndian, false),
+		optSet: &writeOptSet{skipVRVerification: true, skipValueTypeVerification: true},
+	}
+
+	// headerLength returns the length of the tag, VR and value length of an
+	// element.
+	headerLength := func(elem *Element) int64 {
+		if _, implicit := w.writer.GetTransferSyntax(); !implicit {
+			if vr, err := tag.ParseVR(elem.RawValueRepresentation); err == nil {
+				return int64(vr.HeaderLength())
+			}
+			if elem.Value != nil && elem.Value.ValueType() == Sequences {
+				return 12
+			}
+		}
+		return 8
+	}
+
+	// measure returns the encoded length of an element that is not a
+	// sequence.
+	measure := func(elem *Element) int64 {
+		before := counter.n
+		if err := writeElement(w.writer, elem, *w.optSet); err == nil {
+			return counter.n - before
+		}
+		length := headerLength(elem)
+		if elem.ValueLength != tag.VLUndefinedLength {
+			length += int64(elem.ValueLength)
+		}
+		return length
+	}
+
+	var layout func(elems []*Element, pos int64) int64
+	layout = func(elems []*Element, pos int64) int64 {
+		for _, elem := range elems {
+			o.elements[elem] = pos
+			o.values[elem] = pos + headerLength(elem)
+			w.setCodingSystem(elem)
+			if elem.Value == nil || elem.Value.ValueType() != Sequences {
+				pos += measure(elem)
+				continue
+			}
+			pos = o.values[elem]
+			for _, item := range elem.Value.GetValue().([]*SequenceItemValue) {
+				o.items[item] = pos
+				pos = layout(item.elements, pos+8)
+				o.itemEnds[item] = pos
+				pos += 8
+			}
+			o.sequenceEnds[elem] = pos
+			pos += 8
+		}
+		return pos
+	}
+
+	// Write writes the file meta elements after the group length it
+	// computes, in this order.
+	metaOrder := []tag.Tag{tag.FileMetaInformationVersion, tag.MediaStorageSOPClassUID, tag.MediaStorageSOPInstanceUID, tag.TransferSyntaxUID}
+	var meta, body []*Element
+	for _, t := range metaOrder {
+		if elem, err := ds.FindElementByTag(t); err == nil {
+			meta = append(meta, elem)
+		}
+	}
+	for _, elem := range ds.Elements {
+		switch {
+		case elem.Tag == tag.FileMetaInformationGroupLength:
+			o.elements[elem] = int64(128 + len(magicWord))
+		case elem.Tag.Group != tag.MetadataGroup:
+			body = append(body, elem)
+		case !slices.Contains(metaOrder, elem.Tag):
+			meta = append(meta, elem)
+		}
+	}
+	// The group length is an UL element of 12 bytes.
+	pos := layout(meta, int64(128+len(magicWord)+12))
+	bo, implicit, err := ds.transferSyntax()
+	if err != nil {
+		bo, implicit = binary.LittleEndian, true
+	}
+	w.writer.SetTransferSyntax(bo, implicit)
+	layout(body, pos)
+	return o
+}
+
+// recordedOffsets returns the offsets of the Positions recorded by
+// RecordOffsets. Elements and items without a Position, and the delimitation
+// items Dump prints for values of explicit length, have no offset.
+func recordedOffsets(ds Dataset) *dumpOffsets {
+	o := newDumpOffsets()
+	var record func(elems []*Element)
+	record = func(elems []*Element) {
+		for _, elem := range elems {
+			if pos := elem.Position; pos != nil {
+				o.elements[elem] = pos.Offset
+				o.values[elem] = pos.ValueOffset
+				if pos.UndefinedLength() {
+					o.sequenceEnds[elem] = pos.EndOffset - 8
+				}
+			}
+			if elem.Value == nil || elem.Value.ValueType() != Sequences {
+				continue
+			}
+			for _, item := range elem.Value.GetValue().([]*SequenceItemValue) {
+				if pos := item.position; pos != nil {
+					o.items[item] = pos.Offset
+					if pos.UndefinedLength() {
+						o.itemEnds[item] = pos.EndOffset - 8
+					}
+				}
+				record(item.elements)
+			}
+		}
+	}
+	record(ds.Elements)
+	return o
+}
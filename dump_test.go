@@ -0,0 +1,258 @@
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wybaby168/dicom/pkg/frame"
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/uid"
+)
+
+func dumpTestDataset() Dataset {
+	pixelData := mustNewElement(tag.PixelData, PixelDataInfo{
+		IsEncapsulated: true,
+		Frames: []*frame.Frame{
+			{Encapsulated: true, EncapsulatedData: frame.EncapsulatedFrame{Data: []byte{0xff, 0xd8}}},
+		},
+	})
+	pixelData.ValueLength = tag.VLUndefinedLength
+	return Dataset{Elements: []*Element{
+		mustNewElement(tag.MediaStorageSOPClassUID, []string{uid.CTImageStorage}),
+		mustNewElement(tag.TransferSyntaxUID, []string{uid.ExplicitVRLittleEndian}),
+		mustNewElement(tag.PatientName, []string{"Doe^John"}),
+		mustNewElement(tag.PatientID, []string{""}),
+		makeSequenceElement(tag.ReferencedSeriesSequence, [][]*Element{
+			{mustNewElement(tag.SeriesInstanceUID, []string{"1.2.3"})},
+		}),
+		mustNewPrivateElement(tag.Tag{Group: 0x0029, Element: 0x0010}, "LO", []string{"ACME"}),
+		mustNewPrivateElement(tag.Tag{Group: 0x0029, Element: 0x1001}, "UN", []byte{1, 2, 3, 4}),
+		mustNewElement(tag.Rows, []int{2}),
+		mustNewElement(tag.Columns, []int{2}),
+		pixelData,
+	}}
+}
+
+func TestDump(t *testing.T) {
+	var out strings.Builder
+	if err := Dump(&out, dumpTestDataset()); err != nil {
+		t.Fatalf("Dump() unexpected error: %v", err)
+	}
+	want := `# Dicom-File Format
+
+# Dicom-Meta-Information-Header
+# Used TransferSyntax: Explicit VR Little Endian
+(0002,0002) UI =CTImageStorage                          #   0, 1 MediaStorageSOPClassUID
+(0002,0010) UI =ExplicitVRLittleEndian                  #   0, 1 TransferSyntaxUID
+
+# Dicom-Data-Set
+# Used TransferSyntax: Explicit VR Little Endian
+(0010,0010) PN [Doe^John]                               #   0, 1 PatientName
+(0010,0020) LO (no value available)                     #   0, 0 PatientID
+(0008,1115) SQ (Sequence with explicit length #=1)      #   0, 1 ReferencedSeriesSequence
+  (fffe,e000) na (Item with undefined length #=1)         # u/l, 1 Item
+    (0020,000e) UI [1.2.3]                                  #   0, 1 SeriesInstanceUID
+  (fffe,e00d) na (ItemDelimitationItem)                   #   0, 0 ItemDelimitationItem
+(fffe,e0dd) na (SequenceDelimitationItem for re-encod.) #   0, 0 SequenceDelimitationItem
+(0029,0010) LO [ACME]                                   #   0, 1 PrivateCreator
+(0029,1001) UN 01\02\03\04                              #   0, 1 Unknown Tag & Data
+(0028,0010) US 2                                        #   0, 1 Rows
+(0028,0011) US 2                                        #   0, 1 Columns
+(7fe0,0010) OW (PixelSequence #=2)                      # u/l, 1 PixelData
+  (fffe,e000) pi (no value available)                     #   0, 1 Item
+  (fffe,e000) pi ff\d8                                    #   2, 1 Item
+(fffe,e0dd) na (SequenceDelimitationItem)               #   0, 0 SequenceDelimitationItem
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("Dump() unexpected output (-want +got):\n%s", diff)
+	}
+}
+
+func TestDump_NilValue(t *testing.T) {
+	ds := Dataset{Elements: []*Element{
+		{Tag: tag.PatientName, ValueRepresentation: tag.VRStringList, RawValueRepresentation: "PN"},
+		makeSequenceElement(tag.ReferencedSeriesSequence, [][]*Element{
+			{{Tag: tag.SeriesInstanceUID, ValueRepresentation: tag.VRStringList, RawValueRepresentation: "UI"}},
+		}),
+	}}
+	var out strings.Builder
+	if err := Dump(&out, ds); err != nil {
+		t.Fatalf("Dump() unexpected error: %v", err)
+	}
+	want := `# Dicom-Data-Set
+# Used TransferSyntax: Unknown Transfer Syntax
+(0010,0010) PN (no value available)                     #   0, 0 PatientName
+(0008,1115) SQ (Sequence with explicit length #=1)      #   0, 1 ReferencedSeriesSequence
+  (fffe,e000) na (Item with undefined length #=1)         # u/l, 1 Item
+    (0020,000e) UI (no value available)                     #   0, 0 SeriesInstanceUID
+  (fffe,e00d) na (ItemDelimitationItem)                   #   0, 0 ItemDelimitationItem
+(fffe,e0dd) na (SequenceDelimitationItem for re-encod.) #   0, 0 SequenceDelimitationItem
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("Dump() unexpected output (-want +got):\n%s", diff)
+	}
+}
+
+func TestDump_Options(t *testing.T) {
+	cases := []struct {
+		name        string
+		ds          Dataset
+		opts        []DumpOption
+		wantLine    string
+		notWantLine string
+	}{
+		{
+			name:     "values shortened by default",
+			ds:       Dataset{Elements: []*Element{mustNewElement(tag.StudyDescription, []string{strings.Repeat("a", 70)})}},
+			wantLine: "(0008,1030) LO [" + strings.Repeat("a", 63) + "... #   0, 1 StudyDescription",
+		},
+		{
+			name:     "MaxValueLength",
+			ds:       Dataset{Elements: []*Element{mustNewElement(tag.StudyDescription, []string{"abcdefgh"})}},
+			opts:     []DumpOption{MaxValueLength(4)},
+			wantLine: "(0008,1030) LO [abc...                                  #   0, 1 StudyDescription",
+		},
+		{
+			name:     "MaxValueLength unlimited",
+			ds:       Dataset{Elements: []*Element{mustNewElement(tag.StudyDescription, []string{strings.Repeat("a", 70)})}},
+			opts:     []DumpOption{MaxValueLength(0)},
+			wantLine: "(0008,1030) LO [" + strings.Repeat("a", 70) + "] #   0, 1 StudyDescription",
+		},
+		{
+			name: "HidePrivateTags",
+			ds: Dataset{Elements: []*Element{
+				makeSequenceElement(tag.Tag{Group: 0x0029, Element: 0x1001}, [][]*Element{{mustNewElement(tag.PatientName, []string{"Bob"})}}),
+				mustNewElement(tag.PatientID, []string{"123"}),
+			}},
+			opts:        []DumpOption{HidePrivateTags()},
+			wantLine:    "(0010,0020) LO [123]                                    #   0, 1 PatientID",
+			notWantLine: "(0010,0010) PN [Bob]",
+		},
+		{
+			name:     "UN resolved from the dictionary",
+			ds:       Dataset{Elements: []*Element{mustNewPrivateElement(tag.PatientName, "UN", []byte("Bob "))}},
+			wantLine: "(0010,0010) PN 42\\6f\\62\\20                              #   0, 1 PatientName",
+		},
+		{
+			name:     "ShowRawVR",
+			ds:       Dataset{Elements: []*Element{mustNewPrivateElement(tag.PatientName, "UN", []byte("Bob "))}},
+			opts:     []DumpOption{ShowRawVR()},
+			wantLine: "(0010,0010) UN 42\\6f\\62\\20                              #   0, 1 PatientName",
+		},
+		{
+			name:     "ColorOutput",
+			ds:       Dataset{Elements: []*Element{mustNewElement(tag.Rows, []int{2})}},
+			opts:     []DumpOption{ColorOutput()},
+			wantLine: "\x1b[36m(0028,0010)\x1b[0m \x1b[33mUS\x1b[0m \x1b[32m2\x1b[0m" + strings.Repeat(" ", 39) + " \x1b[90m#   0, 1 Rows\x1b[0m",
+		},
+		{
+			name:     "AT",
+			ds:       Dataset{Elements: []*Element{mustNewElement(tag.FrameIncrementPointer, []tag.Tag{tag.FrameTime})}},
+			wantLine: "(0028,0009) AT (0018,1063)                              #   0, 1 FrameIncrementPointer",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := Dump(&out, tc.ds, tc.opts...); err != nil {
+				t.Fatalf("Dump() unexpected error: %v", err)
+			}
+			lines := strings.Split(out.String(), "\n")
+			found := false
+			for _, line := range lines {
+				found = found || line == tc.wantLine
+				if tc.notWantLine != "" && strings.HasPrefix(line, tc.notWantLine) {
+					t.Errorf("Dump() printed %q, want it hidden", line)
+				}
+			}
+			if !found {
+				t.Errorf("Dump() = %q, want a line %q", out.String(), tc.wantLine)
+			}
+		})
+	}
+}
+
+func TestDump_ShowOffsets(t *testing.T) {
+	ds := dumpTestDataset()
+	var out strings.Builder
+	if err := Dump(&out, ds, ShowOffsets()); err != nil {
+		t.Fatalf("Dump() unexpected error: %v", err)
+	}
+	var written bytes.Buffer
+	if err := Write(&written, ds); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	data := written.Bytes()
+
+	lines := 0
+	for _, line := range strings.Split(out.String(), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines++
+		offset, err := strconv.ParseInt(line[:8], 16, 64)
+		if err != nil {
+			t.Fatalf("Dump() line %q does not start with an offset: %v", line, err)
+		}
+		var group, element uint16
+		if _, err := fmt.Sscanf(strings.TrimSpace(line[10:]), "(%04x,%04x)", &group, &element); err != nil {
+			t.Fatalf("Dump() line %q has no tag: %v", line, err)
+		}
+		if int(offset)+4 > len(data) {
+			t.Fatalf("Dump() line %q has offset beyond the written length %d", line, len(data))
+		}
+		gotGroup := binary.LittleEndian.Uint16(data[offset:])
+		gotElement := binary.LittleEndian.Uint16(data[offset+2:])
+		if gotGroup != group || gotElement != element {
+			t.Errorf("Dump() line %q: written data has tag (%04x,%04x) at the offset", line, gotGroup, gotElement)
+		}
+	}
+	if lines != 17 {
+		t.Errorf("Dump() printed %d element lines, want 17", lines)
+	}
+}
+
+func TestDump_ShowOffsetsRecorded(t *testing.T) {
+	var written bytes.Buffer
+	if err := Write(&written, dumpTestDataset()); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	data := written.Bytes()
+	dump := func(opts ...ParseOption) string {
+		t.Helper()
+		ds, err := Parse(bytes.NewReader(data), int64(len(data)), nil, opts...)
+		if err != nil {
+			t.Fatalf("Parse() unexpected error: %v", err)
+		}
+		var out strings.Builder
+		if err := Dump(&out, ds, ShowOffsets()); err != nil {
+			t.Fatalf("Dump() unexpected error: %v", err)
+		}
+		return out.String()
+	}
+
+	// Write lays out the file like computeOffsets, so the recorded offsets
+	// match the computed ones.
+	if diff := cmp.Diff(dump(), dump(RecordOffsets())); diff != "" {
+		t.Errorf("Dump() with recorded offsets differs from computed offsets (-computed +recorded):\n%s", diff)
+	}
+
+	ds, err := Parse(bytes.NewReader(data), int64(len(data)), nil, RecordOffsets())
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	ds.Elements = append(ds.Elements, mustNewElement(tag.StudyDescription, []string{"added"}))
+	var out strings.Builder
+	if err := Dump(&out, ds, ShowOffsets()); err != nil {
+		t.Fatalf("Dump() unexpected error: %v", err)
+	}
+	wantLine := strings.Repeat(" ", 10) + "(0008,1030) LO [added]"
+	if !strings.Contains(out.String(), wantLine) {
+		t.Errorf("Dump() = %q, want a line starting with %q for the element without a Position", out.String(), wantLine)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"strconv"
 
 	"github.com/wybaby168/dicom/pkg/frame"
 	"github.com/wybaby168/dicom/pkg/tag"
@@ -23,6 +25,53 @@ type Element struct {
 	RawValueRepresentation string     `json:"rawVR"`
 	ValueLength            uint32     `json:"valueLength"`
 	Value                  Value      `json:"value"`
+	// Position is where the Element was in the parsed input, if it was parsed
+	// with the RecordOffsets option, and nil otherwise.
+	Position *Position `json:"position,omitempty"`
+}
+
+// Position records where an element or a sequence item was in the parsed
+// input, and how it was encoded. Offsets are in bytes from the start of the
+// input, including the preamble; for the deflated transfer syntax, they are
+// offsets in the decompressed data. A Position is not updated when the
+// Element it belongs to is changed.
+type Position struct {
+	// Offset is the offset of the tag.
+	Offset int64 `json:"offset"`
+	// ValueOffset is the offset of the value, after the tag, VR and value
+	// length.
+	ValueOffset int64 `json:"valueOffset"`
+	// EndOffset is the offset just after the element, including the
+	// delimitation item that ends a value of undefined length.
+	EndOffset int64 `json:"endOffset"`
+	// ValueLength is the value length as read, or tag.VLUndefinedLength.
+	ValueLength uint32 `json:"valueLength"`
+	// Implicit is true if the VR was implicit, i.e. not in the input.
+	Implicit bool `json:"implicit"`
+
+	// encoding is set by RecordEncoding for elements that are not sequences.
+	encoding *recordedEncoding
+	// preamble is set by RecordEncoding on the first file meta element.
+	preamble []byte
+}
+
+// UndefinedLength returns true if the value was encoded with undefined
+// length and ended by a delimitation item.
+func (p *Position) UndefinedLength() bool {
+	return p.ValueLength == tag.VLUndefinedLength
+}
+
+// HeaderLength returns the length of the tag, VR and value length.
+func (p *Position) HeaderLength() int64 {
+	return p.ValueOffset - p.Offset
+}
+
+func (p *Position) clone() *Position {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	return &c
 }
 
 // Equals returns true if this Element equals the provided target Element,
@@ -43,6 +92,32 @@ func (e *Element) Equals(target *Element) bool {
 	return true
 }
 
+// Clone returns a deep copy of this Element, including the items of
+// sequences and the frames of PixelData.
+func (e *Element) Clone() *Element {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	if e.Value != nil {
+		c.Value = e.Value.clone()
+	}
+	c.Position = e.Position.clone()
+	return &c
+}
+
+// cloneElements returns deep copies of elems.
+func cloneElements(elems []*Element) []*Element {
+	if elems == nil {
+		return nil
+	}
+	c := make([]*Element, len(elems))
+	for i, elem := range elems {
+		c[i] = elem.Clone()
+	}
+	return c
+}
+
 func (e *Element) String() string {
 	var tagName string
 	if tagInfo, err := tag.Find(e.Tag); err == nil {
@@ -86,6 +161,8 @@ type Value interface {
 	// All types that can be a "Value" for an element will implement this empty method, similar to how protocol buffers
 	// implement "oneof" in Go
 	isElementValue()
+	// clone returns a deep copy of this Value.
+	clone() Value
 	// ValueType returns the underlying ValueType of this Value. This can be used to unpack the underlying data in this
 	// Value.
 	ValueType() ValueType
@@ -104,13 +181,17 @@ type Value interface {
 // Data must be one of the following types, otherwise and error will be returned
 // (ErrorUnexpectedDataType).
 //
-// Acceptable types: []int, []string, []byte, []float64, PixelDataInfo,
-// [][]*Element (represents a sequence, which contains several
-// items which each contain several elements).
+// Acceptable types: []int, []int64, []uint64, []string, []byte, []float64,
+// []tag.Tag, PixelDataInfo, [][]*Element (represents a sequence, which contains
+// several items which each contain several elements).
 func NewValue(data any) (Value, error) {
 	switch data.(type) {
 	case []int:
 		return &intsValue{value: data.([]int)}, nil
+	case []int64:
+		return &int64sValue{value: data.([]int64)}, nil
+	case []uint64:
+		return &uint64sValue{value: data.([]uint64)}, nil
 	case []string:
 		return &stringsValue{value: data.([]string)}, nil
 	case []byte:
@@ -119,6 +200,8 @@ func NewValue(data any) (Value, error) {
 		return &pixelDataValue{PixelDataInfo: data.(PixelDataInfo)}, nil
 	case []float64:
 		return &floatsValue{value: data.([]float64)}, nil
+	case []tag.Tag:
+		return &tagsValue{value: data.([]tag.Tag)}, nil
 	case [][]*Element:
 		items := data.([][]*Element)
 		sequenceItems := make([]*SequenceItemValue, 0, len(items))
@@ -141,7 +224,8 @@ func mustNewValue(data any) Value {
 
 // NewElement creates a new DICOM Element with the supplied tag and with a value
 // built from the provided data. The data can be one of the types that is
-// acceptable to NewValue.
+// acceptable to NewValue. An error wrapping ErrorValueMultiplicity is returned
+// if the number of values is not allowed by the VM of the tag.
 func NewElement(t tag.Tag, data any) (*Element, error) {
 	tagInfo, err := tag.Find(t)
 	if err != nil {
@@ -155,10 +239,14 @@ func NewElement(t tag.Tag, data any) (*Element, error) {
 	if err != nil {
 		return nil, err
 	}
+	vr, _ := tag.ParseVR(rawVR)
+	if err := checkMultiplicity(tagInfo, vr, value); err != nil {
+		return nil, err
+	}
 
 	return &Element{
 		Tag:                    t,
-		ValueRepresentation:    tag.GetVRKind(t, rawVR),
+		ValueRepresentation:    tag.VRKindOf(t, vr),
 		RawValueRepresentation: rawVR,
 		Value:                  value,
 	}, nil
@@ -208,6 +296,12 @@ const (
 	Sequences
 	// Floats represents an underlying value of []float64
 	Floats
+	// Int64s represents an underlying value of []int64, e.g. for SV elements
+	Int64s
+	// Uint64s represents an underlying value of []uint64, e.g. for UV elements
+	Uint64s
+	// Tags represents an underlying value of []tag.Tag, for AT elements
+	Tags
 )
 
 // Begin definitions of Values:
@@ -220,6 +314,7 @@ type bytesValue struct {
 func (b *bytesValue) isElementValue()      {}
 func (b *bytesValue) ValueType() ValueType { return Bytes }
 func (b *bytesValue) GetValue() any        { return b.value }
+func (b *bytesValue) clone() Value         { return &bytesValue{value: slices.Clone(b.value)} }
 func (b *bytesValue) String() string {
 	return fmt.Sprintf("%v", b.value)
 }
@@ -245,6 +340,7 @@ type stringsValue struct {
 func (s *stringsValue) isElementValue()      {}
 func (s *stringsValue) ValueType() ValueType { return Strings }
 func (s *stringsValue) GetValue() any        { return s.value }
+func (s *stringsValue) clone() Value         { return &stringsValue{value: slices.Clone(s.value)} }
 func (s *stringsValue) String() string {
 	return fmt.Sprintf("%v", s.value)
 }
@@ -276,6 +372,7 @@ type intsValue struct {
 func (i *intsValue) isElementValue()      {}
 func (i *intsValue) ValueType() ValueType { return Ints }
 func (i *intsValue) GetValue() any        { return i.value }
+func (i *intsValue) clone() Value         { return &intsValue{value: slices.Clone(i.value)} }
 func (i *intsValue) String() string {
 	return fmt.Sprintf("%v", i.value)
 }
@@ -299,6 +396,126 @@ func (i *intsValue) Equals(target Value) bool {
 	return true
 }
 
+// maxSafeJSONInteger is the largest integer magnitude that JSON numbers, which
+// are usually decoded as IEEE 754 doubles, represent exactly (2^53 - 1).
+const maxSafeJSONInteger = 1<<53 - 1
+
+// int64sValue represents a value of []int64.
+type int64sValue struct {
+	value []int64
+}
+
+func (i *int64sValue) isElementValue()      {}
+func (i *int64sValue) ValueType() ValueType { return Int64s }
+func (i *int64sValue) GetValue() any        { return i.value }
+func (i *int64sValue) clone() Value         { return &int64sValue{value: slices.Clone(i.value)} }
+func (i *int64sValue) String() string {
+	return fmt.Sprintf("%v", i.value)
+}
+
+// MarshalJSON renders the values as JSON numbers, except for values outside of
+// the range JSON numbers represent exactly, which are rendered as strings as
+// in the DICOM JSON Model (PS3.18 Section F.2.3).
+func (i *int64sValue) MarshalJSON() ([]byte, error) {
+	values := make([]any, len(i.value))
+	for idx, val := range i.value {
+		values[idx] = val
+		if val > maxSafeJSONInteger || val < -maxSafeJSONInteger {
+			values[idx] = strconv.FormatInt(val, 10)
+		}
+	}
+	return json.Marshal(values)
+}
+
+func (i *int64sValue) Equals(target Value) bool {
+	if target.ValueType() != Int64s {
+		return false
+	}
+	targetVal := target.GetValue().([]int64)
+	if len(i.value) != len(targetVal) {
+		return false
+	}
+	for idx, val := range i.value {
+		if val != targetVal[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// uint64sValue represents a value of []uint64.
+type uint64sValue struct {
+	value []uint64
+}
+
+func (u *uint64sValue) isElementValue()      {}
+func (u *uint64sValue) ValueType() ValueType { return Uint64s }
+func (u *uint64sValue) GetValue() any        { return u.value }
+func (u *uint64sValue) clone() Value         { return &uint64sValue{value: slices.Clone(u.value)} }
+func (u *uint64sValue) String() string {
+	return fmt.Sprintf("%v", u.value)
+}
+
+// MarshalJSON renders the values like int64sValue.MarshalJSON.
+func (u *uint64sValue) MarshalJSON() ([]byte, error) {
+	values := make([]any, len(u.value))
+	for idx, val := range u.value {
+		values[idx] = val
+		if val > maxSafeJSONInteger {
+			values[idx] = strconv.FormatUint(val, 10)
+		}
+	}
+	return json.Marshal(values)
+}
+
+func (u *uint64sValue) Equals(target Value) bool {
+	if target.ValueType() != Uint64s {
+		return false
+	}
+	targetVal := target.GetValue().([]uint64)
+	if len(u.value) != len(targetVal) {
+		return false
+	}
+	for idx, val := range u.value {
+		if val != targetVal[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// tagsValue represents a value of []tag.Tag.
+type tagsValue struct {
+	value []tag.Tag
+}
+
+func (t *tagsValue) isElementValue()      {}
+func (t *tagsValue) ValueType() ValueType { return Tags }
+func (t *tagsValue) GetValue() any        { return t.value }
+func (t *tagsValue) clone() Value         { return &tagsValue{value: slices.Clone(t.value)} }
+func (t *tagsValue) String() string {
+	return fmt.Sprintf("%v", t.value)
+}
+func (t *tagsValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.value)
+}
+
+func (t *tagsValue) Equals(target Value) bool {
+	if target.ValueType() != Tags {
+		return false
+	}
+	targetVal := target.GetValue().([]tag.Tag)
+	if len(t.value) != len(targetVal) {
+		return false
+	}
+	for idx, val := range t.value {
+		if val != targetVal[idx] {
+			return false
+		}
+	}
+	return true
+}
+
 // floatsValue represents a value of []float64.
 type floatsValue struct {
 	value []float64
@@ -307,6 +524,7 @@ type floatsValue struct {
 func (f *floatsValue) isElementValue()      {}
 func (f *floatsValue) ValueType() ValueType { return Floats }
 func (f *floatsValue) GetValue() any        { return f.value }
+func (f *floatsValue) clone() Value         { return &floatsValue{value: slices.Clone(f.value)} }
 func (f *floatsValue) String() string {
 	return fmt.Sprintf("%v", f.value)
 }
@@ -334,6 +552,7 @@ func (f *floatsValue) Equals(target Value) bool {
 // https://dicom.nema.org/medical/dicom/current/output/chtml/part05/sect_7.5.html.
 type SequenceItemValue struct {
 	elements []*Element
+	position *Position
 }
 
 func (s *SequenceItemValue) isElementValue() {}
@@ -347,6 +566,17 @@ func (s *SequenceItemValue) ValueType() ValueType { return SequenceItem }
 // (see the ValueType godoc).
 func (s *SequenceItemValue) GetValue() any { return s.elements }
 
+func (s *SequenceItemValue) clone() Value { return s.cloneItem() }
+
+// cloneItem returns a deep copy of this item.
+func (s *SequenceItemValue) cloneItem() *SequenceItemValue {
+	return &SequenceItemValue{elements: cloneElements(s.elements), position: s.position.clone()}
+}
+
+// Position returns where the item was in the parsed input, if it was parsed
+// with the RecordOffsets option, and nil otherwise.
+func (s *SequenceItemValue) Position() *Position { return s.position }
+
 // String is used to get a string representation of this struct.
 func (s *SequenceItemValue) String() string {
 	// TODO: consider adding more sophisticated formatting
@@ -382,6 +612,13 @@ type sequencesValue struct {
 func (s *sequencesValue) isElementValue()      {}
 func (s *sequencesValue) ValueType() ValueType { return Sequences }
 func (s *sequencesValue) GetValue() any        { return s.value }
+func (s *sequencesValue) clone() Value {
+	items := make([]*SequenceItemValue, len(s.value))
+	for i, item := range s.value {
+		items[i] = item.cloneItem()
+	}
+	return &sequencesValue{value: items}
+}
 func (s *sequencesValue) String() string {
 	// TODO: consider adding more sophisticated formatting
 	return fmt.Sprintf("%+v", s.value)
@@ -445,6 +682,18 @@ type pixelDataValue struct {
 func (p *pixelDataValue) isElementValue()      {}
 func (p *pixelDataValue) ValueType() ValueType { return PixelData }
 func (p *pixelDataValue) GetValue() any        { return p.PixelDataInfo }
+func (p *pixelDataValue) clone() Value {
+	c := &pixelDataValue{PixelDataInfo: p.PixelDataInfo}
+	if p.Frames != nil {
+		c.Frames = make([]*frame.Frame, len(p.Frames))
+		for i, f := range p.Frames {
+			c.Frames[i] = f.Clone()
+		}
+	}
+	c.Offsets = slices.Clone(p.Offsets)
+	c.UnprocessedValueData = slices.Clone(p.UnprocessedValueData)
+	return c
+}
 func (p *pixelDataValue) String() string {
 	if len(p.Frames) == 0 {
 		return "empty pixel data"
@@ -494,6 +743,24 @@ func MustGetInts(v Value) []int {
 	return v.GetValue().([]int)
 }
 
+// MustGetInt64s attempts to get an Int64s value out of the provided value, and
+// will panic if it is unable to do so.
+func MustGetInt64s(v Value) []int64 {
+	if v.ValueType() != Int64s {
+		log.Panicf("MustGetInt64s expected ValueType of Int64s, got: %v", v.ValueType())
+	}
+	return v.GetValue().([]int64)
+}
+
+// MustGetUint64s attempts to get an Uint64s value out of the provided value,
+// and will panic if it is unable to do so.
+func MustGetUint64s(v Value) []uint64 {
+	if v.ValueType() != Uint64s {
+		log.Panicf("MustGetUint64s expected ValueType of Uint64s, got: %v", v.ValueType())
+	}
+	return v.GetValue().([]uint64)
+}
+
 // MustGetStrings attempts to get a Strings value out of the provided Value, and
 // will panic if it is unable to do so.
 func MustGetStrings(v Value) []string {
@@ -521,6 +788,15 @@ func MustGetFloats(v Value) []float64 {
 	return v.GetValue().([]float64)
 }
 
+// MustGetTags attempts to get a Tags value out of the provided Value, and will
+// panic if it is unable to do so.
+func MustGetTags(v Value) []tag.Tag {
+	if v.ValueType() != Tags {
+		log.Panicf("MustGetTags expected ValueType of Tags, got: %v", v.ValueType())
+	}
+	return v.GetValue().([]tag.Tag)
+}
+
 // MustGetPixelDataInfo attempts to get a PixelDataInfo value out of the
 // provided Value, and will panic if it is unable to do so.
 func MustGetPixelDataInfo(v Value) PixelDataInfo {
@@ -535,6 +811,9 @@ func MustGetPixelDataInfo(v Value) PixelDataInfo {
 var allValues = []any{
 	floatsValue{},
 	intsValue{},
+	int64sValue{},
+	uint64sValue{},
+	tagsValue{},
 	stringsValue{},
 	pixelDataValue{},
 	sequencesValue{},
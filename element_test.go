@@ -3,6 +3,7 @@ package dicom
 import (
 	"encoding/json"
 	"errors"
+	"math"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -36,6 +37,33 @@ func TestElement_MarshalJSON_NestedElements(t *testing.T) {
 	}
 }
 
+func TestTagsValue_String(t *testing.T) {
+	v := mustNewValue([]tag.Tag{tag.StudyInstanceUID, tag.StackID})
+	if got, want := v.String(), "[(0020,000d) (0020,9056)]"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestValue_MarshalJSON_VeryLong(t *testing.T) {
+	cases := []struct {
+		value Value
+		want  string
+	}{
+		{value: mustNewValue([]int64{-(1<<53 - 1), 1<<53 - 1}), want: `[-9007199254740991,9007199254740991]`},
+		{value: mustNewValue([]int64{math.MinInt64, 1 << 53}), want: `["-9223372036854775808","9007199254740992"]`},
+		{value: mustNewValue([]uint64{7, math.MaxUint64}), want: `[7,"18446744073709551615"]`},
+	}
+	for _, tc := range cases {
+		got, err := json.Marshal(tc.value)
+		if err != nil {
+			t.Fatalf("json.Marshal(%v) unexpected error: %v", tc.value, err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("json.Marshal(%v) = %s, want %s", tc.value, got, tc.want)
+		}
+	}
+}
+
 func TestElement_String(t *testing.T) {
 	e := &Element{
 		Tag:                    tag.Rows,
@@ -85,6 +113,24 @@ func TestNewValue(t *testing.T) {
 			wantValue: &intsValue{value: []int{1, 2}},
 			wantError: nil,
 		},
+		{
+			name:      "int64s",
+			data:      []int64{-1, 1 << 62},
+			wantValue: &int64sValue{value: []int64{-1, 1 << 62}},
+			wantError: nil,
+		},
+		{
+			name:      "uint64s",
+			data:      []uint64{1, 1 << 63},
+			wantValue: &uint64sValue{value: []uint64{1, 1 << 63}},
+			wantError: nil,
+		},
+		{
+			name:      "tags",
+			data:      []tag.Tag{tag.StudyInstanceUID},
+			wantValue: &tagsValue{value: []tag.Tag{tag.StudyInstanceUID}},
+			wantError: nil,
+		},
 		{
 			name:      "bytes",
 			data:      []byte{0x00, 0x01},
@@ -139,6 +185,33 @@ func TestNewValue(t *testing.T) {
 	}
 }
 
+func TestNewElement_ValueMultiplicity(t *testing.T) {
+	cases := []struct {
+		name    string
+		tag     tag.Tag
+		data    any
+		wantErr error
+	}{
+		{name: "VM 1", tag: tag.Modality, data: []string{"CT"}},
+		{name: "VM 1 empty", tag: tag.Modality, data: []string{""}},
+		{name: "VM 1 too many", tag: tag.Modality, data: []string{"CT", "MR"}, wantErr: ErrorValueMultiplicity},
+		{name: "VM 3", tag: tag.ImagePositionPatient, data: []string{"1", "2", "3"}},
+		{name: "VM 3 too few", tag: tag.ImagePositionPatient, data: []string{"1", "2"}, wantErr: ErrorValueMultiplicity},
+		{name: "VM 2-n too few", tag: tag.ImageType, data: []string{"ORIGINAL"}, wantErr: ErrorValueMultiplicity},
+		{name: "VM 1 ints", tag: tag.Rows, data: []int{1, 2}, wantErr: ErrorValueMultiplicity},
+		{name: "VM 1 AT", tag: tag.DimensionIndexPointer, data: []tag.Tag{tag.StackID}},
+		{name: "VM 1 bytes", tag: tag.RedPaletteColorLookupTableData, data: []byte{1, 2, 3, 4}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewElement(tc.tag, tc.data)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("NewElement(%v, %v) returned err %v, want %v", tc.tag, tc.data, err, tc.wantErr)
+			}
+		})
+	}
+}
+
 func TestNewValue_UnexpectedType(t *testing.T) {
 	data := 10
 	_, err := NewValue(data)
@@ -187,20 +260,20 @@ func TestElement_Equals(t *testing.T) {
 		},
 		{
 			name:      "EqualInts",
-			a:         mustNewElement(tag.Rows, []int{1, 2, 3}),
-			b:         mustNewElement(tag.Rows, []int{1, 2, 3}),
+			a:         mustNewElement(tag.SelectorUSValue, []int{1, 2, 3}),
+			b:         mustNewElement(tag.SelectorUSValue, []int{1, 2, 3}),
 			wantEqual: true,
 		},
 		{
 			name:      "UnequalInts",
-			a:         mustNewElement(tag.Rows, []int{1, 2, 6}),
-			b:         mustNewElement(tag.Rows, []int{1, 2, 3}),
+			a:         mustNewElement(tag.SelectorUSValue, []int{1, 2, 6}),
+			b:         mustNewElement(tag.SelectorUSValue, []int{1, 2, 3}),
 			wantEqual: false,
 		},
 		{
 			name:      "UnequalLenInts",
-			a:         mustNewElement(tag.Rows, []int{1, 6}),
-			b:         mustNewElement(tag.Rows, []int{1, 2, 3}),
+			a:         mustNewElement(tag.SelectorUSValue, []int{1, 6}),
+			b:         mustNewElement(tag.SelectorUSValue, []int{1, 2, 3}),
 			wantEqual: false,
 		},
 		{
@@ -223,20 +296,20 @@ func TestElement_Equals(t *testing.T) {
 		},
 		{
 			name:      "EqualStrings",
-			a:         mustNewElement(tag.PatientName, []string{"John", "Smith"}),
-			b:         mustNewElement(tag.PatientName, []string{"John", "Smith"}),
+			a:         mustNewElement(tag.PatientName, []string{"John^Smith"}),
+			b:         mustNewElement(tag.PatientName, []string{"John^Smith"}),
 			wantEqual: true,
 		},
 		{
 			name:      "UnequalStrings",
-			a:         mustNewElement(tag.PatientName, []string{"John", "Doe"}),
-			b:         mustNewElement(tag.PatientName, []string{"John", "Smith"}),
+			a:         mustNewElement(tag.PatientName, []string{"John^Doe"}),
+			b:         mustNewElement(tag.PatientName, []string{"John^Smith"}),
 			wantEqual: false,
 		},
 		{
 			name:      "UnequalLenStrings",
 			a:         mustNewElement(tag.PatientName, []string{"John"}),
-			b:         mustNewElement(tag.PatientName, []string{"John", "Smith"}),
+			b:         mustNewElement(tag.PatientName, []string{"John^Smith"}),
 			wantEqual: false,
 		},
 		{
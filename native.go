@@ -0,0 +1,152 @@
+package dicom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"unsafe"
+
+	"github.com/wybaby168/dicom/pkg/frame"
+	"golang.org/x/exp/constraints"
+)
+
+// isHostByteOrder returns true if bo is the byte order of the host, in which
+// case samples are encoded the way they are laid out in memory.
+func isHostByteOrder(bo binary.ByteOrder) bool {
+	probe := []byte{1, 0}
+	return bo.Uint16(probe) == binary.NativeEndian.Uint16(probe)
+}
+
+// sampleBytes returns the memory of samples as bytes, without copying them.
+func sampleBytes[I constraints.Integer](samples []I) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(samples))), len(samples)*sampleSize[I]())
+}
+
+// sampleSize returns the size in bytes of I.
+func sampleSize[I constraints.Integer]() int {
+	return int(unsafe.Sizeof(*new(I)))
+}
+
+// swapSampleBytes reverses the bytes of each of samples, converting them
+// between the byte order of the host and the other one.
+func swapSampleBytes[I constraints.Integer](samples []I) {
+	switch sampleSize[I]() {
+	case 2:
+		for i, v := range samples {
+			samples[i] = I(bits.ReverseBytes16(uint16(v)))
+		}
+	case 4:
+		for i, v := range samples {
+			samples[i] = I(bits.ReverseBytes32(uint32(v)))
+		}
+	case 8:
+		for i, v := range samples {
+			samples[i] = I(bits.ReverseBytes64(uint64(v)))
+		}
+	}
+}
+
+// readSamples reads samples encoded with the byte order bo from r, directly
+// into the memory of samples.
+func readSamples[I constraints.Integer](samples []I, r io.Reader, bo binary.ByteOrder) error {
+	if _, err := io.ReadFull(r, sampleBytes(samples)); err != nil {
+		return err
+	}
+	if !isHostByteOrder(bo) {
+		swapSampleBytes(samples)
+	}
+	return nil
+}
+
+// nativeFrameBytes returns the samples of f encoded with the byte order bo.
+// For 8-bit samples, or if bo is the byte order of the host, the result
+// aliases the samples of f. Otherwise they are converted into *scratch, which
+// is grown as needed so that it can be reused for the next frame.
+func nativeFrameBytes(f frame.INativeFrame, bo binary.ByteOrder, scratch *[]byte) ([]byte, error) {
+	switch f.BitsPerSample() {
+	case 8:
+		samples, ok := f.RawDataSlice().([]uint8)
+		if !ok {
+			return nil, fmt.Errorf("got frame with bitsAllocated=8 but can't assert RawDataSlice to []uint8")
+		}
+		return samples, nil
+	case 16:
+		samples, ok := f.RawDataSlice().([]uint16)
+		if !ok {
+			return nil, fmt.Errorf("got frame with bitsAllocated=16 but can't assert RawDataSlice to []uint16")
+		}
+		return encodeSamples(samples, bo, scratch), nil
+	case 32:
+		samples, ok := f.RawDataSlice().([]uint32)
+		if !ok {
+			return nil, fmt.Errorf("got frame with bitsAllocated=32 but can't assert RawDataSlice to []uint32")
+		}
+		return encodeSamples(samples, bo, scratch), nil
+	default:
+		return nil, ErrorUnsupportedBitsPerSample
+	}
+}
+
+// encodeSamples returns samples encoded with the byte order bo, see
+// nativeFrameBytes.
+func encodeSamples[I constraints.Integer](samples []I, bo binary.ByteOrder, scratch *[]byte) []byte {
+	data := sampleBytes(samples)
+	if isHostByteOrder(bo) {
+		return data
+	}
+	if cap(*scratch) < len(data) {
+		*scratch = make([]byte, len(data))
+	}
+	swapped := (*scratch)[:len(data)]
+	switch sampleSize[I]() {
+	case 2:
+		for i, v := range samples {
+			binary.NativeEndian.PutUint16(swapped[2*i:], bits.ReverseBytes16(uint16(v)))
+		}
+	case 4:
+		for i, v := range samples {
+			binary.NativeEndian.PutUint32(swapped[4*i:], bits.ReverseBytes32(uint32(v)))
+		}
+	case 8:
+		for i, v := range samples {
+			binary.NativeEndian.PutUint64(swapped[8*i:], bits.ReverseBytes64(uint64(v)))
+		}
+	default:
+		copy(swapped, data)
+	}
+	return swapped
+}
+
+// nativePixelDataLength returns the length of the value writePixelData writes
+// for native PixelData, including padding.
+func nativePixelDataLength(image PixelDataInfo) (int64, error) {
+	if image.IntentionallySkipped {
+		return 0, nil
+	}
+	if image.IntentionallyUnprocessed {
+		return int64(len(image.UnprocessedValueData)), nil
+	}
+	var length int64
+	for _, f := range image.Frames {
+		if f.NativeData == nil {
+			return 0, fmt.Errorf("native PixelData frame without NativeData")
+		}
+		var n int
+		switch samples := f.NativeData.RawDataSlice().(type) {
+		case []uint8:
+			n = len(samples)
+		case []uint16:
+			n = len(samples) * 2
+		case []uint32:
+			n = len(samples) * 4
+		default:
+			return 0, ErrorUnsupportedBitsPerSample
+		}
+		if n != f.NativeData.Rows()*f.NativeData.Cols()*f.NativeData.SamplesPerPixel()*f.NativeData.BitsPerSample()/8 {
+			return 0, fmt.Errorf("native PixelData frame of %d bytes does not match its %dx%d samples of %d bits", n, f.NativeData.Rows(), f.NativeData.Cols(), f.NativeData.BitsPerSample())
+		}
+		length += int64(n)
+	}
+	return length + length%2, nil
+}
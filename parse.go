@@ -284,6 +284,9 @@ type parseOptSet struct {
 	skipProcessingPixelDataValue       bool
 	allowMissingMetaElementGroupLength bool
 	allowUnknownSpecificCharacterSet   bool
+	strictValueMultiplicity            bool
+	recordOffsets                      bool
+	recordEncoding                     bool
 }
 
 func toParseOptSet(opts ...ParseOption) parseOptSet {
@@ -319,6 +322,40 @@ func AllowUnknownSpecificCharacterSet() ParseOption {
 	}
 }
 
+// StrictValueMultiplicity makes parsing fail with an error wrapping
+// ErrorValueMultiplicity when an element holds a number of values the VM of
+// its data dictionary entry does not allow. By default such elements are read
+// as they are.
+func StrictValueMultiplicity() ParseOption {
+	return func(set *parseOptSet) {
+		set.strictValueMultiplicity = true
+	}
+}
+
+// RecordOffsets sets the Position of each parsed Element and sequence item to
+// where it was in the input, e.g. to report where a malformed element is, or
+// to patch a file in place.
+func RecordOffsets() ParseOption {
+	return func(set *parseOptSet) {
+		set.recordOffsets = true
+	}
+}
+
+// RecordEncoding is like RecordOffsets, but additionally records how each
+// value was encoded, so that Write with the PreserveEncoding WriteOption can
+// write the parsed Dataset byte for byte as it was read, apart from the
+// elements changed since. This keeps a copy of the values whose encoding the
+// parsed Value does not reproduce, e.g. because of their padding, and reads
+// the basic offset table of encapsulated PixelData into
+// PixelDataInfo.Offsets. PixelData itself is not recorded, and is written
+// from its frames.
+func RecordEncoding() ParseOption {
+	return func(set *parseOptSet) {
+		set.recordOffsets = true
+		set.recordEncoding = true
+	}
+}
+
 // SkipMetadataReadOnNewParserInit makes NewParser skip trying to parse metadata. This will make the Parser default to implicit little endian byte order.
 // Any metatata tags found in the dataset will still be available when parsing.
 func SkipMetadataReadOnNewParserInit() ParseOption {
@@ -0,0 +1,402 @@
+package dicom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/wybaby168/dicom/pkg/dicomio"
+	"github.com/wybaby168/dicom/pkg/frame"
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/uid"
+)
+
+// ErrorDumpSyntax indicates that a line of the text read by ParseDump is not
+// in the format written by Dump.
+var ErrorDumpSyntax = errors.New("invalid dump syntax")
+
+// ParseDumpOption represents an option that can be passed to ParseDump.
+type ParseDumpOption func(*parseDumpOptSet)
+
+// parseDumpOptSet represents the flattened option set after all
+// ParseDumpOptions have been applied.
+type parseDumpOptSet struct {
+	files fs.FS
+}
+
+// DumpFiles reads the values of binary elements written as "=name", e.g. the
+// PixelData of a fixture, from the file name in fsys.
+func DumpFiles(fsys fs.FS) ParseDumpOption {
+	return func(set *parseDumpOptSet) {
+		set.files = fsys
+	}
+}
+
+// ParseDumpFile is like ParseDump, but reads the text from the file at path,
+// and values written as "=name" from files relative to its directory unless
+// DumpFiles is given.
+func ParseDumpFile(path string, opts ...ParseDumpOption) (Dataset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Dataset{}, err
+	}
+	defer f.Close()
+	opts = append([]ParseDumpOption{DumpFiles(os.DirFS(filepath.Dir(path)))}, opts...)
+	return ParseDump(f, opts...)
+}
+
+// ParseDump parses text in the format written by Dump, or by dcmtk's dcmdump,
+// into a Dataset, e.g. to write test fixtures by hand:
+//
+//	(0010,0010) PN [Doe^John]                               #   8, 1 PatientName
+//	(0008,1115) SQ (Sequence with undefined length #=1)     # u/l, 1 ReferencedSeriesSequence
+//	  (fffe,e000) na (Item with undefined length #=1)         # u/l, 1 Item
+//	    (0020,000e) UI [1.2.3]                                  #   6, 1 SeriesInstanceUID
+//	  (fffe,e00d) na (ItemDelimitationItem)                   #   0, 0 ItemDelimitationItem
+//	(fffe,e0dd) na (SequenceDelimitationItem)               #   0, 0 SequenceDelimitationItem
+//	(7fe0,0010) OW =pixels.raw                              #   0, 1 PixelData
+//
+// Each line holds one element: its tag, its VR and its value. Everything after
+// the value, like the comment starting with "#", is ignored, as are blank
+// lines, comment lines and the offsets written by ShowOffsets. Values are
+// written like this:
+//
+//   - Strings in brackets, with multiple values separated by "\". UIDs may
+//     also be written as "=" followed by their uid.Info.Keyword.
+//   - Numbers and AT tags like "(0018,1063)" separated by "\".
+//   - Binary values as hexadecimal words separated by "\", where the number of
+//     digits of each word sets its size, e.g. "00\01" or "0001". Words are
+//     little endian. Binary values can also be read from a file with "=name",
+//     see DumpFiles.
+//   - Sequences and encapsulated PixelData as in the example, with an item per
+//     line, and items and sequences ended with their delimitation items.
+//   - "(no value available)" for empty values.
+//
+// Native PixelData is read into frames using the Rows, Columns,
+// BitsAllocated, SamplesPerPixel and NumberOfFrames elements before it.
+// Indentation and value lengths are not needed, and values are not shortened
+// as Dump does by default, so Dump with MaxValueLength(0) to write text that
+// ParseDump reads back into the same Dataset.
+func ParseDump(in io.Reader, opts ...ParseDumpOption) (Dataset, error) {
+	p := &dumpParser{}
+	for _, opt := range opts {
+		opt(&p.opts)
+	}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 1<<30)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if err := p.parseLine(scanner.Text()); err != nil {
+			return Dataset{}, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Dataset{}, err
+	}
+	if len(p.open) > 0 {
+		return Dataset{}, fmt.Errorf("%w: %v is missing its SequenceDelimitationItem", ErrorDumpSyntax, p.open[len(p.open)-1].elem.Tag)
+	}
+	return p.ds, nil
+}
+
+// dumpParser holds the state of ParseDump.
+type dumpParser struct {
+	opts parseDumpOptSet
+	ds   Dataset
+	// open are the sequences and encapsulated PixelData elements whose
+	// SequenceDelimitationItem was not parsed yet.
+	open []*openDumpSequence
+}
+
+// openDumpSequence is a sequence, or if pixelData is set, an encapsulated
+// PixelData element, being parsed.
+type openDumpSequence struct {
+	elem *Element
+	// item is the open item of a sequence, or nil after an
+	// ItemDelimitationItem.
+	item      *SequenceItemValue
+	pixelData *pixelDataValue
+	fragments int
+}
+
+// dumpLine matches the start of a line: an optional offset, the tag and the
+// VR.
+var dumpLine = regexp.MustCompile(`^\s*(?:[0-9a-fA-F]{8}:\s*)?\(([0-9a-fA-F]{4}),([0-9a-fA-F]{4})\)\s+(\S+)\s*(.*)$`)
+
+func (p *dumpParser) parseLine(line string) error {
+	if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return nil
+	}
+	m := dumpLine.FindStringSubmatch(line)
+	if m == nil {
+		return fmt.Errorf("%w: expected a tag like (0010,0010) and a VR in %q", ErrorDumpSyntax, line)
+	}
+	group, _ := strconv.ParseUint(m[1], 16, 16)
+	element, _ := strconv.ParseUint(m[2], 16, 16)
+	t := tag.Tag{Group: uint16(group), Element: uint16(element)}
+	vr := m[3]
+	value, err := dumpValue(m[4], vr == "AT")
+	if err != nil {
+		return err
+	}
+
+	var top *openDumpSequence
+	if len(p.open) > 0 {
+		top = p.open[len(p.open)-1]
+	}
+	switch {
+	case t == tag.Item && top != nil && top.pixelData != nil:
+		return p.addFragment(top, value)
+	case t == tag.Item:
+		if top == nil {
+			return fmt.Errorf("%w: Item outside of a sequence", ErrorDumpSyntax)
+		}
+		top.item = &SequenceItemValue{}
+		seq := top.elem.Value.(*sequencesValue)
+		seq.value = append(seq.value, top.item)
+		return nil
+	case t == tag.ItemDelimitationItem:
+		if top == nil || top.item == nil {
+			return fmt.Errorf("%w: ItemDelimitationItem outside of an item", ErrorDumpSyntax)
+		}
+		top.item = nil
+		return nil
+	case t == tag.SequenceDelimitationItem:
+		if top == nil {
+			return fmt.Errorf("%w: SequenceDelimitationItem outside of a sequence", ErrorDumpSyntax)
+		}
+		p.open = p.open[:len(p.open)-1]
+		return nil
+	}
+
+	elem := &Element{Tag: t, ValueRepresentation: tag.GetVRKind(t, vr), RawValueRepresentation: vr}
+	switch {
+	case strings.HasPrefix(value, "(Sequence"):
+		elem.Value = &sequencesValue{}
+		if strings.Contains(value, "undefined length") {
+			elem.ValueLength = tag.VLUndefinedLength
+		}
+		if err := p.addElement(elem); err != nil {
+			return err
+		}
+		p.open = append(p.open, &openDumpSequence{elem: elem})
+		return nil
+	case t == tag.PixelData && strings.HasPrefix(value, "(PixelSequence"):
+		pixelData := &pixelDataValue{PixelDataInfo{IsEncapsulated: true}}
+		elem.Value = pixelData
+		elem.ValueLength = tag.VLUndefinedLength
+		if err := p.addElement(elem); err != nil {
+			return err
+		}
+		p.open = append(p.open, &openDumpSequence{elem: elem, pixelData: pixelData})
+		return nil
+	case t == tag.PixelData:
+		elem.Value, err = p.nativePixelData(value)
+	default:
+		elem.Value, err = p.parseValue(t, vr, value)
+	}
+	if err != nil {
+		return err
+	}
+	return p.addElement(elem)
+}
+
+// dumpValue returns the value at the start of rest, which is followed by
+// nothing but a comment.
+func dumpValue(rest string, isTag bool) (string, error) {
+	var closing string
+	switch {
+	case strings.HasPrefix(rest, "["):
+		closing = "]"
+	case strings.HasPrefix(rest, "(") && !isTag:
+		closing = ")"
+	default:
+		value, _, _ := strings.Cut(rest, " ")
+		value, _, _ = strings.Cut(value, "\t")
+		return value, nil
+	}
+	for i := 1; i < len(rest); i++ {
+		if !strings.HasPrefix(rest[i:], closing) {
+			continue
+		}
+		if after := strings.TrimSpace(rest[i+1:]); after == "" || strings.HasPrefix(after, "#") {
+			return rest[:i+1], nil
+		}
+	}
+	return "", fmt.Errorf("%w: missing %q in %q", ErrorDumpSyntax, closing, rest)
+}
+
+func (p *dumpParser) addElement(elem *Element) error {
+	if len(p.open) == 0 {
+		p.ds.Elements = append(p.ds.Elements, elem)
+		return nil
+	}
+	top := p.open[len(p.open)-1]
+	if top.item == nil {
+		return fmt.Errorf("%w: %v is in %v, but not in one of its items", ErrorDumpSyntax, elem.Tag, top.elem.Tag)
+	}
+	top.item.elements = append(top.item.elements, elem)
+	return nil
+}
+
+// addFragment adds an item of encapsulated PixelData: the basic offset table
+// for the first item, and a frame for the others.
+func (p *dumpParser) addFragment(seq *openDumpSequence, value string) error {
+	data, err := p.binaryValue(value)
+	if err != nil {
+		return err
+	}
+	seq.fragments++
+	if seq.fragments == 1 {
+		if len(data)%4 != 0 {
+			return fmt.Errorf("%w: basic offset table length %d is not a multiple of 4", ErrorDumpSyntax, len(data))
+		}
+		for i := 0; i < len(data); i += 4 {
+			seq.pixelData.Offsets = append(seq.pixelData.Offsets, binary.LittleEndian.Uint32(data[i:]))
+		}
+		return nil
+	}
+	seq.pixelData.Frames = append(seq.pixelData.Frames, &frame.Frame{
+		Encapsulated:     true,
+		EncapsulatedData: frame.EncapsulatedFrame{Data: data},
+	})
+	return nil
+}
+
+// nativePixelData reads native PixelData frames from a binary value, using
+// the elements parsed so far.
+func (p *dumpParser) nativePixelData(value string) (Value, error) {
+	if value == "(not loaded)" {
+		return &pixelDataValue{PixelDataInfo{IntentionallySkipped: true}}, nil
+	}
+	data, err := p.binaryValue(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return &pixelDataValue{}, nil
+	}
+	r := &reader{rawReader: dicomio.NewReader(bufio.NewReader(bytes.NewReader(data)), binary.LittleEndian, int64(len(data)))}
+	info, _, err := r.readNativeFrames(&p.ds, nil, uint32(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	return &pixelDataValue{*info}, nil
+}
+
+func (p *dumpParser) parseValue(t tag.Tag, vr, value string) (Value, error) {
+	parsedVR, err := tag.ParseVR(vr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorDumpSyntax, err)
+	}
+	empty := value == "(no value available)"
+	var words []string
+	if !empty {
+		words = strings.Split(value, "\\")
+	}
+
+	switch parsedVR.Kind() {
+	case tag.VRBytes, tag.VRUnknown:
+		data, err := p.binaryValue(value)
+		if err != nil {
+			return nil, err
+		}
+		return &bytesValue{value: data}, nil
+	case tag.VRUInt16List, tag.VRUInt32List, tag.VRInt16List, tag.VRInt32List:
+		values := make([]int, len(words))
+		for i, w := range words {
+			if values[i], err = strconv.Atoi(w); err != nil {
+				return nil, fmt.Errorf("%w: invalid %v value %q", ErrorDumpSyntax, vr, w)
+			}
+		}
+		return &intsValue{value: values}, nil
+	case tag.VRInt64List:
+		values := make([]int64, len(words))
+		for i, w := range words {
+			if values[i], err = strconv.ParseInt(w, 10, 64); err != nil {
+				return nil, fmt.Errorf("%w: invalid %v value %q", ErrorDumpSyntax, vr, w)
+			}
+		}
+		return &int64sValue{value: values}, nil
+	case tag.VRUInt64List:
+		values := make([]uint64, len(words))
+		for i, w := range words {
+			if values[i], err = strconv.ParseUint(w, 10, 64); err != nil {
+				return nil, fmt.Errorf("%w: invalid %v value %q", ErrorDumpSyntax, vr, w)
+			}
+		}
+		return &uint64sValue{value: values}, nil
+	case tag.VRFloat32List, tag.VRFloat64List:
+		values := make([]float64, len(words))
+		for i, w := range words {
+			if values[i], err = strconv.ParseFloat(w, 64); err != nil {
+				return nil, fmt.Errorf("%w: invalid %v value %q", ErrorDumpSyntax, vr, w)
+			}
+		}
+		return &floatsValue{value: values}, nil
+	case tag.VRTagList:
+		values := make([]tag.Tag, len(words))
+		for i, w := range words {
+			if _, err := fmt.Sscanf(w, "(%04x,%04x)", &values[i].Group, &values[i].Element); err != nil {
+				return nil, fmt.Errorf("%w: invalid AT value %q", ErrorDumpSyntax, w)
+			}
+		}
+		return &tagsValue{value: values}, nil
+	case tag.VRSequence:
+		return nil, fmt.Errorf("%w: expected (Sequence with ...) as the value of %v", ErrorDumpSyntax, t)
+	}
+
+	// Strings
+	switch {
+	case empty:
+		return &stringsValue{value: []string{""}}, nil
+	case strings.HasPrefix(value, "=") && parsedVR == tag.UI:
+		info, err := uid.LookupKeyword(value[1:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrorDumpSyntax, err)
+		}
+		return &stringsValue{value: []string{info.UID}}, nil
+	case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+		return &stringsValue{value: strings.Split(value[1:len(value)-1], "\\")}, nil
+	default:
+		return nil, fmt.Errorf("%w: expected a %v value in brackets, got %q", ErrorDumpSyntax, vr, value)
+	}
+}
+
+// binaryValue returns the bytes of hexadecimal words, or of the file of a
+// "=name" value.
+func (p *dumpParser) binaryValue(value string) ([]byte, error) {
+	if value == "(no value available)" {
+		return nil, nil
+	}
+	if name, ok := strings.CutPrefix(value, "="); ok {
+		if p.opts.files == nil {
+			return nil, fmt.Errorf("%w: no files to read %q from, see DumpFiles", ErrorDumpSyntax, name)
+		}
+		return fs.ReadFile(p.opts.files, name)
+	}
+	var data []byte
+	for _, w := range strings.Split(value, "\\") {
+		if len(w) == 0 || len(w)%2 != 0 || len(w) > 16 {
+			return nil, fmt.Errorf("%w: invalid hexadecimal word %q", ErrorDumpSyntax, w)
+		}
+		word, err := strconv.ParseUint(w, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid hexadecimal word %q", ErrorDumpSyntax, w)
+		}
+		for i := 0; i < len(w)/2; i++ {
+			data = append(data, byte(word>>(8*i)))
+		}
+	}
+	return data, nil
+}
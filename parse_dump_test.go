@@ -0,0 +1,143 @@
+package dicom
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wybaby168/dicom/pkg/frame"
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/uid"
+)
+
+func TestParseDump_RoundTrip(t *testing.T) {
+	cases := []struct {
+		name string
+		ds   Dataset
+	}{
+		{
+			name: "dump test dataset",
+			ds:   dumpTestDataset(),
+		},
+		{
+			name: "values",
+			ds: Dataset{Elements: []*Element{
+				mustNewElement(tag.ImageType, []string{"ORIGINAL", "PRIMARY"}),
+				mustNewElement(tag.StudyDescription, []string{strings.Repeat("a", 100)}),
+				mustNewElement(tag.FrameIncrementPointer, []tag.Tag{tag.FrameTime, tag.FrameDelay}),
+				mustNewElement(tag.PixelSpacing, []string{"0.5", "0.5"}),
+				mustNewElement(tag.RescaleSlope, []string{"1"}),
+				mustNewElement(tag.BitsAllocated, []int{8}),
+				mustNewElement(tag.SamplesPerPixel, []int{1}),
+				mustNewElement(tag.Rows, []int{2}),
+				mustNewElement(tag.Columns, []int{2}),
+				mustNewElement(tag.PixelData, PixelDataInfo{
+					Frames: []*frame.Frame{{
+						NativeData: frame.NewNativeFrame[uint8](8, 2, 2, 4, 1),
+					}},
+				}),
+			}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := Dump(&out, tc.ds, MaxValueLength(0)); err != nil {
+				t.Fatalf("Dump() unexpected error: %v", err)
+			}
+			got, err := ParseDump(strings.NewReader(out.String()))
+			if err != nil {
+				t.Fatalf("ParseDump() unexpected error: %v\n%s", err, out.String())
+			}
+			if changes := Diff(tc.ds, got); len(changes) > 0 {
+				t.Errorf("ParseDump(Dump()) differs from the dumped Dataset: %v\n%s", changes, out.String())
+			}
+		})
+	}
+}
+
+func TestParseDump(t *testing.T) {
+	in := `# Dicom-Data-Set
+00000000: (0002,0010) UI =ImplicitVRLittleEndian    # comment [with brackets]
+(0010,0010) PN [Doe^John]
+(0010,0020) LO [a] b]       # ] ends at the last bracket before the comment
+(0008,1115) SQ (Sequence with undefined length #=2)
+  (fffe,e000) na (Item with undefined length #=1)
+    (0020,000e) UI [1.2.3]
+  (fffe,e000) na (Item with undefined length #=1)
+    (0020,000e) UI [1.2.4]
+(fffe,e0dd) na (SequenceDelimitationItem)
+(0029,1001) OB 0201\0403
+(0029,1002) OB =blob.bin
+(7fe0,0010) OW (PixelSequence #=2)
+  (fffe,e000) pi 00000000
+  (fffe,e000) pi =frame.jpg
+(fffe,e0dd) na (SequenceDelimitationItem)
+`
+	files := fstest.MapFS{
+		"blob.bin":  {Data: []byte{5, 6}},
+		"frame.jpg": {Data: []byte{0xff, 0xd8}},
+	}
+	got, err := ParseDump(strings.NewReader(in), DumpFiles(files))
+	if err != nil {
+		t.Fatalf("ParseDump() unexpected error: %v", err)
+	}
+
+	seq := makeSequenceElement(tag.ReferencedSeriesSequence, [][]*Element{
+		{mustNewElement(tag.SeriesInstanceUID, []string{"1.2.3"})},
+		{mustNewElement(tag.SeriesInstanceUID, []string{"1.2.4"})},
+	})
+	pixelData := mustNewElement(tag.PixelData, PixelDataInfo{
+		IsEncapsulated: true,
+		Offsets:        []uint32{0},
+		Frames: []*frame.Frame{
+			{Encapsulated: true, EncapsulatedData: frame.EncapsulatedFrame{Data: []byte{0xff, 0xd8}}},
+		},
+	})
+	want := Dataset{Elements: []*Element{
+		mustNewElement(tag.TransferSyntaxUID, []string{uid.ImplicitVRLittleEndian}),
+		mustNewElement(tag.PatientName, []string{"Doe^John"}),
+		mustNewElement(tag.PatientID, []string{"a] b"}),
+		seq,
+		mustNewPrivateElement(tag.Tag{Group: 0x0029, Element: 0x1001}, "OB", []byte{1, 2, 3, 4}),
+		mustNewPrivateElement(tag.Tag{Group: 0x0029, Element: 0x1002}, "OB", []byte{5, 6}),
+		pixelData,
+	}}
+	if changes := Diff(want, got); len(changes) > 0 {
+		t.Errorf("ParseDump() unexpected changes: %v", changes)
+	}
+	if got.Elements[3].ValueLength != tag.VLUndefinedLength {
+		t.Errorf("ParseDump() sequence ValueLength = %d, want undefined", got.Elements[3].ValueLength)
+	}
+	if diff := cmp.Diff(pixelData.Value, got.Elements[6].Value, cmp.AllowUnexported(allValues...)); diff != "" {
+		t.Errorf("ParseDump() unexpected PixelData (-want +got): %v", diff)
+	}
+}
+
+func TestParseDump_Errors(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+	}{
+		{name: "no tag", in: "PatientName [Bob]"},
+		{name: "unknown VR", in: "(0010,0010) XX [Bob]"},
+		{name: "unterminated string", in: "(0010,0010) PN [Bob"},
+		{name: "invalid number", in: "(0028,0010) US two"},
+		{name: "odd hex word", in: "(0029,1001) OB 012"},
+		{name: "file without DumpFiles", in: "(0029,1001) OB =blob.bin"},
+		{name: "unknown UID keyword", in: "(0002,0010) UI =NoSuchTransferSyntax"},
+		{name: "item outside of a sequence", in: "(fffe,e000) na (Item with undefined length #=0)"},
+		{name: "element outside of an item", in: "(0008,1115) SQ (Sequence with undefined length #=0)\n(0010,0010) PN [Bob]\n(fffe,e0dd) na"},
+		{name: "unclosed sequence", in: "(0008,1115) SQ (Sequence with undefined length #=0)"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseDump(strings.NewReader(tc.in))
+			if !errors.Is(err, ErrorDumpSyntax) {
+				t.Errorf("ParseDump(%q) = %v, want ErrorDumpSyntax", tc.in, err)
+			}
+		})
+	}
+}
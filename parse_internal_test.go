@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/uid"
 )
 
 // parse_internal_test.go holds tests that must exist in the dicom package (as
@@ -59,10 +60,10 @@ func TestParse_InfersMissingTransferSyntax(t *testing.T) {
 	dsWithMissingTS := Dataset{Elements: []*Element{
 		mustNewElement(tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.1.2"}),
 		mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4.5.6.7"}),
-		mustNewElement(tag.PatientName, []string{"Bob", "Jones"}),
+		mustNewElement(tag.PatientName, []string{"Bob^Jones"}),
 		mustNewElement(tag.Rows, []int{128}),
 		mustNewElement(tag.FloatingPointValue, []float64{128.10}),
-		mustNewElement(tag.DimensionIndexPointer, []int{32, 36950}),
+		mustNewElement(tag.DimensionIndexPointer, []tag.Tag{tag.StackID}),
 		mustNewElement(tag.RedPaletteColorLookupTableData, make([]byte, 200)),
 	}}
 
@@ -107,6 +108,94 @@ func TestParse_InfersMissingTransferSyntax(t *testing.T) {
 	}
 }
 
+func TestParse_RecordOffsets(t *testing.T) {
+	ds := Dataset{Elements: []*Element{
+		mustNewElement(tag.TransferSyntaxUID, []string{uid.ExplicitVRLittleEndian}),
+		mustNewElement(tag.PatientName, []string{"Bob"}),
+		mustNewElement(tag.ReferencedSeriesSequence, [][]*Element{
+			{mustNewElement(tag.SeriesInstanceUID, []string{"1.2.3"})},
+			{mustNewElement(tag.SeriesInstanceUID, []string{"1.2.4"}), mustNewElement(tag.Rows, []int{128})},
+		}),
+		mustNewElement(tag.PatientID, []string{"123"}),
+	}}
+	var buf bytes.Buffer
+	if err := Write(&buf, ds); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	data := buf.Bytes()
+
+	parsed, err := Parse(bytes.NewReader(data), int64(len(data)), nil, RecordOffsets())
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	// checkTag checks that the tag at offset in data is want.
+	checkTag := func(name string, offset int64, want tag.Tag) {
+		t.Helper()
+		got := tag.Tag{
+			Group:   uint16(data[offset]) | uint16(data[offset+1])<<8,
+			Element: uint16(data[offset+2]) | uint16(data[offset+3])<<8,
+		}
+		if got != want {
+			t.Errorf("%s Offset %d points to tag %v, want %v", name, offset, got, want)
+		}
+	}
+	var checkElements func(elems []*Element, end int64)
+	checkElements = func(elems []*Element, end int64) {
+		t.Helper()
+		for i, elem := range elems {
+			pos := elem.Position
+			if pos == nil {
+				t.Fatalf("Parse() element %v has no Position", elem.Tag)
+			}
+			checkTag(elem.Tag.String(), pos.Offset, elem.Tag)
+			if pos.ValueLength != elem.ValueLength {
+				t.Errorf("element %v Position.ValueLength = %d, want %d", elem.Tag, pos.ValueLength, elem.ValueLength)
+			}
+			if !pos.UndefinedLength() && pos.EndOffset != pos.ValueOffset+int64(pos.ValueLength) {
+				t.Errorf("element %v EndOffset = %d, want ValueOffset %d + %d", elem.Tag, pos.EndOffset, pos.ValueOffset, pos.ValueLength)
+			}
+			if i+1 < len(elems) && pos.EndOffset != elems[i+1].Position.Offset {
+				t.Errorf("element %v EndOffset = %d, want the next element's Offset %d", elem.Tag, pos.EndOffset, elems[i+1].Position.Offset)
+			}
+			if i+1 == len(elems) && end >= 0 && pos.EndOffset != end {
+				t.Errorf("element %v EndOffset = %d, want the end of its item %d", elem.Tag, pos.EndOffset, end)
+			}
+			if elem.Value.ValueType() != Sequences {
+				continue
+			}
+			checkTag(elem.Tag.String()+" end", pos.EndOffset-8, tag.SequenceDelimitationItem)
+			for _, item := range elem.Value.GetValue().([]*SequenceItemValue) {
+				itemPos := item.Position()
+				if itemPos == nil || !itemPos.UndefinedLength() || itemPos.HeaderLength() != 8 {
+					t.Fatalf("item of %v has Position %+v, want one of undefined length", elem.Tag, itemPos)
+				}
+				checkTag("item", itemPos.Offset, tag.Item)
+				checkTag("item end", itemPos.EndOffset-8, tag.ItemDelimitationItem)
+				checkElements(item.elements, itemPos.EndOffset-8)
+			}
+		}
+	}
+	checkElements(parsed.Elements, int64(len(data)))
+
+	if got := parsed.Elements[0].Position.Offset; got != 128+4 {
+		t.Errorf("FileMetaInformationGroupLength Offset = %d, want 132 after the preamble", got)
+	}
+	if pos := parsed.Elements[len(parsed.Elements)-1].Position; pos.Implicit || pos.HeaderLength() != 8 {
+		t.Errorf("PatientID Position = %+v, want an explicit VR header of 8 bytes", pos)
+	}
+
+	unrecorded, err := Parse(bytes.NewReader(data), int64(len(data)), nil)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	for _, elem := range unrecorded.Elements {
+		if elem.Position != nil {
+			t.Errorf("Parse() without RecordOffsets set Position of %v", elem.Tag)
+		}
+	}
+}
+
 func readTestdataFile(t *testing.T, name string) *os.File {
 	dcm, err := os.Open("./testdata/" + name)
 	if err != nil {
@@ -3,6 +3,7 @@ package dicom_test
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image/jpeg"
 	"io"
@@ -206,7 +207,7 @@ func TestParseFile_AllowUnknownSpecificCharacterSet(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Unexpected error when creating specific character set element: %v", err)
 	}
-	patientNameElement, err := dicom.NewElement(tag.PatientName, []string{"Bob", "Jones"})
+	patientNameElement, err := dicom.NewElement(tag.PatientName, []string{"Bob^Jones"})
 	if err != nil {
 		t.Fatalf("Unexpected error when creating patient name element: %v", err)
 	}
@@ -235,6 +236,133 @@ func TestParseFile_AllowUnknownSpecificCharacterSet(t *testing.T) {
 	})
 }
 
+func TestParse_StrictValueMultiplicity(t *testing.T) {
+	transferSyntaxUIDElement, err := dicom.NewElement(tag.TransferSyntaxUID, []string{uid.ExplicitVRLittleEndian})
+	if err != nil {
+		t.Fatalf("Unexpected error when creating transfer syntax uid element: %v", err)
+	}
+	// NewElement rejects multiple values for Modality, so build the element
+	// directly.
+	modalityValue, err := dicom.NewValue([]string{"CT", "MR"})
+	if err != nil {
+		t.Fatalf("Unexpected error when creating modality value: %v", err)
+	}
+	ds := dicom.Dataset{Elements: []*dicom.Element{
+		transferSyntaxUIDElement,
+		{Tag: tag.Modality, ValueRepresentation: tag.VRStringList, RawValueRepresentation: "CS", Value: modalityValue},
+	}}
+	var buf bytes.Buffer
+	if err := dicom.Write(&buf, ds); err != nil {
+		t.Fatalf("Unexpected error writing dataset: %v", err)
+	}
+
+	if _, err := dicom.Parse(bytes.NewReader(buf.Bytes()), int64(buf.Len()), nil); err != nil {
+		t.Errorf("Parse() without StrictValueMultiplicity unexpected error: %v", err)
+	}
+	_, err = dicom.Parse(bytes.NewReader(buf.Bytes()), int64(buf.Len()), nil, dicom.StrictValueMultiplicity())
+	if !errors.Is(err, dicom.ErrorValueMultiplicity) {
+		t.Errorf("Parse() with StrictValueMultiplicity returned err %v, want %v", err, dicom.ErrorValueMultiplicity)
+	}
+}
+
+// TestParse_ISO2022CodeExtensions writes and parses the examples of PS3.5
+// Annex H, I and J, which switch character sets with ISO 2022 escape
+// sequences.
+func TestParse_ISO2022CodeExtensions(t *testing.T) {
+	cases := []struct {
+		name    string
+		charset []string
+		tag     tag.Tag
+		raw     string
+		want    string
+		// decodeOnly cases have raw values Write doesn't produce.
+		decodeOnly bool
+	}{
+		{
+			name:    "H.3.1 JIS X 0208",
+			charset: []string{"", "ISO 2022 IR 87"},
+			tag:     tag.PatientName,
+			raw:     "Yamada^Tarou=\x1b$B;3ED\x1b(B^\x1b$BB@O:\x1b(B=\x1b$B$d$^$@\x1b(B^\x1b$B$?$m$&\x1b(B",
+			want:    "Yamada^Tarou=山田^太郎=やまだ^たろう",
+		},
+		{
+			name:    "H.3.2 JIS X 0201 and JIS X 0208",
+			charset: []string{"ISO 2022 IR 13", "ISO 2022 IR 87"},
+			tag:     tag.PatientName,
+			raw:     "\xd4\xcf\xc0\xde^\xc0\xdb\xb3=\x1b$B;3ED\x1b(J^\x1b$BB@O:\x1b(J=\x1b$B$d$^$@\x1b(J^\x1b$B$?$m$&\x1b(J",
+			want:    "ﾔﾏﾀﾞ^ﾀﾛｳ=山田^太郎=やまだ^たろう",
+		},
+		{
+			name:    "I.2 KS X 1001",
+			charset: []string{"", "ISO 2022 IR 149"},
+			tag:     tag.PatientName,
+			raw:     "Hong^Gildong=\x1b$)C\xfb\xf3^\x1b$)C\xd1\xce\xd4\xd7=\x1b$)C\xc8\xab^\x1b$)C\xb1\xe6\xb5\xbf",
+			want:    "Hong^Gildong=洪^吉洞=홍^길동",
+		},
+		{
+			name:    "J.3 GB 2312",
+			charset: []string{"", "ISO 2022 IR 58"},
+			tag:     tag.PatientName,
+			raw:     "Zhang^XiaoDong=\x1b$)A\xd5\xc5^\x1b$)A\xd0\xa1\xb6\xab=",
+			want:    "Zhang^XiaoDong=张^小东=",
+		},
+		{
+			// The second value doesn't designate KS X 1001 again, so it is
+			// decoded with the default G1 set.
+			name:       "values reset to the initial character set",
+			charset:    []string{"", "ISO 2022 IR 149"},
+			tag:        tag.OtherPatientNames,
+			raw:        "\x1b$)C\xfb\xf3\\\xc8\xab",
+			want:       "洪\\È«",
+			decodeOnly: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ds dicom.Dataset
+			for _, e := range []struct {
+				tag  tag.Tag
+				data []string
+			}{
+				{tag.TransferSyntaxUID, []string{uid.ExplicitVRLittleEndian}},
+				{tag.SpecificCharacterSet, tc.charset},
+				{tc.tag, []string{tc.want}},
+			} {
+				elem, err := dicom.NewElement(e.tag, e.data)
+				if err != nil {
+					t.Fatalf("Unexpected error when creating %v element: %v", e.tag, err)
+				}
+				ds.Elements = append(ds.Elements, elem)
+			}
+			var buf bytes.Buffer
+			if tc.decodeOnly {
+				// Write a placeholder of the same length, and replace it.
+				ds.Elements[2], _ = dicom.NewElement(tc.tag, []string{strings.Repeat("x", len(tc.raw))})
+			}
+			if err := dicom.Write(&buf, ds); err != nil {
+				t.Fatalf("Unexpected error writing dataset: %v", err)
+			}
+			data := buf.Bytes()
+			if tc.decodeOnly {
+				data = bytes.Replace(data, []byte(strings.Repeat("x", len(tc.raw))), []byte(tc.raw), 1)
+			} else if !bytes.Contains(data, []byte(tc.raw)) {
+				t.Errorf("Write() did not encode %v as %q", tc.tag, tc.raw)
+			}
+			parsed, err := dicom.Parse(bytes.NewReader(data), int64(len(data)), nil)
+			if err != nil {
+				t.Fatalf("Unexpected error parsing dataset: %v", err)
+			}
+			e, err := parsed.FindElementByTag(tc.tag)
+			if err != nil {
+				t.Fatalf("FindElementByTag(%v) unexpected error: %v", tc.tag, err)
+			}
+			if got := strings.Join(dicom.MustGetStrings(e.Value), "\\"); got != tc.want {
+				t.Errorf("Parse() decoded %v as %q, want %q", tc.tag, got, tc.want)
+			}
+		})
+	}
+}
+
 // BenchmarkParse runs sanity benchmarks over the sample files in testdata.
 func BenchmarkParse(b *testing.B) {
 	cases := []struct {
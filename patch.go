@@ -0,0 +1,268 @@
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/wybaby168/dicom/pkg/dicomio"
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/uid"
+)
+
+var (
+	// ErrorPatchPixelData indicates that Patch was asked to change the
+	// PixelData, or an element following it, which it neither parses nor
+	// rewrites.
+	ErrorPatchPixelData = errors.New("cannot patch the PixelData or elements following it")
+	// ErrorPatchGroupLength indicates that Patch was asked to change a group
+	// length element, which it recomputes instead.
+	ErrorPatchGroupLength = errors.New("cannot patch group length elements")
+)
+
+// patchCopyBufferSize is the size of the buffer used to move the data
+// following the header when its length changes.
+const patchCopyBufferSize = 1 << 20
+
+// ReadWriterAt is the interface of the files PatchAt changes.
+type ReadWriterAt interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// Patch sets the given top-level elements in the DICOM file f, replacing the
+// elements with the same tags, without parsing or rewriting its PixelData. It
+// is meant to fix header attributes like the PatientID of large files. See
+// PatchAt for details.
+func Patch(f *os.File, elems ...*Element) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size, err := PatchAt(f, info.Size(), elems...)
+	if err != nil {
+		return err
+	}
+	if size < info.Size() {
+		return f.Truncate(size)
+	}
+	return nil
+}
+
+// PatchAt sets the given top-level elements in the DICOM file of the given
+// size in rw, replacing the elements with the same tags, and returns the new
+// size of the file. Only the elements preceding the PixelData are parsed, so
+// elems must precede it too, otherwise ErrorPatchPixelData is returned. Group
+// lengths are recomputed and cannot be set.
+//
+// If each new value, encoded like the value it replaces, fits in the length of
+// that value, the values are overwritten in place, padded with the padding
+// character of their VR. This only applies to string values, or to values of
+// the same length, and not to new elements or to the Specific Character Set.
+//
+// Otherwise, the part of the file preceding the PixelData is rewritten with
+// PreserveEncoding, recomputing the File Meta Information Group Length and
+// other group lengths, and the rest of the file is moved as is after it. If
+// the file shrinks, the bytes of rw past the returned size are left over and
+// should be truncated, as Patch does. The file is corrupt if PatchAt fails
+// while moving it, so patch a copy of files that cannot be restored.
+//
+// Deflated files are not supported.
+func PatchAt(rw ReadWriterAt, size int64, elems ...*Element) (int64, error) {
+	for _, elem := range elems {
+		if elem.Tag.Compare(tag.PixelData) >= 0 {
+			return 0, ErrorPatchPixelData
+		}
+		if elem.Tag.Element == 0x0000 {
+			return 0, ErrorPatchGroupLength
+		}
+	}
+	header, headerEnd, err := readPatchHeader(rw, size)
+	if err != nil {
+		return 0, err
+	}
+
+	if edits, ok, err := patchValues(header, elems); err != nil {
+		return 0, err
+	} else if ok {
+		for _, edit := range edits {
+			if _, err := rw.WriteAt(edit.data, edit.offset); err != nil {
+				return 0, err
+			}
+		}
+		return size, nil
+	}
+
+	ds := Dataset{Elements: slices.Clone(header.Elements)}
+	for i, elem := range ds.Elements {
+		if elem.Tag == (tag.Tag{Group: tag.PixelData.Group, Element: 0x0000}) {
+			// The group length also covers the PixelData, which is not
+			// rewritten, so keep it as is.
+			c := *elem
+			c.Position = nil
+			ds.Elements[i] = &c
+		}
+	}
+	for _, elem := range elems {
+		ds.SetElement(elem)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, ds, PreserveEncoding()); err != nil {
+		return 0, err
+	}
+
+	newHeaderEnd := int64(buf.Len())
+	if err := moveAt(rw, newHeaderEnd, headerEnd, size-headerEnd); err != nil {
+		return 0, err
+	}
+	if _, err := rw.WriteAt(buf.Bytes(), 0); err != nil {
+		return 0, err
+	}
+	return newHeaderEnd + size - headerEnd, nil
+}
+
+// readPatchHeader parses the top-level elements of the DICOM file of the
+// given size in r preceding the PixelData, and returns them with the offset
+// of the PixelData, or size if there is none.
+func readPatchHeader(r io.ReaderAt, size int64) (Dataset, int64, error) {
+	p, err := NewParser(io.NewSectionReader(r, 0, size), size, nil, RecordEncoding())
+	if err != nil {
+		return Dataset{}, 0, err
+	}
+	if ts, err := p.dataset.FindElementByTag(tag.TransferSyntaxUID); err == nil {
+		if s, ok := ts.Value.GetValue().([]string); ok && len(s) > 0 && s[0] == uid.DeflatedExplicitVRLittleEndian {
+			return Dataset{}, 0, errorDeflatedTransferSyntaxUnsupported
+		}
+	}
+	for p.reader.moreToRead() {
+		if next, err := p.reader.rawReader.Peek(4); err == nil {
+			bo := p.reader.rawReader.ByteOrder()
+			t := tag.Tag{Group: bo.Uint16(next[0:2]), Element: bo.Uint16(next[2:4])}
+			if t == tag.PixelData {
+				break
+			}
+		}
+		if _, err := p.Next(); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return Dataset{}, 0, err
+		}
+	}
+	return p.dataset, p.reader.rawReader.BytesRead(), nil
+}
+
+// patchEdit is a value to write at an offset of the file.
+type patchEdit struct {
+	offset int64
+	data   []byte
+}
+
+// patchValues returns the edits replacing the values of the elements of
+// header with elems in place, or false if some of them do not fit.
+func patchValues(header Dataset, elems []*Element) ([]patchEdit, bool, error) {
+	bo, implicit, err := header.transferSyntax()
+	if err != nil {
+		bo, implicit = binary.LittleEndian, true
+	}
+	meta := dicomio.NewWriter(nil, binary.LittleEndian, false)
+	body := dicomio.NewWriter(nil, bo, implicit)
+	if cs, err := header.FindElementByTag(tag.SpecificCharacterSet); err == nil {
+		setCodingSystem(body, cs)
+	}
+
+	var edits []patchEdit
+	ok := true
+	for _, elem := range elems {
+		old, err := header.FindElementByTag(elem.Tag)
+		if err != nil || elem.Tag == tag.SpecificCharacterSet {
+			ok = false
+			continue
+		}
+		w := body
+		if elem.Tag.Group == tag.MetadataGroup {
+			w = meta
+		}
+		data, fits, err := patchValue(w, old, elem)
+		if err != nil {
+			return nil, false, err
+		}
+		if !fits {
+			ok = false
+			continue
+		}
+		edits = append(edits, patchEdit{offset: old.Position.ValueOffset, data: data})
+	}
+	return edits, ok, nil
+}
+
+// patchValue returns the value of elem encoded with w to replace the value of
+// old in place, or false if it does not fit.
+func patchValue(w *dicomio.Writer, old, elem *Element) ([]byte, bool, error) {
+	vr, err := verifyVROrDefault(elem.Tag, elem.RawValueRepresentation, writeOptSet{})
+	if err != nil {
+		return nil, false, err
+	}
+	if elem.Value == nil {
+		return nil, false, nil
+	}
+	parsed, _ := tag.ParseVR(vr)
+	if err := verifyValueType(elem.Tag, elem.Value, parsed); err != nil {
+		return nil, false, err
+	}
+	_, implicit := w.GetTransferSyntax()
+	if old.Position == nil || old.Position.UndefinedLength() || !implicit && vr != old.RawValueRepresentation {
+		return nil, false, nil
+	}
+	data, err := encodeValue(w, elem, parsed, elem.ValueLength, writeOptSet{})
+	if err != nil {
+		return nil, false, err
+	}
+	length := int(old.Position.ValueLength)
+	if len(data) == length {
+		return data, true, nil
+	}
+	// Trailing padding is not significant in string values, but a value of
+	// only padding would read as spaces.
+	switch parsed.Kind() {
+	case tag.VRStringList, tag.VRString, tag.VRDate:
+	default:
+		return nil, false, nil
+	}
+	if len(data) == 0 || len(data) > length {
+		return nil, false, nil
+	}
+	return append(data, bytes.Repeat([]byte{parsed.Padding()}, length-len(data))...), true, nil
+}
+
+// moveAt moves the n bytes at offset src of rw to offset dst, which may
+// overlap.
+func moveAt(rw ReadWriterAt, dst, src, n int64) error {
+	if dst == src || n == 0 {
+		return nil
+	}
+	buf := make([]byte, min(n, patchCopyBufferSize))
+	for done := int64(0); done < n; {
+		chunk := min(n-done, int64(len(buf)))
+		// Copy backwards when moving towards the end, so that no byte is
+		// overwritten before it is moved.
+		off := done
+		if dst > src {
+			off = n - done - chunk
+		}
+		if read, err := rw.ReadAt(buf[:chunk], src+off); int64(read) < chunk {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		if _, err := rw.WriteAt(buf[:chunk], dst+off); err != nil {
+			return err
+		}
+		done += chunk
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package dicom
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wybaby168/dicom/pkg/frame"
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/uid"
+)
+
+// patchTestDataset returns a Dataset with PixelData larger than the buffer
+// used to move it, with the given top-level elements set.
+func patchTestDataset(t *testing.T, elems ...*Element) Dataset {
+	t.Helper()
+	rows, cols := 1024, 1536
+	nativeFrame := frame.NewNativeFrame[uint8](8, rows, cols, rows*cols, 1)
+	for i := range nativeFrame.RawData {
+		nativeFrame.RawData[i] = uint8(i % 251)
+	}
+	ds := Dataset{Elements: []*Element{
+		mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3"}),
+		mustNewElement(tag.TransferSyntaxUID, []string{uid.ExplicitVRLittleEndian}),
+		mustNewElement(tag.StudyDescription, []string{"Head CT"}),
+		mustNewElement(tag.PatientName, []string{"Doe^John"}),
+		mustNewElement(tag.PatientID, []string{"123456789"}),
+		mustNewElement(tag.SamplesPerPixel, []int{1}),
+		mustNewElement(tag.Rows, []int{rows}),
+		mustNewElement(tag.Columns, []int{cols}),
+		mustNewElement(tag.BitsAllocated, []int{8}),
+		mustNewElement(tag.PixelData, PixelDataInfo{
+			Frames: []*frame.Frame{{NativeData: nativeFrame}},
+		}),
+	}}
+	for _, elem := range elems {
+		ds.SetElement(elem)
+	}
+	return ds
+}
+
+func writePatchTestFile(t *testing.T, ds Dataset) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, ds); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestPatch(t *testing.T) {
+	cases := []struct {
+		name    string
+		elems   []*Element
+		inPlace bool
+	}{
+		{
+			name:    "shorter string in place",
+			elems:   []*Element{mustNewElement(tag.PatientID, []string{"ABC"})},
+			inPlace: true,
+		},
+		{
+			name: "several values in place",
+			elems: []*Element{
+				mustNewElement(tag.PatientID, []string{"987654321"}),
+				mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2"}),
+				mustNewElement(tag.Rows, []int{1024}),
+			},
+			inPlace: true,
+		},
+		{
+			name:  "longer string",
+			elems: []*Element{mustNewElement(tag.PatientID, []string{"a much longer patient ID"})},
+		},
+		{
+			name:  "longer meta element",
+			elems: []*Element{mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.840.10008.1234.5678"})},
+		},
+		{
+			name:  "empty string",
+			elems: []*Element{mustNewElement(tag.StudyDescription, []string{""})},
+		},
+		{
+			name:  "new element",
+			elems: []*Element{mustNewElement(tag.PatientBirthDate, []string{"19700101"})},
+		},
+		{
+			name: "in place and new element",
+			elems: []*Element{
+				mustNewElement(tag.PatientID, []string{"ABC"}),
+				mustNewElement(tag.PatientSex, []string{"O"}),
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := writePatchTestFile(t, patchTestDataset(t))
+			path := filepath.Join(t.TempDir(), "test.dcm")
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatalf("WriteFile() unexpected error: %v", err)
+			}
+			f, err := os.OpenFile(path, os.O_RDWR, 0)
+			if err != nil {
+				t.Fatalf("OpenFile() unexpected error: %v", err)
+			}
+			defer f.Close()
+
+			if err := Patch(f, tc.elems...); err != nil {
+				t.Fatalf("Patch() unexpected error: %v", err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile() unexpected error: %v", err)
+			}
+
+			if tc.inPlace {
+				if len(got) != len(data) {
+					t.Errorf("Patch() changed the file size from %d to %d, want it patched in place", len(data), len(got))
+				}
+				parsed, err := Parse(bytes.NewReader(got), int64(len(got)), nil)
+				if err != nil {
+					t.Fatalf("Parse() unexpected error: %v", err)
+				}
+				// The group length is kept, with the padding of the values.
+				parsed.Elements = parsed.Elements[1:]
+				if changes := Diff(patchTestDataset(t, tc.elems...), parsed); len(changes) > 0 {
+					t.Errorf("Patch() unexpected changes: %v", changes)
+				}
+				return
+			}
+			// Unchanged elements are written as they were read, so the file
+			// is the file written from the patched Dataset.
+			want := writePatchTestFile(t, patchTestDataset(t, tc.elems...))
+			if !bytes.Equal(got, want) {
+				t.Errorf("Patch() file of %d bytes differs from the written patched Dataset of %d bytes", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestPatchAt_Errors(t *testing.T) {
+	data := writePatchTestFile(t, patchTestDataset(t))
+	cases := []struct {
+		name  string
+		elems []*Element
+		want  error
+	}{
+		{
+			name:  "PixelData",
+			elems: []*Element{mustNewElement(tag.PixelData, []byte{1, 2})},
+			want:  ErrorPatchPixelData,
+		},
+		{
+			name:  "element after PixelData",
+			elems: []*Element{mustNewElement(tag.DataSetTrailingPadding, []byte{0, 0})},
+			want:  ErrorPatchPixelData,
+		},
+		{
+			name:  "group length",
+			elems: []*Element{mustNewElement(tag.FileMetaInformationGroupLength, []int{0})},
+			want:  ErrorPatchGroupLength,
+		},
+		{
+			name:  "mismatched value type",
+			elems: []*Element{{Tag: tag.PatientID, RawValueRepresentation: "LO", Value: &intsValue{value: []int{1}}}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := &bufferFile{data: bytes.Clone(data)}
+			_, err := PatchAt(f, int64(len(f.data)), tc.elems...)
+			if err == nil || tc.want != nil && !errors.Is(err, tc.want) {
+				t.Errorf("PatchAt() = %v, want %v", err, tc.want)
+			}
+			if !bytes.Equal(f.data, data) {
+				t.Errorf("PatchAt() changed the file despite failing")
+			}
+		})
+	}
+}
+
+// bufferFile is an in-memory ReadWriterAt.
+type bufferFile struct {
+	data []byte
+}
+
+func (f *bufferFile) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *bufferFile) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(f.data) {
+		f.data = append(f.data, make([]byte, end-len(f.data))...)
+	}
+	return copy(f.data[off:], p), nil
+}
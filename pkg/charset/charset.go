@@ -1,7 +1,10 @@
 package charset
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"strings"
 
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/htmlindex"
@@ -20,6 +23,90 @@ type CodingSystem struct {
 	Alphabetic  *encoding.Decoder
 	Ideographic *encoding.Decoder
 	Phonetic    *encoding.Decoder
+
+	// codeElements are the ISO 2022 graphic sets of the Specific Character
+	// Set, if all of its values have one. Values are encoded with them.
+	codeElements *iso2022
+	// extensions is true if the Specific Character Set uses ISO 2022 code
+	// extensions, in which case values are decoded by switching between the
+	// codeElements at escape sequences instead of with the decoders above.
+	extensions bool
+	// enc encodes values if there are no codeElements, e.g. for GB18030. It
+	// is nil for UTF-8.
+	enc encoding.Encoding
+}
+
+// ErrorUnrepresentableCharacter is returned when encoding a character the
+// Specific Character Set can't represent.
+var ErrorUnrepresentableCharacter = errors.New("character can not be represented in the specific character set")
+
+// Decode decodes a value of a VR other than PN to a utf8 string.
+func (cs CodingSystem) Decode(data []byte) (string, error) {
+	if cs.extensions {
+		return cs.codeElements.decode(data, valueDelimiters)
+	}
+	return decode(data, cs.Ideographic)
+}
+
+// DecodePersonName decodes a PN value to a utf8 string. Without code
+// extensions, the alphabetic, ideographic and phonetic component groups are
+// decoded with the respective decoder.
+func (cs CodingSystem) DecodePersonName(data []byte) (string, error) {
+	if cs.extensions {
+		return cs.codeElements.decode(data, personNameDelimiters)
+	}
+	decoders := []*encoding.Decoder{cs.Alphabetic, cs.Ideographic, cs.Phonetic}
+	groups := bytes.SplitN(data, []byte("="), len(decoders))
+	decoded := make([]string, len(groups))
+	for i, group := range groups {
+		s, err := decode(group, decoders[i])
+		if err != nil {
+			return "", err
+		}
+		decoded[i] = s
+	}
+	return strings.Join(decoded, "="), nil
+}
+
+// Encode encodes a utf8 value of a VR other than PN. It returns an error
+// wrapping ErrorUnrepresentableCharacter if the Specific Character Set can't
+// represent s.
+func (cs CodingSystem) Encode(s string) ([]byte, error) {
+	return cs.encode(s, valueDelimiters)
+}
+
+// EncodePersonName is like Encode, but for PN values.
+func (cs CodingSystem) EncodePersonName(s string) ([]byte, error) {
+	return cs.encode(s, personNameDelimiters)
+}
+
+func (cs CodingSystem) encode(s string, delimiters string) ([]byte, error) {
+	if cs.codeElements != nil {
+		return cs.codeElements.encode(s, delimiters)
+	}
+	if cs.enc == nil {
+		return []byte(s), nil
+	}
+	data, err := cs.enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorUnrepresentableCharacter, err)
+	}
+	return data, nil
+}
+
+func decode(data []byte, d *encoding.Decoder) (string, error) {
+	if len(data) == 0 {
+		return "", nil
+	}
+	if d == nil {
+		// Assume UTF-8
+		return string(data), nil
+	}
+	decoded, err := d.Bytes(data)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
 }
 
 // CodingSystemType defines the where the coding system is going to be
@@ -65,8 +152,8 @@ var htmlEncodingNames = map[string]string{
 	"ISO 2022 IR 148": "iso-ir-148",
 	"ISO 2022 IR 149": "euc-kr",
 	"ISO 2022 IR 159": "iso-2022-jp",
-	"ISO_IR 166":      "iso-ir-166",
-	"ISO 2022 IR 166": "iso-ir-166",
+	"ISO_IR 166":      "windows-874",
+	"ISO 2022 IR 166": "windows-874",
 	"ISO 2022 IR 87":  "iso-2022-jp",
 	"ISO 2022 IR 58":  "iso-ir-58",
 	"ISO_IR 192":      "utf8",
@@ -79,7 +166,64 @@ var htmlEncodingNames = map[string]string{
 // "ISO-IR 100" to encoding.Decoder(s). It will return nil, nil for the default (UTF-8)
 // encoding. Cf. P3.2 D.6.2.
 // https://dicom.nema.org/medical/dicom/2016d/output/chtml/part02/sect_D.6.2.html
+//
+// Multiple names, or a single "ISO 2022" name, enable ISO 2022 code
+// extensions (P3.5 6.1.2.5) in the Decode methods of the CodingSystem. The
+// Encode methods always encode with the character sets of all names.
 func ParseSpecificCharacterSet(encodingNames []string) (CodingSystem, error) {
+	cs, err := parseDecoders(encodingNames)
+	if err != nil || len(encodingNames) == 0 {
+		return cs, err
+	}
+	cs.codeElements = newISO2022(encodingNames)
+	cs.extensions = cs.codeElements != nil && usesCodeExtensions(encodingNames)
+	if cs.codeElements == nil && htmlEncodingNames[encodingNames[0]] != "utf8" {
+		cs.enc, _ = htmlindex.Get(htmlEncodingNames[encodingNames[0]])
+	}
+	return cs, nil
+}
+
+// autoCharacterSets are the candidates of SelectCharacterSet, in order of
+// preference.
+var autoCharacterSets = []string{
+	"ISO_IR 100", "ISO_IR 101", "ISO_IR 109", "ISO_IR 110", "ISO_IR 126",
+	"ISO_IR 127", "ISO_IR 138", "ISO_IR 144", "ISO_IR 148", "ISO_IR 166",
+}
+
+// SelectCharacterSet returns the Specific Character Set to encode values
+// with: none (nil) for the default repertoire, the first single byte
+// character set able to represent all values, and "ISO_IR 192" (UTF-8) for
+// all others.
+func SelectCharacterSet(values []string) []string {
+	ascii := true
+	for _, v := range values {
+		for i := 0; i < len(v) && ascii; i++ {
+			ascii = v[i] < 0x80
+		}
+	}
+	if ascii {
+		return nil
+	}
+	for _, name := range autoCharacterSets {
+		cs, err := ParseSpecificCharacterSet([]string{name})
+		if err == nil && cs.CanEncode(values) {
+			return []string{name}
+		}
+	}
+	return []string{"ISO_IR 192"}
+}
+
+// CanEncode returns true if all values can be encoded.
+func (cs CodingSystem) CanEncode(values []string) bool {
+	for _, v := range values {
+		if _, err := cs.Encode(v); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func parseDecoders(encodingNames []string) (CodingSystem, error) {
 	var decoders []*encoding.Decoder
 	for _, name := range encodingNames {
 		var c *encoding.Decoder
@@ -98,13 +242,13 @@ func ParseSpecificCharacterSet(encodingNames []string) (CodingSystem, error) {
 		decoders = append(decoders, c)
 	}
 	if len(decoders) == 0 {
-		return CodingSystem{nil, nil, nil}, nil
+		return CodingSystem{}, nil
 	}
 	if len(decoders) == 1 {
-		return CodingSystem{decoders[0], decoders[0], decoders[0]}, nil
+		return CodingSystem{Alphabetic: decoders[0], Ideographic: decoders[0], Phonetic: decoders[0]}, nil
 	}
 	if len(decoders) == 2 {
-		return CodingSystem{decoders[0], decoders[1], decoders[1]}, nil
+		return CodingSystem{Alphabetic: decoders[0], Ideographic: decoders[1], Phonetic: decoders[1]}, nil
 	}
-	return CodingSystem{decoders[0], decoders[1], decoders[2]}, nil
+	return CodingSystem{Alphabetic: decoders[0], Ideographic: decoders[1], Phonetic: decoders[2]}, nil
 }
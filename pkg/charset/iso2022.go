@@ -0,0 +1,303 @@
+package charset
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// graphicSet is a character set that ISO 2022 code extensions designate to
+// the G0 or G1 code element with an escape sequence. See P3.3 C.12.1.1.2 and
+// P3.5 6.1.2.5.
+type graphicSet struct {
+	escape string
+	// g1 is true for sets designated to G1, which holds the bytes with the
+	// high bit set. Sets designated to G0 hold the others.
+	g1 bool
+	// multiByte is true for the two byte JIS X 0208 and JIS X 0212 sets in
+	// G0, which are decoded together with their escape sequence.
+	multiByte bool
+	// width is the number of bytes per character.
+	width int
+	enc   encoding.Encoding
+}
+
+// graphicSets are the graphic sets by their escape sequence.
+var graphicSets = map[string]*graphicSet{}
+
+// graphicSetsByName are the graphic sets initially designated by a Specific
+// Character Set (0008,0005) value.
+var graphicSetsByName = map[string][]*graphicSet{}
+
+func init() {
+	for _, def := range []struct {
+		names     []string
+		escape    string
+		g1        bool
+		multiByte bool
+		htmlName  string
+	}{
+		{[]string{"", "ISO 2022 IR 6", "ISO_IR 6"}, "\x1b(B", false, false, "iso-8859-1"},
+		{[]string{"ISO 2022 IR 100", "ISO_IR 100"}, "\x1b-A", true, false, "iso-8859-1"},
+		{[]string{"ISO 2022 IR 101", "ISO_IR 101"}, "\x1b-B", true, false, "iso-8859-2"},
+		{[]string{"ISO 2022 IR 109", "ISO_IR 109"}, "\x1b-C", true, false, "iso-8859-3"},
+		{[]string{"ISO 2022 IR 110", "ISO_IR 110"}, "\x1b-D", true, false, "iso-8859-4"},
+		{[]string{"ISO 2022 IR 144", "ISO_IR 144"}, "\x1b-L", true, false, "iso-ir-144"},
+		{[]string{"ISO 2022 IR 127", "ISO_IR 127"}, "\x1b-G", true, false, "iso-ir-127"},
+		{[]string{"ISO 2022 IR 126", "ISO_IR 126"}, "\x1b-F", true, false, "iso-ir-126"},
+		{[]string{"ISO 2022 IR 138", "ISO_IR 138"}, "\x1b-H", true, false, "iso-ir-138"},
+		{[]string{"ISO 2022 IR 148", "ISO_IR 148"}, "\x1b-M", true, false, "iso-ir-148"},
+		{[]string{"ISO 2022 IR 166", "ISO_IR 166"}, "\x1b-T", true, false, "windows-874"},
+		// JIS X 0201 has Romaji in G0 and Katakana in G1.
+		{[]string{"ISO 2022 IR 13", "ISO_IR 13"}, "\x1b(J", false, false, "shift_jis"},
+		{[]string{"ISO 2022 IR 13", "ISO_IR 13"}, "\x1b)I", true, false, "shift_jis"},
+		{[]string{"ISO 2022 IR 87"}, "\x1b$B", false, true, "iso-2022-jp"},
+		{[]string{"ISO 2022 IR 159"}, "\x1b$(D", false, true, "iso-2022-jp"},
+		{[]string{"ISO 2022 IR 149"}, "\x1b$)C", true, false, "euc-kr"},
+		{[]string{"ISO 2022 IR 58"}, "\x1b$)A", true, false, "iso-ir-58"},
+	} {
+		enc, err := htmlindex.Get(def.htmlName)
+		if err != nil {
+			panic(fmt.Sprintf("Encoding name %s (for %s) not found", def.htmlName, def.escape))
+		}
+		set := &graphicSet{escape: def.escape, g1: def.g1, multiByte: def.multiByte, width: 1, enc: enc}
+		if strings.HasPrefix(def.escape, "\x1b$") {
+			set.width = 2
+		}
+		graphicSets[def.escape] = set
+		for _, name := range def.names {
+			graphicSetsByName[name] = append(graphicSetsByName[name], set)
+		}
+	}
+}
+
+// usesCodeExtensions returns true if a Specific Character Set uses ISO 2022
+// code extensions, i.e. if it has multiple values or an "ISO 2022" value.
+func usesCodeExtensions(encodingNames []string) bool {
+	return len(encodingNames) > 1 || (len(encodingNames) == 1 && strings.HasPrefix(encodingNames[0], "ISO 2022"))
+}
+
+// codeState is the graphic sets designated to G0 and G1. A nil set is not
+// designated.
+type codeState struct {
+	g0, g1 *graphicSet
+}
+
+// iso2022 decodes and encodes values with ISO 2022 escape sequences, P3.5
+// 6.1.2.5.
+type iso2022 struct {
+	// initial is designated by the first Specific Character Set value, and
+	// active at the start of each value and after each delimiter.
+	initial codeState
+	// sets are the graphic sets of all values that encode characters other
+	// than ASCII, in order of preference when encoding.
+	sets []*graphicSet
+}
+
+// newISO2022 returns nil if a Specific Character Set value can't be used
+// with code extensions, like "ISO_IR 192".
+func newISO2022(encodingNames []string) *iso2022 {
+	c := &iso2022{initial: codeState{g0: graphicSets["\x1b(B"]}}
+	if len(encodingNames) == 0 {
+		encodingNames = []string{""}
+	}
+	for i, name := range encodingNames {
+		sets, ok := graphicSetsByName[name]
+		if !ok {
+			return nil
+		}
+		for _, set := range sets {
+			if set.g1 || set.multiByte {
+				c.sets = append(c.sets, set)
+			}
+			if i > 0 {
+				continue
+			}
+			if set.g1 {
+				c.initial.g1 = set
+			} else {
+				c.initial.g0 = set
+			}
+		}
+	}
+	return c
+}
+
+// Delimiters that reset the code elements to the initial ones, P3.5
+// 6.1.2.5.3. PN additionally resets at its component and group delimiters.
+const (
+	valueDelimiters      = "\\\r\n\f\t"
+	personNameDelimiters = valueDelimiters + "^="
+)
+
+// decode decodes data, switching graphic sets at escape sequences and
+// resetting them at delimiters.
+func (c *iso2022) decode(data []byte, delimiters string) (string, error) {
+	var out strings.Builder
+	state := c.initial
+	start := 0
+	flush := func(end int) error {
+		s, err := state.decode(data[start:end])
+		out.WriteString(s)
+		return err
+	}
+	for i := 0; i < len(data); {
+		b := data[i]
+		if b == 0x1b {
+			if set, n := matchEscape(data[i:]); set != nil {
+				if err := flush(i); err != nil {
+					return "", err
+				}
+				if set.g1 {
+					state.g1 = set
+				} else {
+					state.g0 = set
+				}
+				i += n
+				start = i
+				continue
+			}
+		}
+		// Bytes of two byte G0 sets may look like delimiters, while control
+		// characters can't be part of them.
+		isDelimiter := strings.IndexByte(delimiters, b) >= 0 && (!state.g0.multiByte || b < 0x21)
+		if isDelimiter {
+			if err := flush(i); err != nil {
+				return "", err
+			}
+			out.WriteByte(b)
+			state = c.initial
+			start = i + 1
+		}
+		i++
+	}
+	if err := flush(len(data)); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// matchEscape returns the graphic set designated by the escape sequence at
+// the start of data, and the length of the escape sequence.
+func matchEscape(data []byte) (*graphicSet, int) {
+	for n := 3; n <= 4 && n <= len(data); n++ {
+		if set, ok := graphicSets[string(data[:n])]; ok {
+			return set, n
+		}
+	}
+	return nil, 0
+}
+
+// decode decodes data without escape sequences or delimiters: bytes with the
+// high bit set with the G1 set, and others with the G0 set.
+func (s codeState) decode(data []byte) (string, error) {
+	var out strings.Builder
+	for len(data) > 0 {
+		high := data[0] >= 0x80
+		n := 1
+		for n < len(data) && (data[n] >= 0x80) == high {
+			n++
+		}
+		run := data[:n]
+		data = data[n:]
+
+		set := s.g0
+		if high {
+			set = s.g1
+		}
+		switch {
+		case set == nil:
+			// Undesignated G1, decode like ISO_IR 100 as the most common
+			// default.
+			set = graphicSets["\x1b-A"]
+		case !high && !set.multiByte:
+			// G0 of single byte sets is the ASCII (or JIS X 0201 Romaji)
+			// repertoire.
+			out.Write(run)
+			continue
+		case set.multiByte:
+			run = append([]byte(set.escape), run...)
+		}
+		decoded, err := set.enc.NewDecoder().Bytes(run)
+		if err != nil {
+			return "", err
+		}
+		out.Write(decoded)
+	}
+	return out.String(), nil
+}
+
+// encode encodes s, designating the first graphic set able to represent each
+// character, and returning to the initial graphic sets before delimiters and
+// at the end of s.
+func (c *iso2022) encode(s string, delimiters string) ([]byte, error) {
+	var out bytes.Buffer
+	state := c.initial
+	designate := func(set *graphicSet) {
+		if set.g1 && state.g1 != set {
+			state.g1 = set
+			out.WriteString(set.escape)
+		} else if !set.g1 && state.g0 != set {
+			state.g0 = set
+			out.WriteString(set.escape)
+		}
+	}
+	for _, r := range s {
+		if r < 0x80 {
+			if strings.ContainsRune(delimiters, r) {
+				designate(c.initial.g0)
+				state = c.initial
+			} else if state.g0.multiByte {
+				designate(c.initial.g0)
+			}
+			out.WriteByte(byte(r))
+			continue
+		}
+		encoded := false
+		for _, set := range c.sets {
+			if b := set.encodeRune(r); b != nil {
+				designate(set)
+				out.Write(b)
+				encoded = true
+				break
+			}
+		}
+		if !encoded {
+			return nil, fmt.Errorf("%w: %q", ErrorUnrepresentableCharacter, r)
+		}
+	}
+	designate(c.initial.g0)
+	return out.Bytes(), nil
+}
+
+// encodeRune returns the bytes of r in the graphic set, without escape
+// sequences, or nil if the set can't represent r.
+func (set *graphicSet) encodeRune(r rune) []byte {
+	b, err := set.enc.NewEncoder().Bytes([]byte(string(r)))
+	if err != nil {
+		return nil
+	}
+	if set.multiByte {
+		// The iso-2022-jp encoder wraps characters in escape sequences.
+		b = bytes.TrimPrefix(b, []byte(set.escape))
+		b = bytes.TrimSuffix(b, []byte("\x1b(B"))
+		if len(b) != set.width {
+			return nil
+		}
+		return b
+	}
+	// The encoders of some sets are supersets, like windows-1252 for
+	// ISO-8859-1, or also encode other JIS X or KS X sets, which G1 can't hold.
+	if len(b) != set.width {
+		return nil
+	}
+	for _, c := range b {
+		if c < 0xA0 || (set.width == 2 && c == 0xA0) {
+			return nil
+		}
+	}
+	return b
+}
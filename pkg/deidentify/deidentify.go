@@ -0,0 +1,589 @@
+// Package deidentify implements the Basic Application Level Confidentiality
+// Profile and its options as defined in PS3.15 Annex E
+// (https://dicom.nema.org/medical/dicom/current/output/html/part15.html#chapter_E).
+//
+// A Profile holds the action table after applying the chosen options, and can
+// be reused to de-identify many Datasets (for example, every instance in a
+// study) so that replaced UIDs stay consistent between them:
+//
+//	p := deidentify.NewProfile(deidentify.RetainDeviceIdentity(), deidentify.CleanDescriptors())
+//	for _, ds := range datasets {
+//		if err := p.Apply(ds); err != nil {
+//			// handle error
+//		}
+//	}
+package deidentify
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wybaby168/dicom"
+	"github.com/wybaby168/dicom/pkg/dcmtime"
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/uid"
+	"github.com/wybaby168/dicom/pkg/vrraw"
+)
+
+// ErrorNilDataset is returned when a nil Dataset is passed to Apply.
+var ErrorNilDataset = errors.New("deidentify: nil Dataset")
+
+// Action is a PS3.15 Table E.1-1 action code, describing what happens to an
+// attribute during de-identification.
+type Action int
+
+const (
+	// Keep (K) keeps the attribute unchanged. Sequences are still recursed
+	// into, and the profile is applied to their items.
+	Keep Action = iota
+	// Remove (X) removes the attribute.
+	Remove
+	// ZeroLength (Z) replaces the value with a zero length value.
+	ZeroLength
+	// Dummy (D) replaces the value with a non-zero length dummy value that is
+	// consistent with the VR.
+	Dummy
+	// Clean (C) replaces the value with one of similar meaning that does not
+	// contain identifying information. Dates are shifted when the Retain
+	// Longitudinal Temporal Information With Modified Dates option is used,
+	// other values are passed to the Cleaner (see CleanWith).
+	Clean
+	// ReplaceUID (U) replaces the UID with a new UID that is consistent across
+	// every Dataset de-identified with the same Profile.
+	ReplaceUID
+)
+
+// String returns the PS3.15 action code, e.g. "X".
+func (a Action) String() string {
+	switch a {
+	case Keep:
+		return "K"
+	case Remove:
+		return "X"
+	case ZeroLength:
+		return "Z"
+	case Dummy:
+		return "D"
+	case Clean:
+		return "C"
+	case ReplaceUID:
+		return "U"
+	default:
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+}
+
+// Option represents an option that can be passed to NewProfile. The options
+// map to the Profile Options columns of PS3.15 Table E.1-1.
+type Option func(*optSet)
+
+// optSet represents the flattened option set after all Options have been
+// applied.
+type optSet struct {
+	retainSafePrivate      func(creator string, t tag.Tag) bool
+	retainUIDs             bool
+	retainDeviceIdentity   bool
+	retainInstitution      bool
+	retainPatientChars     bool
+	retainFullDates        bool
+	retainModifiedDates    bool
+	dateOffset             time.Duration
+	cleanDescriptors       bool
+	cleanStructuredContent bool
+	cleanGraphics          bool
+	cleaner                func(e *dicom.Element) error
+	uidRemapper            *UIDRemapper
+}
+
+// RetainSafePrivate keeps private attributes for which isSafe returns true.
+// isSafe receives the Private Creator of the block the attribute belongs to.
+// All other private attributes are removed.
+func RetainSafePrivate(isSafe func(creator string, t tag.Tag) bool) Option {
+	return func(set *optSet) {
+		set.retainSafePrivate = isSafe
+	}
+}
+
+// RetainUIDs keeps UIDs instead of replacing them.
+func RetainUIDs() Option {
+	return func(set *optSet) {
+		set.retainUIDs = true
+	}
+}
+
+// RetainDeviceIdentity keeps attributes identifying the device (e.g.
+// StationName and DeviceSerialNumber).
+func RetainDeviceIdentity() Option {
+	return func(set *optSet) {
+		set.retainDeviceIdentity = true
+	}
+}
+
+// RetainInstitutionIdentity keeps attributes identifying the institution.
+func RetainInstitutionIdentity() Option {
+	return func(set *optSet) {
+		set.retainInstitution = true
+	}
+}
+
+// RetainPatientCharacteristics keeps physical characteristics of the patient
+// such as age, sex, size and weight.
+func RetainPatientCharacteristics() Option {
+	return func(set *optSet) {
+		set.retainPatientChars = true
+	}
+}
+
+// RetainLongitudinalTemporalInformation keeps dates and times unchanged
+// (Retain Longitudinal Temporal Information With Full Dates Option).
+func RetainLongitudinalTemporalInformation() Option {
+	return func(set *optSet) {
+		set.retainFullDates = true
+		set.retainModifiedDates = false
+	}
+}
+
+// RetainLongitudinalTemporalInformationModifiedDates shifts all dates and
+// times by offset, which keeps the temporal relationship between them
+// (Retain Longitudinal Temporal Information With Modified Dates Option). Use
+// the same Profile for every Dataset of a patient to apply a consistent shift.
+func RetainLongitudinalTemporalInformationModifiedDates(offset time.Duration) Option {
+	return func(set *optSet) {
+		set.retainModifiedDates = true
+		set.retainFullDates = false
+		set.dateOffset = offset
+	}
+}
+
+// CleanDescriptors cleans free text descriptions (e.g. StudyDescription)
+// instead of removing them.
+func CleanDescriptors() Option {
+	return func(set *optSet) {
+		set.cleanDescriptors = true
+	}
+}
+
+// CleanStructuredContent keeps structured content (e.g. SR ContentSequence)
+// and applies the profile to its items instead of removing it.
+func CleanStructuredContent() Option {
+	return func(set *optSet) {
+		set.cleanStructuredContent = true
+	}
+}
+
+// CleanGraphics keeps graphic annotations and overlays instead of removing
+// them.
+func CleanGraphics() Option {
+	return func(set *optSet) {
+		set.cleanGraphics = true
+	}
+}
+
+// CleanWith sets the function used to clean the value of elements whose
+// action is Clean (other than shifted dates and sequences). By default, such
+// values are replaced by a dummy value as with the Dummy action.
+func CleanWith(cleaner func(e *dicom.Element) error) Option {
+	return func(set *optSet) {
+		set.cleaner = cleaner
+	}
+}
+
+// WithUIDRemapper sets the UIDRemapper used for the ReplaceUID action. Sharing
+// a UIDRemapper between Profiles, or using a keyed or persisted one, keeps
+// replaced UIDs consistent beyond a single Profile. By default each Profile
+// uses its own NewUIDRemapper(nil).
+func WithUIDRemapper(r *UIDRemapper) Option {
+	return func(set *optSet) {
+		set.uidRemapper = r
+	}
+}
+
+// Profile is the Basic Application Level Confidentiality Profile after
+// applying a set of Options. It is safe for concurrent use.
+type Profile struct {
+	opts optSet
+	uids *UIDRemapper
+}
+
+// NewProfile returns a new Profile with the provided Options applied.
+func NewProfile(opts ...Option) *Profile {
+	optSet := optSet{}
+	for _, opt := range opts {
+		opt(&optSet)
+	}
+	uids := optSet.uidRemapper
+	if uids == nil {
+		uids = NewUIDRemapper(nil)
+	}
+	return &Profile{opts: optSet, uids: uids}
+}
+
+// Deidentify is a convenience function that applies a new Profile with the
+// provided Options to ds.
+func Deidentify(ds *dicom.Dataset, opts ...Option) error {
+	return NewProfile(opts...).Apply(ds)
+}
+
+// Action returns the action this Profile takes for the standard attribute t.
+// Private attributes are handled separately, see RetainSafePrivate.
+func (p *Profile) Action(t tag.Tag) Action {
+	r, ok := lookupRule(t)
+	if !ok {
+		return Keep
+	}
+	switch {
+	case r.action == ReplaceUID && p.opts.retainUIDs,
+		r.columns&retainDevice != 0 && p.opts.retainDeviceIdentity,
+		r.columns&retainInstitution != 0 && p.opts.retainInstitution,
+		r.columns&retainPatientChars != 0 && p.opts.retainPatientChars:
+		return Keep
+	case r.columns&cleanDescriptors != 0 && p.opts.cleanDescriptors,
+		r.columns&cleanStructured != 0 && p.opts.cleanStructuredContent,
+		r.columns&cleanGraphics != 0 && p.opts.cleanGraphics:
+		return Clean
+	}
+	if isTemporal(t) {
+		if p.opts.retainFullDates {
+			return Keep
+		}
+		if p.opts.retainModifiedDates {
+			return Clean
+		}
+	}
+	return r.action
+}
+
+// Apply de-identifies ds in place, recursing into sequences, and records the
+// de-identification in PatientIdentityRemoved, DeidentificationMethod,
+// DeidentificationMethodCodeSequence and
+// LongitudinalTemporalInformationModified.
+func (p *Profile) Apply(ds *dicom.Dataset) error {
+	if ds == nil {
+		return ErrorNilDataset
+	}
+	elems, err := p.applyElements(ds.Elements)
+	if err != nil {
+		return err
+	}
+	ds.Elements = elems
+	return p.addMethodAttributes(ds)
+}
+
+func (p *Profile) applyElements(elems []*dicom.Element) ([]*dicom.Element, error) {
+	out := make([]*dicom.Element, 0, len(elems))
+	for _, elem := range elems {
+		var action Action
+		if tag.IsPrivate(elem.Tag.Group) {
+			action = p.privateAction(elem.Tag, elems)
+		} else {
+			action = p.Action(elem.Tag)
+		}
+		keep, err := p.applyAction(elem, action)
+		if err != nil {
+			return nil, fmt.Errorf("deidentify: unable to apply action %v to %v: %w", action, tag.DebugString(elem.Tag), err)
+		}
+		if keep {
+			out = append(out, elem)
+		}
+	}
+	return out, nil
+}
+
+func (p *Profile) privateAction(t tag.Tag, siblings []*dicom.Element) Action {
+	if p.opts.retainSafePrivate == nil {
+		return Remove
+	}
+	if tag.IsPrivateCreator(t) {
+		// Private Creator elements are kept so that retained attributes can
+		// still be interpreted.
+		return Keep
+	}
+	creator, _ := (&dicom.Dataset{Elements: siblings}).PrivateCreator(t)
+	if p.opts.retainSafePrivate(creator, t) {
+		return Keep
+	}
+	return Remove
+}
+
+// applyAction applies action to elem in place, and returns false if the
+// element should be removed.
+func (p *Profile) applyAction(elem *dicom.Element, action Action) (bool, error) {
+	if action == Remove {
+		return false, nil
+	}
+	if elem.Value == nil {
+		return true, nil
+	}
+	if elem.Value.ValueType() == dicom.Sequences {
+		if action == ZeroLength {
+			return true, setValue(elem, [][]*dicom.Element{})
+		}
+		// Every other action keeps the sequence, but de-identifies its items.
+		items := elem.Value.GetValue().([]*dicom.SequenceItemValue)
+		newItems := make([][]*dicom.Element, 0, len(items))
+		for _, item := range items {
+			itemElems, err := p.applyElements(item.GetValue().([]*dicom.Element))
+			if err != nil {
+				return false, err
+			}
+			newItems = append(newItems, itemElems)
+		}
+		return true, setValue(elem, newItems)
+	}
+
+	switch action {
+	case ZeroLength:
+		return true, setZeroLength(elem)
+	case Dummy:
+		return true, p.setDummy(elem)
+	case Clean:
+		if isTemporal(elem.Tag) && p.opts.retainModifiedDates {
+			return true, shiftDates(elem, p.opts.dateOffset)
+		}
+		if p.opts.cleaner != nil {
+			return true, p.opts.cleaner(elem)
+		}
+		return true, p.setDummy(elem)
+	case ReplaceUID:
+		if elem.Value.ValueType() != dicom.Strings {
+			return true, nil
+		}
+		uids := dicom.MustGetStrings(elem.Value)
+		replaced := make([]string, len(uids))
+		for i, u := range uids {
+			replaced[i] = p.uids.Remap(u)
+		}
+		return true, setValue(elem, replaced)
+	}
+	return true, nil
+}
+
+func (p *Profile) setDummy(elem *dicom.Element) error {
+	switch elem.Value.ValueType() {
+	case dicom.Strings:
+		vr := vrOf(elem)
+		if vr == vrraw.UniqueIdentifier {
+			return setValue(elem, []string{uid.New()})
+		}
+		return setValue(elem, []string{dummyString(vr)})
+	case dicom.Ints:
+		return setValue(elem, []int{0})
+	case dicom.Int64s:
+		return setValue(elem, []int64{0})
+	case dicom.Uint64s:
+		return setValue(elem, []uint64{0})
+	case dicom.Floats:
+		return setValue(elem, []float64{0})
+	case dicom.Bytes:
+		return setValue(elem, make([]byte, len(dicom.MustGetBytes(elem.Value))))
+	default:
+		return nil
+	}
+}
+
+func setZeroLength(elem *dicom.Element) error {
+	switch elem.Value.ValueType() {
+	case dicom.Strings:
+		return setValue(elem, []string{""})
+	case dicom.Ints:
+		return setValue(elem, []int{})
+	case dicom.Int64s:
+		return setValue(elem, []int64{})
+	case dicom.Uint64s:
+		return setValue(elem, []uint64{})
+	case dicom.Tags:
+		return setValue(elem, []tag.Tag{})
+	case dicom.Floats:
+		return setValue(elem, []float64{})
+	case dicom.Bytes:
+		return setValue(elem, []byte{})
+	default:
+		return nil
+	}
+}
+
+func setValue(elem *dicom.Element, data any) error {
+	v, err := dicom.NewValue(data)
+	if err != nil {
+		return err
+	}
+	elem.Value = v
+	return nil
+}
+
+// dummyString returns a dummy value that is valid for the given string VR.
+func dummyString(vr string) string {
+	switch vr {
+	case vrraw.Date:
+		return "19000101"
+	case vrraw.DateTime:
+		return "19000101000000.000000"
+	case vrraw.Time:
+		return "000000.000000"
+	case vrraw.AgeString:
+		return "000Y"
+	case vrraw.DecimalString, vrraw.IntegerString:
+		return "0"
+	case vrraw.UniversalResourceIdentifier:
+		// An empty UR would make D behave like Z, so use a URI under the
+		// reserved .invalid domain (RFC 2606) that can never be resolved.
+		return "http://anonymized.invalid/"
+	default:
+		return "ANONYMIZED"
+	}
+}
+
+func shiftDates(elem *dicom.Element, offset time.Duration) error {
+	if elem.Value.ValueType() != dicom.Strings {
+		return nil
+	}
+	values := dicom.MustGetStrings(elem.Value)
+	shifted := make([]string, len(values))
+	for i, v := range values {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		switch vrOf(elem) {
+		case vrraw.Date:
+			da, err := dcmtime.ParseDate(v)
+			if err != nil {
+				return err
+			}
+			da.Time = da.Time.Add(offset)
+			shifted[i] = da.DCM()
+		case vrraw.DateTime:
+			dt, err := dcmtime.ParseDatetime(v)
+			if err != nil {
+				return err
+			}
+			dt.Time = dt.Time.Add(offset)
+			shifted[i] = dt.DCM()
+		case vrraw.Time:
+			tm, err := dcmtime.ParseTime(v)
+			if err != nil {
+				return err
+			}
+			tm.Time = tm.Time.Add(offset % (24 * time.Hour))
+			shifted[i] = tm.DCM()
+		default:
+			shifted[i] = v
+		}
+	}
+	return setValue(elem, shifted)
+}
+
+// vrOf returns the raw VR of elem, falling back to the dictionary VR.
+func vrOf(elem *dicom.Element) string {
+	if elem.RawValueRepresentation != "" {
+		return elem.RawValueRepresentation
+	}
+	if info, err := tag.Find(elem.Tag); err == nil {
+		return info.VRs[0]
+	}
+	return vrraw.Unknown
+}
+
+// isTemporal returns true if the dictionary VR of t is a date or time VR.
+func isTemporal(t tag.Tag) bool {
+	info, err := tag.Find(t)
+	if err != nil {
+		return false
+	}
+	switch info.VRs[0] {
+	case vrraw.Date, vrraw.DateTime, vrraw.Time:
+		return true
+	}
+	return false
+}
+
+// code is a coded entry from CID 7050 De-identification Method.
+type code struct {
+	value   string
+	meaning string
+}
+
+func (p *Profile) methodCodes() []code {
+	codes := []code{{"113100", "Basic Application Confidentiality Profile"}}
+	if p.opts.cleanGraphics {
+		codes = append(codes, code{"113103", "Clean Graphics Option"})
+	}
+	if p.opts.cleanStructuredContent {
+		codes = append(codes, code{"113104", "Clean Structured Content Option"})
+	}
+	if p.opts.cleanDescriptors {
+		codes = append(codes, code{"113105", "Clean Descriptors Option"})
+	}
+	if p.opts.retainFullDates {
+		codes = append(codes, code{"113106", "Retain Longitudinal Temporal Information Full Dates Option"})
+	}
+	if p.opts.retainModifiedDates {
+		codes = append(codes, code{"113107", "Retain Longitudinal Temporal Information Modified Dates Option"})
+	}
+	if p.opts.retainPatientChars {
+		codes = append(codes, code{"113108", "Retain Patient Characteristics Option"})
+	}
+	if p.opts.retainDeviceIdentity {
+		codes = append(codes, code{"113109", "Retain Device Identity Option"})
+	}
+	if p.opts.retainUIDs {
+		codes = append(codes, code{"113110", "Retain UIDs Option"})
+	}
+	if p.opts.retainSafePrivate != nil {
+		codes = append(codes, code{"113111", "Retain Safe Private Option"})
+	}
+	if p.opts.retainInstitution {
+		codes = append(codes, code{"113112", "Retain Institution Identity Option"})
+	}
+	return codes
+}
+
+func (p *Profile) addMethodAttributes(ds *dicom.Dataset) error {
+	codes := p.methodCodes()
+	meanings := make([]string, 0, len(codes))
+	items := make([][]*dicom.Element, 0, len(codes))
+	for _, c := range codes {
+		meanings = append(meanings, c.meaning)
+		item := make([]*dicom.Element, 0, 3)
+		for _, e := range []struct {
+			t tag.Tag
+			v string
+		}{
+			{tag.CodeValue, c.value},
+			{tag.CodingSchemeDesignator, "DCM"},
+			{tag.CodeMeaning, c.meaning},
+		} {
+			elem, err := dicom.NewElement(e.t, []string{e.v})
+			if err != nil {
+				return err
+			}
+			item = append(item, elem)
+		}
+		items = append(items, item)
+	}
+
+	temporal := "REMOVED"
+	if p.opts.retainFullDates {
+		temporal = "UNMODIFIED"
+	} else if p.opts.retainModifiedDates {
+		temporal = "MODIFIED"
+	}
+
+	for _, e := range []struct {
+		t    tag.Tag
+		data any
+	}{
+		{tag.PatientIdentityRemoved, []string{"YES"}},
+		{tag.DeidentificationMethod, meanings},
+		{tag.DeidentificationMethodCodeSequence, items},
+		{tag.LongitudinalTemporalInformationModified, []string{temporal}},
+	} {
+		elem, err := dicom.NewElement(e.t, e.data)
+		if err != nil {
+			return err
+		}
+		ds.SetElement(elem)
+	}
+	return nil
+}
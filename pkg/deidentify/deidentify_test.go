@@ -0,0 +1,319 @@
+package deidentify
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wybaby168/dicom"
+	"github.com/wybaby168/dicom/pkg/tag"
+)
+
+func TestApply(t *testing.T) {
+	ds := dicom.Dataset{Elements: []*dicom.Element{
+		mustNewElement(t, tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4"}),
+		mustNewElement(t, tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}),
+		mustNewElement(t, tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.2"}),
+		mustNewElement(t, tag.SOPInstanceUID, []string{"1.2.3.4"}),
+		mustNewElement(t, tag.StudyDate, []string{"20200102"}),
+		mustNewElement(t, tag.AccessionNumber, []string{"ACC123"}),
+		mustNewElement(t, tag.Modality, []string{"CT"}),
+		mustNewElement(t, tag.InstitutionName, []string{"General Hospital"}),
+		mustNewElement(t, tag.StudyDescription, []string{"Head CT"}),
+		mustNewElement(t, tag.PatientName, []string{"Doe^John"}),
+		mustNewElement(t, tag.PatientID, []string{"12345"}),
+		mustNewElement(t, tag.PatientAge, []string{"042Y"}),
+		mustNewElement(t, tag.Rows, []int{128}),
+	}}
+
+	if err := Deidentify(&ds); err != nil {
+		t.Fatalf("Deidentify() unexpected error: %v", err)
+	}
+
+	for _, removed := range []tag.Tag{tag.InstitutionName, tag.StudyDescription, tag.PatientAge} {
+		if _, err := ds.FindElementByTag(removed); err == nil {
+			t.Errorf("Deidentify() did not remove %v", tag.DebugString(removed))
+		}
+	}
+	for _, emptied := range []tag.Tag{tag.StudyDate, tag.AccessionNumber, tag.PatientName, tag.PatientID} {
+		assertStrings(t, ds, emptied, []string{""})
+	}
+	assertStrings(t, ds, tag.Modality, []string{"CT"})
+	assertStrings(t, ds, tag.SOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.2"})
+	assertStrings(t, ds, tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"})
+	assertStrings(t, ds, tag.PatientIdentityRemoved, []string{"YES"})
+	assertStrings(t, ds, tag.DeidentificationMethod, []string{"Basic Application Confidentiality Profile"})
+	assertStrings(t, ds, tag.LongitudinalTemporalInformationModified, []string{"REMOVED"})
+
+	sopInstanceUID := getStrings(t, ds, tag.SOPInstanceUID)[0]
+	if sopInstanceUID == "1.2.3.4" || !strings.HasPrefix(sopInstanceUID, "2.25.") {
+		t.Errorf("Deidentify() SOPInstanceUID = %q, want a new 2.25 UID", sopInstanceUID)
+	}
+	assertStrings(t, ds, tag.MediaStorageSOPInstanceUID, []string{sopInstanceUID})
+
+	code, err := ds.FindElementByTagNested(tag.CodeValue)
+	if err != nil {
+		t.Fatalf("Deidentify() did not add DeidentificationMethodCodeSequence: %v", err)
+	}
+	if got := dicom.MustGetStrings(code.Value); !cmp.Equal(got, []string{"113100"}) {
+		t.Errorf("Deidentify() CodeValue = %v, want [113100]", got)
+	}
+
+	for i := 1; i < len(ds.Elements); i++ {
+		if ds.Elements[i-1].Tag.Compare(ds.Elements[i].Tag) >= 0 {
+			t.Errorf("Deidentify() elements not sorted: %v before %v", ds.Elements[i-1].Tag, ds.Elements[i].Tag)
+		}
+	}
+}
+
+func TestApply_Options(t *testing.T) {
+	cases := []struct {
+		name  string
+		opts  []Option
+		in    *dicom.Element
+		want  []string
+		found bool
+	}{
+		{
+			name:  "retain device identity",
+			opts:  []Option{RetainDeviceIdentity()},
+			in:    mustNewElement(t, tag.StationName, []string{"CT01"}),
+			want:  []string{"CT01"},
+			found: true,
+		},
+		{
+			name:  "device identity removed by default",
+			in:    mustNewElement(t, tag.StationName, []string{"CT01"}),
+			found: false,
+		},
+		{
+			name:  "retain institution identity",
+			opts:  []Option{RetainInstitutionIdentity()},
+			in:    mustNewElement(t, tag.InstitutionName, []string{"General Hospital"}),
+			want:  []string{"General Hospital"},
+			found: true,
+		},
+		{
+			name:  "retain patient characteristics",
+			opts:  []Option{RetainPatientCharacteristics()},
+			in:    mustNewElement(t, tag.PatientAge, []string{"042Y"}),
+			want:  []string{"042Y"},
+			found: true,
+		},
+		{
+			name:  "retain full dates",
+			opts:  []Option{RetainLongitudinalTemporalInformation()},
+			in:    mustNewElement(t, tag.StudyDate, []string{"20200102"}),
+			want:  []string{"20200102"},
+			found: true,
+		},
+		{
+			name:  "modified dates",
+			opts:  []Option{RetainLongitudinalTemporalInformationModifiedDates(-48 * time.Hour)},
+			in:    mustNewElement(t, tag.StudyDate, []string{"20200102"}),
+			want:  []string{"20191231"},
+			found: true,
+		},
+		{
+			name:  "modified times",
+			opts:  []Option{RetainLongitudinalTemporalInformationModifiedDates(25 * time.Hour)},
+			in:    mustNewElement(t, tag.StudyTime, []string{"101010"}),
+			want:  []string{"111010"},
+			found: true,
+		},
+		{
+			name:  "retain UIDs",
+			opts:  []Option{RetainUIDs()},
+			in:    mustNewElement(t, tag.StudyInstanceUID, []string{"1.2.3"}),
+			want:  []string{"1.2.3"},
+			found: true,
+		},
+		{
+			name:  "clean descriptors",
+			opts:  []Option{CleanDescriptors()},
+			in:    mustNewElement(t, tag.StudyDescription, []string{"Head CT of John Doe"}),
+			want:  []string{"ANONYMIZED"},
+			found: true,
+		},
+		{
+			name: "clean descriptors with cleaner",
+			opts: []Option{CleanDescriptors(), CleanWith(func(e *dicom.Element) error {
+				v, err := dicom.NewValue([]string{strings.ReplaceAll(dicom.MustGetStrings(e.Value)[0], " of John Doe", "")})
+				e.Value = v
+				return err
+			})},
+			in:    mustNewElement(t, tag.StudyDescription, []string{"Head CT of John Doe"}),
+			want:  []string{"Head CT"},
+			found: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ds := dicom.Dataset{Elements: []*dicom.Element{tc.in}}
+			if err := NewProfile(tc.opts...).Apply(&ds); err != nil {
+				t.Fatalf("Apply() unexpected error: %v", err)
+			}
+			e, err := ds.FindElementByTag(tc.in.Tag)
+			if found := err == nil; found != tc.found {
+				t.Fatalf("Apply() element %v found = %v, want %v", tc.in.Tag, found, tc.found)
+			}
+			if !tc.found {
+				return
+			}
+			if diff := cmp.Diff(tc.want, dicom.MustGetStrings(e.Value)); diff != "" {
+				t.Errorf("Apply() unexpected value diff: %v", diff)
+			}
+		})
+	}
+}
+
+func TestApply_ConsistentUIDs(t *testing.T) {
+	p := NewProfile()
+	var got []string
+	for i := 0; i < 2; i++ {
+		ds := dicom.Dataset{Elements: []*dicom.Element{
+			mustNewElement(t, tag.StudyInstanceUID, []string{"1.2.3"}),
+			mustNewSequence(t, tag.ReferencedSeriesSequence, [][]*dicom.Element{
+				{mustNewElement(t, tag.SeriesInstanceUID, []string{"1.2.3"})},
+			}),
+		}}
+		if err := p.Apply(&ds); err != nil {
+			t.Fatalf("Apply() unexpected error: %v", err)
+		}
+		got = append(got, getStrings(t, ds, tag.StudyInstanceUID)[0])
+		series, err := ds.FindElementByTagNested(tag.SeriesInstanceUID)
+		if err != nil {
+			t.Fatalf("Apply() removed nested SeriesInstanceUID: %v", err)
+		}
+		got = append(got, dicom.MustGetStrings(series.Value)[0])
+	}
+	for _, u := range got {
+		if u != got[0] {
+			t.Errorf("Apply() replaced the same UID inconsistently: %v", got)
+		}
+	}
+	if got[0] == "1.2.3" {
+		t.Errorf("Apply() did not replace StudyInstanceUID")
+	}
+}
+
+func TestApply_Private(t *testing.T) {
+	newDataset := func() dicom.Dataset {
+		return dicom.Dataset{Elements: []*dicom.Element{
+			mustNewPrivateElement(t, tag.Tag{Group: 0x0019, Element: 0x0010}, "LO", []string{"SAFE"}),
+			mustNewPrivateElement(t, tag.Tag{Group: 0x0019, Element: 0x0011}, "LO", []string{"UNSAFE"}),
+			mustNewPrivateElement(t, tag.Tag{Group: 0x0019, Element: 0x1001}, "SH", []string{"kept"}),
+			mustNewPrivateElement(t, tag.Tag{Group: 0x0019, Element: 0x1101}, "SH", []string{"removed"}),
+		}}
+	}
+
+	ds := newDataset()
+	if err := Deidentify(&ds); err != nil {
+		t.Fatalf("Deidentify() unexpected error: %v", err)
+	}
+	for _, e := range ds.Elements {
+		if tag.IsPrivate(e.Tag.Group) {
+			t.Errorf("Deidentify() did not remove private element %v", e.Tag)
+		}
+	}
+
+	ds = newDataset()
+	err := Deidentify(&ds, RetainSafePrivate(func(creator string, _ tag.Tag) bool {
+		return creator == "SAFE"
+	}))
+	if err != nil {
+		t.Fatalf("Deidentify() unexpected error: %v", err)
+	}
+	if _, err := ds.FindElementByTag(tag.Tag{Group: 0x0019, Element: 0x1001}); err != nil {
+		t.Errorf("Deidentify() removed safe private element: %v", err)
+	}
+	if _, err := ds.FindElementByTag(tag.Tag{Group: 0x0019, Element: 0x1101}); err == nil {
+		t.Errorf("Deidentify() kept unsafe private element")
+	}
+}
+
+func TestProfile_Action(t *testing.T) {
+	p := NewProfile(CleanGraphics())
+	for _, tc := range []struct {
+		t    tag.Tag
+		want Action
+	}{
+		{tag.PatientName, ZeroLength},
+		{tag.Modality, Keep},
+		{tag.SOPInstanceUID, ReplaceUID},
+		{tag.Tag{Group: 0x5002, Element: 0x3000}, Remove},
+		{tag.Tag{Group: 0x6002, Element: 0x3000}, Clean},
+	} {
+		if got := p.Action(tc.t); got != tc.want {
+			t.Errorf("Action(%v) = %v, want %v", tc.t, got, tc.want)
+		}
+	}
+}
+
+func TestProfile_SetDummy(t *testing.T) {
+	for _, tc := range []struct {
+		t    tag.Tag
+		data any
+		want []string
+	}{
+		{tag.StudyDate, []string{"20200102"}, []string{"19000101"}},
+		{tag.PatientAge, []string{"042Y"}, []string{"000Y"}},
+		{tag.PatientName, []string{"Doe^John"}, []string{"ANONYMIZED"}},
+		{tag.RetrieveURI, []string{"http://pacs.example.com/wado?study=1.2.3"}, []string{"http://anonymized.invalid/"}},
+	} {
+		elem := mustNewElement(t, tc.t, tc.data)
+		if err := NewProfile().setDummy(elem); err != nil {
+			t.Fatalf("setDummy(%v) unexpected error: %v", tag.DebugString(tc.t), err)
+		}
+		if diff := cmp.Diff(tc.want, dicom.MustGetStrings(elem.Value)); diff != "" {
+			t.Errorf("setDummy(%v) unexpected value diff: %v", tag.DebugString(tc.t), diff)
+		}
+	}
+}
+
+func mustNewElement(t *testing.T, tg tag.Tag, data any) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	if err != nil {
+		t.Fatalf("NewElement(%v) unexpected error: %v", tg, err)
+	}
+	return e
+}
+
+func mustNewSequence(t *testing.T, tg tag.Tag, items [][]*dicom.Element) *dicom.Element {
+	t.Helper()
+	e := mustNewElement(t, tg, items)
+	e.ValueLength = tag.VLUndefinedLength
+	return e
+}
+
+func mustNewPrivateElement(t *testing.T, tg tag.Tag, rawVR string, data any) *dicom.Element {
+	t.Helper()
+	v, err := dicom.NewValue(data)
+	if err != nil {
+		t.Fatalf("NewValue(%v) unexpected error: %v", data, err)
+	}
+	return &dicom.Element{
+		Tag:                    tg,
+		ValueRepresentation:    tag.GetVRKind(tg, rawVR),
+		RawValueRepresentation: rawVR,
+		Value:                  v,
+	}
+}
+
+func getStrings(t *testing.T, ds dicom.Dataset, tg tag.Tag) []string {
+	t.Helper()
+	e, err := ds.FindElementByTag(tg)
+	if err != nil {
+		t.Fatalf("FindElementByTag(%v) unexpected error: %v", tg, err)
+	}
+	return dicom.MustGetStrings(e.Value)
+}
+
+func assertStrings(t *testing.T, ds dicom.Dataset, tg tag.Tag, want []string) {
+	t.Helper()
+	if diff := cmp.Diff(want, getStrings(t, ds, tg)); diff != "" {
+		t.Errorf("unexpected value diff for %v: %v", tag.DebugString(tg), diff)
+	}
+}
@@ -0,0 +1,290 @@
+package deidentify
+
+import "github.com/wybaby168/dicom/pkg/tag"
+
+// column is a set of PS3.15 Table E.1-1 Profile Option columns that change the
+// basic action of an attribute. Options affecting dates and times are derived
+// from the VR of the attribute instead, and the Retain UIDs Option applies to
+// every attribute with a ReplaceUID action.
+type column uint8
+
+const (
+	retainDevice column = 1 << iota
+	retainInstitution
+	retainPatientChars
+	cleanDescriptors
+	cleanStructured
+	cleanGraphics
+)
+
+// rule is a row of PS3.15 Table E.1-1. Where the table lists more than one
+// basic action (e.g. "X/Z"), the most conservative action that keeps the
+// attribute Type (1, 2 or 3) intact in typical IODs is used.
+type rule struct {
+	action  Action
+	columns column
+}
+
+// lookupRule returns the rule for t, including the repeating group
+// attributes of curves (50xx) and overlays (60xx).
+func lookupRule(t tag.Tag) (rule, bool) {
+	if r, ok := basicProfile[t]; ok {
+		return r, true
+	}
+	base, isRepeating := tag.RepeatingGroupBase(t)
+	switch {
+	case !isRepeating:
+	case base.Group == 0x5000:
+		// Curve Data, retired.
+		return rule{Remove, 0}, true
+	case base == tag.OverlayData:
+		return rule{Remove, cleanGraphics}, true
+	case base == tag.OverlayComments:
+		return rule{Remove, cleanDescriptors}, true
+	}
+	return rule{}, false
+}
+
+var basicProfile = map[tag.Tag]rule{
+	tag.AccessionNumber:                                   {ZeroLength, 0},
+	tag.AcquisitionComments:                               {Remove, cleanDescriptors},
+	tag.AcquisitionContextSequence:                        {Remove, cleanStructured},
+	tag.AcquisitionDate:                                   {ZeroLength, 0},
+	tag.AcquisitionDateTime:                               {Remove, 0},
+	tag.AcquisitionDeviceProcessingDescription:            {Remove, retainDevice | cleanDescriptors},
+	tag.AcquisitionProtocolDescription:                    {Remove, cleanDescriptors},
+	tag.AcquisitionTime:                                   {ZeroLength, 0},
+	tag.ActualHumanPerformersSequence:                     {Remove, 0},
+	tag.AdditionalPatientHistory:                          {Remove, cleanDescriptors},
+	tag.AdmissionID:                                       {Remove, 0},
+	tag.AdmittingDate:                                     {Remove, 0},
+	tag.AdmittingDiagnosesCodeSequence:                    {Remove, cleanDescriptors},
+	tag.AdmittingDiagnosesDescription:                     {Remove, cleanDescriptors},
+	tag.AdmittingTime:                                     {Remove, 0},
+	tag.Allergies:                                         {Remove, retainPatientChars},
+	tag.Arbitrary:                                         {Remove, 0},
+	tag.AuthorObserverSequence:                            {Remove, 0},
+	tag.BranchOfService:                                   {Remove, 0},
+	tag.CassetteID:                                        {Remove, retainDevice},
+	tag.CommentsOnThePerformedProcedureStep:               {Remove, cleanDescriptors},
+	tag.ConcatenationUID:                                  {ReplaceUID, 0},
+	tag.ConfidentialityConstraintOnPatientDataDescription: {Remove, 0},
+	tag.ContentCreatorName:                                {ZeroLength, 0},
+	tag.ContentCreatorIdentificationCodeSequence:          {Remove, 0},
+	tag.ContentDate:                                       {ZeroLength, 0},
+	tag.ContentSequence:                                   {Remove, cleanStructured},
+	tag.ContentTime:                                       {ZeroLength, 0},
+	tag.ContextGroupExtensionCreatorUID:                   {ReplaceUID, 0},
+	tag.ContrastBolusAgent:                                {Dummy, cleanDescriptors},
+	tag.ContributionDescription:                           {Remove, cleanDescriptors},
+	tag.CountryOfResidence:                                {Remove, 0},
+	tag.CreatorVersionUID:                                 {ReplaceUID, 0},
+	tag.CurrentPatientLocation:                            {Remove, 0},
+	tag.CurveDate:                                         {Remove, 0},
+	tag.CurveTime:                                         {Remove, 0},
+	tag.CustodialOrganizationSequence:                     {Remove, 0},
+	tag.DataSetTrailingPadding:                            {Remove, 0},
+	tag.DerivationDescription:                             {Remove, cleanDescriptors},
+	tag.DetectorID:                                        {Remove, retainDevice},
+	tag.DeviceSerialNumber:                                {Remove, retainDevice},
+	tag.DeviceUID:                                         {ReplaceUID, retainDevice},
+	tag.DigitalSignaturesSequence:                         {Remove, 0},
+	tag.DigitalSignatureUID:                               {Remove, 0},
+	tag.DimensionOrganizationUID:                          {ReplaceUID, 0},
+	tag.DischargeDiagnosisDescription:                     {Remove, cleanDescriptors},
+	tag.DistributionAddress:                               {Remove, 0},
+	tag.DistributionName:                                  {Remove, 0},
+	tag.DoseReferenceUID:                                  {ReplaceUID, 0},
+	tag.EthnicGroup:                                       {Remove, retainPatientChars},
+	tag.FailedSOPInstanceUIDList:                          {ReplaceUID, 0},
+	tag.FiducialUID:                                       {ReplaceUID, 0},
+	tag.FillerOrderNumberImagingServiceRequest:            {ZeroLength, 0},
+	tag.FrameComments:                                     {Remove, cleanDescriptors},
+	tag.FrameOfReferenceUID:                               {ReplaceUID, 0},
+	tag.GantryID:                                          {Remove, retainDevice},
+	tag.GeneratorID:                                       {Remove, retainDevice},
+	tag.GraphicAnnotationSequence:                         {Dummy, cleanGraphics},
+	tag.HumanPerformerName:                                {Remove, 0},
+	tag.HumanPerformerOrganization:                        {Remove, 0},
+	tag.IconImageSequence:                                 {Remove, 0},
+	tag.IdentifyingComments:                               {Remove, cleanDescriptors},
+	tag.ImageComments:                                     {Remove, cleanDescriptors},
+	tag.ImagePresentationComments:                         {Remove, cleanDescriptors},
+	tag.ImagingServiceRequestComments:                     {Remove, cleanDescriptors},
+	tag.Impressions:                                       {Remove, cleanDescriptors},
+	tag.InstanceCreationDate:                              {Remove, 0},
+	tag.InstanceCreationTime:                              {Remove, 0},
+	tag.InstanceCreatorUID:                                {ReplaceUID, 0},
+	tag.InstitutionAddress:                                {Remove, retainInstitution},
+	tag.InstitutionCodeSequence:                           {Remove, retainInstitution},
+	tag.InstitutionName:                                   {Remove, retainInstitution},
+	tag.InstitutionalDepartmentName:                       {Remove, retainInstitution},
+	tag.InsurancePlanIdentification:                       {Remove, 0},
+	tag.IntendedRecipientsOfResultsIdentificationSequence: {Remove, 0},
+	tag.InterpretationApproverSequence:                    {Remove, 0},
+	tag.InterpretationAuthor:                              {Remove, 0},
+	tag.InterpretationDiagnosisDescription:                {Remove, cleanDescriptors},
+	tag.InterpretationIDIssuer:                            {Remove, 0},
+	tag.InterpretationRecorder:                            {Remove, 0},
+	tag.InterpretationText:                                {Remove, cleanDescriptors},
+	tag.InterpretationTranscriber:                         {Remove, 0},
+	tag.IrradiationEventUID:                               {ReplaceUID, 0},
+	tag.IssuerOfAdmissionID:                               {Remove, 0},
+	tag.IssuerOfPatientID:                                 {Remove, 0},
+	tag.IssuerOfServiceEpisodeID:                          {Remove, 0},
+	tag.LargePaletteColorLookupTableUID:                   {ReplaceUID, 0},
+	tag.MAC:                                               {Remove, 0},
+	tag.MediaStorageSOPInstanceUID:                        {ReplaceUID, 0},
+	tag.MedicalAlerts:                                     {Remove, retainPatientChars},
+	tag.MedicalRecordLocator:                              {Remove, 0},
+	tag.MilitaryRank:                                      {Remove, 0},
+	tag.ModifiedAttributesSequence:                        {Remove, 0},
+	tag.ModifiedImageDescription:                          {Remove, cleanDescriptors},
+	tag.ModifyingDeviceID:                                 {Remove, retainDevice},
+	tag.NamesOfIntendedRecipientsOfResults:                {Remove, 0},
+	tag.NetworkID:                                         {Remove, 0},
+	tag.ObservationUID:                                    {ReplaceUID, 0},
+	tag.Occupation:                                        {Remove, cleanDescriptors},
+	tag.OperatorIdentificationSequence:                    {Remove, 0},
+	tag.OperatorsName:                                     {Remove, 0},
+	tag.OrderCallbackPhoneNumber:                          {Remove, 0},
+	tag.OrderEnteredBy:                                    {Remove, 0},
+	tag.OrderEntererLocation:                              {Remove, 0},
+	tag.OriginalAttributesSequence:                        {Remove, 0},
+	tag.OtherPatientIDs:                                   {Remove, 0},
+	tag.OtherPatientIDsSequence:                           {Remove, 0},
+	tag.OtherPatientNames:                                 {Remove, 0},
+	tag.OverlayDate:                                       {Remove, 0},
+	tag.OverlayTime:                                       {Remove, 0},
+	tag.PaletteColorLookupTableUID:                        {ReplaceUID, 0},
+	tag.ParticipantSequence:                               {Remove, 0},
+	tag.PatientAddress:                                    {Remove, 0},
+	tag.PatientAge:                                        {Remove, retainPatientChars},
+	tag.PatientBirthDate:                                  {ZeroLength, 0},
+	tag.PatientBirthName:                                  {Remove, 0},
+	tag.PatientBirthTime:                                  {Remove, 0},
+	tag.PatientComments:                                   {Remove, cleanDescriptors},
+	tag.PatientID:                                         {ZeroLength, 0},
+	tag.PatientInstitutionResidence:                       {Remove, 0},
+	tag.PatientInsurancePlanCodeSequence:                  {Remove, 0},
+	tag.PatientMotherBirthName:                            {Remove, 0},
+	tag.PatientName:                                       {ZeroLength, 0},
+	tag.PatientPrimaryLanguageCodeSequence:                {Remove, 0},
+	tag.PatientPrimaryLanguageModifierCodeSequence:        {Remove, 0},
+	tag.PatientReligiousPreference:                        {Remove, 0},
+	tag.PatientSex:                                        {ZeroLength, retainPatientChars},
+	tag.PatientSexNeutered:                                {Remove, retainPatientChars},
+	tag.PatientSize:                                       {Remove, retainPatientChars},
+	tag.PatientState:                                      {Remove, retainPatientChars},
+	tag.PatientTelephoneNumbers:                           {Remove, 0},
+	tag.PatientWeight:                                     {Remove, retainPatientChars},
+	tag.PerformedLocation:                                 {Remove, 0},
+	tag.PerformedProcedureStepDescription:                 {Remove, cleanDescriptors},
+	tag.PerformedProcedureStepEndDate:                     {Remove, 0},
+	tag.PerformedProcedureStepEndTime:                     {Remove, 0},
+	tag.PerformedProcedureStepID:                          {Remove, 0},
+	tag.PerformedProcedureStepStartDate:                   {Remove, 0},
+	tag.PerformedProcedureStepStartTime:                   {Remove, 0},
+	tag.PerformedStationAETitle:                           {Remove, retainDevice},
+	tag.PerformedStationGeographicLocationCodeSequence:    {Remove, retainDevice},
+	tag.PerformedStationName:                              {Remove, retainDevice},
+	tag.PerformedStationNameCodeSequence:                  {Remove, retainDevice},
+	tag.PerformingPhysicianIdentificationSequence:         {Remove, 0},
+	tag.PerformingPhysicianName:                           {Remove, 0},
+	tag.PersonAddress:                                     {Remove, 0},
+	tag.PersonIdentificationCodeSequence:                  {Dummy, 0},
+	tag.PersonName:                                        {Dummy, 0},
+	tag.PersonTelephoneNumbers:                            {Remove, 0},
+	tag.PhysicianApprovingInterpretation:                  {Remove, 0},
+	tag.PhysiciansOfRecord:                                {Remove, 0},
+	tag.PhysiciansOfRecordIdentificationSequence:          {Remove, 0},
+	tag.PhysiciansReadingStudyIdentificationSequence:      {Remove, 0},
+	tag.PlacerOrderNumberImagingServiceRequest:            {ZeroLength, 0},
+	tag.PlateID:                                           {Remove, retainDevice},
+	tag.PreMedication:                                     {Remove, retainPatientChars},
+	tag.PregnancyStatus:                                   {Remove, retainPatientChars},
+	tag.ProtocolName:                                      {Remove, cleanDescriptors},
+	tag.ReasonForStudy:                                    {Remove, cleanDescriptors},
+	tag.ReferencedDigitalSignatureSequence:                {Remove, 0},
+	tag.ReferencedFrameOfReferenceUID:                     {ReplaceUID, 0},
+	tag.ReferencedGeneralPurposeScheduledProcedureStepTransactionUID: {ReplaceUID, 0},
+	tag.ReferencedPatientAliasSequence:                               {Remove, 0},
+	tag.ReferencedPatientSequence:                                    {Remove, 0},
+	tag.ReferencedPerformedProcedureStepSequence:                     {Remove, 0},
+	tag.ReferencedSOPInstanceMACSequence:                             {Remove, 0},
+	tag.ReferencedSOPInstanceUID:                                     {ReplaceUID, 0},
+	tag.ReferencedSOPInstanceUIDInFile:                               {ReplaceUID, 0},
+	tag.ReferencedStudySequence:                                      {Remove, 0},
+	tag.ReferringPhysicianAddress:                                    {Remove, 0},
+	tag.ReferringPhysicianIdentificationSequence:                     {Remove, 0},
+	tag.ReferringPhysicianName:                                       {ZeroLength, 0},
+	tag.ReferringPhysicianTelephoneNumbers:                           {Remove, 0},
+	tag.RegionOfResidence:                                            {Remove, 0},
+	tag.RelatedFrameOfReferenceUID:                                   {ReplaceUID, 0},
+	tag.RequestAttributesSequence:                                    {Remove, 0},
+	tag.RequestedContrastAgent:                                       {Remove, cleanDescriptors},
+	tag.RequestedProcedureComments:                                   {Remove, cleanDescriptors},
+	tag.RequestedProcedureDescription:                                {Remove, cleanDescriptors},
+	tag.RequestedProcedureID:                                         {Remove, 0},
+	tag.RequestedProcedureLocation:                                   {Remove, 0},
+	tag.RequestingPhysician:                                          {Remove, 0},
+	tag.RequestingService:                                            {Remove, 0},
+	tag.ResponsibleOrganization:                                      {Remove, 0},
+	tag.ResponsiblePerson:                                            {Remove, 0},
+	tag.ResultsComments:                                              {Remove, cleanDescriptors},
+	tag.ResultsDistributionListSequence:                              {Remove, 0},
+	tag.ResultsIDIssuer:                                              {Remove, 0},
+	tag.ReviewerName:                                                 {Remove, 0},
+	tag.ScheduledHumanPerformersSequence:                             {Remove, 0},
+	tag.ScheduledPatientInstitutionResidence:                         {Remove, 0},
+	tag.ScheduledPerformingPhysicianIdentificationSequence:           {Remove, 0},
+	tag.ScheduledPerformingPhysicianName:                             {Remove, 0},
+	tag.ScheduledProcedureStepDescription:                            {Remove, cleanDescriptors},
+	tag.ScheduledProcedureStepEndDate:                                {Remove, 0},
+	tag.ScheduledProcedureStepEndTime:                                {Remove, 0},
+	tag.ScheduledProcedureStepLocation:                               {Remove, 0},
+	tag.ScheduledProcedureStepStartDate:                              {Remove, 0},
+	tag.ScheduledProcedureStepStartTime:                              {Remove, 0},
+	tag.ScheduledStationAETitle:                                      {Remove, retainDevice},
+	tag.ScheduledStationGeographicLocationCodeSequence:               {Remove, retainDevice},
+	tag.ScheduledStationName:                                         {Remove, retainDevice},
+	tag.ScheduledStationNameCodeSequence:                             {Remove, retainDevice},
+	tag.ScheduledStudyLocation:                                       {Remove, 0},
+	tag.ScheduledStudyLocationAETitle:                                {Remove, 0},
+	tag.SeriesDate:                                                   {Remove, 0},
+	tag.SeriesDescription:                                            {Remove, cleanDescriptors},
+	tag.SeriesInstanceUID:                                            {ReplaceUID, 0},
+	tag.SeriesTime:                                                   {Remove, 0},
+	tag.ServiceEpisodeDescription:                                    {Remove, cleanDescriptors},
+	tag.ServiceEpisodeID:                                             {Remove, 0},
+	tag.SmokingStatus:                                                {Remove, retainPatientChars},
+	tag.SOPInstanceUID:                                               {ReplaceUID, 0},
+	tag.SourceImageSequence:                                          {Remove, 0},
+	tag.SpecialNeeds:                                                 {Remove, retainPatientChars},
+	tag.StationName:                                                  {Remove, retainDevice},
+	tag.StorageMediaFileSetUID:                                       {ReplaceUID, 0},
+	tag.StudyComments:                                                {Remove, cleanDescriptors},
+	tag.StudyDate:                                                    {ZeroLength, 0},
+	tag.StudyDescription:                                             {Remove, cleanDescriptors},
+	tag.StudyID:                                                      {ZeroLength, 0},
+	tag.StudyIDIssuer:                                                {Remove, 0},
+	tag.StudyInstanceUID:                                             {ReplaceUID, 0},
+	tag.StudyTime:                                                    {ZeroLength, 0},
+	tag.SynchronizationFrameOfReferenceUID:                           {ReplaceUID, 0},
+	tag.TemplateExtensionCreatorUID:                                  {ReplaceUID, 0},
+	tag.TemplateExtensionOrganizationUID:                             {ReplaceUID, 0},
+	tag.TextComments:                                                 {Remove, cleanDescriptors},
+	tag.TextString:                                                   {Remove, 0},
+	tag.TimezoneOffsetFromUTC:                                        {Remove, 0},
+	tag.TopicAuthor:                                                  {Remove, 0},
+	tag.TopicKeywords:                                                {Remove, cleanDescriptors},
+	tag.TopicSubject:                                                 {Remove, cleanDescriptors},
+	tag.TopicTitle:                                                   {Remove, cleanDescriptors},
+	tag.TransactionUID:                                               {ReplaceUID, 0},
+	tag.UID:                                                          {ReplaceUID, 0},
+	tag.VerifyingObserverIdentificationCodeSequence:                  {ZeroLength, 0},
+	tag.VerifyingObserverName:                                        {Dummy, 0},
+	tag.VerifyingObserverSequence:                                    {Dummy, 0},
+	tag.VerifyingOrganization:                                        {Remove, 0},
+	tag.VisitComments:                                                {Remove, cleanDescriptors},
+}
@@ -0,0 +1,194 @@
+package deidentify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/wybaby168/dicom"
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/uid"
+	"github.com/wybaby168/dicom/pkg/vrraw"
+)
+
+// ErrorMalformedUIDMapping is returned by ReadUIDMapping when a record of the
+// mapping table does not have exactly two fields.
+var ErrorMalformedUIDMapping = errors.New("deidentify: malformed UID mapping record")
+
+// classUIDs are UI attributes that identify a class or a syntax rather than an
+// instance, and are never remapped even if they are not standard UIDs (e.g.
+// private SOP Classes or Transfer Syntaxes).
+var classUIDs = map[tag.Tag]bool{
+	tag.MediaStorageSOPClassUID:        true,
+	tag.TransferSyntaxUID:              true,
+	tag.ImplementationClassUID:         true,
+	tag.SOPClassUID:                    true,
+	tag.ReferencedSOPClassUID:          true,
+	tag.ReferencedSOPClassUIDInFile:    true,
+	tag.SOPClassesInStudy:              true,
+	tag.RelatedGeneralSOPClassUID:      true,
+	tag.OriginalSpecializedSOPClassUID: true,
+	tag.CodingSchemeUID:                true,
+	tag.MappingResourceUID:             true,
+	tag.PrivateInformationCreatorUID:   true,
+}
+
+// UIDRemapper replaces UIDs with new ones, consistently across every Dataset
+// it is applied to. A UIDRemapper is safe for concurrent use, so a single one
+// can be shared while processing many files in parallel.
+//
+// Standard UIDs (those under the 1.2.840.10008 root) are never remapped.
+type UIDRemapper struct {
+	// key is the HMAC key for deterministic remapping, or nil to generate
+	// random UIDs. Keyed replacements are recomputed on every call rather than
+	// stored in mapping, so that long-running services don't grow without
+	// bound.
+	key []byte
+
+	mu      sync.RWMutex
+	mapping map[string]string
+}
+
+// NewUIDRemapper returns a UIDRemapper that generates random UIDs for UIDs it
+// has not seen before. mapping holds previously generated replacements
+// (original UID to new UID), typically loaded with ReadUIDMapping, and may be
+// nil. Use WriteMapping to persist the mapping for later runs.
+func NewUIDRemapper(mapping map[string]string) *UIDRemapper {
+	m := make(map[string]string, len(mapping))
+	for k, v := range mapping {
+		m[k] = v
+	}
+	return &UIDRemapper{mapping: m}
+}
+
+// NewKeyedUIDRemapper returns a UIDRemapper that derives new UIDs from an
+// HMAC-SHA256 of the original UID keyed with key. The same key always produces
+// the same replacement, so no mapping table needs to be persisted between
+// runs, and none is kept: Mapping and WriteMapping report no replacements. The
+// key must be kept secret, otherwise original UIDs can be confirmed by
+// recomputing the hash.
+func NewKeyedUIDRemapper(key []byte) *UIDRemapper {
+	return &UIDRemapper{key: append([]byte(nil), key...), mapping: map[string]string{}}
+}
+
+// Remap returns the replacement for u.
+func (r *UIDRemapper) Remap(u string) string {
+	u = strings.TrimRight(u, "\x00 ")
+	if u == "" || strings.HasPrefix(u, "1.2.840.10008.") {
+		return u
+	}
+	if r.key != nil {
+		mac := hmac.New(sha256.New, r.key)
+		mac.Write([]byte(u))
+		var h [16]byte
+		copy(h[:], mac.Sum(nil))
+		return uid.FromUUID(h)
+	}
+	r.mu.RLock()
+	replacement, ok := r.mapping[u]
+	r.mu.RUnlock()
+	if ok {
+		return replacement
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Another goroutine may have added u while the lock was released.
+	if replacement, ok := r.mapping[u]; ok {
+		return replacement
+	}
+	replacement = uid.New()
+	r.mapping[u] = replacement
+	return replacement
+}
+
+// RemapElement replaces the UIDs in elem if it is a UI element that identifies
+// an instance. It does not recurse into sequences, which allows it to be used
+// while walking a Dataset, e.g. with Dataset.FlatStatefulIterator.
+func (r *UIDRemapper) RemapElement(elem *dicom.Element) error {
+	if elem.Value == nil || elem.Value.ValueType() != dicom.Strings || classUIDs[elem.Tag] || vrOf(elem) != vrraw.UniqueIdentifier {
+		return nil
+	}
+	uids := dicom.MustGetStrings(elem.Value)
+	remapped := make([]string, len(uids))
+	for i, u := range uids {
+		remapped[i] = r.Remap(u)
+	}
+	return setValue(elem, remapped)
+}
+
+// RemapDataset replaces every instance UID in ds, including those in the file
+// meta information (e.g. MediaStorageSOPInstanceUID) and in nested sequences
+// (e.g. ReferencedSOPInstanceUID).
+func (r *UIDRemapper) RemapDataset(ds *dicom.Dataset) error {
+	if ds == nil {
+		return ErrorNilDataset
+	}
+	for iter := ds.FlatStatefulIterator(); iter.HasNext(); {
+		elem := iter.Next()
+		if err := r.RemapElement(elem); err != nil {
+			return fmt.Errorf("deidentify: unable to remap %v: %w", tag.DebugString(elem.Tag), err)
+		}
+	}
+	return nil
+}
+
+// Mapping returns a copy of all the replacements made so far, keyed by the
+// original UID. It is empty for a UIDRemapper returned by NewKeyedUIDRemapper.
+func (r *UIDRemapper) Mapping() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m := make(map[string]string, len(r.mapping))
+	for k, v := range r.mapping {
+		m[k] = v
+	}
+	return m
+}
+
+// WriteMapping writes the replacements made so far to w as CSV records of
+// the original and the new UID, sorted by original UID. It can be read back
+// with ReadUIDMapping.
+func (r *UIDRemapper) WriteMapping(w io.Writer) error {
+	m := r.Mapping()
+	originals := make([]string, 0, len(m))
+	for k := range m {
+		originals = append(originals, k)
+	}
+	sort.Strings(originals)
+
+	cw := csv.NewWriter(w)
+	for _, o := range originals {
+		if err := cw.Write([]string{o, m[o]}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadUIDMapping reads a mapping table written by UIDRemapper.WriteMapping,
+// suitable for passing to NewUIDRemapper.
+func ReadUIDMapping(in io.Reader) (map[string]string, error) {
+	cr := csv.NewReader(in)
+	cr.FieldsPerRecord = -1
+	m := map[string]string{}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return m, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) != 2 {
+			return nil, fmt.Errorf("%w: %q", ErrorMalformedUIDMapping, record)
+		}
+		m[record[0]] = record[1]
+	}
+}
@@ -0,0 +1,139 @@
+package deidentify
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wybaby168/dicom"
+	"github.com/wybaby168/dicom/pkg/tag"
+)
+
+func newRemapTestDataset(t *testing.T) dicom.Dataset {
+	return dicom.Dataset{Elements: []*dicom.Element{
+		mustNewElement(t, tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.2"}),
+		mustNewElement(t, tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4"}),
+		mustNewElement(t, tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"}),
+		mustNewElement(t, tag.SOPClassUID, []string{"1.2.999.1"}),
+		mustNewElement(t, tag.SOPInstanceUID, []string{"1.2.3.4"}),
+		mustNewSequence(t, tag.ReferencedImageSequence, [][]*dicom.Element{
+			{
+				mustNewElement(t, tag.ReferencedSOPClassUID, []string{"1.2.999.1"}),
+				mustNewElement(t, tag.ReferencedSOPInstanceUID, []string{"1.2.3.5"}),
+			},
+		}),
+		mustNewElement(t, tag.StudyInstanceUID, []string{"1.2.3"}),
+		mustNewElement(t, tag.SeriesInstanceUID, []string{"1.2.3.1"}),
+	}}
+}
+
+func TestUIDRemapper_RemapDataset(t *testing.T) {
+	r := NewUIDRemapper(nil)
+	ds := newRemapTestDataset(t)
+	if err := r.RemapDataset(&ds); err != nil {
+		t.Fatalf("RemapDataset() unexpected error: %v", err)
+	}
+
+	m := r.Mapping()
+	if len(m) != 4 {
+		t.Errorf("RemapDataset() remapped %d UIDs, want 4: %v", len(m), m)
+	}
+	assertStrings(t, ds, tag.MediaStorageSOPInstanceUID, []string{m["1.2.3.4"]})
+	assertStrings(t, ds, tag.SOPInstanceUID, []string{m["1.2.3.4"]})
+	assertStrings(t, ds, tag.StudyInstanceUID, []string{m["1.2.3"]})
+	assertStrings(t, ds, tag.SeriesInstanceUID, []string{m["1.2.3.1"]})
+	// Class and syntax UIDs are kept, even when they are not standard UIDs.
+	assertStrings(t, ds, tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.2"})
+	assertStrings(t, ds, tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2.1"})
+	assertStrings(t, ds, tag.SOPClassUID, []string{"1.2.999.1"})
+
+	ref, err := ds.FindElementByTagNested(tag.ReferencedSOPInstanceUID)
+	if err != nil {
+		t.Fatalf("FindElementByTagNested() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{m["1.2.3.5"]}, dicom.MustGetStrings(ref.Value)); diff != "" {
+		t.Errorf("RemapDataset() unexpected nested ReferencedSOPInstanceUID diff: %v", diff)
+	}
+	for _, replacement := range m {
+		if !strings.HasPrefix(replacement, "2.25.") || len(replacement) > 64 {
+			t.Errorf("RemapDataset() generated invalid UID %q", replacement)
+		}
+	}
+}
+
+func TestUIDRemapper_Keyed(t *testing.T) {
+	a := NewKeyedUIDRemapper([]byte("secret"))
+	b := NewKeyedUIDRemapper([]byte("secret"))
+	c := NewKeyedUIDRemapper([]byte("other secret"))
+	if a.Remap("1.2.3") != b.Remap("1.2.3") {
+		t.Errorf("Remap() with the same key returned different UIDs")
+	}
+	if a.Remap("1.2.3") == c.Remap("1.2.3") {
+		t.Errorf("Remap() with different keys returned the same UID")
+	}
+	if a.Remap("1.2.3") == a.Remap("1.2.4") {
+		t.Errorf("Remap() returned the same UID for different inputs")
+	}
+	if m := a.Mapping(); len(m) != 0 {
+		t.Errorf("Mapping() = %v, want keyed replacements not to be cached", m)
+	}
+}
+
+func TestUIDRemapper_PersistedMapping(t *testing.T) {
+	r := NewUIDRemapper(nil)
+	first := r.Remap("1.2.3")
+	var buf bytes.Buffer
+	if err := r.WriteMapping(&buf); err != nil {
+		t.Fatalf("WriteMapping() unexpected error: %v", err)
+	}
+
+	mapping, err := ReadUIDMapping(&buf)
+	if err != nil {
+		t.Fatalf("ReadUIDMapping() unexpected error: %v", err)
+	}
+	if got := NewUIDRemapper(mapping).Remap("1.2.3"); got != first {
+		t.Errorf("Remap() with loaded mapping = %q, want %q", got, first)
+	}
+
+	if _, err := ReadUIDMapping(strings.NewReader("1.2.3\n")); !errors.Is(err, ErrorMalformedUIDMapping) {
+		t.Errorf("ReadUIDMapping() returned err %v, want %v", err, ErrorMalformedUIDMapping)
+	}
+}
+
+func TestUIDRemapper_Concurrent(t *testing.T) {
+	r := NewUIDRemapper(nil)
+	results := make([]string, 50)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ds := newRemapTestDataset(t)
+			if err := r.RemapDataset(&ds); err != nil {
+				t.Errorf("RemapDataset() unexpected error: %v", err)
+				return
+			}
+			results[i] = dicom.MustGetStrings(ds.Elements[4].Value)[0]
+		}(i)
+	}
+	wg.Wait()
+	for _, got := range results {
+		if got != results[0] {
+			t.Fatalf("RemapDataset() remapped the same UID inconsistently across goroutines: %q != %q", got, results[0])
+		}
+	}
+}
+
+func TestProfile_WithUIDRemapper(t *testing.T) {
+	r := NewKeyedUIDRemapper([]byte("secret"))
+	ds := dicom.Dataset{Elements: []*dicom.Element{
+		mustNewElement(t, tag.StudyInstanceUID, []string{"1.2.3"}),
+	}}
+	if err := Deidentify(&ds, WithUIDRemapper(r)); err != nil {
+		t.Fatalf("Deidentify() unexpected error: %v", err)
+	}
+	assertStrings(t, ds, tag.StudyInstanceUID, []string{r.Remap("1.2.3")})
+}
@@ -10,7 +10,6 @@ import (
 	"math"
 
 	"github.com/wybaby168/dicom/pkg/charset"
-	"golang.org/x/text/encoding"
 )
 
 var (
@@ -35,6 +34,9 @@ type Reader struct {
 	// particular encoding.Decoder within this CodingSystem is nil, assume
 	// UTF-8.
 	cs charset.CodingSystem
+	// captured holds the bytes read since StartCapture, if capturing is true.
+	captured  []byte
+	capturing bool
 }
 
 // NewReader creates and returns a new *dicomio.Reader.
@@ -54,6 +56,13 @@ func (r *Reader) BytesLeftUntilLimit() int64 {
 	return r.limit - r.bytesRead
 }
 
+// BytesRead returns the number of bytes read so far, which is the offset of
+// the next byte to read from the start of the input. After SetDeflate, bytes
+// are counted in the decompressed stream.
+func (r *Reader) BytesRead() int64 {
+	return r.bytesRead
+}
+
 func (r *Reader) Read(p []byte) (int, error) {
 	// Check if we've hit the limit
 	if r.BytesLeftUntilLimit() <= 0 {
@@ -72,9 +81,28 @@ func (r *Reader) Read(p []byte) (int, error) {
 	if n >= 0 {
 		r.bytesRead += int64(n)
 	}
+	if r.capturing && n > 0 {
+		r.captured = append(r.captured, p[:n]...)
+	}
 	return n, err
 }
 
+// StartCapture makes the Reader keep a copy of all bytes read, including
+// skipped ones, until EndCapture is called.
+func (r *Reader) StartCapture() {
+	r.capturing = true
+	r.captured = nil
+}
+
+// EndCapture returns the bytes read since StartCapture, and stops keeping a
+// copy of them.
+func (r *Reader) EndCapture() []byte {
+	captured := r.captured
+	r.capturing = false
+	r.captured = nil
+	return captured
+}
+
 // ReadUInt8 reads an uint8 from the underlying *Reader.
 func (r *Reader) ReadUInt8() (uint8, error) {
 	var out uint8
@@ -96,6 +124,13 @@ func (r *Reader) ReadUInt32() (uint32, error) {
 	return out, err
 }
 
+// ReadUInt64 reads an uint64 from the underlying *Reader.
+func (r *Reader) ReadUInt64() (uint64, error) {
+	var out uint64
+	err := binary.Read(r, r.bo, &out)
+	return out, err
+}
+
 // ReadInt16 reads an int16 from the underlying *Reader.
 func (r *Reader) ReadInt16() (int16, error) {
 	var out int16
@@ -110,6 +145,13 @@ func (r *Reader) ReadInt32() (int32, error) {
 	return out, err
 }
 
+// ReadInt64 reads an int64 from the underlying *Reader.
+func (r *Reader) ReadInt64() (int64, error) {
+	var out int64
+	err := binary.Read(r, r.bo, &out)
+	return out, err
+}
+
 // ReadFloat32 reads a float32 from the underlying *Reader.
 func (r *Reader) ReadFloat32() (float32, error) {
 	var out float32
@@ -124,29 +166,26 @@ func (r *Reader) ReadFloat64() (float64, error) {
 	return out, err
 }
 
-func internalReadString(data []byte, d *encoding.Decoder) (string, error) {
-	if len(data) == 0 {
-		return "", nil
-	}
-	if d == nil {
-		// Assume UTF-8
-		return string(data), nil
-	}
-	bytes, err := d.Bytes(data)
+// ReadString reads a string from the underlying *Reader.
+func (r *Reader) ReadString(n uint32) (string, error) {
+	data := make([]byte, n)
+	_, err := io.ReadFull(r, data)
 	if err != nil {
 		return "", err
 	}
-	return string(bytes), nil
+	return r.cs.Decode(data)
 }
 
-// ReadString reads a string from the underlying *Reader.
-func (r *Reader) ReadString(n uint32) (string, error) {
+// ReadPersonName reads a PN string from the underlying *Reader. Unlike
+// ReadString, PN component groups may be decoded with different character
+// sets.
+func (r *Reader) ReadPersonName(n uint32) (string, error) {
 	data := make([]byte, n)
 	_, err := io.ReadFull(r, data)
 	if err != nil {
 		return "", err
 	}
-	return internalReadString(data, r.cs.Ideographic)
+	return r.cs.DecodePersonName(data)
 }
 
 // Skip skips the *Reader ahead by n bytes.
@@ -221,6 +260,11 @@ func (r *Reader) SetCodingSystem(cs charset.CodingSystem) {
 	r.cs = cs
 }
 
+// GetCodingSystem returns the charset.CodingSystem used by ReadString.
+func (r *Reader) GetCodingSystem() charset.CodingSystem {
+	return r.cs
+}
+
 // Peek reads and returns the next n bytes (if possible) without advancing the
 // underlying reader.
 func (r *Reader) Peek(n int) ([]byte, error) {
@@ -3,6 +3,8 @@ package dicomio
 import (
 	"encoding/binary"
 	"io"
+
+	"github.com/wybaby168/dicom/pkg/charset"
 )
 
 // Writer is a lower level encoder that manages writing out entities to an
@@ -11,6 +13,8 @@ type Writer struct {
 	out      io.Writer
 	bo       binary.ByteOrder
 	implicit bool
+	// cs is the CodingSystem to encode strings with, see SetCodingSystem.
+	cs charset.CodingSystem
 }
 
 // NewWriter initializes and returns a Writer.
@@ -33,6 +37,17 @@ func (w *Writer) GetTransferSyntax() (binary.ByteOrder, bool) {
 	return w.bo, w.implicit
 }
 
+// SetCodingSystem sets the charset.CodingSystem that strings affected by the
+// Specific Character Set should be encoded with.
+func (w *Writer) SetCodingSystem(cs charset.CodingSystem) {
+	w.cs = cs
+}
+
+// GetCodingSystem gets the current charset.CodingSystem of this Writer.
+func (w *Writer) GetCodingSystem() charset.CodingSystem {
+	return w.cs
+}
+
 // WriteZeros writes len bytes of zeros at the current position of the Writer.
 func (w *Writer) WriteZeros(len int) error {
 	zeros := make([]byte, len)
@@ -57,6 +72,13 @@ func (w *Writer) WriteBytes(v []byte) error {
 	return err
 }
 
+// WriteFrom copies exactly n bytes from r to the Writer, without holding them
+// in memory at once. It returns io.EOF if r holds fewer than n bytes.
+func (w *Writer) WriteFrom(r io.Reader, n int64) error {
+	_, err := io.CopyN(w.out, r, n)
+	return err
+}
+
 // WriteUInt16 writes the provided uint16 to the Writer.
 func (w *Writer) WriteUInt16(v uint16) error {
 	return binary.Write(w.out, w.bo, &v)
@@ -67,6 +89,11 @@ func (w *Writer) WriteUInt32(v uint32) error {
 	return binary.Write(w.out, w.bo, &v)
 }
 
+// WriteUInt64 writes the provided uint64 to the Writer.
+func (w *Writer) WriteUInt64(v uint64) error {
+	return binary.Write(w.out, w.bo, &v)
+}
+
 // WriteFloat32 writes the provided float32 to the Writer.
 func (w *Writer) WriteFloat32(v float32) error {
 	return binary.Write(w.out, w.bo, &v)
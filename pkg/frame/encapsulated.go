@@ -43,3 +43,8 @@ func (e *EncapsulatedFrame) Equals(target *EncapsulatedFrame) bool {
 	}
 	return true
 }
+
+// Clone returns a deep copy of this frame.
+func (e *EncapsulatedFrame) Clone() *EncapsulatedFrame {
+	return &EncapsulatedFrame{Data: bytes.Clone(e.Data)}
+}
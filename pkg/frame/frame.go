@@ -89,3 +89,15 @@ func (f *Frame) Equals(target *Frame) bool {
 	}
 	return true
 }
+
+// Clone returns a deep copy of this frame.
+func (f *Frame) Clone() *Frame {
+	if f == nil {
+		return nil
+	}
+	c := &Frame{Encapsulated: f.Encapsulated, EncapsulatedData: *f.EncapsulatedData.Clone()}
+	if f.NativeData != nil {
+		c.NativeData = f.NativeData.Clone()
+	}
+	return c
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"slices"
 
 	"golang.org/x/exp/constraints"
 )
@@ -50,6 +51,8 @@ type INativeFrame interface {
 	// In the future we may compute a one time hash during construction to make
 	// this less expensive in the future if called multiple time.
 	Equals(frame INativeFrame) bool
+	// Clone returns a deep copy of this INativeFrame.
+	Clone() INativeFrame
 	CommonFrame
 }
 
@@ -164,6 +167,13 @@ func (n *NativeFrame[I]) GetImage() (image.Image, error) {
 	return i, nil
 }
 
+// Clone returns a deep copy of this frame.
+func (n *NativeFrame[I]) Clone() INativeFrame {
+	c := *n
+	c.RawData = slices.Clone(n.RawData)
+	return &c
+}
+
 // Equals returns true if this frame equals the provided target frame, otherwise
 // false. This may be expensive.
 func (n *NativeFrame[I]) Equals(target INativeFrame) bool {
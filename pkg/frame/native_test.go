@@ -299,3 +299,17 @@ func within(pt point, set []point) bool {
 	}
 	return false
 }
+
+func TestNativeFrame_Clone(t *testing.T) {
+	f := frame.NewNativeFrame[uint16](16, 1, 2, 2, 1)
+	f.RawData[0] = 1
+
+	c := f.Clone()
+	if !c.Equals(f) {
+		t.Fatalf("Clone() = %v, want a frame equal to %v", c, f)
+	}
+	f.RawData[0] = 2
+	if got := c.RawDataSlice().([]uint16)[0]; got != 1 {
+		t.Errorf("Clone() shares RawData with the original frame, got sample %d after modifying the original, want 1", got)
+	}
+}
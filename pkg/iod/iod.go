@@ -0,0 +1,321 @@
+// Package iod checks Datasets for conformance to the Information Object
+// Definitions (IODs) of PS3.3, similar to what dciodvfy does: every module the
+// IOD requires must be present with its Type 1 attributes present and not
+// empty, its Type 2 attributes present, and conditional attributes present
+// only when allowed.
+//
+// Definitions are included for the CT Image, MR Image, Secondary Capture Image
+// and Basic Text, Enhanced and Comprehensive SR Storage SOP Classes. Other
+// IODs can be added with Register.
+package iod
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/wybaby168/dicom"
+	"github.com/wybaby168/dicom/pkg/tag"
+)
+
+// ErrorUnknownSOPClass is returned when no IOD is registered for the SOP Class
+// UID of a Dataset.
+var ErrorUnknownSOPClass = errors.New("iod: no IOD registered for SOP Class UID")
+
+// Type is the PS3.5 Section 7.4 data element type of an attribute in a module.
+type Type int
+
+const (
+	// Type1 attributes shall be present with a value.
+	Type1 Type = iota
+	// Type1C attributes shall be present with a value when their Condition is
+	// met.
+	Type1C
+	// Type2 attributes shall be present, but may be empty.
+	Type2
+	// Type2C attributes shall be present, possibly empty, when their
+	// Condition is met.
+	Type2C
+	// Type3 attributes are optional.
+	Type3
+)
+
+// String returns the type as written in PS3.3, e.g. "1C".
+func (t Type) String() string {
+	switch t {
+	case Type1:
+		return "1"
+	case Type1C:
+		return "1C"
+	case Type2:
+		return "2"
+	case Type2C:
+		return "2C"
+	case Type3:
+		return "3"
+	default:
+		return fmt.Sprintf("Type(%d)", int(t))
+	}
+}
+
+// Usage is the usage of a module in an IOD.
+type Usage int
+
+const (
+	// Mandatory (M) modules shall be present.
+	Mandatory Usage = iota
+	// Conditional (C) modules shall be present when their Condition is met.
+	Conditional
+	// UserOption (U) modules may be present.
+	UserOption
+)
+
+// Condition reports whether the condition of a conditional attribute or
+// module is met. ds is the Dataset, or the sequence item, that contains the
+// attribute.
+type Condition func(ds *dicom.Dataset) bool
+
+// Attribute is an attribute of a module, or of the items of a sequence
+// attribute.
+type Attribute struct {
+	Tag  tag.Tag
+	Type Type
+	// Condition applies to Type1C and Type2C attributes. A nil Condition
+	// means the condition cannot be evaluated from the Dataset alone, and the
+	// attribute is treated as optional.
+	Condition Condition
+	// NotAllowedOtherwise marks conditional attributes that shall not be
+	// present when their Condition is not met.
+	NotAllowedOtherwise bool
+	// Items are the attributes of each item of a sequence attribute.
+	Items []Attribute
+}
+
+// Module is a PS3.3 Annex C module.
+type Module struct {
+	Name       string
+	Attributes []Attribute
+}
+
+// ModuleRef is a module used by an IOD.
+type ModuleRef struct {
+	Module *Module
+	Usage  Usage
+	// Condition applies to Conditional modules. A nil Condition means the
+	// module is only checked when it is present.
+	Condition Condition
+}
+
+// IOD is a PS3.3 Information Object Definition.
+type IOD struct {
+	Name    string
+	Modules []ModuleRef
+	// NotAllowed are attributes that shall not be present in instances of
+	// this IOD, e.g. PixelData in Structured Reports.
+	NotAllowed []tag.Tag
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]*IOD{}
+)
+
+// Register registers iod for the given SOP Class UID, replacing any IOD
+// registered for it before.
+func Register(sopClassUID string, iod *IOD) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[sopClassUID] = iod
+}
+
+// Lookup returns the IOD registered for sopClassUID.
+func Lookup(sopClassUID string) (*IOD, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	iod, ok := registry[sopClassUID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrorUnknownSOPClass, sopClassUID)
+	}
+	return iod, nil
+}
+
+// Kind is the kind of a Finding.
+type Kind int
+
+const (
+	// MissingType1 is a Type 1 (or applicable Type 1C) attribute that is not
+	// present.
+	MissingType1 Kind = iota
+	// EmptyType1 is a Type 1 (or applicable Type 1C) attribute that is
+	// present with an empty value.
+	EmptyType1
+	// MissingType2 is a Type 2 (or applicable Type 2C) attribute that is not
+	// present.
+	MissingType2
+	// NotAllowed is an attribute that is present where the IOD does not allow
+	// it.
+	NotAllowed
+)
+
+// String returns a description of the kind.
+func (k Kind) String() string {
+	switch k {
+	case MissingType1:
+		return "missing Type 1 attribute"
+	case EmptyType1:
+		return "empty Type 1 attribute"
+	case MissingType2:
+		return "missing Type 2 attribute"
+	case NotAllowed:
+		return "attribute not allowed"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Finding is a conformance problem found by Check.
+type Finding struct {
+	Kind Kind
+	// Path is the location of the attribute in the Dataset.
+	Path dicom.Path
+	// Module is the name of the module the attribute belongs to, or empty for
+	// attributes the IOD does not allow.
+	Module string
+}
+
+// String returns a description of the finding, e.g.
+// "General Study: missing Type 1 attribute (0020,000d) StudyInstanceUID".
+func (f Finding) String() string {
+	prefix := ""
+	if f.Module != "" {
+		prefix = f.Module + ": "
+	}
+	keyword := ""
+	if info, err := tag.Find(f.Path.Tag()); err == nil {
+		keyword = " " + info.Keyword
+	}
+	return fmt.Sprintf("%s%v %v%s", prefix, f.Kind, f.Path, keyword)
+}
+
+// Check looks up the IOD for the SOPClassUID of ds and checks ds against it.
+func Check(ds dicom.Dataset) ([]Finding, error) {
+	e, err := ds.FindElementByTag(tag.SOPClassUID)
+	if err != nil {
+		return nil, err
+	}
+	if e.Value == nil || e.Value.ValueType() != dicom.Strings {
+		return nil, fmt.Errorf("%w: SOPClassUID is not a string", ErrorUnknownSOPClass)
+	}
+	sopClass := dicom.MustGetStrings(e.Value)
+	if len(sopClass) == 0 {
+		return nil, fmt.Errorf("%w: empty SOPClassUID", ErrorUnknownSOPClass)
+	}
+	iod, err := Lookup(sopClass[0])
+	if err != nil {
+		return nil, err
+	}
+	return iod.Check(ds), nil
+}
+
+// Check checks ds against the IOD, and returns the problems found. A nil
+// result means ds conforms to the IOD.
+func (iod *IOD) Check(ds dicom.Dataset) []Finding {
+	var findings []Finding
+	for _, ref := range iod.Modules {
+		if !moduleApplies(ref, &ds) {
+			continue
+		}
+		checkAttributes(&ds, ref.Module.Attributes, nil, ref.Module.Name, &findings)
+	}
+	for _, t := range iod.NotAllowed {
+		if _, err := ds.FindElementByTag(t); err == nil {
+			findings = append(findings, Finding{Kind: NotAllowed, Path: dicom.Path{{Tag: t}}})
+		}
+	}
+	return findings
+}
+
+// moduleApplies returns true if the module is required, or is present.
+func moduleApplies(ref ModuleRef, ds *dicom.Dataset) bool {
+	switch {
+	case ref.Usage == Mandatory:
+		return true
+	case ref.Usage == Conditional && ref.Condition != nil && ref.Condition(ds):
+		return true
+	}
+	for _, a := range ref.Module.Attributes {
+		if _, err := ds.FindElementByTag(a.Tag); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func checkAttributes(ds *dicom.Dataset, attrs []Attribute, parent dicom.Path, module string, findings *[]Finding) {
+	for _, a := range attrs {
+		path := append(append(dicom.Path{}, parent...), dicom.PathStep{Tag: a.Tag})
+		report := func(kind Kind) {
+			*findings = append(*findings, Finding{Kind: kind, Path: path, Module: module})
+		}
+		elem, err := ds.FindElementByTag(a.Tag)
+		present := err == nil
+
+		required := a.Type == Type1 || a.Type == Type2
+		if a.Type == Type1C || a.Type == Type2C {
+			if a.Condition != nil && a.Condition(ds) {
+				required = true
+			} else if a.Condition != nil && a.NotAllowedOtherwise && present {
+				report(NotAllowed)
+				continue
+			}
+		}
+
+		switch {
+		case !present && required && (a.Type == Type1 || a.Type == Type1C):
+			report(MissingType1)
+		case !present && required:
+			report(MissingType2)
+		case present && required && (a.Type == Type1 || a.Type == Type1C) && isEmpty(elem):
+			report(EmptyType1)
+		}
+
+		if present && len(a.Items) > 0 && elem.Value != nil && elem.Value.ValueType() == dicom.Sequences {
+			for i, item := range elem.Value.GetValue().([]*dicom.SequenceItemValue) {
+				itemDS := &dicom.Dataset{Elements: item.GetValue().([]*dicom.Element)}
+				itemPath := append(append(dicom.Path{}, parent...), dicom.PathStep{Tag: a.Tag, Item: i})
+				checkAttributes(itemDS, a.Items, itemPath, module, findings)
+			}
+		}
+	}
+}
+
+func isEmpty(elem *dicom.Element) bool {
+	if elem.Value == nil {
+		return true
+	}
+	switch elem.Value.ValueType() {
+	case dicom.Strings:
+		for _, s := range dicom.MustGetStrings(elem.Value) {
+			if s != "" {
+				return false
+			}
+		}
+		return true
+	case dicom.Ints:
+		return len(dicom.MustGetInts(elem.Value)) == 0
+	case dicom.Int64s:
+		return len(dicom.MustGetInt64s(elem.Value)) == 0
+	case dicom.Uint64s:
+		return len(dicom.MustGetUint64s(elem.Value)) == 0
+	case dicom.Tags:
+		return len(dicom.MustGetTags(elem.Value)) == 0
+	case dicom.Floats:
+		return len(dicom.MustGetFloats(elem.Value)) == 0
+	case dicom.Bytes:
+		return len(dicom.MustGetBytes(elem.Value)) == 0
+	case dicom.Sequences:
+		return len(elem.Value.GetValue().([]*dicom.SequenceItemValue)) == 0
+	default:
+		return false
+	}
+}
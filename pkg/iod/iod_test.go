@@ -0,0 +1,263 @@
+package iod
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wybaby168/dicom"
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/uid"
+)
+
+func TestCheck(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(ds *dicom.Dataset)
+		want   []Finding
+	}{
+		{
+			name:   "conforming",
+			modify: func(ds *dicom.Dataset) {},
+		},
+		{
+			name: "missing Type 1",
+			modify: func(ds *dicom.Dataset) {
+				remove(ds, tag.StudyInstanceUID)
+			},
+			want: []Finding{{Kind: MissingType1, Path: dicom.Path{{Tag: tag.StudyInstanceUID}}, Module: "General Study"}},
+		},
+		{
+			name: "empty Type 1",
+			modify: func(ds *dicom.Dataset) {
+				set(t, ds, tag.Modality, []string{""})
+			},
+			want: []Finding{{Kind: EmptyType1, Path: dicom.Path{{Tag: tag.Modality}}, Module: "General Series"}},
+		},
+		{
+			name: "missing Type 2",
+			modify: func(ds *dicom.Dataset) {
+				remove(ds, tag.PatientName)
+			},
+			want: []Finding{{Kind: MissingType2, Path: dicom.Path{{Tag: tag.PatientName}}, Module: "Patient"}},
+		},
+		{
+			name: "empty Type 2",
+			modify: func(ds *dicom.Dataset) {
+				set(t, ds, tag.PatientName, []string{""})
+			},
+		},
+		{
+			name: "Type 1C condition met",
+			modify: func(ds *dicom.Dataset) {
+				set(t, ds, tag.SamplesPerPixel, []int{3})
+			},
+			want: []Finding{{Kind: MissingType1, Path: dicom.Path{{Tag: tag.PlanarConfiguration}}, Module: "Image Pixel"}},
+		},
+		{
+			name: "Type 1C not allowed",
+			modify: func(ds *dicom.Dataset) {
+				set(t, ds, tag.PlanarConfiguration, []int{0})
+			},
+			want: []Finding{{Kind: NotAllowed, Path: dicom.Path{{Tag: tag.PlanarConfiguration}}, Module: "Image Pixel"}},
+		},
+		{
+			name: "Type 2C condition met",
+			modify: func(ds *dicom.Dataset) {
+				remove(ds, tag.PatientPosition)
+			},
+			want: []Finding{{Kind: MissingType2, Path: dicom.Path{{Tag: tag.PatientPosition}}, Module: "General Series"}},
+		},
+		{
+			name: "nested",
+			modify: func(ds *dicom.Dataset) {
+				set(t, ds, tag.ReferencedStudySequence, [][]*dicom.Element{
+					{mustNewElement(t, tag.ReferencedSOPClassUID, []string{"1.2.3"})},
+				})
+			},
+			want: []Finding{{
+				Kind:   MissingType1,
+				Path:   dicom.Path{{Tag: tag.ReferencedStudySequence, Item: 0}, {Tag: tag.ReferencedSOPInstanceUID}},
+				Module: "General Study",
+			}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ds := newCTDataset(t)
+			tc.modify(&ds)
+			got, err := Check(ds)
+			if err != nil {
+				t.Fatalf("Check() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Check() unexpected findings diff: %v", diff)
+			}
+		})
+	}
+}
+
+func TestCheck_StructuredReport(t *testing.T) {
+	ds := dicom.Dataset{Elements: []*dicom.Element{
+		mustNewElement(t, tag.SOPClassUID, []string{uid.ComprehensiveSRStorage}),
+		mustNewElement(t, tag.SOPInstanceUID, []string{"1.2.3.4"}),
+		mustNewElement(t, tag.StudyDate, []string{""}),
+		mustNewElement(t, tag.StudyTime, []string{""}),
+		mustNewElement(t, tag.AccessionNumber, []string{""}),
+		mustNewElement(t, tag.Modality, []string{"SR"}),
+		mustNewElement(t, tag.Manufacturer, []string{""}),
+		mustNewElement(t, tag.ReferringPhysicianName, []string{""}),
+		mustNewElement(t, tag.PatientName, []string{""}),
+		mustNewElement(t, tag.PatientID, []string{""}),
+		mustNewElement(t, tag.PatientBirthDate, []string{""}),
+		mustNewElement(t, tag.PatientSex, []string{""}),
+		mustNewElement(t, tag.StudyInstanceUID, []string{"1.2.3"}),
+		mustNewElement(t, tag.SeriesInstanceUID, []string{"1.2.3.1"}),
+		mustNewElement(t, tag.StudyID, []string{""}),
+		mustNewElement(t, tag.SeriesNumber, []string{"1"}),
+		mustNewElement(t, tag.InstanceNumber, []string{"1"}),
+		mustNewElement(t, tag.ContentDate, []string{"20200102"}),
+		mustNewElement(t, tag.ContentTime, []string{"101010"}),
+		mustNewElement(t, tag.ReferencedPerformedProcedureStepSequence, [][]*dicom.Element{}),
+		mustNewElement(t, tag.PerformedProcedureCodeSequence, [][]*dicom.Element{}),
+		mustNewElement(t, tag.ValueType, []string{"CONTAINER"}),
+		mustNewElement(t, tag.ConceptNameCodeSequence, [][]*dicom.Element{{
+			mustNewElement(t, tag.CodeValue, []string{"18748-4"}),
+			mustNewElement(t, tag.CodingSchemeDesignator, []string{"LN"}),
+			mustNewElement(t, tag.CodeMeaning, []string{"Diagnostic Imaging Report"}),
+		}}),
+		mustNewElement(t, tag.ContinuityOfContent, []string{"SEPARATE"}),
+		mustNewElement(t, tag.CompletionFlag, []string{"COMPLETE"}),
+		mustNewElement(t, tag.VerificationFlag, []string{"VERIFIED"}),
+		mustNewElement(t, tag.PixelData, dicom.PixelDataInfo{}),
+	}}
+	got, err := Check(ds)
+	if err != nil {
+		t.Fatalf("Check() unexpected error: %v", err)
+	}
+	want := []Finding{
+		{Kind: MissingType1, Path: dicom.Path{{Tag: tag.VerifyingObserverSequence}}, Module: "SR Document General"},
+		{Kind: NotAllowed, Path: dicom.Path{{Tag: tag.PixelData}}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Check() unexpected findings diff: %v", diff)
+	}
+}
+
+func TestCheck_UnknownSOPClass(t *testing.T) {
+	cases := []struct {
+		name     string
+		sopClass *dicom.Element
+	}{
+		{name: "unknown", sopClass: mustNewElement(t, tag.SOPClassUID, []string{"1.2.3"})},
+		{name: "empty", sopClass: mustNewElement(t, tag.SOPClassUID, []string{})},
+		{name: "nil value", sopClass: &dicom.Element{Tag: tag.SOPClassUID, ValueRepresentation: tag.VRStringList, RawValueRepresentation: "UI"}},
+		{name: "not a string", sopClass: &dicom.Element{Tag: tag.SOPClassUID, ValueRepresentation: tag.VRBytes, RawValueRepresentation: "UN", Value: mustNewValue(t, []byte{1, 2})}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ds := dicom.Dataset{Elements: []*dicom.Element{tc.sopClass}}
+			if _, err := Check(ds); !errors.Is(err, ErrorUnknownSOPClass) {
+				t.Errorf("Check() returned err %v, want %v", err, ErrorUnknownSOPClass)
+			}
+		})
+	}
+}
+
+func TestCheck_Testdata(t *testing.T) {
+	ds, err := dicom.ParseFile("../../testdata/3.dcm", nil)
+	if err != nil {
+		t.Fatalf("ParseFile() unexpected error: %v", err)
+	}
+	got, err := Check(ds)
+	if err != nil {
+		t.Fatalf("Check() unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Check() returned unexpected findings: %v", got)
+	}
+}
+
+func TestFinding_String(t *testing.T) {
+	f := Finding{Kind: MissingType1, Path: dicom.Path{{Tag: tag.StudyInstanceUID}}, Module: "General Study"}
+	if want := "General Study: missing Type 1 attribute (0020,000d) StudyInstanceUID"; f.String() != want {
+		t.Errorf("String() = %q, want %q", f.String(), want)
+	}
+}
+
+func newCTDataset(t *testing.T) dicom.Dataset {
+	t.Helper()
+	return dicom.Dataset{Elements: []*dicom.Element{
+		mustNewElement(t, tag.ImageType, []string{"ORIGINAL", "PRIMARY", "AXIAL"}),
+		mustNewElement(t, tag.SOPClassUID, []string{uid.CTImageStorage}),
+		mustNewElement(t, tag.SOPInstanceUID, []string{"1.2.3.4"}),
+		mustNewElement(t, tag.StudyDate, []string{"20200102"}),
+		mustNewElement(t, tag.StudyTime, []string{"101010"}),
+		mustNewElement(t, tag.AccessionNumber, []string{""}),
+		mustNewElement(t, tag.Modality, []string{"CT"}),
+		mustNewElement(t, tag.Manufacturer, []string{"ACME"}),
+		mustNewElement(t, tag.ReferringPhysicianName, []string{""}),
+		mustNewElement(t, tag.PatientName, []string{"Doe^John"}),
+		mustNewElement(t, tag.PatientID, []string{"12345"}),
+		mustNewElement(t, tag.PatientBirthDate, []string{""}),
+		mustNewElement(t, tag.PatientSex, []string{"M"}),
+		mustNewElement(t, tag.KVP, []string{"120"}),
+		mustNewElement(t, tag.SliceThickness, []string{"1"}),
+		mustNewElement(t, tag.PatientPosition, []string{"HFS"}),
+		mustNewElement(t, tag.StudyInstanceUID, []string{"1.2.3"}),
+		mustNewElement(t, tag.SeriesInstanceUID, []string{"1.2.3.1"}),
+		mustNewElement(t, tag.StudyID, []string{"1"}),
+		mustNewElement(t, tag.SeriesNumber, []string{"1"}),
+		mustNewElement(t, tag.AcquisitionNumber, []string{"1"}),
+		mustNewElement(t, tag.InstanceNumber, []string{"1"}),
+		mustNewElement(t, tag.ImagePositionPatient, []string{"0", "0", "0"}),
+		mustNewElement(t, tag.ImageOrientationPatient, []string{"1", "0", "0", "0", "1", "0"}),
+		mustNewElement(t, tag.FrameOfReferenceUID, []string{"1.2.3.2"}),
+		mustNewElement(t, tag.PositionReferenceIndicator, []string{""}),
+		mustNewElement(t, tag.SamplesPerPixel, []int{1}),
+		mustNewElement(t, tag.PhotometricInterpretation, []string{"MONOCHROME2"}),
+		mustNewElement(t, tag.Rows, []int{2}),
+		mustNewElement(t, tag.Columns, []int{2}),
+		mustNewElement(t, tag.PixelSpacing, []string{"1", "1"}),
+		mustNewElement(t, tag.BitsAllocated, []int{16}),
+		mustNewElement(t, tag.BitsStored, []int{12}),
+		mustNewElement(t, tag.HighBit, []int{11}),
+		mustNewElement(t, tag.PixelRepresentation, []int{0}),
+		mustNewElement(t, tag.RescaleIntercept, []string{"-1024"}),
+		mustNewElement(t, tag.RescaleSlope, []string{"1"}),
+		mustNewElement(t, tag.PixelData, dicom.PixelDataInfo{}),
+	}}
+}
+
+func mustNewValue(t *testing.T, data any) dicom.Value {
+	t.Helper()
+	v, err := dicom.NewValue(data)
+	if err != nil {
+		t.Fatalf("NewValue(%v) unexpected error: %v", data, err)
+	}
+	return v
+}
+
+func mustNewElement(t *testing.T, tg tag.Tag, data any) *dicom.Element {
+	t.Helper()
+	e, err := dicom.NewElement(tg, data)
+	if err != nil {
+		t.Fatalf("NewElement(%v) unexpected error: %v", tg, err)
+	}
+	return e
+}
+
+func set(t *testing.T, ds *dicom.Dataset, tg tag.Tag, data any) {
+	t.Helper()
+	remove(ds, tg)
+	ds.Elements = append(ds.Elements, mustNewElement(t, tg, data))
+}
+
+func remove(ds *dicom.Dataset, tg tag.Tag) {
+	for i, e := range ds.Elements {
+		if e.Tag == tg {
+			ds.Elements = append(ds.Elements[:i], ds.Elements[i+1:]...)
+			return
+		}
+	}
+}
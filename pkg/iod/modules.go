@@ -0,0 +1,491 @@
+package iod
+
+import (
+	"github.com/wybaby168/dicom"
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/uid"
+)
+
+// present returns a Condition that is met when t is present.
+func present(t tag.Tag) Condition {
+	return func(ds *dicom.Dataset) bool {
+		_, err := ds.FindElementByTag(t)
+		return err == nil
+	}
+}
+
+// absent returns a Condition that is met when t is not present.
+func absent(t tag.Tag) Condition {
+	return func(ds *dicom.Dataset) bool {
+		_, err := ds.FindElementByTag(t)
+		return err != nil
+	}
+}
+
+// hasValue returns a Condition that is met when one of the values of t is
+// equal to one of values.
+func hasValue(t tag.Tag, values ...string) Condition {
+	return func(ds *dicom.Dataset) bool {
+		e, err := ds.FindElementByTag(t)
+		if err != nil || e.Value == nil || e.Value.ValueType() != dicom.Strings {
+			return false
+		}
+		for _, v := range dicom.MustGetStrings(e.Value) {
+			for _, want := range values {
+				if v == want {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// intGreaterThan returns a Condition that is met when the first value of t is
+// greater than n.
+func intGreaterThan(t tag.Tag, n int) Condition {
+	return func(ds *dicom.Dataset) bool {
+		e, err := ds.FindElementByTag(t)
+		if err != nil || e.Value == nil || e.Value.ValueType() != dicom.Ints {
+			return false
+		}
+		v := dicom.MustGetInts(e.Value)
+		return len(v) > 0 && v[0] > n
+	}
+}
+
+// codeSequenceMacro is the PS3.3 Table 8.8-1 Code Sequence Macro, restricted
+// to its basic attributes.
+var codeSequenceMacro = []Attribute{
+	{Tag: tag.CodeValue, Type: Type1C, Condition: absent(tag.LongCodeValue)},
+	{Tag: tag.CodingSchemeDesignator, Type: Type1C, Condition: present(tag.CodeValue)},
+	{Tag: tag.CodingSchemeVersion, Type: Type1C},
+	{Tag: tag.CodeMeaning, Type: Type1},
+	{Tag: tag.LongCodeValue, Type: Type1C},
+	{Tag: tag.URNCodeValue, Type: Type1C},
+}
+
+// sopInstanceReferenceMacro is the PS3.3 Table 10-11 SOP Instance Reference
+// Macro.
+var sopInstanceReferenceMacro = []Attribute{
+	{Tag: tag.ReferencedSOPClassUID, Type: Type1},
+	{Tag: tag.ReferencedSOPInstanceUID, Type: Type1},
+}
+
+// PatientModule is the PS3.3 C.7.1.1 Patient Module.
+var PatientModule = &Module{
+	Name: "Patient",
+	Attributes: []Attribute{
+		{Tag: tag.PatientName, Type: Type2},
+		{Tag: tag.PatientID, Type: Type2},
+		{Tag: tag.IssuerOfPatientID, Type: Type3},
+		{Tag: tag.PatientBirthDate, Type: Type2},
+		{Tag: tag.PatientBirthTime, Type: Type3},
+		{Tag: tag.PatientSex, Type: Type2},
+		{Tag: tag.OtherPatientIDsSequence, Type: Type3},
+		{Tag: tag.EthnicGroup, Type: Type3},
+		{Tag: tag.PatientComments, Type: Type3},
+		{Tag: tag.PatientIdentityRemoved, Type: Type3},
+		{Tag: tag.DeidentificationMethod, Type: Type1C, Condition: hasValue(tag.PatientIdentityRemoved, "YES")},
+		{Tag: tag.DeidentificationMethodCodeSequence, Type: Type1C, Items: codeSequenceMacro},
+	},
+}
+
+// GeneralStudyModule is the PS3.3 C.7.2.1 General Study Module.
+var GeneralStudyModule = &Module{
+	Name: "General Study",
+	Attributes: []Attribute{
+		{Tag: tag.StudyInstanceUID, Type: Type1},
+		{Tag: tag.StudyDate, Type: Type2},
+		{Tag: tag.StudyTime, Type: Type2},
+		{Tag: tag.ReferringPhysicianName, Type: Type2},
+		{Tag: tag.StudyID, Type: Type2},
+		{Tag: tag.AccessionNumber, Type: Type2},
+		{Tag: tag.StudyDescription, Type: Type3},
+		{Tag: tag.ReferencedStudySequence, Type: Type3, Items: sopInstanceReferenceMacro},
+		{Tag: tag.ProcedureCodeSequence, Type: Type3, Items: codeSequenceMacro},
+	},
+}
+
+// PatientStudyModule is the PS3.3 C.7.2.2 Patient Study Module.
+var PatientStudyModule = &Module{
+	Name: "Patient Study",
+	Attributes: []Attribute{
+		{Tag: tag.AdmittingDiagnosesDescription, Type: Type3},
+		{Tag: tag.PatientAge, Type: Type3},
+		{Tag: tag.PatientSize, Type: Type3},
+		{Tag: tag.PatientWeight, Type: Type3},
+		{Tag: tag.AdditionalPatientHistory, Type: Type3},
+	},
+}
+
+// GeneralSeriesModule is the PS3.3 C.7.3.1 General Series Module.
+var GeneralSeriesModule = &Module{
+	Name: "General Series",
+	Attributes: []Attribute{
+		{Tag: tag.Modality, Type: Type1},
+		{Tag: tag.SeriesInstanceUID, Type: Type1},
+		{Tag: tag.SeriesNumber, Type: Type2},
+		{Tag: tag.Laterality, Type: Type2C},
+		{Tag: tag.SeriesDate, Type: Type3},
+		{Tag: tag.SeriesTime, Type: Type3},
+		{Tag: tag.PerformingPhysicianName, Type: Type3},
+		{Tag: tag.ProtocolName, Type: Type3},
+		{Tag: tag.SeriesDescription, Type: Type3},
+		{Tag: tag.OperatorsName, Type: Type3},
+		{Tag: tag.BodyPartExamined, Type: Type3},
+		{Tag: tag.PatientPosition, Type: Type2C, Condition: hasValue(tag.SOPClassUID, uid.CTImageStorage, uid.MRImageStorage)},
+		{Tag: tag.ReferencedPerformedProcedureStepSequence, Type: Type3, Items: sopInstanceReferenceMacro},
+	},
+}
+
+// FrameOfReferenceModule is the PS3.3 C.7.4.1 Frame of Reference Module.
+var FrameOfReferenceModule = &Module{
+	Name: "Frame of Reference",
+	Attributes: []Attribute{
+		{Tag: tag.FrameOfReferenceUID, Type: Type1},
+		{Tag: tag.PositionReferenceIndicator, Type: Type2},
+	},
+}
+
+// GeneralEquipmentModule is the PS3.3 C.7.5.1 General Equipment Module.
+var GeneralEquipmentModule = &Module{
+	Name: "General Equipment",
+	Attributes: []Attribute{
+		{Tag: tag.Manufacturer, Type: Type2},
+		{Tag: tag.InstitutionName, Type: Type3},
+		{Tag: tag.InstitutionAddress, Type: Type3},
+		{Tag: tag.StationName, Type: Type3},
+		{Tag: tag.InstitutionalDepartmentName, Type: Type3},
+		{Tag: tag.ManufacturerModelName, Type: Type3},
+		{Tag: tag.DeviceSerialNumber, Type: Type3},
+		{Tag: tag.SoftwareVersions, Type: Type3},
+	},
+}
+
+// SCEquipmentModule is the PS3.3 C.8.6.1 SC Equipment Module.
+var SCEquipmentModule = &Module{
+	Name: "SC Equipment",
+	Attributes: []Attribute{
+		{Tag: tag.ConversionType, Type: Type1},
+		{Tag: tag.Modality, Type: Type3},
+		{Tag: tag.SecondaryCaptureDeviceID, Type: Type3},
+		{Tag: tag.SecondaryCaptureDeviceManufacturer, Type: Type3},
+		{Tag: tag.SecondaryCaptureDeviceManufacturerModelName, Type: Type3},
+		{Tag: tag.SecondaryCaptureDeviceSoftwareVersions, Type: Type3},
+	},
+}
+
+// GeneralImageModule is the PS3.3 C.7.6.1 General Image Module.
+var GeneralImageModule = &Module{
+	Name: "General Image",
+	Attributes: []Attribute{
+		{Tag: tag.InstanceNumber, Type: Type2},
+		{Tag: tag.PatientOrientation, Type: Type2C, Condition: absent(tag.ImageOrientationPatient)},
+		{Tag: tag.ContentDate, Type: Type2C},
+		{Tag: tag.ContentTime, Type: Type2C},
+		{Tag: tag.ImageType, Type: Type3},
+		{Tag: tag.AcquisitionNumber, Type: Type3},
+		{Tag: tag.AcquisitionDate, Type: Type3},
+		{Tag: tag.AcquisitionTime, Type: Type3},
+		{Tag: tag.ImageComments, Type: Type3},
+		{Tag: tag.BurnedInAnnotation, Type: Type3},
+		{Tag: tag.LossyImageCompression, Type: Type3},
+		{Tag: tag.IconImageSequence, Type: Type3},
+	},
+}
+
+// ImagePlaneModule is the PS3.3 C.7.6.2 Image Plane Module.
+var ImagePlaneModule = &Module{
+	Name: "Image Plane",
+	Attributes: []Attribute{
+		{Tag: tag.PixelSpacing, Type: Type1},
+		{Tag: tag.ImageOrientationPatient, Type: Type1},
+		{Tag: tag.ImagePositionPatient, Type: Type1},
+		{Tag: tag.SliceThickness, Type: Type2},
+		{Tag: tag.SliceLocation, Type: Type3},
+	},
+}
+
+// ImagePixelModule is the PS3.3 C.7.6.3 Image Pixel Module.
+var ImagePixelModule = &Module{
+	Name: "Image Pixel",
+	Attributes: []Attribute{
+		{Tag: tag.SamplesPerPixel, Type: Type1},
+		{Tag: tag.PhotometricInterpretation, Type: Type1},
+		{Tag: tag.Rows, Type: Type1},
+		{Tag: tag.Columns, Type: Type1},
+		{Tag: tag.BitsAllocated, Type: Type1},
+		{Tag: tag.BitsStored, Type: Type1},
+		{Tag: tag.HighBit, Type: Type1},
+		{Tag: tag.PixelRepresentation, Type: Type1},
+		{Tag: tag.PixelData, Type: Type1C, Condition: absent(tag.PixelDataProviderURL), NotAllowedOtherwise: true},
+		{Tag: tag.PlanarConfiguration, Type: Type1C, Condition: intGreaterThan(tag.SamplesPerPixel, 1), NotAllowedOtherwise: true},
+		{Tag: tag.PixelAspectRatio, Type: Type1C},
+		{Tag: tag.RedPaletteColorLookupTableDescriptor, Type: Type1C, Condition: hasValue(tag.PhotometricInterpretation, "PALETTE COLOR")},
+		{Tag: tag.GreenPaletteColorLookupTableDescriptor, Type: Type1C, Condition: hasValue(tag.PhotometricInterpretation, "PALETTE COLOR")},
+		{Tag: tag.BluePaletteColorLookupTableDescriptor, Type: Type1C, Condition: hasValue(tag.PhotometricInterpretation, "PALETTE COLOR")},
+		{Tag: tag.RedPaletteColorLookupTableData, Type: Type1C, Condition: hasValue(tag.PhotometricInterpretation, "PALETTE COLOR")},
+		{Tag: tag.GreenPaletteColorLookupTableData, Type: Type1C, Condition: hasValue(tag.PhotometricInterpretation, "PALETTE COLOR")},
+		{Tag: tag.BluePaletteColorLookupTableData, Type: Type1C, Condition: hasValue(tag.PhotometricInterpretation, "PALETTE COLOR")},
+	},
+}
+
+// ContrastBolusModule is the PS3.3 C.7.6.4 Contrast/Bolus Module.
+var ContrastBolusModule = &Module{
+	Name: "Contrast/Bolus",
+	Attributes: []Attribute{
+		{Tag: tag.ContrastBolusAgent, Type: Type2},
+		{Tag: tag.ContrastBolusAgentSequence, Type: Type3, Items: codeSequenceMacro},
+		{Tag: tag.ContrastBolusRoute, Type: Type3},
+		{Tag: tag.ContrastBolusVolume, Type: Type3},
+		{Tag: tag.ContrastBolusStartTime, Type: Type3},
+		{Tag: tag.ContrastBolusStopTime, Type: Type3},
+		{Tag: tag.ContrastBolusTotalDose, Type: Type3},
+	},
+}
+
+// CTImageModule is the PS3.3 C.8.2.1 CT Image Module.
+var CTImageModule = &Module{
+	Name: "CT Image",
+	Attributes: []Attribute{
+		{Tag: tag.ImageType, Type: Type1},
+		{Tag: tag.SamplesPerPixel, Type: Type1},
+		{Tag: tag.PhotometricInterpretation, Type: Type1},
+		{Tag: tag.BitsAllocated, Type: Type1},
+		{Tag: tag.BitsStored, Type: Type1},
+		{Tag: tag.HighBit, Type: Type1},
+		{Tag: tag.RescaleIntercept, Type: Type1},
+		{Tag: tag.RescaleSlope, Type: Type1},
+		{Tag: tag.RescaleType, Type: Type1C},
+		{Tag: tag.KVP, Type: Type2},
+		{Tag: tag.AcquisitionNumber, Type: Type2},
+		{Tag: tag.ScanOptions, Type: Type3},
+		{Tag: tag.DataCollectionDiameter, Type: Type3},
+		{Tag: tag.ReconstructionDiameter, Type: Type3},
+		{Tag: tag.DistanceSourceToDetector, Type: Type3},
+		{Tag: tag.DistanceSourceToPatient, Type: Type3},
+		{Tag: tag.GantryDetectorTilt, Type: Type3},
+		{Tag: tag.TableHeight, Type: Type3},
+		{Tag: tag.RotationDirection, Type: Type3},
+		{Tag: tag.ExposureTime, Type: Type3},
+		{Tag: tag.XRayTubeCurrent, Type: Type3},
+		{Tag: tag.Exposure, Type: Type3},
+		{Tag: tag.FilterType, Type: Type3},
+		{Tag: tag.GeneratorPower, Type: Type3},
+		{Tag: tag.FocalSpots, Type: Type3},
+		{Tag: tag.ConvolutionKernel, Type: Type3},
+	},
+}
+
+// MRImageModule is the PS3.3 C.8.3.1 MR Image Module.
+var MRImageModule = &Module{
+	Name: "MR Image",
+	Attributes: []Attribute{
+		{Tag: tag.ImageType, Type: Type1},
+		{Tag: tag.SamplesPerPixel, Type: Type1},
+		{Tag: tag.PhotometricInterpretation, Type: Type1},
+		{Tag: tag.BitsAllocated, Type: Type1},
+		{Tag: tag.ScanningSequence, Type: Type1},
+		{Tag: tag.SequenceVariant, Type: Type1},
+		{Tag: tag.ScanOptions, Type: Type2},
+		{Tag: tag.MRAcquisitionType, Type: Type2},
+		{Tag: tag.RepetitionTime, Type: Type2C},
+		{Tag: tag.EchoTime, Type: Type2},
+		{Tag: tag.EchoTrainLength, Type: Type2},
+		{Tag: tag.InversionTime, Type: Type2C, Condition: hasValue(tag.ScanningSequence, "IR")},
+		{Tag: tag.TriggerTime, Type: Type2C},
+		{Tag: tag.SequenceName, Type: Type3},
+		{Tag: tag.AngioFlag, Type: Type3},
+		{Tag: tag.NumberOfAverages, Type: Type3},
+		{Tag: tag.ImagingFrequency, Type: Type3},
+		{Tag: tag.ImagedNucleus, Type: Type3},
+		{Tag: tag.EchoNumbers, Type: Type3},
+		{Tag: tag.MagneticFieldStrength, Type: Type3},
+		{Tag: tag.SpacingBetweenSlices, Type: Type3},
+		{Tag: tag.NumberOfPhaseEncodingSteps, Type: Type3},
+		{Tag: tag.PercentSampling, Type: Type3},
+		{Tag: tag.PercentPhaseFieldOfView, Type: Type3},
+		{Tag: tag.PixelBandwidth, Type: Type3},
+		{Tag: tag.FlipAngle, Type: Type3},
+	},
+}
+
+// SCImageModule is the PS3.3 C.8.6.2 SC Image Module.
+var SCImageModule = &Module{
+	Name: "SC Image",
+	Attributes: []Attribute{
+		{Tag: tag.DateOfSecondaryCapture, Type: Type3},
+		{Tag: tag.TimeOfSecondaryCapture, Type: Type3},
+		{Tag: tag.NominalScannedPixelSpacing, Type: Type3},
+	},
+}
+
+// VOILUTModule is the PS3.3 C.11.2 VOI LUT Module.
+var VOILUTModule = &Module{
+	Name: "VOI LUT",
+	Attributes: []Attribute{
+		{Tag: tag.VOILUTSequence, Type: Type1C},
+		{Tag: tag.WindowCenter, Type: Type1C},
+		{Tag: tag.WindowWidth, Type: Type1C, Condition: present(tag.WindowCenter)},
+		{Tag: tag.WindowCenterWidthExplanation, Type: Type3},
+	},
+}
+
+// SOPCommonModule is the PS3.3 C.12.1 SOP Common Module.
+var SOPCommonModule = &Module{
+	Name: "SOP Common",
+	Attributes: []Attribute{
+		{Tag: tag.SOPClassUID, Type: Type1},
+		{Tag: tag.SOPInstanceUID, Type: Type1},
+		{Tag: tag.SpecificCharacterSet, Type: Type1C},
+		{Tag: tag.InstanceCreationDate, Type: Type3},
+		{Tag: tag.InstanceCreationTime, Type: Type3},
+		{Tag: tag.InstanceCreatorUID, Type: Type3},
+		{Tag: tag.TimezoneOffsetFromUTC, Type: Type3},
+		{Tag: tag.InstanceNumber, Type: Type3},
+	},
+}
+
+// SRDocumentSeriesModule is the PS3.3 C.17.1 SR Document Series Module.
+var SRDocumentSeriesModule = &Module{
+	Name: "SR Document Series",
+	Attributes: []Attribute{
+		{Tag: tag.Modality, Type: Type1},
+		{Tag: tag.SeriesInstanceUID, Type: Type1},
+		{Tag: tag.SeriesNumber, Type: Type1},
+		{Tag: tag.SeriesDate, Type: Type3},
+		{Tag: tag.SeriesTime, Type: Type3},
+		{Tag: tag.SeriesDescription, Type: Type3},
+		{Tag: tag.ReferencedPerformedProcedureStepSequence, Type: Type2, Items: sopInstanceReferenceMacro},
+	},
+}
+
+// SRDocumentGeneralModule is the PS3.3 C.17.2 SR Document General Module.
+var SRDocumentGeneralModule = &Module{
+	Name: "SR Document General",
+	Attributes: []Attribute{
+		{Tag: tag.InstanceNumber, Type: Type1},
+		{Tag: tag.CompletionFlag, Type: Type1},
+		{Tag: tag.CompletionFlagDescription, Type: Type3},
+		{Tag: tag.VerificationFlag, Type: Type1},
+		{Tag: tag.ContentDate, Type: Type1},
+		{Tag: tag.ContentTime, Type: Type1},
+		{
+			Tag:                 tag.VerifyingObserverSequence,
+			Type:                Type1C,
+			Condition:           hasValue(tag.VerificationFlag, "VERIFIED"),
+			NotAllowedOtherwise: true,
+			Items: []Attribute{
+				{Tag: tag.VerifyingObserverName, Type: Type1},
+				{Tag: tag.VerifyingObserverIdentificationCodeSequence, Type: Type2, Items: codeSequenceMacro},
+				{Tag: tag.VerifyingOrganization, Type: Type2},
+				{Tag: tag.VerificationDateTime, Type: Type1},
+			},
+		},
+		{Tag: tag.PredecessorDocumentsSequence, Type: Type1C},
+		{Tag: tag.ReferencedRequestSequence, Type: Type1C},
+		{Tag: tag.PerformedProcedureCodeSequence, Type: Type2, Items: codeSequenceMacro},
+		{Tag: tag.CurrentRequestedProcedureEvidenceSequence, Type: Type1C},
+		{Tag: tag.PertinentOtherEvidenceSequence, Type: Type1C},
+	},
+}
+
+// SRDocumentContentModule is the PS3.3 C.17.3 SR Document Content Module,
+// restricted to the attributes of the root content item.
+var SRDocumentContentModule = &Module{
+	Name: "SR Document Content",
+	Attributes: []Attribute{
+		{Tag: tag.ValueType, Type: Type1},
+		{Tag: tag.ConceptNameCodeSequence, Type: Type1, Items: codeSequenceMacro},
+		{Tag: tag.ContinuityOfContent, Type: Type1C, Condition: hasValue(tag.ValueType, "CONTAINER"), NotAllowedOtherwise: true},
+		{Tag: tag.ContentTemplateSequence, Type: Type1C},
+		{Tag: tag.ContentSequence, Type: Type1C},
+	},
+}
+
+// CTImage is the PS3.3 A.3 CT Image IOD.
+var CTImage = &IOD{
+	Name: "CT Image",
+	Modules: []ModuleRef{
+		{Module: PatientModule, Usage: Mandatory},
+		{Module: GeneralStudyModule, Usage: Mandatory},
+		{Module: PatientStudyModule, Usage: UserOption},
+		{Module: GeneralSeriesModule, Usage: Mandatory},
+		{Module: FrameOfReferenceModule, Usage: Mandatory},
+		{Module: GeneralEquipmentModule, Usage: Mandatory},
+		{Module: GeneralImageModule, Usage: Mandatory},
+		{Module: ImagePlaneModule, Usage: Mandatory},
+		{Module: ImagePixelModule, Usage: Mandatory},
+		{Module: ContrastBolusModule, Usage: Conditional},
+		{Module: CTImageModule, Usage: Mandatory},
+		{Module: VOILUTModule, Usage: UserOption},
+		{Module: SOPCommonModule, Usage: Mandatory},
+	},
+}
+
+// MRImage is the PS3.3 A.4 MR Image IOD.
+var MRImage = &IOD{
+	Name: "MR Image",
+	Modules: []ModuleRef{
+		{Module: PatientModule, Usage: Mandatory},
+		{Module: GeneralStudyModule, Usage: Mandatory},
+		{Module: PatientStudyModule, Usage: UserOption},
+		{Module: GeneralSeriesModule, Usage: Mandatory},
+		{Module: FrameOfReferenceModule, Usage: Mandatory},
+		{Module: GeneralEquipmentModule, Usage: Mandatory},
+		{Module: GeneralImageModule, Usage: Mandatory},
+		{Module: ImagePlaneModule, Usage: Mandatory},
+		{Module: ImagePixelModule, Usage: Mandatory},
+		{Module: ContrastBolusModule, Usage: Conditional},
+		{Module: MRImageModule, Usage: Mandatory},
+		{Module: VOILUTModule, Usage: UserOption},
+		{Module: SOPCommonModule, Usage: Mandatory},
+	},
+}
+
+// SecondaryCaptureImage is the PS3.3 A.8.1 Secondary Capture Image IOD.
+var SecondaryCaptureImage = &IOD{
+	Name: "Secondary Capture Image",
+	Modules: []ModuleRef{
+		{Module: PatientModule, Usage: Mandatory},
+		{Module: GeneralStudyModule, Usage: Mandatory},
+		{Module: PatientStudyModule, Usage: UserOption},
+		{Module: GeneralSeriesModule, Usage: Mandatory},
+		{Module: GeneralEquipmentModule, Usage: UserOption},
+		{Module: SCEquipmentModule, Usage: Mandatory},
+		{Module: GeneralImageModule, Usage: Mandatory},
+		{Module: ImagePlaneModule, Usage: UserOption},
+		{Module: ImagePixelModule, Usage: Mandatory},
+		{Module: SCImageModule, Usage: Mandatory},
+		{Module: VOILUTModule, Usage: UserOption},
+		{Module: SOPCommonModule, Usage: Mandatory},
+	},
+}
+
+// StructuredReport is the PS3.3 A.35.1 Basic Text SR IOD, which is also used
+// for the Enhanced SR and Comprehensive SR IODs (A.35.2, A.35.3) as they only
+// differ in the content items they allow.
+var StructuredReport = &IOD{
+	Name: "Structured Report",
+	Modules: []ModuleRef{
+		{Module: PatientModule, Usage: Mandatory},
+		{Module: GeneralStudyModule, Usage: Mandatory},
+		{Module: PatientStudyModule, Usage: UserOption},
+		{Module: SRDocumentSeriesModule, Usage: Mandatory},
+		{Module: GeneralEquipmentModule, Usage: Mandatory},
+		{Module: SRDocumentGeneralModule, Usage: Mandatory},
+		{Module: SRDocumentContentModule, Usage: Mandatory},
+		{Module: SOPCommonModule, Usage: Mandatory},
+	},
+	NotAllowed: []tag.Tag{tag.PixelData},
+}
+
+func init() {
+	Register(uid.CTImageStorage, CTImage)
+	Register(uid.MRImageStorage, MRImage)
+	Register(uid.SecondaryCaptureImageStorage, SecondaryCaptureImage)
+	Register(uid.BasicTextSRStorage, StructuredReport)
+	Register(uid.EnhancedSRStorage, StructuredReport)
+	Register(uid.ComprehensiveSRStorage, StructuredReport)
+}
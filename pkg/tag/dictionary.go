@@ -0,0 +1,213 @@
+package tag
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// dictionary is a snapshot of the entries registered on top of the generated
+// tagDict, which is never modified. Published snapshots are never modified
+// either, so lookups use the current one without locking, while writers
+// change a copy and publish it with update.
+type dictionary struct {
+	// tags holds the entries added with Add, including those replacing
+	// entries of tagDict.
+	tags map[Tag]Info
+	// added holds the keys of tags in the order they were last added, so that
+	// later entries take priority in the keyword index.
+	added []Tag
+	// ranges holds the ranges of tags by the high byte of the group, in the
+	// order they were added. A range is in the bucket of every group it spans,
+	// so that lookups only scan the ranges that may hold the group.
+	ranges [256][]tagRange
+
+	private map[privateKey]PrivateInfo
+	// privateRanges holds the ranges of private attributes by Private
+	// Creator, in the order they were added.
+	privateRanges map[string][]privateRange
+
+	// index is built on the first lookup by keyword, as most programs never
+	// look up tags by keyword.
+	indexOnce sync.Once
+	index     keywordIndex
+}
+
+var (
+	// dictMu serializes writers of dict.
+	dictMu sync.Mutex
+	dict   atomic.Pointer[dictionary]
+)
+
+func init() {
+	dict.Store(&dictionary{
+		tags:          map[Tag]Info{},
+		private:       map[privateKey]PrivateInfo{},
+		privateRanges: map[string][]privateRange{},
+	})
+}
+
+// currentDictionary returns the current snapshot of the dictionary.
+func currentDictionary() *dictionary {
+	return dict.Load()
+}
+
+// update publishes a copy of the current dictionary changed by fn, unless fn
+// returns an error, in which case the dictionary is left unchanged.
+func update(fn func(d *dictionary) error) error {
+	dictMu.Lock()
+	defer dictMu.Unlock()
+	d := dict.Load().clone()
+	if err := fn(d); err != nil {
+		return err
+	}
+	dict.Store(d)
+	return nil
+}
+
+// clone returns a copy of d that can be changed without affecting d. Slices
+// shared with d must be copied before they are changed in place, or appended
+// to with slices.Clip.
+func (d *dictionary) clone() *dictionary {
+	return &dictionary{
+		tags:          maps.Clone(d.tags),
+		added:         d.added,
+		ranges:        d.ranges,
+		private:       maps.Clone(d.private),
+		privateRanges: maps.Clone(d.privateRanges),
+	}
+}
+
+// lookup returns the entry for t, without resolving repeating groups or
+// ranges.
+func (d *dictionary) lookup(t Tag) (Info, bool) {
+	if info, ok := d.tags[t]; ok {
+		return info, true
+	}
+	info, ok := tagDict[t]
+	return info, ok
+}
+
+// each calls fn for every entry of the dictionary.
+func (d *dictionary) each(fn func(info Info)) {
+	for t, info := range tagDict {
+		if _, replaced := d.tags[t]; !replaced {
+			fn(info)
+		}
+	}
+	for _, t := range d.added {
+		fn(d.tags[t])
+	}
+}
+
+// add adds info to d. If force is true an existing entry is overwritten,
+// otherwise an error is returned.
+func (d *dictionary) add(info Info, force bool) error {
+	if _, found := d.lookup(info.Tag); found && !force {
+		return errors.New("tag already exists")
+	}
+	if _, found := d.tags[info.Tag]; found {
+		d.added = slices.DeleteFunc(slices.Clone(d.added), func(t Tag) bool { return t == info.Tag })
+	}
+	d.tags[info.Tag] = info
+	d.added = append(slices.Clip(d.added), info.Tag)
+	return nil
+}
+
+// addRange adds r to d. If force is true an existing entry for the same range
+// is overwritten, otherwise an error is returned.
+func (d *dictionary) addRange(r tagRange, force bool) error {
+	first, last := r.group.min>>8, r.group.max>>8
+	if i := slices.IndexFunc(d.ranges[first], r.sameRange); i >= 0 {
+		if !force {
+			return errors.New("tag already exists")
+		}
+		for b := first; b <= last; b++ {
+			bucket := slices.Clone(d.ranges[b])
+			bucket[slices.IndexFunc(bucket, r.sameRange)] = r
+			d.ranges[b] = bucket
+		}
+		return nil
+	}
+	for b := first; b <= last; b++ {
+		d.ranges[b] = append(slices.Clip(d.ranges[b]), r)
+	}
+	return nil
+}
+
+// findRange returns the Info for t from the last range added that contains
+// it.
+func (d *dictionary) findRange(t Tag) (Info, bool) {
+	bucket := d.ranges[t.Group>>8]
+	for i := len(bucket) - 1; i >= 0; i-- {
+		if r := bucket[i]; r.group.contains(t.Group) && r.element.contains(t.Element) {
+			info := r.info
+			info.Tag = t
+			return info, true
+		}
+	}
+	return Info{}, false
+}
+
+// addPrivate adds info to the private dictionary of d. If force is true an
+// existing entry for the same Private Creator, group and offset is
+// overwritten, otherwise an error is returned.
+func (d *dictionary) addPrivate(info PrivateInfo, force bool) error {
+	if !IsPrivate(info.Group) {
+		return fmt.Errorf("group 0x%04x of private tag %q is not private", info.Group, info.Keyword)
+	}
+	key := newPrivateKey(info.Creator, info.Group, info.Offset)
+	info.Creator = key.creator
+	if _, found := d.private[key]; found && !force {
+		return errors.New("private tag already exists")
+	}
+	d.private[key] = info
+	return nil
+}
+
+// addPrivateRange adds r to the private dictionary of d. If force is true an
+// existing entry for the same Private Creator and range is overwritten,
+// otherwise an error is returned.
+func (d *dictionary) addPrivateRange(r privateRange, force bool) error {
+	// The range only holds odd groups if its first group is odd and it either
+	// steps over even groups or keeps the last digit fixed.
+	if !IsPrivate(r.group.min) || r.group.step != 2 && r.group.mask&0xF != 0xF {
+		return fmt.Errorf("groups %04x-%04x of private tag %q are not private", r.group.min, r.group.max, r.info.Keyword)
+	}
+	r.info.Creator = strings.TrimRight(r.info.Creator, " \x00")
+	ranges := d.privateRanges[r.info.Creator]
+	if i := slices.IndexFunc(ranges, func(existing privateRange) bool {
+		return existing.group == r.group && existing.offset == r.offset
+	}); i >= 0 {
+		if !force {
+			return errors.New("private tag already exists")
+		}
+		ranges = slices.Clone(ranges)
+		ranges[i] = r
+		d.privateRanges[r.info.Creator] = ranges
+		return nil
+	}
+	d.privateRanges[r.info.Creator] = append(slices.Clip(ranges), r)
+	return nil
+}
+
+// findPrivate returns the PrivateInfo for key, from the last range added that
+// contains it if it has no entry of its own.
+func (d *dictionary) findPrivate(key privateKey) (PrivateInfo, bool) {
+	if info, ok := d.private[key]; ok {
+		return info, true
+	}
+	ranges := d.privateRanges[key.creator]
+	for i := len(ranges) - 1; i >= 0; i-- {
+		if r := ranges[i]; r.group.contains(key.group) && r.offset.contains(uint16(key.offset)) {
+			info := r.info
+			info.Group, info.Offset = key.group, key.offset
+			return info, true
+		}
+	}
+	return PrivateInfo{}, false
+}
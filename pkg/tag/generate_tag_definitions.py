@@ -2,7 +2,7 @@
 import json
 import logging
 import urllib.request
-from typing import IO, NamedTuple, List
+from typing import IO, NamedTuple, List, Tuple
 
 logging.basicConfig(level=logging.DEBUG)
 
@@ -48,6 +48,8 @@ def read_tags_from_innolitics(version_hash: str) -> List[Tag]:
     return [
         Tag(
             # The id field should always follow format "ggggeeee", so this should be safe.
+            # Repeating groups (e.g. "60xx3000") are stored under their base group,
+            # and tag.Find resolves the other groups of the range from it.
             group=int(resolvable_tag_id[:4], 16),
             elem=int(resolvable_tag_id[4:], 16),
             # To understand this ternary expression, see: https://dicom.nema.org/medical/dicom/2024a/output/html/part06.html#note_6_2.
@@ -60,10 +62,34 @@ def read_tags_from_innolitics(version_hash: str) -> List[Tag]:
         if (resolvable_tag_id := e["id"].replace("x", "0")) and len(e["keyword"]) > 0
     ]
 
+def parse_vm(vm: str) -> Tuple[int, int, int, bool]:
+    """Parses a VM like "1", "1-3", "1-n" or "2-2n" into (min, max, multiplier, unbounded).
+    Alternatives like "1-n or 1" allow what any of the alternatives allows, as in tag.ParseVM."""
+    alternatives = []
+    for alt in vm.split(" or "):
+        lo, sep, hi = alt.partition("-")
+        if not sep:
+            alternatives.append((int(lo), int(lo), 1, False))
+        elif hi.endswith("n"):
+            alternatives.append((int(lo), 0, int(hi[:-1] or "1"), True))
+        else:
+            alternatives.append((int(lo), int(hi), 1, False))
+    multipliers = {a[2] for a in alternatives}
+    return (min(a[0] for a in alternatives),
+            max(a[1] for a in alternatives),
+            multipliers.pop() if len(multipliers) == 1 else 1,
+            any(a[3] for a in alternatives))
+
+def vmLiteral(vm: str) -> str:
+    minimum, maximum, multiplier, unbounded = parse_vm(vm)
+    if unbounded:
+        return f'VM{{Min: {minimum}, Multiplier: {multiplier}, Unbounded: true}}'
+    return f'VM{{Min: {minimum}, Max: {maximum}, Multiplier: {multiplier}}}'
+
 def tagDictEntry(t: Tag) -> str:
     wrap_in_quotes = lambda s: f'\"{s}\"'
     start_indent = '	'
-    return f'{start_indent}{t.keyword}: Info{{{t.keyword}, []string{{{", ".join(map(wrap_in_quotes, t.vr))}}}, "{t.name}", "{t.keyword}", "{t.vm}", {str(t.retired).lower()}}},'
+    return f'{start_indent}{t.keyword}: Info{{{t.keyword}, []string{{{", ".join(map(wrap_in_quotes, t.vr))}}}, "{t.name}", "{t.keyword}", "{t.vm}", {str(t.retired).lower()}, {vmLiteral(t.vm)}}},'
 
 def generate(out: IO[str]):
     tags = read_tags_from_innolitics(INNOLITICS_VERSION_HASH)
@@ -0,0 +1,129 @@
+package tag
+
+import (
+	"sort"
+	"strings"
+)
+
+// keywordIndex maps the Keyword, and the Name, of every entry of a dictionary
+// to its tag. Keywords take priority over names.
+type keywordIndex struct {
+	exact map[string]Tag
+	// folded is exact with lower case keys.
+	folded map[string]Tag
+}
+
+// keywords returns the keyword index of d, building it on first use. The
+// index is built from scratch for every snapshot, so that replaced entries
+// never leave stale or missing keys behind.
+func (d *dictionary) keywords() *keywordIndex {
+	d.indexOnce.Do(func() {
+		n := 2 * (len(tagDict) + len(d.tags))
+		d.index = keywordIndex{exact: make(map[string]Tag, n), folded: make(map[string]Tag, n)}
+		d.each(func(info Info) {
+			d.index.add(d, info)
+		})
+	})
+	return &d.index
+}
+
+// add adds the keys of info to the index of d.
+func (idx *keywordIndex) add(d *dictionary, info Info) {
+	keywordOf := func(t Tag) string {
+		entry, _ := d.lookup(t)
+		return entry.Keyword
+	}
+	for _, key := range []string{info.Name, info.Keyword} {
+		if key == "" {
+			continue
+		}
+		// Names never shadow keywords of other entries.
+		if t, found := idx.exact[key]; !found || key == info.Keyword || keywordOf(t) != key {
+			idx.exact[key] = info.Tag
+		}
+		folded := strings.ToLower(key)
+		if t, found := idx.folded[folded]; !found || key == info.Keyword || !strings.EqualFold(keywordOf(t), key) {
+			idx.folded[folded] = info.Tag
+		}
+	}
+}
+
+// lookupKeyword returns the entry for keyword, which is lower case if folded
+// is true.
+func (d *dictionary) lookupKeyword(keyword string, folded bool) (Info, bool) {
+	index := d.keywords().exact
+	if folded {
+		index = d.keywords().folded
+	}
+	t, ok := index[keyword]
+	if !ok {
+		return Info{}, false
+	}
+	return d.lookup(t)
+}
+
+// FindByKeywordFold is like FindByKeyword, but matches keyword and name case
+// insensitively, e.g. "patientname" finds PatientName.
+func FindByKeywordFold(keyword string) (Info, error) {
+	return findByKeyword(strings.ToLower(keyword), true)
+}
+
+// SearchKeywords returns the entries whose Keyword matches query case
+// insensitively, for autocompletion. Keywords starting with query come first,
+// followed by keywords containing query, followed by keywords containing the
+// characters of query in order, e.g. "ptnm" matches PatientName. Within each
+// group, shorter keywords come first. At most limit entries are returned, or
+// all if limit is not positive.
+func SearchKeywords(query string, limit int) []Info {
+	query = strings.ToLower(query)
+
+	type match struct {
+		info Info
+		rank int
+	}
+	var matches []match
+	currentDictionary().each(func(info Info) {
+		if info.Keyword == "" {
+			return
+		}
+		keyword := strings.ToLower(info.Keyword)
+		switch {
+		case strings.HasPrefix(keyword, query):
+			matches = append(matches, match{info, 0})
+		case strings.Contains(keyword, query):
+			matches = append(matches, match{info, 1})
+		case isSubsequence(query, keyword):
+			matches = append(matches, match{info, 2})
+		}
+	})
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if len(a.info.Keyword) != len(b.info.Keyword) {
+			return len(a.info.Keyword) < len(b.info.Keyword)
+		}
+		return a.info.Keyword < b.info.Keyword
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	infos := make([]Info, len(matches))
+	for i, m := range matches {
+		infos[i] = m.info
+	}
+	return infos
+}
+
+// isSubsequence returns true if the bytes of sub appear in s in order.
+func isSubsequence(sub, s string) bool {
+	i := 0
+	for j := 0; i < len(sub) && j < len(s); j++ {
+		if sub[i] == s[j] {
+			i++
+		}
+	}
+	return i == len(sub)
+}
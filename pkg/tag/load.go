@@ -0,0 +1,620 @@
+package tag
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrorMalformedDictionary is returned when an external data dictionary can
+// not be parsed.
+var ErrorMalformedDictionary = errors.New("malformed data dictionary")
+
+// dictEntry is a data dictionary entry read from an external dictionary. The
+// group and element may describe a range of tags.
+type dictEntry struct {
+	// groups is the group, or range of groups, e.g. "0019", "60xx",
+	// "6000-60ff" or "6001-o-60ff".
+	groups string
+	// element is the element, e.g. "0010" or "04x0". For private entries it is
+	// the offset within the block, e.g. "02" or "xx02".
+	element string
+	// creator is the Private Creator of private entries.
+	creator string
+	vrs     []string
+	name    string
+	keyword string
+	vm      string
+	retired bool
+}
+
+// LoadDictionaryFile loads the data dictionary at path, see
+// LoadDcmtkDictionary and LoadPydicomDictionary. Files ending in ".py" are
+// read as pydicom dictionaries, all other files as dcmtk dictionaries.
+func LoadDictionaryFile(path string, force bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".py") {
+		return LoadPydicomDictionary(f, force)
+	}
+	return LoadDcmtkDictionary(f, force)
+}
+
+// LoadDcmtkDictionary registers all entries of a data dictionary in the text
+// format of dcmtk's dicom.dic and private.dic, e.g.:
+//
+//	(0008,0005)	CS	SpecificCharacterSet	1-n	DICOM
+//	(6000-60ff,3000)	ox	OverlayData	1	DICOM
+//	(0019,"GEMS_ACQU_01",02)	SL	NumberOfCellsInDetector	1	PrivateTag
+//
+// Public entries are registered as with Add and private entries, which name
+// their Private Creator, with AddPrivate. Entries of the repeating groups
+// 50xx, 60xx and 7Fxx are registered for the base group, see
+// RepeatingGroupBase; other ranges of groups or elements, e.g. the
+// (0009-o-ffff,0010-u-00ff) Private Creator entry of dicom.dic, are registered
+// as a single entry that Find and FindPrivate fall back to for tags missing
+// from the dictionary, without changing the keywords of existing entries. If
+// force is false, an error is returned for entries that already exist. If an
+// error is returned, none of the entries are registered.
+func LoadDcmtkDictionary(r io.Reader, force bool) error {
+	var entries []dictEntry
+	var lineNums []int
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry, err := parseDcmtkLine(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		entries = append(entries, entry)
+		lineNums = append(lineNums, lineNum)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return update(func(d *dictionary) error {
+		for i, entry := range entries {
+			if err := entry.register(d, force); err != nil {
+				return fmt.Errorf("line %d: %w", lineNums[i], err)
+			}
+		}
+		return nil
+	})
+}
+
+func parseDcmtkLine(line string) (dictEntry, error) {
+	end := strings.IndexByte(line, ')')
+	if !strings.HasPrefix(line, "(") || end < 0 {
+		return dictEntry{}, fmt.Errorf("%w: tag not of the form (gggg,eeee): %q", ErrorMalformedDictionary, line)
+	}
+	fields := strings.Fields(line[end+1:])
+	if len(fields) < 4 {
+		return dictEntry{}, fmt.Errorf("%w: expected tag, VR, keyword, VM and version: %q", ErrorMalformedDictionary, line)
+	}
+
+	var entry dictEntry
+	tagParts := strings.Split(line[1:end], ",")
+	switch len(tagParts) {
+	case 2:
+		entry.groups, entry.element = tagParts[0], tagParts[1]
+	case 3:
+		entry.groups, entry.element = tagParts[0], tagParts[2]
+		creator, err := strconv.Unquote(tagParts[1])
+		if err != nil {
+			return dictEntry{}, fmt.Errorf("%w: Private Creator not quoted: %q", ErrorMalformedDictionary, line)
+		}
+		entry.creator = creator
+	default:
+		return dictEntry{}, fmt.Errorf("%w: tag not of the form (gggg,eeee): %q", ErrorMalformedDictionary, line)
+	}
+
+	entry.vrs = dcmtkVRs(fields[0])
+	entry.keyword = fields[1]
+	entry.name = fields[1]
+	entry.vm = fields[2]
+	entry.retired = strings.Contains(strings.ToLower(fields[3]), "retired")
+	return entry, nil
+}
+
+// dcmtkVRs returns the VRs for a dcmtk VR, which may be one of the internal
+// lower case VRs of dcmtk, e.g. "ox" for OB or OW.
+func dcmtkVRs(vr string) []string {
+	switch vr {
+	case "ox", "px":
+		return []string{"OB", "OW"}
+	case "xs":
+		return []string{"US", "SS"}
+	case "lt":
+		return []string{"US", "SS", "OW"}
+	case "up":
+		return []string{"UL"}
+	case "na":
+		return []string{"NA"}
+	}
+	if strings.ToUpper(vr) != vr {
+		return []string{UnknownVR}
+	}
+	return strings.Split(vr, "/")
+}
+
+// LoadPydicomDictionary registers all entries of a data dictionary in the
+// format of pydicom's _dicom_dict.py and _private_dict.py, i.e. Python source
+// assigning dictionary literals of the following shapes:
+//
+//	DicomDictionary = {0x00080005: ('CS', '1-n', "Specific Character Set", '', 'SpecificCharacterSet'), ...}
+//	RepeatersDictionary = {'60xx3000': ('OB or OW', '1', "Overlay Data", '', 'OverlayData'), ...}
+//	private_dictionaries = {'GEMS_ACQU_01': {'0019xx02': ('SL', '1', 'Number of Cells In Detector', ''), ...}, ...}
+//
+// Entries are registered as in LoadDcmtkDictionary. Private entries have no
+// keyword in pydicom, so their Keyword is left empty.
+func LoadPydicomDictionary(r io.Reader, force bool) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var entries []dictEntry
+	p := &pyParser{src: string(src)}
+	for {
+		lit, found, err := p.nextAssignedDict()
+		if err != nil {
+			return err
+		}
+		if !found {
+			break
+		}
+		litEntries, err := pydicomEntries(lit)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, litEntries...)
+	}
+	return update(func(d *dictionary) error {
+		for _, entry := range entries {
+			if err := entry.register(d, force); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// pydicomEntries interprets a dictionary literal of one of the shapes
+// described in LoadPydicomDictionary.
+func pydicomEntries(dict pyDict) ([]dictEntry, error) {
+	var entries []dictEntry
+	for _, item := range dict {
+		switch key := item.key.(type) {
+		case int64:
+			entry, err := pydicomEntry(item.value, 5)
+			if err != nil {
+				return nil, err
+			}
+			entry.groups = fmt.Sprintf("%04x", key>>16)
+			entry.element = fmt.Sprintf("%04x", key&0xFFFF)
+			entries = append(entries, entry)
+		case string:
+			if private, ok := item.value.(pyDict); ok {
+				for _, privateItem := range private {
+					k, ok := privateItem.key.(string)
+					if !ok || len(k) != 8 {
+						return nil, fmt.Errorf("%w: private tag %v of %q not of the form 'ggggxxee'", ErrorMalformedDictionary, privateItem.key, key)
+					}
+					entry, err := pydicomEntry(privateItem.value, 4)
+					if err != nil {
+						return nil, err
+					}
+					entry.groups, entry.element, entry.creator = k[:4], k[4:], key
+					entry.keyword = ""
+					entries = append(entries, entry)
+				}
+				continue
+			}
+			if len(key) != 8 {
+				return nil, fmt.Errorf("%w: repeater tag %q not of the form 'ggggeeee'", ErrorMalformedDictionary, key)
+			}
+			entry, err := pydicomEntry(item.value, 5)
+			if err != nil {
+				return nil, err
+			}
+			entry.groups, entry.element = key[:4], key[4:]
+			entries = append(entries, entry)
+		default:
+			return nil, fmt.Errorf("%w: unexpected key %v", ErrorMalformedDictionary, item.key)
+		}
+	}
+	return entries, nil
+}
+
+// pydicomEntry converts a (VR, VM, Name, Retired[, Keyword]) tuple.
+func pydicomEntry(value any, fields int) (dictEntry, error) {
+	tuple, ok := value.([]any)
+	if !ok || len(tuple) < fields {
+		return dictEntry{}, fmt.Errorf("%w: expected a tuple of %d strings, got %v", ErrorMalformedDictionary, fields, value)
+	}
+	strs := make([]string, fields)
+	for i := range strs {
+		if strs[i], ok = tuple[i].(string); !ok {
+			return dictEntry{}, fmt.Errorf("%w: expected a tuple of %d strings, got %v", ErrorMalformedDictionary, fields, value)
+		}
+	}
+	entry := dictEntry{
+		vrs:     strings.Split(strs[0], " or "),
+		vm:      strs[1],
+		name:    strs[2],
+		retired: strs[3] == "Retired",
+	}
+	switch {
+	case strs[0] == "NONE":
+		entry.vrs = []string{"NA"}
+	case strs[0] == "":
+		entry.vrs = []string{UnknownVR}
+	}
+	if fields > 4 {
+		entry.keyword = strs[4]
+	}
+	return entry, nil
+}
+
+// register adds the entry to d, or to the private dictionary of d if it has a
+// Private Creator. Entries for a range of tags are registered as a single
+// range entry rather than for every tag in the range.
+func (e dictEntry) register(d *dictionary, force bool) error {
+	groups, err := parseHexRange(e.groups, 4)
+	if err != nil {
+		return err
+	}
+	if e.creator != "" {
+		parts := strings.Split(e.element, "-")
+		for i, part := range parts {
+			if len(part) == 4 {
+				// The block is not part of the private tag, e.g. "xx02" or "1002".
+				parts[i] = part[2:]
+			}
+		}
+		element := strings.Join(parts, "-")
+		offsets, err := parseHexRange(element, 2)
+		if err != nil {
+			return err
+		}
+		info := PrivateInfo{Creator: e.creator, Group: groups.min, Offset: uint8(offsets.min), VRs: e.vrs, Name: e.name, Keyword: e.keyword, VM: e.vm}
+		if groups.single() && offsets.single() {
+			err = d.addPrivate(info, force)
+		} else {
+			err = d.addPrivateRange(privateRange{group: groups, offset: offsets, info: info}, force)
+		}
+		if err != nil {
+			return fmt.Errorf("%q (%s,xx%s): %w", e.creator, e.groups, element, err)
+		}
+		return nil
+	}
+
+	elements, err := parseHexRange(e.element, 4)
+	if err != nil {
+		return err
+	}
+	vm, err := ParseVM(e.vm)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrorMalformedDictionary, err)
+	}
+	if base, isRepeating := RepeatingGroupBase(Tag{Group: groups.min, Element: elements.min}); isRepeating && base.Group == groups.min {
+		// Only the base group of repeating groups is in the dictionary.
+		groups = singleHex(groups.min)
+	}
+	info := Info{Tag: Tag{Group: groups.min, Element: elements.min}, VRs: e.vrs, Name: e.name, Keyword: e.keyword, VM: e.vm, Retired: e.retired, Multiplicity: vm}
+	if groups.single() && elements.single() {
+		err = d.add(info, force)
+	} else {
+		err = d.addRange(tagRange{group: groups, element: elements, info: info}, force)
+	}
+	if err != nil {
+		return fmt.Errorf("(%s,%s) %s: %w", e.groups, e.element, e.keyword, err)
+	}
+	return nil
+}
+
+// hexRange is a range of groups, elements or private offsets of a dictionary
+// entry.
+type hexRange struct {
+	min, max uint16
+	// mask has the bits of the digits that are not "x" in masks like "04x0".
+	mask uint16
+	// step is 2 for ranges of only even or only odd values.
+	step uint16
+}
+
+func singleHex(v uint16) hexRange {
+	return hexRange{min: v, max: v, mask: 0xFFFF, step: 1}
+}
+
+// parseHexRange parses a hex number of the given number of digits, a mask in
+// which any digit may be "x", e.g. "60xx" or "04x0", or a range, e.g.
+// "6000-60ff", "0009-o-ffff" or "0010-u-00ff". Ranges only hold values of the
+// same parity as their first value, unless marked with "-u-"; "-o-" and "-e-"
+// ranges only hold odd and even values.
+func parseHexRange(s string, digits int) (hexRange, error) {
+	parts := strings.Split(strings.ToLower(s), "-")
+	if len(parts) == 1 {
+		if !strings.Contains(parts[0], "x") {
+			v, err := parseHex(parts[0], digits)
+			return singleHex(v), err
+		}
+		r := hexRange{step: 1}
+		var err error
+		if r.min, err = parseHex(strings.ReplaceAll(parts[0], "x", "0"), digits); err != nil {
+			return hexRange{}, err
+		}
+		if r.max, err = parseHex(strings.ReplaceAll(parts[0], "x", "f"), digits); err != nil {
+			return hexRange{}, err
+		}
+		for _, c := range parts[0] {
+			r.mask <<= 4
+			if c != 'x' {
+				r.mask |= 0xF
+			}
+		}
+		return r, nil
+	}
+
+	r := hexRange{step: 2}
+	var startStr, endStr string
+	switch len(parts) {
+	case 2:
+		startStr, endStr = parts[0], parts[1]
+	case 3:
+		startStr, endStr = parts[0], parts[2]
+	default:
+		return hexRange{}, fmt.Errorf("%w: range %q", ErrorMalformedDictionary, s)
+	}
+	var err error
+	if r.min, err = parseHex(startStr, digits); err != nil {
+		return hexRange{}, err
+	}
+	if r.max, err = parseHex(endStr, digits); err != nil {
+		return hexRange{}, err
+	}
+	if len(parts) == 3 {
+		switch parts[1] {
+		case "u":
+			r.step = 1
+		case "o":
+			r.min |= 1
+		case "e":
+			if r.min%2 == 1 {
+				r.min++
+			}
+		default:
+			return hexRange{}, fmt.Errorf("%w: range %q", ErrorMalformedDictionary, s)
+		}
+	}
+	if r.min > r.max {
+		return hexRange{}, fmt.Errorf("%w: empty range %q", ErrorMalformedDictionary, s)
+	}
+	return r, nil
+}
+
+func (r hexRange) single() bool {
+	return r.min == r.max
+}
+
+// contains returns true if v is in r.
+func (r hexRange) contains(v uint16) bool {
+	return v >= r.min && v <= r.max && v&r.mask == r.min&r.mask && (v-r.min)%r.step == 0
+}
+
+func parseHex(s string, digits int) (uint16, error) {
+	v, err := strconv.ParseUint(s, 16, 16)
+	if err != nil || len(s) != digits {
+		return 0, fmt.Errorf("%w: %q is not %d hex digits", ErrorMalformedDictionary, s, digits)
+	}
+	return uint16(v), nil
+}
+
+// pyDict is a parsed Python dictionary literal, in source order.
+type pyDict []pyDictItem
+
+type pyDictItem struct {
+	key, value any
+}
+
+// pyParser parses the subset of Python used by pydicom's dictionaries:
+// assignments of dict, tuple, list, string and integer literals. Values are
+// parsed as pyDict, []any, string and int64.
+type pyParser struct {
+	src string
+	pos int
+}
+
+// nextAssignedDict finds the next top level assignment of a dictionary
+// literal, e.g. "DicomDictionary: dict[int, tuple] = {", and parses it.
+func (p *pyParser) nextAssignedDict() (pyDict, bool, error) {
+	for p.pos < len(p.src) {
+		lineEnd := strings.IndexByte(p.src[p.pos:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(p.src) - p.pos
+		}
+		line := p.src[p.pos : p.pos+lineEnd]
+		if strings.HasPrefix(line, `"""`) || strings.HasPrefix(line, `'''`) {
+			// Skip docstrings, which may contain anything.
+			if _, err := p.parseString(); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+		eq := strings.Index(line, "=")
+		if len(line) > 0 && isPyIdentStart(rune(line[0])) && eq > 0 && strings.HasPrefix(strings.TrimSpace(line[eq+1:]), "{") {
+			p.pos += eq + 1
+			p.skipSpace()
+			v, err := p.parseValue()
+			if err != nil {
+				return nil, false, err
+			}
+			return v.(pyDict), true, nil
+		}
+		p.pos += lineEnd + 1
+	}
+	return nil, false, nil
+}
+
+func (p *pyParser) errorf(format string, args ...any) error {
+	line := strings.Count(p.src[:p.pos], "\n") + 1
+	return fmt.Errorf("%w: line %d: %s", ErrorMalformedDictionary, line, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips whitespace and comments.
+func (p *pyParser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\\':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *pyParser) parseValue() (any, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of file")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '{':
+		return p.parseDict()
+	case c == '(' || c == '[':
+		return p.parseSequence()
+	case c == '"' || c == '\'':
+		return p.parseString()
+	case c >= '0' && c <= '9' || c == '-':
+		return p.parseInt()
+	default:
+		return nil, p.errorf("unexpected %q", c)
+	}
+}
+
+func (p *pyParser) parseDict() (pyDict, error) {
+	p.pos++ // {
+	dict := pyDict{}
+	for {
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == '}' {
+			p.pos++
+			return dict, nil
+		}
+		key, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] != ':' {
+			return nil, p.errorf("expected ':' after dictionary key %v", key)
+		}
+		p.pos++
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		dict = append(dict, pyDictItem{key: key, value: value})
+		if err := p.parseSeparator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *pyParser) parseSequence() ([]any, error) {
+	closing := byte(')')
+	if p.src[p.pos] == '[' {
+		closing = ']'
+	}
+	p.pos++
+	var values []any
+	for {
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == closing {
+			p.pos++
+			return values, nil
+		}
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		if err := p.parseSeparator(closing); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseSeparator consumes the "," between items, or checks that closing
+// follows.
+func (p *pyParser) parseSeparator(closing byte) error {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return p.errorf("unexpected end of file")
+	}
+	switch p.src[p.pos] {
+	case ',':
+		p.pos++
+		return nil
+	case closing:
+		return nil
+	default:
+		return p.errorf("expected ',' or %q, got %q", closing, p.src[p.pos])
+	}
+}
+
+func (p *pyParser) parseString() (string, error) {
+	quote := p.src[p.pos : p.pos+1]
+	if strings.HasPrefix(p.src[p.pos:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	p.pos += len(quote)
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		if strings.HasPrefix(p.src[p.pos:], quote) {
+			p.pos += len(quote)
+			return b.String(), nil
+		}
+		c := p.src[p.pos]
+		if c == '\\' && p.pos+1 < len(p.src) {
+			p.pos++
+			c = p.src[p.pos]
+		} else if c == '\n' && len(quote) == 1 {
+			break
+		}
+		b.WriteByte(c)
+		p.pos++
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *pyParser) parseInt() (int64, error) {
+	start := p.pos
+	for p.pos < len(p.src) && (isPyIdentStart(rune(p.src[p.pos])) || unicode.IsDigit(rune(p.src[p.pos])) || p.src[p.pos] == '-') {
+		p.pos++
+	}
+	v, err := strconv.ParseInt(strings.ReplaceAll(p.src[start:p.pos], "_", ""), 0, 64)
+	if err != nil {
+		return 0, p.errorf("invalid integer %q", p.src[start:p.pos])
+	}
+	return v, nil
+}
+
+func isPyIdentStart(c rune) bool {
+	return c == '_' || unicode.IsLetter(c)
+}
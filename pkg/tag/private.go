@@ -0,0 +1,86 @@
+package tag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorPrivateTagNotFound is returned by FindPrivate when no private
+// attribute is registered for the given Private Creator and tag.
+var ErrorPrivateTagNotFound = errors.New("private tag not found in private dictionary")
+
+// PrivateInfo stores detailed information about a private attribute. Private
+// attributes are identified by the Private Creator reserving a block of
+// elements, the group and the element offset within the block, as the block
+// itself, (gggg,xx00)-(gggg,xxFF), varies between files. See PS3.5 Section
+// 7.8.1.
+type PrivateInfo struct {
+	// Creator is the value of the Private Creator element reserving the block.
+	Creator string
+	// Group is the private group, e.g. 0x0019.
+	Group uint16
+	// Offset is the low byte of the element, e.g. 0x01 for (0019,1001).
+	Offset uint8
+	// VRs, Name, Keyword and VM are the same as in Info.
+	VRs     []string
+	Name    string
+	Keyword string
+	VM      string
+}
+
+type privateKey struct {
+	creator string
+	group   uint16
+	offset  uint8
+}
+
+// privateRange is a private dictionary entry for a range of groups or
+// offsets, e.g. (0031-o-0033,"ACME 1.0",xx02). Its PrivateInfo holds the first
+// group and offset of the range.
+type privateRange struct {
+	group, offset hexRange
+	info          PrivateInfo
+}
+
+func newPrivateKey(creator string, group uint16, offset uint8) privateKey {
+	return privateKey{creator: strings.TrimRight(creator, " \x00"), group: group, offset: offset}
+}
+
+// AddPrivate adds a private attribute to the private dictionary. If force is
+// true an existing entry for the same Private Creator, group and offset is
+// overwritten, otherwise an error is returned.
+func AddPrivate(info PrivateInfo, force bool) error {
+	return update(func(d *dictionary) error {
+		return d.addPrivate(info, force)
+	})
+}
+
+// FindPrivate finds information about the private attribute t in the block
+// reserved by creator. Only the group and the low byte of the element of t are
+// used, so t can be in any block. Attributes missing from the dictionary are
+// resolved from the ranges loaded by LoadDcmtkDictionary and
+// LoadPydicomDictionary.
+func FindPrivate(creator string, t Tag) (PrivateInfo, error) {
+	info, ok := currentDictionary().findPrivate(newPrivateKey(creator, t.Group, uint8(t.Element)))
+	if !ok {
+		return PrivateInfo{}, fmt.Errorf("%w: (%04x,xx%02x) for creator %q", ErrorPrivateTagNotFound, t.Group, t.Element&0xFF, creator)
+	}
+	return info, nil
+}
+
+// IsPrivateCreator returns true if t is a Private Creator element,
+// (gggg,0010)-(gggg,00FF) in a private group.
+func IsPrivateCreator(t Tag) bool {
+	return IsPrivate(t.Group) && t.Element >= 0x0010 && t.Element <= 0x00FF
+}
+
+// PrivateCreatorTag returns the tag of the Private Creator element reserving
+// the block of the private data element t, e.g. (0019,0010) for (0019,1001).
+// It returns false if t is not a private data element.
+func PrivateCreatorTag(t Tag) (Tag, bool) {
+	if !IsPrivate(t.Group) || t.Element < 0x1000 {
+		return Tag{}, false
+	}
+	return Tag{Group: t.Group, Element: t.Element >> 8}, true
+}
@@ -7,7 +7,6 @@ package tag
 //go:generate stringer -type VRKind
 
 import (
-	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -93,6 +92,8 @@ type Info struct {
 	VM string
 	// Whether the tag is retired.
 	Retired bool
+	// Multiplicity is VM parsed, or the zero VM for entries added without it.
+	Multiplicity VM
 }
 
 // MetadataGroup is the value of Tag.Group for metadata tags.
@@ -134,53 +135,86 @@ const (
 	// VRUnknown means the VR of the element is unknown (possibly a private
 	// element seen while reading DICOMs in implicit transfer syntax).
 	VRUnknown
+	// VRInt64List means the element stores a list of int64s
+	VRInt64List
+	// VRUInt64List means the element stores a list of uint64s
+	VRUInt64List
 )
 
-// GetVRKind returns the golang value encoding of an element with <tag, vr>.
+// GetVRKind returns the golang value encoding of an element with <tag, vr>,
+// see VRKindOf. Strings that are not a VR are treated as VRStringList.
 func GetVRKind(tag Tag, vr string) VRKind {
+	parsed, _ := ParseVR(vr)
+	return VRKindOf(tag, parsed)
+}
+
+// VRKindOf returns the golang value encoding of an element with <tag, vr>. It
+// is the Kind of the VR, except for Items and PixelData.
+func VRKindOf(tag Tag, vr VR) VRKind {
 	if tag == Item {
 		return VRItem
 	} else if tag == PixelData {
 		return VRPixelData
 	}
-	switch vr {
-	case "DA":
-		return VRDate
-	case "AT":
-		return VRTagList
-	case "OW", "OB":
-		return VRBytes
-	case "LT", "UT":
-		return VRString
-	case "UL":
-		return VRUInt32List
-	case "SL":
-		return VRInt32List
-	case "US":
-		return VRUInt16List
-	case "SS":
-		return VRInt16List
-	case "FL":
-		return VRFloat32List
-	case "FD":
-		return VRFloat64List
-	case "SQ":
-		return VRSequence
-	case "UN":
-		return VRUnknown
-	default:
-		return VRStringList
+	return vr.Kind()
+}
+
+// repeatingGroups are the base groups of the repeating groups defined in
+// PS3.5 Section 7.6: Curve Data (50xx), Overlays (60xx) and Variable Pixel Data
+// (7Fxx). The dictionary only holds the base group of each.
+var repeatingGroups = []uint16{0x5000, 0x6000, 0x7F00}
+
+// RepeatingGroupBase returns the dictionary tag for a tag in one of the
+// repeating groups (50xx, 60xx or 7Fxx), e.g. (6000,3000) for (6002,3000). It
+// returns false if t is not in a repeating group.
+func RepeatingGroupBase(t Tag) (Tag, bool) {
+	// Repeating groups only use even groups, and (7FE0,xxxx) is the Pixel
+	// Data group rather than a Variable Pixel Data group.
+	if IsPrivate(t.Group) || t.Group == PixelData.Group {
+		return Tag{}, false
+	}
+	for _, base := range repeatingGroups {
+		if t.Group&0xFF00 == base {
+			return Tag{Group: base, Element: t.Element}, true
+		}
+	}
+	return Tag{}, false
+}
+
+// repeatingGroupInfo returns the Info for t, in the same repeating group as
+// the dictionary entry base. The group is appended to the keyword and name of
+// tags outside of the base group, e.g. "OverlayData6002".
+func repeatingGroupInfo(base Info, t Tag) Info {
+	info := base
+	info.Tag = t
+	if t.Group != base.Tag.Group {
+		info.Keyword = fmt.Sprintf("%s%04X", base.Keyword, t.Group)
+		info.Name = fmt.Sprintf("%s (%04X)", base.Name, t.Group)
 	}
+	return info
 }
 
 // Find finds information about the given tag. If the tag is not part of
 // the DICOM standard, or is retired from the standard, it returns an error.
+// Tags in the repeating groups 50xx, 60xx and 7Fxx are resolved from the
+// dictionary entry of their base group, see RepeatingGroupBase, and other tags
+// missing from the dictionary from the ranges loaded by LoadDcmtkDictionary
+// and LoadPydicomDictionary.
 func Find(tag Tag) (Info, error) {
-	entry, ok := tagDict[tag]
+	d := currentDictionary()
+	entry, ok := d.lookup(tag)
 	if !ok {
+		if base, isRepeating := RepeatingGroupBase(tag); isRepeating {
+			if baseEntry, found := d.lookup(base); found {
+				return repeatingGroupInfo(baseEntry, tag), nil
+			}
+		}
+		if rangeEntry, found := d.findRange(tag); found {
+			return rangeEntry, nil
+		}
 		// (0000-u-ffff,0000)	UL	GenericGroupLength	1	GENERIC
 		if tag.Group%2 == 0 && tag.Element == 0x0000 {
-			entry = Info{tag, []string{"UL"}, "Generic Group Length", "GenericGroupLength", "1", false}
+			entry = Info{tag, []string{"UL"}, "Generic Group Length", "GenericGroupLength", "1", false, VM{Min: 1, Max: 1, Multiplier: 1}}
 		} else {
 			return Info{}, fmt.Errorf("could not find tag (0x%x, 0x%x) in dictionary", tag.Group, tag.Element)
 		}
@@ -201,11 +235,31 @@ func MustFind(tag Tag) Info {
 // not part of the DICOM standard, or is retired from the standard, it returns
 // an error.
 //
+// Keywords of tags in repeating groups other than the base group carry the
+// group as a suffix, e.g. "OverlayData6002".
+//
+// Lookups use an index of all keywords and names, which is rebuilt on the
+// first lookup after entries are added.
+//
 // Example: FindTagByKeyword("TransferSyntaxUID")
 func FindByKeyword(keyword string) (Info, error) {
-	for _, ent := range tagDict {
-		if ent.Keyword == keyword || ent.Name == keyword {
-			return ent, nil
+	return findByKeyword(keyword, false)
+}
+
+// findByKeyword implements FindByKeyword, and FindByKeywordFold for a lower
+// case keyword if folded is true.
+func findByKeyword(keyword string, folded bool) (Info, error) {
+	d := currentDictionary()
+	if info, ok := d.lookupKeyword(keyword, folded); ok {
+		return info, nil
+	}
+	if n := len(keyword); n > 4 {
+		if group, err := strconv.ParseUint(keyword[n-4:], 16, 16); err == nil {
+			base, ok := d.lookupKeyword(keyword[:n-4], folded)
+			t := Tag{Group: uint16(group), Element: base.Tag.Element}
+			if b, isRepeating := RepeatingGroupBase(t); ok && isRepeating && b == base.Tag {
+				return repeatingGroupInfo(base, t), nil
+			}
 		}
 	}
 	return Info{}, fmt.Errorf("could not find tag with name %s", keyword)
@@ -232,11 +286,16 @@ func DebugString(tag Tag) string {
 	return fmt.Sprintf("(%04x,%04x)[%s]", tag.Group, tag.Element, e.Keyword)
 }
 
-// Split a tag into a group and element, represented as a hex value
-// TODO: support group ranges (6000-60FF,0803)
+// Split a tag into a group and element, represented as a hex value. Repeating
+// groups, written either as a range like (6000-60FF,3000) or with a mask like
+// (60xx,3000), resolve to the base group.
 func parseTag(tag string) (Tag, error) {
 	parts := strings.Split(strings.Trim(tag, "()"), ",")
-	group, err := strconv.ParseInt(parts[0], 16, 0)
+	if len(parts) != 2 {
+		return Tag{}, fmt.Errorf("tag %q is not of the form (gggg,eeee)", tag)
+	}
+	groupStart, _, _ := strings.Cut(parts[0], "-")
+	group, err := strconv.ParseInt(strings.ReplaceAll(strings.ToLower(groupStart), "x", "0"), 16, 0)
 	if err != nil {
 		return Tag{}, err
 	}
@@ -251,11 +310,22 @@ func parseTag(tag string) (Tag, error) {
 // and to create private tags.
 // If force == true existing tags will be overwritten.
 // Otherwise an error is returned when attempting to add an already existing tag.
+//
+// Add is safe to call concurrently with lookups.
 func Add(info Info, force bool) error {
-	_, found := tagDict[info.Tag]
-	if found && !force {
-		return errors.New("tag already exists")
-	}
-	tagDict[info.Tag] = info
-	return nil
+	return update(func(d *dictionary) error {
+		return d.add(info, force)
+	})
+}
+
+// tagRange is a dictionary entry for a range of tags, e.g.
+// (0009-o-ffff,0010-u-00ff). Its Info holds the first tag of the range.
+type tagRange struct {
+	group, element hexRange
+	info           Info
+}
+
+// sameRange returns true if other is an entry for the same range as r.
+func (r tagRange) sameRange(other tagRange) bool {
+	return r.group == other.group && r.element == other.element
 }
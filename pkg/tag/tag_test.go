@@ -1,6 +1,7 @@
 package tag
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -80,7 +81,6 @@ func TestFind(t *testing.T) {
 	}
 }
 
-// TODO: add a test for correctly splitting ranges
 func TestSplitTag(t *testing.T) {
 	tag, err := parseTag("(7FE0,0010)")
 	if err != nil {
@@ -94,6 +94,59 @@ func TestSplitTag(t *testing.T) {
 	}
 }
 
+func TestSplitTag_RepeatingGroups(t *testing.T) {
+	for _, s := range []string{"(6000-60FF,3000)", "(60xx,3000)", "(60XX,3000)"} {
+		got, err := parseTag(s)
+		if err != nil {
+			t.Fatalf("parseTag(%q) unexpected error: %v", s, err)
+		}
+		if want := (Tag{Group: 0x6000, Element: 0x3000}); got != want {
+			t.Errorf("parseTag(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestFind_RepeatingGroups(t *testing.T) {
+	cases := []struct {
+		tag         Tag
+		wantKeyword string
+		wantName    string
+		wantVRs     []string
+	}{
+		{Tag{0x6000, 0x3000}, "OverlayData", "Overlay Data", []string{"OB", "OW"}},
+		{Tag{0x6002, 0x3000}, "OverlayData6002", "Overlay Data (6002)", []string{"OB", "OW"}},
+		{Tag{0x60FE, 0x0010}, "OverlayRows60FE", "Overlay Rows (60FE)", []string{"US"}},
+		{Tag{0x5012, 0x0005}, "CurveDimensions5012", "Curve Dimensions (5012)", []string{"US"}},
+	}
+	for _, tc := range cases {
+		got, err := Find(tc.tag)
+		if err != nil {
+			t.Fatalf("Find(%v) unexpected error: %v", tc.tag, err)
+		}
+		want := Info{Tag: tc.tag, VRs: tc.wantVRs, Name: tc.wantName, Keyword: tc.wantKeyword, VM: got.VM, Retired: got.Retired, Multiplicity: got.Multiplicity}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Find(%v) unexpected diff: %v", tc.tag, diff)
+		}
+
+		byKeyword, err := FindByKeyword(tc.wantKeyword)
+		if err != nil {
+			t.Fatalf("FindByKeyword(%q) unexpected error: %v", tc.wantKeyword, err)
+		}
+		if byKeyword.Tag != tc.tag {
+			t.Errorf("FindByKeyword(%q) returned tag %v, want %v", tc.wantKeyword, byKeyword.Tag, tc.tag)
+		}
+	}
+
+	for _, notRepeating := range []Tag{{0x6001, 0x3000}, {0x6100, 0x3000}, {0x7FE0, 0x0050}} {
+		if info, err := Find(notRepeating); err == nil {
+			t.Errorf("Find(%v) = %v, want error", notRepeating, info)
+		}
+	}
+	if _, err := FindByKeyword("OverlayData6001"); err == nil {
+		t.Errorf("FindByKeyword(\"OverlayData6001\") want error")
+	}
+}
+
 func TestUint32Conversion(t *testing.T) {
 	var got, want uint32 = Tag{Group: 0x7FE0, Element: 0x0010}.Uint32(), 0x7FE00010
 	if got != want {
@@ -205,3 +258,504 @@ func BenchmarkFindPixelDataTag(b *testing.B) {
 
 	}
 }
+
+func TestAddPrivate(t *testing.T) {
+	info := PrivateInfo{
+		Creator: "ACME TEST 1.0",
+		Group:   0x0029,
+		Offset:  0x01,
+		VRs:     []string{"US"},
+		Name:    "Acme Test Count",
+		Keyword: "AcmeTestCount",
+		VM:      "1",
+	}
+	if err := AddPrivate(info, false); err != nil {
+		t.Fatalf("AddPrivate(%v, false) unexpected error: %v", info, err)
+	}
+	if err := AddPrivate(info, false); err == nil {
+		t.Errorf("AddPrivate(%v, false) expected error for existing private tag, got nil", info)
+	}
+	if err := AddPrivate(info, true); err != nil {
+		t.Errorf("AddPrivate(%v, true) unexpected error: %v", info, err)
+	}
+	if err := AddPrivate(PrivateInfo{Creator: "ACME", Group: 0x0028}, false); err == nil {
+		t.Errorf("AddPrivate() expected error for non-private group, got nil")
+	}
+
+	// The same attribute may be in any block of the group.
+	for _, tg := range []Tag{{Group: 0x0029, Element: 0x1001}, {Group: 0x0029, Element: 0x4201}} {
+		got, err := FindPrivate("ACME TEST 1.0 ", tg)
+		if err != nil {
+			t.Fatalf("FindPrivate(%v) unexpected error: %v", tg, err)
+		}
+		if diff := cmp.Diff(info, got); diff != "" {
+			t.Errorf("FindPrivate(%v) unexpected diff: %v", tg, diff)
+		}
+	}
+	if _, err := FindPrivate("OTHER", Tag{Group: 0x0029, Element: 0x1001}); !errors.Is(err, ErrorPrivateTagNotFound) {
+		t.Errorf("FindPrivate() for unknown creator returned err %v, want %v", err, ErrorPrivateTagNotFound)
+	}
+}
+
+func TestPrivateCreatorTag(t *testing.T) {
+	cases := []struct {
+		tag         Tag
+		wantCreator Tag
+		wantOK      bool
+		isCreator   bool
+	}{
+		{tag: Tag{Group: 0x0019, Element: 0x1001}, wantCreator: Tag{Group: 0x0019, Element: 0x0010}, wantOK: true},
+		{tag: Tag{Group: 0x0019, Element: 0xFF10}, wantCreator: Tag{Group: 0x0019, Element: 0x00FF}, wantOK: true},
+		{tag: Tag{Group: 0x0019, Element: 0x0010}, isCreator: true},
+		{tag: Tag{Group: 0x0019, Element: 0x0001}},
+		{tag: Tag{Group: 0x0018, Element: 0x1001}},
+	}
+	for _, tc := range cases {
+		got, ok := PrivateCreatorTag(tc.tag)
+		if got != tc.wantCreator || ok != tc.wantOK {
+			t.Errorf("PrivateCreatorTag(%v) = %v, %v, want %v, %v", tc.tag, got, ok, tc.wantCreator, tc.wantOK)
+		}
+		if got := IsPrivateCreator(tc.tag); got != tc.isCreator {
+			t.Errorf("IsPrivateCreator(%v) = %v, want %v", tc.tag, got, tc.isCreator)
+		}
+	}
+}
+
+func TestLoadDcmtkDictionary(t *testing.T) {
+	dict := `# A dcmtk data dictionary
+(00f2,0010)	LO	AcmeDeviceName	1	PrivateTag
+(00f2,0020)	ox	AcmeBlob	1	DICOM/retired
+(00f4,001x)	US/SS	AcmeCounter	1-n	DICOM
+(6000-60ff,7777)	FD	OverlayAcmeFactor	1	DICOM
+
+(0029,"ACME DCMTK 1.0",01)	SL	AcmeNumberOfCells	1	PrivateTag
+(0031-o-0033,"ACME DCMTK 1.0",xx02)	DS	AcmeSpacing	2	PrivateTag
+`
+	if err := LoadDcmtkDictionary(strings.NewReader(dict), false); err != nil {
+		t.Fatalf("LoadDcmtkDictionary() unexpected error: %v", err)
+	}
+
+	cases := []Info{
+		{Tag: Tag{Group: 0x00F2, Element: 0x0010}, VRs: []string{"LO"}, Name: "AcmeDeviceName", Keyword: "AcmeDeviceName", VM: "1"},
+		{Tag: Tag{Group: 0x00F2, Element: 0x0020}, VRs: []string{"OB", "OW"}, Name: "AcmeBlob", Keyword: "AcmeBlob", VM: "1", Retired: true},
+		{Tag: Tag{Group: 0x00F4, Element: 0x0010}, VRs: []string{"US", "SS"}, Name: "AcmeCounter", Keyword: "AcmeCounter", VM: "1-n"},
+		{Tag: Tag{Group: 0x00F4, Element: 0x001F}, VRs: []string{"US", "SS"}, Name: "AcmeCounter", Keyword: "AcmeCounter", VM: "1-n"},
+		{Tag: Tag{Group: 0x6002, Element: 0x7777}, VRs: []string{"FD"}, Name: "OverlayAcmeFactor (6002)", Keyword: "OverlayAcmeFactor6002", VM: "1"},
+	}
+	for _, want := range cases {
+		want.Multiplicity = MustParseVM(want.VM)
+		got, err := Find(want.Tag)
+		if err != nil {
+			t.Fatalf("Find(%v) unexpected error: %v", want.Tag, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Find(%v) unexpected diff: %v", want.Tag, diff)
+		}
+	}
+	// Only the base group of repeating groups is added to the dictionary.
+	if _, found := currentDictionary().lookup(Tag{Group: 0x6002, Element: 0x7777}); found {
+		t.Errorf("LoadDcmtkDictionary() added (6002,7777), want only the base group")
+	}
+
+	privateCases := []PrivateInfo{
+		{Creator: "ACME DCMTK 1.0", Group: 0x0029, Offset: 0x01, VRs: []string{"SL"}, Name: "AcmeNumberOfCells", Keyword: "AcmeNumberOfCells", VM: "1"},
+		{Creator: "ACME DCMTK 1.0", Group: 0x0031, Offset: 0x02, VRs: []string{"DS"}, Name: "AcmeSpacing", Keyword: "AcmeSpacing", VM: "2"},
+		{Creator: "ACME DCMTK 1.0", Group: 0x0033, Offset: 0x02, VRs: []string{"DS"}, Name: "AcmeSpacing", Keyword: "AcmeSpacing", VM: "2"},
+	}
+	for _, want := range privateCases {
+		got, err := FindPrivate(want.Creator, Tag{Group: want.Group, Element: 0x1000 | uint16(want.Offset)})
+		if err != nil {
+			t.Fatalf("FindPrivate(%v) unexpected error: %v", want, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("FindPrivate() unexpected diff: %v", diff)
+		}
+	}
+
+	if err := LoadDcmtkDictionary(strings.NewReader(dict), false); err == nil {
+		t.Errorf("LoadDcmtkDictionary() expected error for existing entries, got nil")
+	}
+	if err := LoadDcmtkDictionary(strings.NewReader(dict), true); err != nil {
+		t.Errorf("LoadDcmtkDictionary(force) unexpected error: %v", err)
+	}
+	if err := LoadDcmtkDictionary(strings.NewReader("(00f2,00zz)	LO	Bad	1	DICOM\n"), true); !errors.Is(err, ErrorMalformedDictionary) {
+		t.Errorf("LoadDcmtkDictionary() returned err %v, want %v", err, ErrorMalformedDictionary)
+	}
+
+	// A dictionary that fails to load leaves no entries behind.
+	partial := "(00fe,0010)	LO	AcmePartial	1	DICOM\n(00f2,0010)	LO	AcmeStudyLabel	1	DICOM\n"
+	if err := LoadDcmtkDictionary(strings.NewReader(partial), false); err == nil {
+		t.Errorf("LoadDcmtkDictionary() expected error for existing entries, got nil")
+	}
+	if info, err := Find(Tag{Group: 0x00FE, Element: 0x0010}); err == nil {
+		t.Errorf("Find() = %v, want the entries of a failed load not to be registered", info)
+	}
+}
+
+func TestLoadDcmtkDictionary_Ranges(t *testing.T) {
+	// Entries of dcmtk's dicom.dic for ranges of groups and elements.
+	dict := `(0000-u-ffff,0000)	UL	GenericGroupLength	1	GENERIC
+(0009-o-ffff,0000)	UL	PrivateGroupLength	1	PRIVATE
+(0009-o-ffff,0010-u-00ff)	LO	PrivateCreator	1	PRIVATE
+(0001-o-0007,0000)	UL	IllegalGroupLength	1	ILLEGAL
+(0020,3100-31ff)	CS	SourceImageIDs	1-n	DICOM/retired
+(0041-o-0043,"ACME DCMTK RANGE",xx10-u-xx1f)	US	AcmeRangeValue	1	PrivateTag
+`
+	if err := LoadDcmtkDictionary(strings.NewReader(dict), false); err != nil {
+		t.Fatalf("LoadDcmtkDictionary() unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		tag         Tag
+		wantKeyword string
+	}{
+		{tag: Tag{Group: 0x0010, Element: 0x0000}, wantKeyword: "GenericGroupLength"},
+		{tag: Tag{Group: 0xFFFE, Element: 0x0000}, wantKeyword: "GenericGroupLength"},
+		{tag: Tag{Group: 0x0009, Element: 0x0000}, wantKeyword: "PrivateGroupLength"},
+		{tag: Tag{Group: 0x0009, Element: 0x0010}, wantKeyword: "PrivateCreator"},
+		{tag: Tag{Group: 0x7FE1, Element: 0x00FF}, wantKeyword: "PrivateCreator"},
+		{tag: Tag{Group: 0x0003, Element: 0x0000}, wantKeyword: "IllegalGroupLength"},
+		{tag: Tag{Group: 0x0020, Element: 0x3100}, wantKeyword: "SourceImageIDs"},
+		{tag: Tag{Group: 0x0020, Element: 0x31FE}, wantKeyword: "SourceImageIDs"},
+		// Entries in the dictionary take priority over ranges.
+		{tag: Tag{Group: 0x0002, Element: 0x0000}, wantKeyword: "FileMetaInformationGroupLength"},
+	}
+	for _, tc := range cases {
+		got, err := Find(tc.tag)
+		if err != nil {
+			t.Fatalf("Find(%v) unexpected error: %v", tc.tag, err)
+		}
+		if got.Tag != tc.tag || got.Keyword != tc.wantKeyword {
+			t.Errorf("Find(%v) = %v %s, want %s", tc.tag, got.Tag, got.Keyword, tc.wantKeyword)
+		}
+	}
+	for _, notInRange := range []Tag{{Group: 0x0009, Element: 0x0100}, {Group: 0x0020, Element: 0x3101}} {
+		if info, err := Find(notInRange); err == nil {
+			t.Errorf("Find(%v) = %v, want error", notInRange, info)
+		}
+	}
+
+	// Ranges are not added tag by tag, and do not replace existing keywords.
+	if _, found := currentDictionary().lookup(Tag{Group: 0x0010, Element: 0x0000}); found {
+		t.Errorf("LoadDcmtkDictionary() added (0010,0000), want a single range entry")
+	}
+	if info, err := FindByKeyword("FileMetaInformationGroupLength"); err != nil || info.Tag != FileMetaInformationGroupLength {
+		t.Errorf("FindByKeyword(\"FileMetaInformationGroupLength\") = %v, %v, want %v", info.Tag, err, FileMetaInformationGroupLength)
+	}
+
+	got, err := FindPrivate("ACME DCMTK RANGE", Tag{Group: 0x0043, Element: 0x101F})
+	if err != nil {
+		t.Fatalf("FindPrivate() unexpected error: %v", err)
+	}
+	want := PrivateInfo{Creator: "ACME DCMTK RANGE", Group: 0x0043, Offset: 0x1F, VRs: []string{"US"}, Name: "AcmeRangeValue", Keyword: "AcmeRangeValue", VM: "1"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FindPrivate() unexpected diff: %v", diff)
+	}
+
+	if err := LoadDcmtkDictionary(strings.NewReader(dict), false); err == nil {
+		t.Errorf("LoadDcmtkDictionary() expected error for existing ranges, got nil")
+	}
+	if err := LoadDcmtkDictionary(strings.NewReader(dict), true); err != nil {
+		t.Errorf("LoadDcmtkDictionary(force) unexpected error: %v", err)
+	}
+	if err := LoadDcmtkDictionary(strings.NewReader("(00f2,0010-x-00ff)	LO	Bad	1	DICOM\n"), true); !errors.Is(err, ErrorMalformedDictionary) {
+		t.Errorf("LoadDcmtkDictionary() returned err %v, want %v", err, ErrorMalformedDictionary)
+	}
+}
+
+func TestLoadPydicomDictionary(t *testing.T) {
+	dict := `"""DICOM data dictionary auto-generated by generate_dicom_dict.py"""
+
+# Each dict entry is Tag : (VR, VM, Name, Retired, Keyword)
+DicomDictionary: dict[int, tuple[str, str, str, str, str]] = {
+    0x00F60010: ('LO', '1', "Acme Device Name", '', 'AcmePyDeviceName'),  # Acme
+    0x00F60020: ('US or SS', '1-n', "Acme \"Quoted\" Value", 'Retired', 'AcmePyValue'),
+}
+
+# Repeater dictionary
+RepeatersDictionary = {
+    '60xx7778': ('FL', '1', "Overlay Acme Scale", '', 'OverlayAcmeScale'),
+    '00F831x0': ('CS', '1', "Acme Flag", '', 'AcmeFlag'),
+}
+
+private_dictionaries = {
+    'ACME PYDICOM 1.0': {
+        '0029xx01': ('SL', '1', 'Acme Number of Cells', ''),
+        '0029xx02': ('OB', '1', 'Acme Blob', 'Retired'),
+    },
+}
+`
+	if err := LoadPydicomDictionary(strings.NewReader(dict), false); err != nil {
+		t.Fatalf("LoadPydicomDictionary() unexpected error: %v", err)
+	}
+
+	cases := []Info{
+		{Tag: Tag{Group: 0x00F6, Element: 0x0010}, VRs: []string{"LO"}, Name: "Acme Device Name", Keyword: "AcmePyDeviceName", VM: "1"},
+		{Tag: Tag{Group: 0x00F6, Element: 0x0020}, VRs: []string{"US", "SS"}, Name: `Acme "Quoted" Value`, Keyword: "AcmePyValue", VM: "1-n", Retired: true},
+		{Tag: Tag{Group: 0x6000, Element: 0x7778}, VRs: []string{"FL"}, Name: "Overlay Acme Scale", Keyword: "OverlayAcmeScale", VM: "1"},
+		{Tag: Tag{Group: 0x00F8, Element: 0x31F0}, VRs: []string{"CS"}, Name: "Acme Flag", Keyword: "AcmeFlag", VM: "1"},
+	}
+	for _, want := range cases {
+		want.Multiplicity = MustParseVM(want.VM)
+		got, err := Find(want.Tag)
+		if err != nil {
+			t.Fatalf("Find(%v) unexpected error: %v", want.Tag, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Find(%v) unexpected diff: %v", want.Tag, diff)
+		}
+	}
+
+	got, err := FindPrivate("ACME PYDICOM 1.0", Tag{Group: 0x0029, Element: 0x1201})
+	if err != nil {
+		t.Fatalf("FindPrivate() unexpected error: %v", err)
+	}
+	want := PrivateInfo{Creator: "ACME PYDICOM 1.0", Group: 0x0029, Offset: 0x01, VRs: []string{"SL"}, Name: "Acme Number of Cells", VM: "1"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FindPrivate() unexpected diff: %v", diff)
+	}
+
+	if err := LoadPydicomDictionary(strings.NewReader("DicomDictionary = {0x00F60030: ('LO', '1')}"), true); !errors.Is(err, ErrorMalformedDictionary) {
+		t.Errorf("LoadPydicomDictionary() returned err %v, want %v", err, ErrorMalformedDictionary)
+	}
+}
+
+func BenchmarkFindByKeyword(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := FindByKeyword("PixelData"); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+func TestFindByKeywordFold(t *testing.T) {
+	for _, keyword := range []string{"PatientName", "patientname", "PATIENTNAME", "patient's name"} {
+		got, err := FindByKeywordFold(keyword)
+		if err != nil {
+			t.Fatalf("FindByKeywordFold(%q) unexpected error: %v", keyword, err)
+		}
+		if got.Tag != PatientName {
+			t.Errorf("FindByKeywordFold(%q) = %v, want %v", keyword, got.Tag, PatientName)
+		}
+	}
+	got, err := FindByKeywordFold("overlaydata6002")
+	if err != nil {
+		t.Fatalf("FindByKeywordFold(%q) unexpected error: %v", "overlaydata6002", err)
+	}
+	if want := (Tag{Group: 0x6002, Element: 0x3000}); got.Tag != want {
+		t.Errorf("FindByKeywordFold(%q) = %v, want %v", "overlaydata6002", got.Tag, want)
+	}
+	if _, err := FindByKeyword("patientname"); err == nil {
+		t.Errorf("FindByKeyword(%q) expected case sensitive lookup to fail, got nil error", "patientname")
+	}
+}
+
+func TestSearchKeywords(t *testing.T) {
+	got := SearchKeywords("patientna", 3)
+	if len(got) != 3 {
+		t.Fatalf("SearchKeywords() returned %d results, want limit of 3", len(got))
+	}
+	// The prefix match comes before keywords containing the query, followed by
+	// fuzzy matches.
+	if got[0].Keyword != "PatientName" || got[1].Keyword != "OtherPatientNames" {
+		t.Errorf("SearchKeywords() = %q, %q, ..., want PatientName, OtherPatientNames, ...", got[0].Keyword, got[1].Keyword)
+	}
+
+	var fuzzy []string
+	for _, info := range SearchKeywords("ptntnm", 0) {
+		fuzzy = append(fuzzy, info.Keyword)
+	}
+	found := false
+	for _, keyword := range fuzzy {
+		found = found || keyword == "PatientName"
+	}
+	if !found {
+		t.Errorf("SearchKeywords(%q) = %v, want PatientName among the results", "ptntnm", fuzzy)
+	}
+}
+
+func TestAdd_ConcurrentLookups(t *testing.T) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			info := Info{Tag: Tag{Group: 0x00FA, Element: uint16(i)}, VRs: []string{"LO"}, Name: fmt.Sprintf("Concurrent %d", i), Keyword: fmt.Sprintf("Concurrent%d", i), VM: "1"}
+			if err := Add(info, true); err != nil {
+				t.Errorf("Add(%v) unexpected error: %v", info, err)
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if _, err := FindByKeyword("PatientName"); err != nil {
+			t.Errorf("FindByKeyword() unexpected error: %v", err)
+		}
+		_ = SearchKeywords("concurrent", 5)
+		_, _ = Find(Tag{Group: 0x00FA, Element: uint16(i)})
+	}
+	<-done
+	got, err := FindByKeyword("Concurrent99")
+	if err != nil {
+		t.Fatalf("FindByKeyword() unexpected error after Add: %v", err)
+	}
+	if want := (Tag{Group: 0x00FA, Element: 99}); got.Tag != want {
+		t.Errorf("FindByKeyword() = %v, want %v", got.Tag, want)
+	}
+}
+
+func TestAdd_OverrideKeywordIndex(t *testing.T) {
+	first := Info{Tag: Tag{Group: 0x00FC, Element: 0x0010}, VRs: []string{"LO"}, Name: "Acme Shared Name", Keyword: "AcmeSharedFirst", VM: "1"}
+	second := Info{Tag: Tag{Group: 0x00FC, Element: 0x0011}, VRs: []string{"LO"}, Name: "Acme Shared Name", Keyword: "AcmeSharedSecond", VM: "1"}
+	for _, info := range []Info{first, second} {
+		if err := Add(info, false); err != nil {
+			t.Fatalf("Add(%v) unexpected error: %v", info, err)
+		}
+	}
+	if got, err := FindByKeyword("Acme Shared Name"); err != nil || got.Tag != second.Tag {
+		t.Fatalf("FindByKeyword() = %v, %v, want %v", got.Tag, err, second.Tag)
+	}
+
+	// Replacing the entry the name points to leaves it to the other entry.
+	second.Name, second.Keyword = "Acme Renamed", "AcmeRenamed"
+	if err := Add(second, true); err != nil {
+		t.Fatalf("Add(%v, true) unexpected error: %v", second, err)
+	}
+	for keyword, want := range map[string]Tag{"Acme Shared Name": first.Tag, "AcmeRenamed": second.Tag} {
+		if got, err := FindByKeyword(keyword); err != nil || got.Tag != want {
+			t.Errorf("FindByKeyword(%q) = %v, %v, want %v", keyword, got.Tag, err, want)
+		}
+		if got, err := FindByKeywordFold(strings.ToUpper(keyword)); err != nil || got.Tag != want {
+			t.Errorf("FindByKeywordFold(%q) = %v, %v, want %v", strings.ToUpper(keyword), got.Tag, err, want)
+		}
+	}
+	if info, err := FindByKeyword("AcmeSharedSecond"); err == nil {
+		t.Errorf("FindByKeyword(%q) = %v, want the replaced keyword to be gone", "AcmeSharedSecond", info.Tag)
+	}
+}
+
+func BenchmarkFindPrivateTag(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, _ = Find(Tag{Group: 0x0019, Element: 0x1001})
+	}
+}
+
+func TestParseVM(t *testing.T) {
+	cases := []struct {
+		vm      string
+		want    VM
+		allowed []int
+		denied  []int
+	}{
+		{vm: "1", want: VM{Min: 1, Max: 1, Multiplier: 1}, allowed: []int{0, 1}, denied: []int{2}},
+		{vm: "1-3", want: VM{Min: 1, Max: 3, Multiplier: 1}, allowed: []int{1, 3}, denied: []int{4}},
+		{vm: "1-n", want: VM{Min: 1, Multiplier: 1, Unbounded: true}, allowed: []int{1, 100}},
+		{vm: "2-2n", want: VM{Min: 2, Multiplier: 2, Unbounded: true}, allowed: []int{2, 6}, denied: []int{1, 3}},
+		{vm: "3-3n", want: VM{Min: 3, Multiplier: 3, Unbounded: true}, allowed: []int{3, 9}, denied: []int{4}},
+		{vm: "1-n or 1", want: VM{Min: 1, Multiplier: 1, Unbounded: true}, allowed: []int{1, 5}},
+	}
+	for _, tc := range cases {
+		got, err := ParseVM(tc.vm)
+		if err != nil {
+			t.Fatalf("ParseVM(%q) unexpected error: %v", tc.vm, err)
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("ParseVM(%q) unexpected diff: %v", tc.vm, diff)
+		}
+		for _, n := range tc.allowed {
+			if !got.Allows(n) {
+				t.Errorf("ParseVM(%q).Allows(%d) = false, want true", tc.vm, n)
+			}
+		}
+		for _, n := range tc.denied {
+			if got.Allows(n) {
+				t.Errorf("ParseVM(%q).Allows(%d) = true, want false", tc.vm, n)
+			}
+		}
+		if alternatives := strings.Contains(tc.vm, " or "); !alternatives && got.String() != tc.vm {
+			t.Errorf("ParseVM(%q).String() = %q", tc.vm, got.String())
+		}
+	}
+	for _, invalid := range []string{"", "n", "3-1", "1-0n", "a-b"} {
+		if _, err := ParseVM(invalid); err == nil {
+			t.Errorf("ParseVM(%q) expected error, got nil", invalid)
+		}
+	}
+	if !(VM{}).Allows(42) {
+		t.Errorf("unspecified VM does not allow 42 values")
+	}
+}
+
+func TestInfo_Multiplicity(t *testing.T) {
+	// The generated Multiplicity must match the VM of every entry. Entries
+	// added by other tests without a Multiplicity are skipped.
+	checked := 0
+	for _, info := range tagDict {
+		if !info.Multiplicity.IsSpecified() {
+			continue
+		}
+		checked++
+		want, err := ParseVM(info.VM)
+		if err != nil {
+			t.Fatalf("ParseVM(%q) for %s unexpected error: %v", info.VM, info.Keyword, err)
+		}
+		if info.Multiplicity != want {
+			t.Errorf("%s: Multiplicity = %+v, want %+v", info.Keyword, info.Multiplicity, want)
+		}
+	}
+	if checked < 5000 {
+		t.Errorf("only %d dictionary entries have a Multiplicity, want all generated entries", checked)
+	}
+}
+
+func TestParseVR(t *testing.T) {
+	cases := []struct {
+		raw           string
+		want          VR
+		headerLength  int
+		padding       byte
+		maxLength     int
+		charsetAffect bool
+		wordSize      int
+		kind          VRKind
+	}{
+		{raw: "PN", want: PN, headerLength: 8, padding: ' ', maxLength: 64, charsetAffect: true, kind: VRStringList},
+		{raw: "UI", want: UI, headerLength: 8, padding: 0, maxLength: 64, kind: VRStringList},
+		{raw: "UT", want: UT, headerLength: 12, padding: ' ', charsetAffect: true, kind: VRString},
+		{raw: "US", want: US, headerLength: 8, maxLength: 2, wordSize: 2, kind: VRUInt16List},
+		{raw: "OF", want: OF, headerLength: 12, wordSize: 4, kind: VRBytes},
+		{raw: "OV", want: OV, headerLength: 12, wordSize: 8, kind: VRBytes},
+		{raw: "SQ", want: SQ, headerLength: 12, kind: VRSequence},
+		{raw: "UN", want: UN, headerLength: 12, wordSize: 1, kind: VRUnknown},
+	}
+	for _, tc := range cases {
+		got, err := ParseVR(tc.raw)
+		if err != nil {
+			t.Fatalf("ParseVR(%q) unexpected error: %v", tc.raw, err)
+		}
+		if got != tc.want || got.String() != tc.raw {
+			t.Errorf("ParseVR(%q) = %v, want %v", tc.raw, got, tc.want)
+		}
+		if got.HeaderLength() != tc.headerLength {
+			t.Errorf("%v.HeaderLength() = %d, want %d", got, got.HeaderLength(), tc.headerLength)
+		}
+		if got.Padding() != tc.padding {
+			t.Errorf("%v.Padding() = %q, want %q", got, got.Padding(), tc.padding)
+		}
+		if got.MaxLength() != tc.maxLength {
+			t.Errorf("%v.MaxLength() = %d, want %d", got, got.MaxLength(), tc.maxLength)
+		}
+		if got.IsCharacterSetAffected() != tc.charsetAffect {
+			t.Errorf("%v.IsCharacterSetAffected() = %v, want %v", got, got.IsCharacterSetAffected(), tc.charsetAffect)
+		}
+		if got.IsBinary() != (tc.wordSize > 0) || got.WordSize() != tc.wordSize {
+			t.Errorf("%v.WordSize() = %d, want %d", got, got.WordSize(), tc.wordSize)
+		}
+		if got.Kind() != tc.kind {
+			t.Errorf("%v.Kind() = %v, want %v", got, got.Kind(), tc.kind)
+		}
+	}
+	for _, invalid := range []string{"", "us", "XX"} {
+		if got, err := ParseVR(invalid); err == nil || got != InvalidVR {
+			t.Errorf("ParseVR(%q) = %v, %v, want InvalidVR and an error", invalid, got, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package tag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// VM is a parsed Value Multiplicity, the number of values an element may hold
+// (PS3.5 Section 6.4), e.g. "1", "1-3", "1-n" or "2-2n".
+//
+// The zero VM is unspecified, and allows any number of values.
+type VM struct {
+	// Min is the minimum number of values.
+	Min int
+	// Max is the maximum number of values, unless Unbounded is true.
+	Max int
+	// Multiplier is the number the number of values must be a multiple of,
+	// e.g. 2 for "2-2n". It is 1 for all other VMs.
+	Multiplier int
+	// Unbounded is true if there is no maximum number of values, e.g. for
+	// "1-n".
+	Unbounded bool
+}
+
+// ParseVM parses a data dictionary VM like "1", "1-3", "1-n" or "2-2n". VMs
+// listing alternatives, like "1-n or 1", allow the number of values any of the
+// alternatives allows.
+func ParseVM(s string) (VM, error) {
+	if alternatives := strings.Split(s, " or "); len(alternatives) > 1 {
+		var vm VM
+		for i, alt := range alternatives {
+			parsed, err := ParseVM(alt)
+			if err != nil {
+				return VM{}, err
+			}
+			if i == 0 {
+				vm = parsed
+				continue
+			}
+			vm.Min = min(vm.Min, parsed.Min)
+			vm.Max = max(vm.Max, parsed.Max)
+			vm.Unbounded = vm.Unbounded || parsed.Unbounded
+			if vm.Multiplier != parsed.Multiplier {
+				vm.Multiplier = 1
+			}
+		}
+		if vm.Unbounded {
+			vm.Max = 0
+		}
+		return vm, nil
+	}
+
+	lo, hi, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	minimum, err := strconv.Atoi(lo)
+	if err != nil || minimum < 0 {
+		return VM{}, fmt.Errorf("invalid VM %q", s)
+	}
+	vm := VM{Min: minimum, Max: minimum, Multiplier: 1}
+	switch {
+	case !isRange:
+	case strings.HasSuffix(hi, "n"):
+		vm.Max, vm.Unbounded = 0, true
+		if multiplier := strings.TrimSuffix(hi, "n"); multiplier != "" {
+			if vm.Multiplier, err = strconv.Atoi(multiplier); err != nil || vm.Multiplier < 1 {
+				return VM{}, fmt.Errorf("invalid VM %q", s)
+			}
+		}
+	default:
+		if vm.Max, err = strconv.Atoi(hi); err != nil || vm.Max < minimum {
+			return VM{}, fmt.Errorf("invalid VM %q", s)
+		}
+	}
+	return vm, nil
+}
+
+// MustParseVM is like ParseVM, but panics on error.
+func MustParseVM(s string) VM {
+	vm, err := ParseVM(s)
+	if err != nil {
+		panic(err)
+	}
+	return vm
+}
+
+// IsSpecified returns false for the zero VM.
+func (vm VM) IsSpecified() bool {
+	return vm.Multiplier != 0
+}
+
+// Allows returns true if an element may hold n values. Empty elements (n == 0)
+// are always allowed, as are any number of values for an unspecified VM.
+func (vm VM) Allows(n int) bool {
+	if n == 0 || !vm.IsSpecified() {
+		return true
+	}
+	if n < vm.Min || (!vm.Unbounded && n > vm.Max) {
+		return false
+	}
+	return n%vm.Multiplier == 0
+}
+
+// String returns the VM in data dictionary notation, e.g. "2-2n".
+func (vm VM) String() string {
+	switch {
+	case !vm.IsSpecified():
+		return ""
+	case vm.Unbounded && vm.Multiplier > 1:
+		return fmt.Sprintf("%d-%dn", vm.Min, vm.Multiplier)
+	case vm.Unbounded:
+		return fmt.Sprintf("%d-n", vm.Min)
+	case vm.Min == vm.Max:
+		return strconv.Itoa(vm.Min)
+	default:
+		return fmt.Sprintf("%d-%d", vm.Min, vm.Max)
+	}
+}
@@ -0,0 +1,189 @@
+package tag
+
+import (
+	"fmt"
+
+	"github.com/wybaby168/dicom/pkg/vrraw"
+)
+
+// VR is a Value Representation (PS3.5 Section 6.2). Unlike the raw VR strings
+// in package vrraw, a VR carries the properties needed to encode and decode
+// values of it.
+type VR uint8
+
+// The VRs of PS3.5 Table 6.2-1, and NA for the Item and delimitation tags,
+// which have no VR. The zero VR is InvalidVR.
+const (
+	InvalidVR VR = iota
+	AE
+	AS
+	AT
+	CS
+	DA
+	DS
+	DT
+	FD
+	FL
+	IS
+	LO
+	LT
+	OB
+	OD
+	OF
+	OL
+	OV
+	OW
+	PN
+	SH
+	SL
+	SQ
+	SS
+	ST
+	SV
+	TM
+	UC
+	UI
+	UL
+	UN
+	UR
+	US
+	UT
+	UV
+	NA
+)
+
+// vrProperties are the properties of a VR.
+type vrProperties struct {
+	raw string
+	// longLength is true for VRs with 2 reserved bytes and a 32-bit length in
+	// explicit VR encodings, PS3.5 Section 7.1.2.
+	longLength bool
+	padding    byte
+	maxLength  int
+	charset    bool
+	binary     bool
+	wordSize   int
+	kind       VRKind
+}
+
+var vrs = [...]vrProperties{
+	InvalidVR: {kind: VRStringList},
+	AE:        {raw: vrraw.ApplicationEntity, padding: ' ', maxLength: 16, kind: VRStringList},
+	AS:        {raw: vrraw.AgeString, padding: ' ', maxLength: 4, kind: VRStringList},
+	AT:        {raw: vrraw.AttributeTag, maxLength: 4, binary: true, wordSize: 2, kind: VRTagList},
+	CS:        {raw: vrraw.CodeString, padding: ' ', maxLength: 16, kind: VRStringList},
+	DA:        {raw: vrraw.Date, padding: ' ', maxLength: 8, kind: VRDate},
+	DS:        {raw: vrraw.DecimalString, padding: ' ', maxLength: 16, kind: VRStringList},
+	DT:        {raw: vrraw.DateTime, padding: ' ', maxLength: 26, kind: VRStringList},
+	FD:        {raw: vrraw.FloatingPointDouble, maxLength: 8, binary: true, wordSize: 8, kind: VRFloat64List},
+	FL:        {raw: vrraw.FloatingPointSingle, maxLength: 4, binary: true, wordSize: 4, kind: VRFloat32List},
+	IS:        {raw: vrraw.IntegerString, padding: ' ', maxLength: 12, kind: VRStringList},
+	LO:        {raw: vrraw.LongString, padding: ' ', maxLength: 64, charset: true, kind: VRStringList},
+	LT:        {raw: vrraw.LongText, padding: ' ', maxLength: 10240, charset: true, kind: VRString},
+	OB:        {raw: vrraw.OtherByte, longLength: true, binary: true, wordSize: 1, kind: VRBytes},
+	OD:        {raw: vrraw.OtherDouble, longLength: true, binary: true, wordSize: 8, kind: VRBytes},
+	OF:        {raw: vrraw.OtherFloat, longLength: true, binary: true, wordSize: 4, kind: VRBytes},
+	OL:        {raw: vrraw.OtherLong, longLength: true, binary: true, wordSize: 4, kind: VRBytes},
+	OV:        {raw: vrraw.OtherVeryLong, longLength: true, binary: true, wordSize: 8, kind: VRBytes},
+	OW:        {raw: vrraw.OtherWord, longLength: true, binary: true, wordSize: 2, kind: VRBytes},
+	PN:        {raw: vrraw.PersonName, padding: ' ', maxLength: 64, charset: true, kind: VRStringList},
+	SH:        {raw: vrraw.ShortString, padding: ' ', maxLength: 16, charset: true, kind: VRStringList},
+	SL:        {raw: vrraw.SignedLong, maxLength: 4, binary: true, wordSize: 4, kind: VRInt32List},
+	SQ:        {raw: vrraw.Sequence, longLength: true, kind: VRSequence},
+	SS:        {raw: vrraw.SignedShort, maxLength: 2, binary: true, wordSize: 2, kind: VRInt16List},
+	ST:        {raw: vrraw.ShortText, padding: ' ', maxLength: 1024, charset: true, kind: VRStringList},
+	SV:        {raw: vrraw.SignedVeryLong, longLength: true, maxLength: 8, binary: true, wordSize: 8, kind: VRInt64List},
+	TM:        {raw: vrraw.Time, padding: ' ', maxLength: 14, kind: VRStringList},
+	UC:        {raw: vrraw.UnlimitedCharacters, longLength: true, padding: ' ', charset: true, kind: VRStringList},
+	UI:        {raw: vrraw.UniqueIdentifier, maxLength: 64, kind: VRStringList},
+	UL:        {raw: vrraw.UnsignedLong, maxLength: 4, binary: true, wordSize: 4, kind: VRUInt32List},
+	UN:        {raw: vrraw.Unknown, longLength: true, binary: true, wordSize: 1, kind: VRUnknown},
+	UR:        {raw: vrraw.UniversalResourceIdentifier, longLength: true, padding: ' ', kind: VRStringList},
+	US:        {raw: vrraw.UnsignedShort, maxLength: 2, binary: true, wordSize: 2, kind: VRUInt16List},
+	UT:        {raw: vrraw.UnlimitedText, longLength: true, padding: ' ', charset: true, kind: VRString},
+	UV:        {raw: vrraw.UnsignedVeryLong, longLength: true, maxLength: 8, binary: true, wordSize: 8, kind: VRUInt64List},
+	NA:        {raw: "NA", longLength: true, kind: VRItem},
+}
+
+var vrsByRaw = func() map[string]VR {
+	m := make(map[string]VR, len(vrs))
+	for vr, props := range vrs {
+		if props.raw != "" {
+			m[props.raw] = VR(vr)
+		}
+	}
+	return m
+}()
+
+// ParseVR returns the VR for a raw VR string like "US". It returns InvalidVR
+// and an error for strings that are not a VR.
+func ParseVR(raw string) (VR, error) {
+	vr, ok := vrsByRaw[raw]
+	if !ok {
+		return InvalidVR, fmt.Errorf("invalid VR %q", raw)
+	}
+	return vr, nil
+}
+
+func (vr VR) props() vrProperties {
+	if int(vr) >= len(vrs) {
+		return vrs[InvalidVR]
+	}
+	return vrs[vr]
+}
+
+// String returns the raw VR string, e.g. "US".
+func (vr VR) String() string {
+	if vr == InvalidVR || int(vr) >= len(vrs) {
+		return fmt.Sprintf("VR(%d)", int(vr))
+	}
+	return vrs[vr].raw
+}
+
+// HeaderLength returns the length in bytes of the header (tag, VR and value
+// length) of an element with this VR in explicit VR encodings: 12 for VRs
+// with a 32-bit value length, like OB and SQ, and 8 for all others.
+func (vr VR) HeaderLength() int {
+	if vr.props().longLength {
+		return 12
+	}
+	return 8
+}
+
+// Padding returns the byte used to pad values to an even length: a space for
+// character string VRs and NULL for UI and binary VRs.
+func (vr VR) Padding() byte {
+	return vr.props().padding
+}
+
+// MaxLength returns the maximum length of a single value, in characters for
+// VRs affected by the Specific Character Set and in bytes for the others. For
+// PN it applies to each component group. It returns 0 for VRs only limited by
+// the maximum value length of an element.
+func (vr VR) MaxLength() int {
+	return vr.props().maxLength
+}
+
+// IsCharacterSetAffected returns true for VRs whose values are encoded with the
+// Specific Character Set (0008,0005), rather than the default repertoire.
+func (vr VR) IsCharacterSetAffected() bool {
+	return vr.props().charset
+}
+
+// IsBinary returns true for VRs whose values are binary numbers or words
+// rather than character strings.
+func (vr VR) IsBinary() bool {
+	return vr.props().binary
+}
+
+// WordSize returns the size in bytes of the words of binary VRs, which are
+// byte swapped when changing byte order. It returns 0 for non-binary VRs.
+func (vr VR) WordSize() int {
+	return vr.props().wordSize
+}
+
+// Kind returns the Go encoding of values of this VR. See VRKindOf for the
+// kind of a specific tag.
+func (vr VR) Kind() VRKind {
+	return vr.props().kind
+}
@@ -0,0 +1,135 @@
+package uid
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// MaxLength is the maximum length of a UID, see PS3.5 Section 9.1.
+const MaxLength = 64
+
+// Generated UIDs are the root followed by a time, a counter and a random
+// component. The time is in seconds, which takes 10 digits until the year 2286,
+// and the counter wraps around after counterModulus UIDs, so that the random
+// component always has at least minRandomDigits digits.
+const (
+	timeDigits      = 10
+	counterModulus  = 1000000
+	counterDigits   = 6
+	minRandomDigits = 15
+	// maxRandomDigits keeps the random component within 128 bits.
+	maxRandomDigits = 38
+	// maxRootLength is the maximum length of a Generator root.
+	maxRootLength = MaxLength - (1 + timeDigits) - (1 + counterDigits) - (1 + minRandomDigits)
+)
+
+var (
+	// ErrorEmpty is returned by Validate for an empty UID.
+	ErrorEmpty = errors.New("uid: empty UID")
+	// ErrorTooLong is returned by Validate for a UID longer than MaxLength.
+	ErrorTooLong = errors.New("uid: UID longer than 64 characters")
+	// ErrorInvalidComponent is returned by Validate for a UID with an empty
+	// component, a component with a leading zero or a non-digit character.
+	ErrorInvalidComponent = errors.New("uid: invalid UID component")
+	// ErrorRootTooLong is returned by NewGenerator when the root does not leave
+	// enough room for a unique suffix.
+	ErrorRootTooLong = fmt.Errorf("uid: root longer than %d characters", maxRootLength)
+)
+
+// Validate checks that uid is syntactically valid according to PS3.5 Section
+// 9.1: at most 64 characters, made of dot separated components that only
+// contain digits and do not start with a zero (unless the component is "0").
+// Trailing NULL padding is ignored.
+func Validate(uid string) error {
+	uid = strings.TrimRight(uid, "\x00")
+	if uid == "" {
+		return ErrorEmpty
+	}
+	if len(uid) > MaxLength {
+		return fmt.Errorf("%w: %q has %d characters", ErrorTooLong, uid, len(uid))
+	}
+	for i, c := range strings.Split(uid, ".") {
+		if c == "" {
+			return fmt.Errorf("%w: component %d of %q is empty", ErrorInvalidComponent, i, uid)
+		}
+		if len(c) > 1 && c[0] == '0' {
+			return fmt.Errorf("%w: component %d of %q has a leading zero", ErrorInvalidComponent, i, uid)
+		}
+		for _, r := range c {
+			if r < '0' || r > '9' {
+				return fmt.Errorf("%w: component %d of %q contains %q", ErrorInvalidComponent, i, uid, r)
+			}
+		}
+	}
+	return nil
+}
+
+// New returns a new UID under the 2.25 root, derived from a random (version 4)
+// UUID interpreted as an unsigned 128 bit integer, see PS3.5 Annex B.2. Such
+// UIDs do not require a registered organization root.
+func New() string {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		panic(fmt.Sprintf("uid: unable to read random bytes: %v", err))
+	}
+	u[6] = (u[6] & 0x0f) | 0x40 // Version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // Variant RFC 4122
+	return FromUUID(u)
+}
+
+// FromUUID returns the 2.25 UID for the UUID u, see PS3.5 Annex B.2.
+func FromUUID(u [16]byte) string {
+	return "2.25." + new(big.Int).SetBytes(u[:]).String()
+}
+
+// Generator generates UIDs under an organization root. Generated UIDs are the
+// root followed by components from the current time, a counter and random
+// data, the latter taking up the remaining room up to MaxLength characters. A
+// Generator is safe for concurrent use.
+type Generator struct {
+	root    string
+	counter atomic.Uint64
+	now     func() time.Time
+}
+
+// NewGenerator returns a Generator for root, which must be a valid UID of at
+// most 30 characters so that generated UIDs keep room for all of their
+// components.
+func NewGenerator(root string) (*Generator, error) {
+	root = strings.TrimSuffix(root, ".")
+	if err := Validate(root); err != nil {
+		return nil, err
+	}
+	if len(root) > maxRootLength {
+		return nil, fmt.Errorf("%w: %q", ErrorRootTooLong, root)
+	}
+	return &Generator{root: root, now: time.Now}, nil
+}
+
+// New returns a new UID under the Generator root.
+func (g *Generator) New() string {
+	prefix := strings.Join([]string{
+		g.root,
+		strconv.FormatInt(g.now().Unix(), 10),
+		strconv.FormatUint(g.counter.Add(1)%counterModulus, 10),
+	}, ".")
+	digits := min(MaxLength-len(prefix)-1, maxRandomDigits)
+	return prefix + "." + randomDigits(digits)
+}
+
+// randomDigits returns a random number of exactly n digits, which therefore
+// does not start with a zero.
+func randomDigits(n int) string {
+	low := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n-1)), nil)
+	r, err := rand.Int(rand.Reader, new(big.Int).Mul(low, big.NewInt(9)))
+	if err != nil {
+		panic(fmt.Sprintf("uid: unable to read random bytes: %v", err))
+	}
+	return r.Add(r, low).String()
+}
@@ -0,0 +1,179 @@
+package uid
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		uid  string
+		want error
+	}{
+		{uid: "1.2.840.10008.1.2.1", want: nil},
+		{uid: "1.2.840.10008.1.2\x00", want: nil},
+		{uid: "0.1.0", want: nil},
+		{uid: "1." + strings.Repeat("1", 62), want: nil},
+		{uid: "", want: ErrorEmpty},
+		{uid: "\x00", want: ErrorEmpty},
+		{uid: "1." + strings.Repeat("1", 63), want: ErrorTooLong},
+		{uid: "1.2..3", want: ErrorInvalidComponent},
+		{uid: ".1.2", want: ErrorInvalidComponent},
+		{uid: "1.2.", want: ErrorInvalidComponent},
+		{uid: "1.02.3", want: ErrorInvalidComponent},
+		{uid: "1.00", want: ErrorInvalidComponent},
+		{uid: "1.2a.3", want: ErrorInvalidComponent},
+		{uid: "1.-2", want: ErrorInvalidComponent},
+	}
+	for _, tc := range cases {
+		if err := Validate(tc.uid); !errors.Is(err, tc.want) {
+			t.Errorf("Validate(%q) = %v, want %v", tc.uid, err, tc.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	max := new(big.Int).Lsh(big.NewInt(1), 128)
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		got := New()
+		if err := Validate(got); err != nil {
+			t.Fatalf("New() = %q, not valid: %v", got, err)
+		}
+		suffix, found := strings.CutPrefix(got, "2.25.")
+		if !found {
+			t.Fatalf("New() = %q, want a 2.25 UID", got)
+		}
+		n, ok := new(big.Int).SetString(suffix, 10)
+		if !ok || n.Cmp(max) >= 0 {
+			t.Fatalf("New() = %q, want a 128 bit integer after 2.25", got)
+		}
+		if seen[got] {
+			t.Fatalf("New() returned %q twice", got)
+		}
+		seen[got] = true
+	}
+}
+
+func TestFromUUID(t *testing.T) {
+	cases := []struct {
+		uuid [16]byte
+		want string
+	}{
+		{
+			// The example of PS3.5 Annex B.2.
+			uuid: [16]byte{0xf8, 0x1d, 0x4f, 0xae, 0x7d, 0xec, 0x11, 0xd0, 0xa7, 0x65, 0x00, 0xa0, 0xc9, 0x1e, 0x6b, 0xf6},
+			want: "2.25.329800735698586629295641978511506172918",
+		},
+		{uuid: [16]byte{}, want: "2.25.0"},
+		{uuid: [16]byte{15: 1}, want: "2.25.1"},
+	}
+	for _, tc := range cases {
+		if got := FromUUID(tc.uuid); got != tc.want {
+			t.Errorf("FromUUID(%x) = %q, want %q", tc.uuid, got, tc.want)
+		}
+	}
+}
+
+func TestNewGenerator(t *testing.T) {
+	cases := []struct {
+		root string
+		want error
+	}{
+		{root: "1.2.3", want: nil},
+		{root: "1.2.3.", want: nil},
+		{root: "1." + strings.Repeat("2", maxRootLength-2), want: nil},
+		{root: "1." + strings.Repeat("2", maxRootLength-1), want: ErrorRootTooLong},
+		{root: "", want: ErrorEmpty},
+		{root: "1.02", want: ErrorInvalidComponent},
+	}
+	for _, tc := range cases {
+		if _, err := NewGenerator(tc.root); !errors.Is(err, tc.want) {
+			t.Errorf("NewGenerator(%q) = %v, want %v", tc.root, err, tc.want)
+		}
+	}
+}
+
+func TestGenerator_New(t *testing.T) {
+	cases := []struct {
+		name string
+		root string
+	}{
+		{name: "short root", root: "1.2.3"},
+		{name: "max length root", root: "1." + strings.Repeat("9", maxRootLength-2)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g, err := NewGenerator(tc.root)
+			if err != nil {
+				t.Fatalf("NewGenerator(%q) unexpected error: %v", tc.root, err)
+			}
+			// A stopped clock, as with a coarse clock or UIDs generated within
+			// the same second, leaves uniqueness to the counter and random data.
+			now := time.Unix(1792335035, 0)
+			g.now = func() time.Time { return now }
+
+			var mu sync.Mutex
+			seen := map[string]bool{}
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 250; j++ {
+						got := g.New()
+						mu.Lock()
+						if seen[got] {
+							t.Errorf("New() returned %q twice", got)
+						}
+						seen[got] = true
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			for got := range seen {
+				if err := Validate(got); err != nil {
+					t.Fatalf("New() = %q, not valid: %v", got, err)
+				}
+				suffix, found := strings.CutPrefix(got, tc.root+".")
+				if !found {
+					t.Fatalf("New() = %q, want a UID under %q", got, tc.root)
+				}
+				components := strings.Split(suffix, ".")
+				if len(components) != 3 {
+					t.Fatalf("New() = %q, want time, counter and random components after the root", got)
+				}
+				if components[0] != "1792335035" {
+					t.Errorf("New() = %q, want time component 1792335035", got)
+				}
+				if random := components[2]; len(random) < minRandomDigits || len(got) != MaxLength && len(random) != maxRandomDigits {
+					t.Errorf("New() = %q, random component %q does not fill the UID", got, random)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerator_New_CounterWraps(t *testing.T) {
+	g, err := NewGenerator("1." + strings.Repeat("9", maxRootLength-2))
+	if err != nil {
+		t.Fatalf("NewGenerator() unexpected error: %v", err)
+	}
+	g.counter.Store(counterModulus - 1)
+	for _, wantCounter := range []string{"0", "1"} {
+		got := g.New()
+		if err := Validate(got); err != nil || len(got) > MaxLength {
+			t.Fatalf("New() = %q, not valid: %v", got, err)
+		}
+		components := strings.Split(got, ".")
+		if counter := components[len(components)-2]; counter != wantCounter {
+			t.Errorf("New() = %q, counter %q, want %q", got, counter, wantCounter)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package uid
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	// keywordMap maps the Info.Keyword of every UID in uidMap to its Info. It
+	// is built on first use by LookupKeyword.
+	keywordMap     map[string]Info
+	keywordMapOnce sync.Once
+)
+
+// Keyword returns the Name without spaces, e.g. "CTImageStorage" for "CT Image
+// Storage", as dcmdump prints UIDs.
+func (i Info) Keyword() string {
+	return strings.ReplaceAll(i.Name, " ", "")
+}
+
+// buildKeywordMap builds keywordMap from uidMap. If a retired and an active
+// UID share a keyword, the active one is kept, otherwise the lowest UID.
+func buildKeywordMap() {
+	keywordMap = make(map[string]Info, len(uidMap))
+	for _, e := range uidMap {
+		keyword := e.Keyword()
+		if keyword == "" {
+			continue
+		}
+		found, ok := keywordMap[keyword]
+		active, foundActive := e.Status == "", found.Status == ""
+		if !ok || (active && !foundActive) || (active == foundActive && e.UID < found.UID) {
+			keywordMap[keyword] = e
+		}
+	}
+}
+
+// LookupKeyword finds the UID whose Info.Keyword is keyword. If a retired and
+// an active UID share the keyword, the active one is returned.
+func LookupKeyword(keyword string) (Info, error) {
+	keywordMapOnce.Do(buildKeywordMap)
+	e, ok := keywordMap[keyword]
+	if !ok {
+		return Info{}, fmt.Errorf("UID keyword '%s' not found in dictionary", keyword)
+	}
+	return e, nil
+}
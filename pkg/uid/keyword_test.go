@@ -0,0 +1,28 @@
+package uid
+
+import "testing"
+
+func TestLookupKeyword(t *testing.T) {
+	cases := []struct {
+		keyword string
+		want    string
+		wantErr bool
+	}{
+		{keyword: "CTImageStorage", want: CTImageStorage},
+		{keyword: "ExplicitVRLittleEndian", want: ExplicitVRLittleEndian},
+		// A retired UID shares the keyword with the active one.
+		{keyword: "UltrasoundImageStorage", want: "1.2.840.10008.5.1.4.1.1.6.1"},
+		{keyword: "NuclearMedicineImageStorage", want: "1.2.840.10008.5.1.4.1.1.20"},
+		{keyword: "", wantErr: true},
+		{keyword: "CT Image Storage", wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := LookupKeyword(tc.keyword)
+		if gotErr := err != nil; gotErr != tc.wantErr {
+			t.Fatalf("LookupKeyword(%q) returned err %v, want error: %v", tc.keyword, err, tc.wantErr)
+		}
+		if got.UID != tc.want {
+			t.Errorf("LookupKeyword(%q) = %q, want %q", tc.keyword, got.UID, tc.want)
+		}
+	}
+}
@@ -28,6 +28,13 @@ var (
 	ModalityWorklistInformationFind = standardUID("1.2.840.10008.5.1.4.31")
 	VerificationSOPClass            = standardUID("1.2.840.10008.1.1")
 
+	CTImageStorage               = standardUID("1.2.840.10008.5.1.4.1.1.2")
+	MRImageStorage               = standardUID("1.2.840.10008.5.1.4.1.1.4")
+	SecondaryCaptureImageStorage = standardUID("1.2.840.10008.5.1.4.1.1.7")
+	BasicTextSRStorage           = standardUID("1.2.840.10008.5.1.4.1.1.88.11")
+	EnhancedSRStorage            = standardUID("1.2.840.10008.5.1.4.1.1.88.22")
+	ComprehensiveSRStorage       = standardUID("1.2.840.10008.5.1.4.1.1.88.33")
+
 	// https://www.dicomlibrary.com/dicom/transfer-syntax/
 	ImplicitVRLittleEndian         = standardUID("1.2.840.10008.1.2")
 	ExplicitVRLittleEndian         = standardUID("1.2.840.10008.1.2.1")
@@ -0,0 +1,87 @@
+package dicom
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/wybaby168/dicom/pkg/dicomio"
+	"github.com/wybaby168/dicom/pkg/tag"
+)
+
+// recordedEncoding is what the RecordEncoding ParseOption records about the
+// value of an element that is not a sequence, so that Write with
+// PreserveEncoding can write it as it was read unless it was changed.
+type recordedEncoding struct {
+	// value is the value as read, or nil if it is the same as the encoding of
+	// the parsed Value, as most values are.
+	value []byte
+	// sum is the checksum of the encoding of the parsed Value, which changes
+	// with the Value.
+	sum [sha256.Size]byte
+	// encodable is false if the parsed Value could not be encoded, e.g. for
+	// malformed values; value is then always set.
+	encodable bool
+}
+
+// recordEncoding returns the recordedEncoding of elem, read from raw with
+// the transfer syntax of r and the given implicit VR.
+func (r *reader) recordEncoding(elem *Element, raw []byte, implicit bool) *recordedEncoding {
+	w := dicomio.NewWriter(nil, r.rawReader.ByteOrder(), implicit)
+	w.SetCodingSystem(r.rawReader.GetCodingSystem())
+	vr, _ := tag.ParseVR(elem.RawValueRepresentation)
+	data, err := encodeValue(w, elem, vr, elem.ValueLength, writeOptSet{})
+	if err != nil {
+		return &recordedEncoding{value: raw}
+	}
+	e := &recordedEncoding{sum: sha256.Sum256(data), encodable: true}
+	if !bytes.Equal(data, raw) {
+		e.value = raw
+	}
+	return e
+}
+
+// preservedValue returns the value as read instead of data, the encoding of
+// the current Value of the element p belongs to (or the error encoding it),
+// unless the Value was changed since it was read.
+func (p *Position) preservedValue(data []byte, err error) ([]byte, error) {
+	if p == nil || p.encoding == nil {
+		return data, err
+	}
+	e := p.encoding
+	switch {
+	case err != nil && !e.encodable:
+		// Still the malformed value that was read.
+		return e.value, nil
+	case err != nil:
+		return nil, err
+	case e.encodable && e.value != nil && sha256.Sum256(data) == e.sum:
+		return e.value, nil
+	default:
+		return data, nil
+	}
+}
+
+// withGroupLength returns elem, or if it is a group length element read with
+// RecordOffsets, a copy of it holding the length of the elements of its group
+// following it in next, as written to w.
+func withGroupLength(w *dicomio.Writer, elem *Element, next []*Element, opts writeOptSet) (*Element, error) {
+	if elem.Tag.Element != 0x0000 || elem.Position == nil || elem.Value == nil || elem.Value.ValueType() != Ints {
+		return elem, nil
+	}
+	var counter offsetCounter
+	bo, implicit := w.GetTransferSyntax()
+	sub := dicomio.NewWriter(&counter, bo, implicit)
+	sub.SetCodingSystem(w.GetCodingSystem())
+	for _, e := range next {
+		if e.Tag.Group != elem.Tag.Group {
+			break
+		}
+		setCodingSystem(sub, e)
+		if err := writeElement(sub, e, opts); err != nil {
+			return nil, err
+		}
+	}
+	c := *elem
+	c.Value = &intsValue{value: []int{int(counter.n)}}
+	return &c, nil
+}
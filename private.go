@@ -0,0 +1,146 @@
+package dicom
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/vrraw"
+)
+
+var (
+	// ErrorPrivateCreatorNotFound indicates that no Private Creator element
+	// reserves the block of a private element, or that no block in a group is
+	// reserved by a Private Creator.
+	ErrorPrivateCreatorNotFound = errors.New("private creator not found")
+	// ErrorNoFreePrivateBlock indicates that all 240 blocks of a private group
+	// are already reserved.
+	ErrorNoFreePrivateBlock = errors.New("no free private block left in group")
+	// ErrorNotPrivateGroup indicates that a group passed to a private element
+	// API is not a private (odd) group.
+	ErrorNotPrivateGroup = errors.New("group is not a private group")
+)
+
+// PrivateCreator returns the value of the Private Creator element that
+// reserves the block of the private data element t, e.g. the value of
+// (0019,0010) for (0019,1001). Only top level elements of the Dataset are
+// searched, as blocks are reserved per Dataset or sequence item.
+func (d *Dataset) PrivateCreator(t tag.Tag) (string, error) {
+	creatorTag, ok := tag.PrivateCreatorTag(t)
+	if !ok {
+		return "", fmt.Errorf("%w: %v is not a private data element", ErrorPrivateCreatorNotFound, t)
+	}
+	e, err := d.FindElementByTag(creatorTag)
+	if err != nil || e.Value == nil || e.Value.ValueType() != Strings {
+		return "", fmt.Errorf("%w: no Private Creator element %v for %v", ErrorPrivateCreatorNotFound, creatorTag, t)
+	}
+	s := MustGetStrings(e.Value)
+	if len(s) == 0 {
+		return "", fmt.Errorf("%w: empty Private Creator element %v for %v", ErrorPrivateCreatorNotFound, creatorTag, t)
+	}
+	return strings.TrimRight(s[0], " \x00"), nil
+}
+
+// privateBlock returns the block (the high byte of the element) reserved by
+// creator in group.
+func (d *Dataset) privateBlock(creator string, group uint16) (uint16, error) {
+	if !tag.IsPrivate(group) {
+		return 0, fmt.Errorf("%w: 0x%04x", ErrorNotPrivateGroup, group)
+	}
+	creator = strings.TrimRight(creator, " \x00")
+	for _, e := range d.Elements {
+		if e.Tag.Group != group || !tag.IsPrivateCreator(e.Tag) || e.Value == nil || e.Value.ValueType() != Strings {
+			continue
+		}
+		if s := MustGetStrings(e.Value); len(s) > 0 && strings.TrimRight(s[0], " \x00") == creator {
+			return e.Tag.Element, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q in group 0x%04x", ErrorPrivateCreatorNotFound, creator, group)
+}
+
+// FindPrivateElement finds the private element at offset in the block of
+// group reserved by creator, e.g. (0019,1001) for offset 0x01 when creator
+// reserves (0019,0010).
+func (d *Dataset) FindPrivateElement(creator string, group uint16, offset uint8) (*Element, error) {
+	block, err := d.privateBlock(creator, group)
+	if err != nil {
+		return nil, err
+	}
+	return d.FindElementByTag(tag.Tag{Group: group, Element: block<<8 | uint16(offset)})
+}
+
+// SetPrivateElement sets the private element at offset in the block of group
+// reserved by creator to data, which is interpreted as in NewValue. If
+// creator has not reserved a block in group yet, the first free block is
+// reserved by adding a Private Creator element. An existing element is
+// replaced.
+//
+// If rawVR is empty, the VR is looked up in the private dictionary (see
+// tag.AddPrivate).
+//
+// New elements are inserted in tag order, assuming the Dataset is sorted.
+func (d *Dataset) SetPrivateElement(creator string, group uint16, offset uint8, rawVR string, data any) (*Element, error) {
+	block, err := d.privateBlock(creator, group)
+	if errors.Is(err, ErrorPrivateCreatorNotFound) {
+		if block, err = d.reservePrivateBlock(creator, group); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	t := tag.Tag{Group: group, Element: block<<8 | uint16(offset)}
+	if rawVR == "" {
+		info, err := tag.FindPrivate(creator, t)
+		if err != nil {
+			return nil, err
+		}
+		rawVR = info.VRs[0]
+	}
+	value, err := NewValue(data)
+	if err != nil {
+		return nil, err
+	}
+	elem := &Element{
+		Tag:                    t,
+		ValueRepresentation:    tag.GetVRKind(t, rawVR),
+		RawValueRepresentation: rawVR,
+		Value:                  value,
+	}
+	if rawVR == vrraw.Sequence {
+		elem.ValueLength = tag.VLUndefinedLength
+	}
+	d.SetElement(elem)
+	return elem, nil
+}
+
+// reservePrivateBlock adds a Private Creator element for creator in the
+// first free block of group, and returns the block.
+func (d *Dataset) reservePrivateBlock(creator string, group uint16) (uint16, error) {
+	used := map[uint16]bool{}
+	for _, e := range d.Elements {
+		if e.Tag.Group == group && tag.IsPrivateCreator(e.Tag) {
+			used[e.Tag.Element] = true
+		}
+	}
+	for block := uint16(0x10); block <= 0xFF; block++ {
+		if used[block] {
+			continue
+		}
+		creatorTag := tag.Tag{Group: group, Element: block}
+		value, err := NewValue([]string{creator})
+		if err != nil {
+			return 0, err
+		}
+		d.SetElement(&Element{
+			Tag:                    creatorTag,
+			ValueRepresentation:    tag.VRString,
+			RawValueRepresentation: vrraw.LongString,
+			Value:                  value,
+		})
+		return block, nil
+	}
+	return 0, fmt.Errorf("%w: 0x%04x", ErrorNoFreePrivateBlock, group)
+}
@@ -0,0 +1,157 @@
+package dicom
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/uid"
+	"github.com/wybaby168/dicom/pkg/vrraw"
+)
+
+func TestDataset_PrivateCreator(t *testing.T) {
+	ds := Dataset{Elements: []*Element{
+		mustNewPrivateElement(tag.Tag{Group: 0x0019, Element: 0x0010}, vrraw.LongString, []string{"ACME 1.0"}),
+		mustNewPrivateElement(tag.Tag{Group: 0x0019, Element: 0x1001}, vrraw.ShortString, []string{"value"}),
+	}}
+	got, err := ds.PrivateCreator(tag.Tag{Group: 0x0019, Element: 0x1001})
+	if err != nil {
+		t.Fatalf("PrivateCreator() unexpected error: %v", err)
+	}
+	if got != "ACME 1.0" {
+		t.Errorf("PrivateCreator() = %q, want %q", got, "ACME 1.0")
+	}
+	for _, tg := range []tag.Tag{{Group: 0x0019, Element: 0x1101}, {Group: 0x0019, Element: 0x0010}, tag.PatientName} {
+		if _, err := ds.PrivateCreator(tg); !errors.Is(err, ErrorPrivateCreatorNotFound) {
+			t.Errorf("PrivateCreator(%v) returned err %v, want %v", tg, err, ErrorPrivateCreatorNotFound)
+		}
+	}
+}
+
+func TestDataset_SetPrivateElement(t *testing.T) {
+	ds := Dataset{Elements: []*Element{
+		mustNewElement(tag.PatientName, []string{"Bob"}),
+		mustNewElement(tag.PatientID, []string{"123"}),
+		mustNewPrivateElement(tag.Tag{Group: 0x0019, Element: 0x0010}, vrraw.LongString, []string{"OTHER"}),
+		mustNewPrivateElement(tag.Tag{Group: 0x0019, Element: 0x1001}, vrraw.ShortString, []string{"other"}),
+		mustNewElement(tag.StudyInstanceUID, []string{"1.2.3"}),
+	}}
+
+	if _, err := ds.SetPrivateElement("ACME 1.0", 0x0019, 0x01, vrraw.ShortString, []string{"first"}); err != nil {
+		t.Fatalf("SetPrivateElement() unexpected error: %v", err)
+	}
+	// Setting a second attribute reuses the block reserved above.
+	if _, err := ds.SetPrivateElement("ACME 1.0", 0x0019, 0x02, vrraw.UnsignedShort, []int{7}); err != nil {
+		t.Fatalf("SetPrivateElement() unexpected error: %v", err)
+	}
+	// Setting an existing attribute replaces it.
+	if _, err := ds.SetPrivateElement("ACME 1.0", 0x0019, 0x01, vrraw.ShortString, []string{"second"}); err != nil {
+		t.Fatalf("SetPrivateElement() unexpected error: %v", err)
+	}
+
+	var gotTags []tag.Tag
+	for _, e := range ds.Elements {
+		gotTags = append(gotTags, e.Tag)
+	}
+	wantTags := []tag.Tag{
+		tag.PatientName,
+		tag.PatientID,
+		{Group: 0x0019, Element: 0x0010},
+		{Group: 0x0019, Element: 0x0011},
+		{Group: 0x0019, Element: 0x1001},
+		{Group: 0x0019, Element: 0x1101},
+		{Group: 0x0019, Element: 0x1102},
+		tag.StudyInstanceUID,
+	}
+	if diff := cmp.Diff(wantTags, gotTags); diff != "" {
+		t.Errorf("SetPrivateElement() unexpected tags diff: %v", diff)
+	}
+
+	e, err := ds.FindPrivateElement("ACME 1.0", 0x0019, 0x01)
+	if err != nil {
+		t.Fatalf("FindPrivateElement() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"second"}, MustGetStrings(e.Value)); diff != "" {
+		t.Errorf("FindPrivateElement() unexpected value diff: %v", diff)
+	}
+	if _, err := ds.FindPrivateElement("UNKNOWN", 0x0019, 0x01); !errors.Is(err, ErrorPrivateCreatorNotFound) {
+		t.Errorf("FindPrivateElement() returned err %v, want %v", err, ErrorPrivateCreatorNotFound)
+	}
+	if _, err := ds.SetPrivateElement("ACME 1.0", 0x0018, 0x01, vrraw.ShortString, []string{"x"}); !errors.Is(err, ErrorNotPrivateGroup) {
+		t.Errorf("SetPrivateElement() returned err %v, want %v", err, ErrorNotPrivateGroup)
+	}
+	if _, err := ds.SetPrivateElement("ACME 1.0", 0x0019, 0x03, "", []string{"x"}); !errors.Is(err, tag.ErrorPrivateTagNotFound) {
+		t.Errorf("SetPrivateElement() returned err %v, want %v", err, tag.ErrorPrivateTagNotFound)
+	}
+}
+
+func TestDataset_SetPrivateElement_NoFreeBlock(t *testing.T) {
+	ds := Dataset{}
+	for block := 0x10; block <= 0xFF; block++ {
+		ds.Elements = append(ds.Elements, mustNewPrivateElement(tag.Tag{Group: 0x0021, Element: uint16(block)}, vrraw.LongString, []string{"X"}))
+	}
+	if _, err := ds.SetPrivateElement("ACME 1.0", 0x0021, 0x01, vrraw.ShortString, []string{"x"}); !errors.Is(err, ErrorNoFreePrivateBlock) {
+		t.Errorf("SetPrivateElement() returned err %v, want %v", err, ErrorNoFreePrivateBlock)
+	}
+}
+
+func TestParse_ImplicitPrivateDictionary(t *testing.T) {
+	if err := tag.AddPrivate(tag.PrivateInfo{
+		Creator: "ACME IMPLICIT 1.0",
+		Group:   0x0041,
+		Offset:  0x02,
+		VRs:     []string{vrraw.UnsignedShort},
+		Name:    "Acme Count",
+		Keyword: "AcmeCount",
+		VM:      "1",
+	}, true); err != nil {
+		t.Fatalf("AddPrivate() unexpected error: %v", err)
+	}
+
+	ds := Dataset{Elements: []*Element{
+		mustNewElement(tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.1.2"}),
+		mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4.5.6.7"}),
+		mustNewElement(tag.TransferSyntaxUID, []string{uid.ImplicitVRLittleEndian}),
+	}}
+	// Use the second block, so that the block has to be resolved by the
+	// Private Creator.
+	if _, err := ds.SetPrivateElement("OTHER", 0x0041, 0x02, vrraw.ShortString, []string{"ab"}); err != nil {
+		t.Fatalf("SetPrivateElement() unexpected error: %v", err)
+	}
+	if _, err := ds.SetPrivateElement("ACME IMPLICIT 1.0", 0x0041, 0x02, "", []int{513}); err != nil {
+		t.Fatalf("SetPrivateElement() unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, ds); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	parsed, err := Parse(&buf, int64(buf.Len()), nil)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	e, err := parsed.FindPrivateElement("ACME IMPLICIT 1.0", 0x0041, 0x02)
+	if err != nil {
+		t.Fatalf("FindPrivateElement() unexpected error: %v", err)
+	}
+	if e.Tag != (tag.Tag{Group: 0x0041, Element: 0x1102}) {
+		t.Errorf("FindPrivateElement() returned tag %v, want (0041,1102)", e.Tag)
+	}
+	if e.RawValueRepresentation != vrraw.UnsignedShort {
+		t.Errorf("FindPrivateElement() returned VR %v, want %v", e.RawValueRepresentation, vrraw.UnsignedShort)
+	}
+	if diff := cmp.Diff([]int{513}, MustGetInts(e.Value)); diff != "" {
+		t.Errorf("FindPrivateElement() unexpected value diff: %v", diff)
+	}
+	// Attributes of other creators in the same group are still unknown.
+	other, err := parsed.FindPrivateElement("OTHER", 0x0041, 0x02)
+	if err != nil {
+		t.Fatalf("FindPrivateElement() unexpected error: %v", err)
+	}
+	if other.RawValueRepresentation != vrraw.Unknown {
+		t.Errorf("FindPrivateElement() returned VR %v, want %v", other.RawValueRepresentation, vrraw.Unknown)
+	}
+}
@@ -55,70 +55,78 @@ func (r *reader) readTag() (*tag.Tag, error) {
 	return nil, fmt.Errorf("error reading tag: %w", errors.Join(gerr, eerr))
 }
 
-// TODO: Parsed VR should be an enum. Will require refactors of tag pkg.
-func (r *reader) readVR(isImplicit bool, t tag.Tag) (string, error) {
+// readVR returns the VR of the element with tag t, and the VR as read or
+// looked up, which is kept as the RawValueRepresentation of the element even
+// if it is not a valid VR.
+func (r *reader) readVR(isImplicit bool, t tag.Tag) (tag.VR, string, error) {
 	if isImplicit {
 		if entry, err := tag.Find(t); err == nil {
-			switch entry.Tag {
+			dictTag := entry.Tag
+			if base, isRepeating := tag.RepeatingGroupBase(t); isRepeating {
+				// Overlays may be in any of the 60xx groups.
+				dictTag = base
+			}
+			switch dictTag {
 			case tag.PixelData, tag.OverlayData:
 				// OW takes priority in these cases. See notes at:
 				// 1. https://dicom.nema.org/medical/dicom/2024a/output/html/part05.html#sect_8.1.2
 				// 2. https://dicom.nema.org/medical/dicom/2024a/output/html/part05.html#sect_8.2
-				return "OW", nil
+				return tag.OW, vrraw.OtherWord, nil
 			default:
-				return entry.VRs[0], nil
+				vr, _ := tag.ParseVR(entry.VRs[0])
+				return vr, entry.VRs[0], nil
 			}
 		}
-		return tag.UnknownVR, nil
+		if tag.IsPrivateCreator(t) {
+			// Private Creator elements are always LO, PS3.5 Section 7.8.1.
+			return tag.LO, vrraw.LongString, nil
+		}
+		return tag.UN, tag.UnknownVR, nil
 	}
 
 	// Explicit Transfer Syntax, read 2 byte VR:
-	return r.rawReader.ReadString(2)
-
+	raw, err := r.rawReader.ReadString(2)
+	if err != nil {
+		return tag.InvalidVR, "", err
+	}
+	vr, _ := tag.ParseVR(raw)
+	return vr, raw, nil
 }
 
-func (r *reader) readVL(isImplicit bool, t tag.Tag, vr string) (uint32, error) {
+func (r *reader) readVL(isImplicit bool, t tag.Tag, vr tag.VR) (uint32, error) {
 	if isImplicit {
 		return r.rawReader.ReadUInt32()
 	}
 
 	// Explicit Transfer Syntax
 	// More details here: https://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_7.1.2
-	switch vr {
-	// TODO: Parsed VR should be an enum. Will require refactors of tag pkg.
-	case "NA", vrraw.OtherByte, vrraw.OtherDouble, vrraw.OtherFloat,
-		vrraw.OtherLong, vrraw.OtherWord, vrraw.Sequence, vrraw.Unknown,
-		vrraw.UnlimitedCharacters, vrraw.UniversalResourceIdentifier,
-		vrraw.UnlimitedText:
+	if vr.HeaderLength() == 12 {
 		_ = r.rawReader.Skip(2) // ignore two reserved bytes (0000H)
 		vl, err := r.rawReader.ReadUInt32()
 		if err != nil {
 			return 0, err
 		}
 
-		if vl == tag.VLUndefinedLength &&
-			(vr == vrraw.UnlimitedCharacters ||
-				vr == vrraw.UniversalResourceIdentifier ||
-				vr == vrraw.UnlimitedText) {
+		if vl == tag.VLUndefinedLength && (vr == tag.UC || vr == tag.UR || vr == tag.UT) {
 			return 0, errors.New("UC, UR and UT may not have an Undefined Length, i.e.,a Value Length of FFFFFFFFH")
 		}
 		return vl, nil
-	default:
-		vl16, err := r.rawReader.ReadUInt16()
-		if err != nil {
-			return 0, err
-		}
-		vl := uint32(vl16)
-		// Rectify Undefined Length VL
-		if vl == 0xffff {
-			vl = tag.VLUndefinedLength
-		}
-		return vl, nil
 	}
+
+	vl16, err := r.rawReader.ReadUInt16()
+	if err != nil {
+		return 0, err
+	}
+	vl := uint32(vl16)
+	// Rectify Undefined Length VL
+	if vl == 0xffff {
+		vl = tag.VLUndefinedLength
+	}
+	return vl, nil
 }
 
-func (r *reader) readValue(t tag.Tag, vr string, vl uint32, isImplicit bool, d *Dataset, fc chan<- *frame.Frame) (Value, error) {
-	vrkind := tag.GetVRKind(t, vr)
+func (r *reader) readValue(t tag.Tag, vr tag.VR, vl uint32, isImplicit bool, d *Dataset, fc chan<- *frame.Frame) (Value, error) {
+	vrkind := tag.VRKindOf(t, vr)
 	// TODO: if we keep consistent function signature, consider a static map of VR to func?
 	switch vrkind {
 	case tag.VRBytes:
@@ -127,8 +135,12 @@ func (r *reader) readValue(t tag.Tag, vr string, vl uint32, isImplicit bool, d *
 		return r.readString(t, vr, vl)
 	case tag.VRDate:
 		return r.readDate(t, vr, vl)
-	case tag.VRUInt16List, tag.VRUInt32List, tag.VRInt16List, tag.VRInt32List, tag.VRTagList:
+	case tag.VRUInt16List, tag.VRUInt32List, tag.VRInt16List, tag.VRInt32List:
 		return r.readInt(t, vr, vl)
+	case tag.VRTagList:
+		return r.readTags(t, vl)
+	case tag.VRInt64List, tag.VRUInt64List:
+		return r.readVeryLong(t, vr, vl)
 	case tag.VRSequence:
 		return r.readSequence(t, vr, vl, d)
 	case tag.VRItem:
@@ -170,6 +182,8 @@ func (r *reader) readHeader() ([]*Element, error) {
 		// why we return nil error.
 		return nil, nil
 	}
+	// Peeked data is only valid until the next read.
+	preamble := bytes.Clone(data[:128])
 
 	err = r.rawReader.Skip(128 + 4) // skip preamble + magic word
 	if err != nil {
@@ -182,6 +196,9 @@ func (r *reader) readHeader() ([]*Element, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error reading DICOM header element: %w", err)
 	}
+	if r.opts.recordEncoding {
+		maybeMetaLen.Position.preamble = preamble
+	}
 
 	metaElems := []*Element{maybeMetaLen} // TODO: maybe set capacity to a reasonable initial size
 	metaElementGroupLengthDefined := true
@@ -245,12 +262,17 @@ func (r *reader) readPixelData(vl uint32, d *Dataset, fc chan<- *frame.Frame) (V
 	if vl == tag.VLUndefinedLength {
 		var image PixelDataInfo
 		image.IsEncapsulated = true
-		// The first Item in PixelData is the basic offset table. Skip this for now.
+		// The first Item in PixelData is the basic offset table. Skip this for
+		// now, unless it is needed to write the PixelData as read.
 		// TODO: use basic offset table
-		_, _, err := r.readRawItem(true /*shouldSkip*/)
+		readTable := r.opts.recordEncoding && !r.opts.skipPixelData
+		table, _, err := r.readRawItem(!readTable /*shouldSkip*/)
 		if err != nil {
 			return nil, fmt.Errorf("readPixelData: error skipping basic offset table: %w", err)
 		}
+		for i := 0; i+4 <= len(table); i += 4 {
+			image.Offsets = append(image.Offsets, r.rawReader.ByteOrder().Uint32(table[i:]))
+		}
 
 		for !r.rawReader.IsLimitExhausted() {
 			data, endOfItems, err := r.readRawItem(r.opts.skipPixelData /*shouldSkip*/)
@@ -454,7 +476,6 @@ func (r *reader) readNativeFrames(parsedData *Dataset, fc chan<- *frame.Frame, v
 	}
 	image.Frames = make([]*frame.Frame, nFrames)
 	bo := r.rawReader.ByteOrder()
-	pixelBuf := make([]byte, bytesAllocated)
 	for frameIdx := 0; frameIdx < nFrames; frameIdx++ {
 		// Init current frame
 		currentFrame := frame.Frame{
@@ -476,11 +497,11 @@ func (r *reader) readNativeFrames(parsedData *Dataset, fc chan<- *frame.Frame, v
 		} else {
 			switch bitsAllocated {
 			case 8:
-				currentFrame, _, err = readNativeFrame[uint8](bitsAllocated, MustGetInts(rows.Value)[0], MustGetInts(cols.Value)[0], bytesToRead, samplesPerPixel, pixelsPerFrame, pixelBuf, r.rawReader)
+				currentFrame, _, err = readNativeFrame[uint8](bitsAllocated, MustGetInts(rows.Value)[0], MustGetInts(cols.Value)[0], bytesToRead, samplesPerPixel, pixelsPerFrame, r.rawReader)
 			case 16:
-				currentFrame, _, err = readNativeFrame[uint16](bitsAllocated, MustGetInts(rows.Value)[0], MustGetInts(cols.Value)[0], bytesToRead, samplesPerPixel, pixelsPerFrame, pixelBuf, r.rawReader)
+				currentFrame, _, err = readNativeFrame[uint16](bitsAllocated, MustGetInts(rows.Value)[0], MustGetInts(cols.Value)[0], bytesToRead, samplesPerPixel, pixelsPerFrame, r.rawReader)
 			case 32:
-				currentFrame, _, err = readNativeFrame[uint32](bitsAllocated, MustGetInts(rows.Value)[0], MustGetInts(cols.Value)[0], bytesToRead, samplesPerPixel, pixelsPerFrame, pixelBuf, r.rawReader)
+				currentFrame, _, err = readNativeFrame[uint32](bitsAllocated, MustGetInts(rows.Value)[0], MustGetInts(cols.Value)[0], bytesToRead, samplesPerPixel, pixelsPerFrame, r.rawReader)
 			default:
 				return nil, bytesToRead, fmt.Errorf("unsupported bitsAllocated, got: %v, %w", bitsAllocated, ErrorUnsupportedBitsAllocated)
 			}
@@ -503,46 +524,21 @@ func (r *reader) readNativeFrames(parsedData *Dataset, fc chan<- *frame.Frame, v
 	return &image, bytesToRead, nil
 }
 
-// readNativeFrame builds and reads a single NativeFrame[I] from the rawReader.
+// readNativeFrame builds and reads a single NativeFrame[I] from the rawReader,
+// reading the whole frame at once directly into its samples, and converting
+// them in place if they are not encoded in the byte order of the host.
 // TODO(suyashkumar): refactor args to an options struct, or something more compact and readable.
-func readNativeFrame[I constraints.Integer](bitsAllocated, rows, cols, bytesToRead, samplesPerPixel, pixelsPerFrame int, pixelBuf []byte, rawReader *dicomio.Reader) (frame.Frame, int, error) {
+func readNativeFrame[I constraints.Integer](bitsAllocated, rows, cols, bytesToRead, samplesPerPixel, pixelsPerFrame int, rawReader *dicomio.Reader) (frame.Frame, int, error) {
+	if sampleSize[I]()*8 != bitsAllocated {
+		return frame.Frame{}, bytesToRead, fmt.Errorf("readNativeFrame unsupported bitsAllocated=%d for %T samples: %w", bitsAllocated, *new(I), ErrorUnsupportedBitsAllocated)
+	}
 	nativeFrame := frame.NewNativeFrame[I](bitsAllocated, rows, cols, pixelsPerFrame, samplesPerPixel)
 	currentFrame := frame.Frame{
 		Encapsulated: false,
 		NativeData:   nativeFrame,
 	}
-
-	bo := rawReader.ByteOrder()
-	for pixel := 0; pixel < pixelsPerFrame; pixel++ {
-		for value := 0; value < samplesPerPixel; value++ {
-			_, err := io.ReadFull(rawReader, pixelBuf)
-			if err != nil {
-				return frame.Frame{}, bytesToRead,
-					fmt.Errorf("could not read uint%d from input: %w", bitsAllocated, err)
-			}
-			switch bitsAllocated {
-			case 8:
-				v, ok := any(pixelBuf[0]).(I)
-				if !ok {
-					return frame.Frame{}, bytesToRead, fmt.Errorf("internal error - readNativeFrame unexpectedly unable to type cast pixel buffer data to the I type (%T), where bitsAllocated=%v", *new(I), bitsAllocated)
-				}
-				nativeFrame.RawData[(pixel*samplesPerPixel)+value] = v
-			case 16:
-				v, ok := any(bo.Uint16(pixelBuf)).(I)
-				if !ok {
-					return frame.Frame{}, bytesToRead, fmt.Errorf("internal error - readNativeFrame unexpectedly unable to type cast pixel buffer data to the I type (%T), where bitsAllocated=%v", *new(I), bitsAllocated)
-				}
-				nativeFrame.RawData[(pixel*samplesPerPixel)+value] = v
-			case 32:
-				v, ok := any(bo.Uint32(pixelBuf)).(I)
-				if !ok {
-					return frame.Frame{}, bytesToRead, fmt.Errorf("internal error - readNativeFrame unexpectedly unable to type cast pixel buffer data to the I type (%T), where bitsAllocated=%v", *new(I), bitsAllocated)
-				}
-				nativeFrame.RawData[(pixel*samplesPerPixel)+value] = v
-			default:
-				return frame.Frame{}, bytesToRead, fmt.Errorf("readNativeFrame unsupported bitsAllocated=%d : %w", bitsAllocated, ErrorUnsupportedBitsAllocated)
-			}
-		}
+	if err := readSamples(nativeFrame.RawData, rawReader, rawReader.ByteOrder()); err != nil {
+		return frame.Frame{}, bytesToRead, fmt.Errorf("could not read uint%d from input: %w", bitsAllocated, err)
 	}
 	return currentFrame, bytesToRead, nil
 }
@@ -550,7 +546,7 @@ func readNativeFrame[I constraints.Integer](bitsAllocated, rows, cols, bytesToRe
 // readSequence reads a sequence element (VR = SQ) that contains a subset of Items. Each item contains
 // a set of Elements.
 // See https://dicom.nema.org/medical/dicom/current/output/chtml/part05/sect_7.5.2.html#table_7.5-1
-func (r *reader) readSequence(t tag.Tag, vr string, vl uint32, d *Dataset) (Value, error) {
+func (r *reader) readSequence(t tag.Tag, vr tag.VR, vl uint32, d *Dataset) (Value, error) {
 	var sequences sequencesValue
 
 	seqElements := &Dataset{}
@@ -599,7 +595,7 @@ func (r *reader) readSequence(t tag.Tag, vr string, vl uint32, d *Dataset) (Valu
 
 // readSequenceItem reads an item component of a sequence dicom element and returns an Element
 // with a SequenceItem value.
-func (r *reader) readSequenceItem(t tag.Tag, vr string, vl uint32, d *Dataset) (Value, error) {
+func (r *reader) readSequenceItem(t tag.Tag, vr tag.VR, vl uint32, d *Dataset) (Value, error) {
 	var sequenceItem SequenceItemValue
 
 	// seqElements holds items read so far.
@@ -640,39 +636,54 @@ func (r *reader) readSequenceItem(t tag.Tag, vr string, vl uint32, d *Dataset) (
 	return &sequenceItem, nil
 }
 
-func (r *reader) readBytes(t tag.Tag, vr string, vl uint32) (Value, error) {
+func (r *reader) readBytes(t tag.Tag, vr tag.VR, vl uint32) (Value, error) {
 	// TODO: add special handling of PixelData
-	if vr == vrraw.OtherByte || vr == vrraw.Unknown {
+	wordSize := vr.WordSize()
+	if wordSize == 0 {
+		return nil, fmt.Errorf("error reading bytes element (%v): %w", t, ErrorUnsupportedVR)
+	}
+	if wordSize == 1 {
 		data := make([]byte, vl)
 		_, err := io.ReadFull(r.rawReader, data)
 		return &bytesValue{value: data}, err
-	} else if vr == vrraw.OtherWord {
-		// OW -> stream of 16 bit words
-		if vl%2 != 0 {
+	}
+
+	// Streams of 16, 32 or 64 bit words, e.g. OW.
+	if vl%uint32(wordSize) != 0 {
+		if vr == tag.OW {
 			return nil, fmt.Errorf("error reading bytes element (%v) value: %w", t, ErrorOWRequiresEvenVL)
 		}
-
-		buf := bytes.NewBuffer(make([]byte, 0, vl))
-		numWords := int(vl / 2)
-		for i := 0; i < numWords; i++ {
-			word, err := r.rawReader.ReadUInt16()
-			if err != nil {
-				return nil, fmt.Errorf("error reading bytes element (%v) value: %w", t, err)
-			}
-			// TODO: support bytes.BigEndian byte ordering
-			err = binary.Write(buf, binary.LittleEndian, word)
-			if err != nil {
-				return nil, err
-			}
+		return nil, fmt.Errorf("error reading bytes element (%v) value: length %d is not a multiple of %d", t, vl, wordSize)
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, vl))
+	for i := 0; i < int(vl)/wordSize; i++ {
+		var word any
+		var err error
+		switch wordSize {
+		case 2:
+			word, err = r.rawReader.ReadUInt16()
+		case 4:
+			word, err = r.rawReader.ReadUInt32()
+		default:
+			word, err = r.rawReader.ReadUInt64()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading bytes element (%v) value: %w", t, err)
+		}
+		// TODO: support bytes.BigEndian byte ordering
+		if err := binary.Write(buf, binary.LittleEndian, word); err != nil {
+			return nil, err
 		}
-		return &bytesValue{value: buf.Bytes()}, nil
 	}
-
-	return nil, fmt.Errorf("error reading bytes element (%v): %w", t, ErrorUnsupportedVR)
+	return &bytesValue{value: buf.Bytes()}, nil
 }
 
-func (r *reader) readString(t tag.Tag, vr string, vl uint32) (Value, error) {
-	str, err := r.rawReader.ReadString(vl)
+func (r *reader) readString(t tag.Tag, vr tag.VR, vl uint32) (Value, error) {
+	read := r.rawReader.ReadString
+	if vr == tag.PN {
+		read = r.rawReader.ReadPersonName
+	}
+	str, err := read(vl)
 	if err != nil {
 		return nil, fmt.Errorf("error reading string element (%v) value: %w", t, err)
 	}
@@ -692,7 +703,7 @@ func (r *reader) readString(t tag.Tag, vr string, vl uint32) (Value, error) {
 	return &stringsValue{value: strs}, nil
 }
 
-func (r *reader) readFloat(t tag.Tag, vr string, vl uint32) (Value, error) {
+func (r *reader) readFloat(t tag.Tag, vr tag.VR, vl uint32) (Value, error) {
 	err := r.rawReader.PushLimit(int64(vl))
 	if err != nil {
 		return nil, err
@@ -700,7 +711,7 @@ func (r *reader) readFloat(t tag.Tag, vr string, vl uint32) (Value, error) {
 	retVal := &floatsValue{value: make([]float64, 0, vl/2)}
 	for !r.rawReader.IsLimitExhausted() {
 		switch vr {
-		case vrraw.FloatingPointSingle:
+		case tag.FL:
 			val, err := r.rawReader.ReadFloat32()
 			if err != nil {
 				return nil, fmt.Errorf("error reading floating point element (%v) value: %w", t, err)
@@ -714,7 +725,7 @@ func (r *reader) readFloat(t tag.Tag, vr string, vl uint32) (Value, error) {
 			}
 			retVal.value = append(retVal.value, pval)
 			break
-		case vrraw.FloatingPointDouble:
+		case tag.FD:
 			val, err := r.rawReader.ReadFloat64()
 			if err != nil {
 				return nil, fmt.Errorf("error reading floating point element (%v) value: %w", t, err)
@@ -729,7 +740,7 @@ func (r *reader) readFloat(t tag.Tag, vr string, vl uint32) (Value, error) {
 	return retVal, nil
 }
 
-func (r *reader) readDate(t tag.Tag, vr string, vl uint32) (Value, error) {
+func (r *reader) readDate(t tag.Tag, vr tag.VR, vl uint32) (Value, error) {
 	rawDate, err := r.rawReader.ReadString(vl)
 	if err != nil {
 		return nil, fmt.Errorf("error reading date element (%v) value: %w", t, err)
@@ -740,7 +751,7 @@ func (r *reader) readDate(t tag.Tag, vr string, vl uint32) (Value, error) {
 
 }
 
-func (r *reader) readInt(t tag.Tag, vr string, vl uint32) (Value, error) {
+func (r *reader) readInt(t tag.Tag, vr tag.VR, vl uint32) (Value, error) {
 	// TODO: add other integer types here
 	err := r.rawReader.PushLimit(int64(vl))
 	if err != nil {
@@ -749,28 +760,28 @@ func (r *reader) readInt(t tag.Tag, vr string, vl uint32) (Value, error) {
 	retVal := &intsValue{value: make([]int, 0, vl/2)}
 	for !r.rawReader.IsLimitExhausted() {
 		switch vr {
-		case vrraw.UnsignedShort, vrraw.AttributeTag:
+		case tag.US:
 			val, err := r.rawReader.ReadUInt16()
 			if err != nil {
 				return nil, fmt.Errorf("error reading int element (%v) value (ReadUInt16): %w", t, err)
 			}
 			retVal.value = append(retVal.value, int(val))
 			break
-		case vrraw.UnsignedLong:
+		case tag.UL:
 			val, err := r.rawReader.ReadUInt32()
 			if err != nil {
 				return nil, fmt.Errorf("error reading int element (%v) value (ReadUInt32): %w", t, err)
 			}
 			retVal.value = append(retVal.value, int(val))
 			break
-		case vrraw.SignedLong:
+		case tag.SL:
 			val, err := r.rawReader.ReadInt32()
 			if err != nil {
 				return nil, fmt.Errorf("error reading int element (%v) value (ReadInt32): %w", t, err)
 			}
 			retVal.value = append(retVal.value, int(val))
 			break
-		case vrraw.SignedShort:
+		case tag.SS:
 			val, err := r.rawReader.ReadInt16()
 			if err != nil {
 				return nil, fmt.Errorf("error reading int element (%v) value (ReadInt16): %w", t, err)
@@ -785,12 +796,62 @@ func (r *reader) readInt(t tag.Tag, vr string, vl uint32) (Value, error) {
 	return retVal, err
 }
 
+// readTags reads the (group, element) pairs of AT elements.
+func (r *reader) readTags(t tag.Tag, vl uint32) (Value, error) {
+	if vl%4 != 0 {
+		return nil, fmt.Errorf("error reading AT element (%v) value: length %d is not a multiple of 4", t, vl)
+	}
+	tags := make([]tag.Tag, 0, vl/4)
+	for i := uint32(0); i < vl/4; i++ {
+		group, err := r.rawReader.ReadUInt16()
+		if err != nil {
+			return nil, fmt.Errorf("error reading AT element (%v) value (ReadUInt16): %w", t, err)
+		}
+		element, err := r.rawReader.ReadUInt16()
+		if err != nil {
+			return nil, fmt.Errorf("error reading AT element (%v) value (ReadUInt16): %w", t, err)
+		}
+		tags = append(tags, tag.Tag{Group: group, Element: element})
+	}
+	return &tagsValue{value: tags}, nil
+}
+
+// readVeryLong reads the 64-bit integers of SV and UV elements, which need
+// int64 and uint64 values to not lose precision.
+func (r *reader) readVeryLong(t tag.Tag, vr tag.VR, vl uint32) (Value, error) {
+	if vl%8 != 0 {
+		return nil, fmt.Errorf("error reading %v element (%v) value: length %d is not a multiple of 8", vr, t, vl)
+	}
+	n := int(vl / 8)
+	if vr == tag.SV {
+		values := make([]int64, 0, n)
+		for i := 0; i < n; i++ {
+			val, err := r.rawReader.ReadInt64()
+			if err != nil {
+				return nil, fmt.Errorf("error reading int element (%v) value (ReadInt64): %w", t, err)
+			}
+			values = append(values, val)
+		}
+		return &int64sValue{value: values}, nil
+	}
+	values := make([]uint64, 0, n)
+	for i := 0; i < n; i++ {
+		val, err := r.rawReader.ReadUInt64()
+		if err != nil {
+			return nil, fmt.Errorf("error reading int element (%v) value (ReadUInt64): %w", t, err)
+		}
+		values = append(values, val)
+	}
+	return &uint64sValue{value: values}, nil
+}
+
 // readElement reads the next element. If the next element is a sequence element,
 // it may result in a collection of Elements. It takes a pointer to the Dataset of
 // elements read so far, since previously read elements may be needed to parse
 // certain Elements (like native PixelData). If the Dataset is nil, it is
 // treated as an empty Dataset.
 func (r *reader) readElement(d *Dataset, fc chan<- *frame.Frame) (*Element, error) {
+	offset := r.rawReader.BytesRead()
 	t, err := r.readTag()
 	if err != nil {
 		return nil, fmt.Errorf("readElement: error when reading element tag: %w", err)
@@ -803,11 +864,21 @@ func (r *reader) readElement(d *Dataset, fc chan<- *frame.Frame) (*Element, erro
 		readImplicit = true
 	}
 
-	vr, err := r.readVR(readImplicit, *t)
+	vr, rawVR, err := r.readVR(readImplicit, *t)
 	if err != nil {
 		return nil, fmt.Errorf("readElement: error when reading VR for element %v: %w", t, err)
 	}
-	debug.Logf("readElement: vr: %s", vr)
+	if readImplicit && vr == tag.UN && d != nil {
+		// Private attributes can only be looked up with the Private Creator
+		// reserving their block, which was read earlier in the Dataset.
+		if creator, err := d.PrivateCreator(*t); err == nil {
+			if info, err := tag.FindPrivate(creator, *t); err == nil {
+				rawVR = info.VRs[0]
+				vr, _ = tag.ParseVR(rawVR)
+			}
+		}
+	}
+	debug.Logf("readElement: vr: %s", rawVR)
 
 	vl, err := r.readVL(readImplicit, *t, vr)
 	if err != nil {
@@ -815,12 +886,50 @@ func (r *reader) readElement(d *Dataset, fc chan<- *frame.Frame) (*Element, erro
 	}
 	debug.Logf("readElement: vl: %d", vl)
 
+	valueOffset := r.rawReader.BytesRead()
+	// Values that are not sequences are recorded as read, see RecordEncoding.
+	// PixelData is written back from its frames instead, as capturing,
+	// encoding and hashing a copy of it would multiply the memory and time
+	// taken to read large images.
+	vrKind := tag.VRKindOf(*t, vr)
+	recordEncoding := r.opts.recordEncoding && vl != tag.VLUndefinedLength &&
+		vrKind != tag.VRSequence && vrKind != tag.VRItem && vrKind != tag.VRPixelData
+	if recordEncoding {
+		r.rawReader.StartCapture()
+	}
 	val, err := r.readValue(*t, vr, vl, readImplicit, d, fc)
+	var raw []byte
+	if recordEncoding {
+		raw = r.rawReader.EndCapture()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("readElement: error when reading value for element %v: %w", t, err)
 	}
+	if r.opts.strictValueMultiplicity {
+		if info, err := tag.Find(*t); err == nil {
+			if err := checkMultiplicity(info, vr, val); err != nil {
+				return nil, fmt.Errorf("readElement: %w", err)
+			}
+		}
+	}
 
-	return &Element{Tag: *t, ValueRepresentation: tag.GetVRKind(*t, vr), RawValueRepresentation: vr, ValueLength: vl, Value: val}, nil
+	elem := &Element{Tag: *t, ValueRepresentation: vrKind, RawValueRepresentation: rawVR, ValueLength: vl, Value: val}
+	if r.opts.recordOffsets {
+		elem.Position = &Position{
+			Offset:      offset,
+			ValueOffset: valueOffset,
+			EndOffset:   r.rawReader.BytesRead(),
+			ValueLength: vl,
+			Implicit:    readImplicit,
+		}
+		if item, ok := val.(*SequenceItemValue); ok {
+			item.position = elem.Position
+		}
+		if recordEncoding {
+			elem.Position.encoding = r.recordEncoding(elem, raw, readImplicit)
+		}
+	}
+	return elem, nil
 
 }
 
@@ -833,7 +942,7 @@ func (r *reader) readRawItem(shouldSkip bool) ([]byte, bool, error) {
 		return nil, true, fmt.Errorf("readRawItem: error when reading item tag: %w", err)
 	}
 	// Item is always encoded implicit. PS3.6 7.5
-	vr, err := r.readVR(true, *t)
+	vr, _, err := r.readVR(true, *t)
 	if err != nil {
 		return nil, true, fmt.Errorf("readRawItem: error when reading VR for item %v: %w", t, err)
 	}
@@ -856,7 +965,7 @@ func (r *reader) readRawItem(shouldSkip bool) ([]byte, bool, error) {
 		log.Println("Expect defined-length item in pixeldata")
 		return nil, false, nil
 	}
-	if vr != "NA" {
+	if vr != tag.NA {
 		return nil, true, fmt.Errorf("readRawItem: expected VR=NA, got VR=%s", vr)
 	}
 
@@ -77,21 +77,21 @@ func TestReadFloat_float64(t *testing.T) {
 	cases := []struct {
 		name        string
 		floats      []float64
-		VR          string
+		VR          tag.VR
 		want        Value
 		expectedErr error
 	}{
 		{
 			name:        "float64",
 			floats:      []float64{20.1, 32.22},
-			VR:          vrraw.FloatingPointDouble,
+			VR:          tag.FD,
 			want:        &floatsValue{value: []float64{20.1, 32.22}},
 			expectedErr: nil,
 		},
 		{
 			name:        "float64 with wrong VR",
 			floats:      []float64{20.1, 32.22},
-			VR:          "XX",
+			VR:          tag.UL,
 			want:        nil,
 			expectedErr: errorUnableToParseFloat,
 		},
@@ -124,21 +124,21 @@ func TestReadFloat_float32(t *testing.T) {
 	cases := []struct {
 		name        string
 		floats      []float32
-		VR          string
+		VR          tag.VR
 		want        Value
 		expectedErr error
 	}{
 		{
 			name:        "float32",
 			floats:      []float32{20.1001, 32.22},
-			VR:          vrraw.FloatingPointSingle,
+			VR:          tag.FL,
 			want:        &floatsValue{value: []float64{20.1001, 32.22}},
 			expectedErr: nil,
 		},
 		{
 			name:        "float32 with wrong VR",
 			floats:      []float32{20.1001, 32.22},
-			VR:          "XX",
+			VR:          tag.UL,
 			want:        nil,
 			expectedErr: errorUnableToParseFloat,
 		},
@@ -167,32 +167,61 @@ func TestReadFloat_float32(t *testing.T) {
 	}
 }
 
+func TestReadVR_Implicit(t *testing.T) {
+	cases := []struct {
+		name string
+		tag  tag.Tag
+		want string
+	}{
+		{name: "dictionary", tag: tag.Rows, want: vrraw.UnsignedShort},
+		{name: "pixel data", tag: tag.PixelData, want: vrraw.OtherWord},
+		{name: "overlay data", tag: tag.OverlayData, want: vrraw.OtherWord},
+		{name: "overlay data in repeating group", tag: tag.Tag{Group: 0x6002, Element: 0x3000}, want: vrraw.OtherWord},
+		{name: "overlay rows in repeating group", tag: tag.Tag{Group: 0x601E, Element: 0x0010}, want: vrraw.UnsignedShort},
+		{name: "curve data in repeating group", tag: tag.Tag{Group: 0x5004, Element: 0x0005}, want: vrraw.UnsignedShort},
+		{name: "unknown", tag: tag.Tag{Group: 0x0011, Element: 0x1010}, want: vrraw.Unknown},
+		{name: "private creator", tag: tag.Tag{Group: 0x0011, Element: 0x0010}, want: vrraw.LongString},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &reader{}
+			got, gotRaw, err := r.readVR(true, tc.tag)
+			if err != nil {
+				t.Fatalf("readVR(true, %v) unexpected error: %v", tc.tag, err)
+			}
+			if want, _ := tag.ParseVR(tc.want); got != want || gotRaw != tc.want {
+				t.Errorf("readVR(true, %v) = %v, %q, want %v", tc.tag, got, gotRaw, tc.want)
+			}
+		})
+	}
+}
+
 func TestReadOWBytes(t *testing.T) {
 	cases := []struct {
 		name        string
 		bytes       []byte
-		VR          string
+		VR          tag.VR
 		want        Value
 		expectedErr error
 	}{
 		{
 			name:        "OW VR with even-number bytes",
 			bytes:       []byte{0x1, 0x2, 0x3, 0x4},
-			VR:          vrraw.OtherWord,
+			VR:          tag.OW,
 			want:        &bytesValue{value: []byte{0x1, 0x2, 0x3, 0x4}},
 			expectedErr: nil,
 		},
 		{
 			name:        "UN VR even-number bytes",
 			bytes:       []byte{0x1, 0x2, 0x3, 0x4},
-			VR:          vrraw.Unknown,
+			VR:          tag.UN,
 			want:        &bytesValue{value: []byte{0x1, 0x2, 0x3, 0x4}},
 			expectedErr: nil,
 		},
 		{
 			name:        "error on odd-number bytes",
 			bytes:       []byte{0x1, 0x2, 0x3},
-			VR:          vrraw.OtherWord,
+			VR:          tag.OW,
 			want:        nil,
 			expectedErr: ErrorOWRequiresEvenVL,
 		},
@@ -822,14 +851,14 @@ func makeEncapsulatedSequence(t *testing.T) []byte {
 	buf := &bytes.Buffer{}
 	w := dicomio.NewWriter(buf, binary.LittleEndian, true)
 
-	writePixelData(w, tag.PixelData, &pixelDataValue{PixelDataInfo{IsEncapsulated: true, Frames: []*frame.Frame{
+	writePixelData(w, &pixelDataValue{PixelDataInfo{IsEncapsulated: true, Frames: []*frame.Frame{
 		{
 			Encapsulated: true,
 			EncapsulatedData: frame.EncapsulatedFrame{
 				Data: []byte{1, 2, 3, 4},
 			},
 		},
-	}}}, "", tag.VLUndefinedLength)
+	}}}, tag.VLUndefinedLength)
 
 	return buf.Bytes()
 
@@ -936,6 +965,7 @@ func BenchmarkReadNativeFrames(b *testing.B) {
 		Cols            int
 		NumFrames       int
 		SamplesPerPixel int
+		ByteOrder       binary.ByteOrder
 	}{
 		{
 			Name:            "10x10, 10 frames, 1 sample/pixel",
@@ -965,22 +995,42 @@ func BenchmarkReadNativeFrames(b *testing.B) {
 			NumFrames:       10,
 			SamplesPerPixel: 5,
 		},
+		{
+			Name:            "512x512, 100 frames, 1 sample/pixel",
+			Rows:            512,
+			Cols:            512,
+			NumFrames:       100,
+			SamplesPerPixel: 1,
+		},
+		{
+			Name:            "512x512, 100 frames, 1 sample/pixel, big endian",
+			Rows:            512,
+			Cols:            512,
+			NumFrames:       100,
+			SamplesPerPixel: 1,
+			ByteOrder:       binary.BigEndian,
+		},
 	}
 	for _, c := range cases {
 		b.Run(c.Name, func(b *testing.B) {
-			dataset, rawReader := buildReadNativeFramesInput(c.Rows, c.Cols, c.NumFrames, c.SamplesPerPixel, b)
-			r := &reader{rawReader: rawReader}
+			bo := c.ByteOrder
+			if bo == nil {
+				bo = binary.LittleEndian
+			}
+			data := buildReadNativeFramesData(c.Rows, c.Cols, c.NumFrames, c.SamplesPerPixel, bo, b)
+			dataset := buildReadNativeFramesDataset(c.Rows, c.Cols, c.NumFrames, c.SamplesPerPixel)
+			b.SetBytes(int64(len(data)))
 			b.ResetTimer()
 			for i := 0; i < b.N; i++ {
-				_, _, _ = r.readNativeFrames(dataset, nil, uint32(c.Rows*c.Cols*c.NumFrames))
+				r := &reader{rawReader: dicomio.NewReader(bufio.NewReader(bytes.NewReader(data)), bo, int64(len(data)))}
+				_, _, _ = r.readNativeFrames(dataset, nil, uint32(len(data)))
 			}
 		})
 	}
 }
 
-func buildReadNativeFramesInput(rows, cols, numFrames, samplesPerPixel int, b *testing.B) (*Dataset, *dicomio.Reader) {
-	b.Helper()
-	dataset := Dataset{
+func buildReadNativeFramesDataset(rows, cols, numFrames, samplesPerPixel int) *Dataset {
+	return &Dataset{
 		Elements: []*Element{
 			mustNewElement(tag.Rows, []int{rows}),
 			mustNewElement(tag.Columns, []int{cols}),
@@ -989,21 +1039,15 @@ func buildReadNativeFramesInput(rows, cols, numFrames, samplesPerPixel int, b *t
 			mustNewElement(tag.SamplesPerPixel, []int{samplesPerPixel}),
 		},
 	}
-	dcmdata := bytes.Buffer{}
-	dcmdata.Grow(2 * numFrames * rows * cols * samplesPerPixel)
-	for fr := 0; fr < numFrames; fr++ {
-		for r := 0; r < rows; r++ {
-			for c := 0; c < cols; c++ {
-				for pxs := 0; pxs < samplesPerPixel; pxs++ {
-					if err := binary.Write(&dcmdata, binary.LittleEndian, uint16(rand.Intn(100))); err != nil {
-						b.Fatalf("TestReadNativeFrames: Unable to setup test buffer")
-					}
-				}
-			}
-		}
-	}
+}
 
-	return &dataset, dicomio.NewReader(bufio.NewReader(&dcmdata), binary.LittleEndian, int64(dcmdata.Len()))
+func buildReadNativeFramesData(rows, cols, numFrames, samplesPerPixel int, bo binary.ByteOrder, b *testing.B) []byte {
+	b.Helper()
+	data := make([]byte, 2*numFrames*rows*cols*samplesPerPixel)
+	for i := 0; i < len(data); i += 2 {
+		bo.PutUint16(data[i:], uint16(rand.Intn(100)))
+	}
+	return data
 }
 
 func buildTagData(t *testing.T, tg tag.Tag) []byte {
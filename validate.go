@@ -0,0 +1,400 @@
+package dicom
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/uid"
+)
+
+// ErrorInvalidDataset is returned when writing with StrictValidation a Dataset
+// for which Validate reports violations. The returned error also wraps each
+// Violation.
+var ErrorInvalidDataset = errors.New("dataset does not conform to VR and VM rules")
+
+// ErrorValueMultiplicity indicates that an element holds a number of values
+// its data dictionary VM does not allow.
+var ErrorValueMultiplicity = errors.New("value multiplicity does not match the VM in the data dictionary")
+
+// PathStep is one step of a Path: an element Tag and, for every step except
+// the last, the index of the sequence item the next step is in.
+type PathStep struct {
+	Tag  tag.Tag
+	Item int
+}
+
+// Path locates an element in a Dataset, including elements nested in
+// sequences.
+type Path []PathStep
+
+// String returns the Path formatted like "(0008,1115)[0].(0008,1150)".
+func (p Path) String() string {
+	var b strings.Builder
+	for i, step := range p {
+		if i > 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(step.Tag.String())
+		if i < len(p)-1 {
+			fmt.Fprintf(&b, "[%d]", step.Item)
+		}
+	}
+	return b.String()
+}
+
+// Tag returns the Tag of the element the Path points to.
+func (p Path) Tag() tag.Tag {
+	if len(p) == 0 {
+		return tag.Tag{}
+	}
+	return p[len(p)-1].Tag
+}
+
+// with returns a copy of p extended with step.
+func (p Path) with(step PathStep) Path {
+	c := make(Path, len(p), len(p)+1)
+	copy(c, p)
+	return append(c, step)
+}
+
+// Violation describes an element that does not conform to the rules of its VR
+// (PS3.5 Section 6.2) or to the VM defined in the data dictionary.
+type Violation struct {
+	// Path is the location of the element in the Dataset.
+	Path Path
+	// VR is the VR the element was validated against.
+	VR string
+	// Message describes the violation.
+	Message string
+}
+
+// Error returns a description of the violation including its Path, so that a
+// Violation can be used as an error.
+func (v Violation) Error() string {
+	return fmt.Sprintf("%v %s: %s", v.Path, v.VR, v.Message)
+}
+
+// Validate checks every element of ds, including elements nested in
+// sequences, against the rules of its VR: maximum value lengths, allowed
+// character repertoires and formats, value ranges and padding. Elements with
+// a dictionary entry are also checked against their tag.Info.Multiplicity.
+// Empty values are always allowed. A nil result means no violations were
+// found.
+func Validate(ds Dataset) []Violation {
+	var violations []Violation
+	validateElements(ds.Elements, nil, &violations)
+	return violations
+}
+
+func validateElements(elems []*Element, parent Path, violations *[]Violation) {
+	for _, elem := range elems {
+		validateElement(elem, parent, violations)
+	}
+}
+
+func validateElement(elem *Element, parent Path, violations *[]Violation) {
+	path := parent.with(PathStep{Tag: elem.Tag})
+	rawVR := elem.RawValueRepresentation
+	info, infoErr := tag.Find(elem.Tag)
+	if rawVR == "" && infoErr == nil {
+		rawVR = info.VRs[0]
+	}
+	vr, _ := tag.ParseVR(rawVR)
+	report := func(format string, args ...any) {
+		*violations = append(*violations, Violation{Path: path, VR: rawVR, Message: fmt.Sprintf(format, args...)})
+	}
+	if elem.Value == nil {
+		return
+	}
+
+	switch elem.Value.ValueType() {
+	case Strings:
+		values := MustGetStrings(elem.Value)
+		for i, v := range values {
+			if msg := validateString(v, vr); msg != "" {
+				report("value %d %q: %s", i, v, msg)
+			}
+		}
+		if msg := validatePadding(values, vr); msg != "" {
+			report("%s", msg)
+		}
+	case Ints:
+		min, max := intRange(vr)
+		for i, v := range MustGetInts(elem.Value) {
+			if int64(v) < min || int64(v) > max {
+				report("value %d (%d) is out of range [%d, %d]", i, v, min, max)
+			}
+		}
+	case Int64s:
+		min, max := intRange(vr)
+		for i, v := range MustGetInt64s(elem.Value) {
+			if v < min || v > max {
+				report("value %d (%d) is out of range [%d, %d]", i, v, min, max)
+			}
+		}
+	case Uint64s:
+		if vr == tag.UV {
+			break
+		}
+		min, max := intRange(vr)
+		for i, v := range MustGetUint64s(elem.Value) {
+			if v > uint64(max) {
+				report("value %d (%d) is out of range [%d, %d]", i, v, min, max)
+			}
+		}
+	case Bytes:
+		if vr.WordSize() > 1 && len(MustGetBytes(elem.Value))%vr.WordSize() != 0 {
+			report("value length %d is not a multiple of %d", len(MustGetBytes(elem.Value)), vr.WordSize())
+		}
+	case Sequences:
+		for i, item := range elem.Value.GetValue().([]*SequenceItemValue) {
+			validateElements(item.elements, parent.with(PathStep{Tag: elem.Tag, Item: i}), violations)
+		}
+	}
+
+	if infoErr == nil {
+		if err := checkMultiplicity(info, vr, elem.Value); err != nil {
+			report("value multiplicity %d does not match VM %v", valueMultiplicity(elem.Value, vr), info.Multiplicity)
+		}
+	}
+}
+
+// valueMultiplicity returns the number of values of v to check against a VM,
+// or 0 if v is empty or the VM does not apply to v. Multi-valued numeric VRs
+// are binary, so their VM is only checked when v holds a count of values.
+func valueMultiplicity(v Value, vr tag.VR) int {
+	if v == nil || isSingleValuedVR(vr) {
+		return 0
+	}
+	switch v.ValueType() {
+	case Strings:
+		values := MustGetStrings(v)
+		if len(values) == 1 && values[0] == "" {
+			return 0
+		}
+		return len(values)
+	case Ints:
+		if vr == tag.AT {
+			return len(MustGetInts(v)) / 2
+		}
+		return len(MustGetInts(v))
+	case Tags:
+		return len(MustGetTags(v))
+	case Int64s:
+		return len(MustGetInt64s(v))
+	case Uint64s:
+		return len(MustGetUint64s(v))
+	case Floats:
+		return len(MustGetFloats(v))
+	default:
+		return 0
+	}
+}
+
+// checkMultiplicity returns an error wrapping ErrorValueMultiplicity if the
+// number of values of v is not allowed by the VM of info.
+func checkMultiplicity(info tag.Info, vr tag.VR, v Value) error {
+	if n := valueMultiplicity(v, vr); !info.Multiplicity.Allows(n) {
+		return fmt.Errorf("%w: %v %s has %d values, VM is %v", ErrorValueMultiplicity, info.Tag, info.Keyword, n, info.Multiplicity)
+	}
+	return nil
+}
+
+var (
+	asRegex = regexp.MustCompile(`^\d{3}[DWMY]$`)
+	csRegex = regexp.MustCompile(`^[A-Z0-9 _]*$`)
+	daRegex = regexp.MustCompile(`^\d{8}$`)
+	dsRegex = regexp.MustCompile(`^ *[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)? *$`)
+	dtRegex = regexp.MustCompile(`^\d{4}(\d{2}(\d{2}(\d{2}(\d{2}(\d{2}(\.\d{1,6})?)?)?)?)?)?([+-]\d{4})?$`)
+	isRegex = regexp.MustCompile(`^ *[+-]?\d+ *$`)
+	tmRegex = regexp.MustCompile(`^\d{2}(\d{2}(\d{2}(\.\d{1,6})?)?)?$`)
+)
+
+// validateString returns a description of the problem with the single value v
+// of the given VR, or an empty string if v is valid.
+func validateString(v string, vr tag.VR) string {
+	if vr == tag.UI {
+		if strings.HasSuffix(v, " ") {
+			return "UI values must be padded with NULL, not space"
+		}
+		v = strings.TrimRight(v, "\x00")
+	} else if strings.HasSuffix(v, "\x00") {
+		return "value must be padded with space, not NULL"
+	}
+	if v == "" {
+		return ""
+	}
+
+	// The maximum length of PN applies to each component group, see below.
+	if vr.MaxLength() > 0 && vr != tag.PN {
+		length := len(v)
+		if vr.IsCharacterSetAffected() {
+			length = utf8.RuneCountInString(v)
+		}
+		if length > vr.MaxLength() {
+			return fmt.Sprintf("length %d exceeds maximum of %d", length, vr.MaxLength())
+		}
+	}
+
+	trimmed := strings.TrimRight(v, " ")
+	switch vr {
+	case tag.AE:
+		if strings.TrimSpace(v) == "" {
+			return "value must not consist solely of spaces"
+		}
+		return checkRepertoire(v, false)
+	case tag.AS:
+		if !asRegex.MatchString(v) {
+			return "value is not of the form nnnD, nnnW, nnnM or nnnY"
+		}
+	case tag.CS:
+		if !csRegex.MatchString(v) {
+			return "value contains characters other than uppercase letters, digits, space and underscore"
+		}
+	case tag.DA:
+		if !daRegex.MatchString(trimmed) {
+			return "value is not of the form YYYYMMDD"
+		}
+		if _, err := time.Parse("20060102", trimmed); err != nil {
+			return "value is not a valid date"
+		}
+	case tag.DT:
+		if !dtRegex.MatchString(trimmed) {
+			return "value is not of the form YYYYMMDDHHMMSS.FFFFFF&ZZXX"
+		}
+	case tag.DS:
+		if !dsRegex.MatchString(v) {
+			return "value is not a decimal number"
+		}
+	case tag.IS:
+		if !isRegex.MatchString(v) {
+			return "value is not an integer"
+		}
+		i, err := strconv.ParseInt(strings.Trim(v, " "), 10, 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return "value is not an integer"
+		}
+		if err != nil || i < math.MinInt32 || i > math.MaxInt32 {
+			return "value is out of range [-2^31, 2^31-1]"
+		}
+	case tag.TM:
+		if !tmRegex.MatchString(trimmed) {
+			return "value is not of the form HHMMSS.FFFFFF"
+		}
+	case tag.UI:
+		if err := uid.Validate(v); err != nil {
+			return err.Error()
+		}
+	case tag.PN:
+		groups := strings.Split(v, "=")
+		if len(groups) > 3 {
+			return "value has more than 3 component groups"
+		}
+		for _, g := range groups {
+			if utf8.RuneCountInString(g) > 64 {
+				return fmt.Sprintf("component group length %d exceeds maximum of 64", utf8.RuneCountInString(g))
+			}
+			if strings.Count(g, "^") > 4 {
+				return "component group has more than 5 components"
+			}
+		}
+		return checkRepertoire(v, false)
+	case tag.LO, tag.SH, tag.UC:
+		return checkRepertoire(v, false)
+	case tag.LT, tag.ST, tag.UT:
+		return checkRepertoire(v, true)
+	}
+	return ""
+}
+
+// validatePadding returns a description of the problem with the padding of
+// values, or an empty string if it is valid. Values are joined with
+// backslashes and padded to an even length with the padding of vr when
+// written, so NULL padding of UI values is only allowed as a single trailing
+// character of the last value that brings the element to an even length.
+func validatePadding(values []string, vr tag.VR) string {
+	if vr != tag.UI || len(values) == 0 {
+		return ""
+	}
+	for _, v := range values[:len(values)-1] {
+		if strings.HasSuffix(v, "\x00") {
+			return "only the last value may be padded"
+		}
+	}
+	last := values[len(values)-1]
+	if !strings.HasSuffix(last, "\x00") {
+		return ""
+	}
+	length := len(values) - 1
+	for _, v := range values {
+		length += len(v)
+	}
+	if strings.HasSuffix(last, "\x00\x00") || length%2 != 0 {
+		return fmt.Sprintf("value length %d is not padded to an even length with a single NULL", length)
+	}
+	return ""
+}
+
+// checkRepertoire checks that v does not contain control characters other
+// than ESC (used for ISO 2022 code extensions), and for text VRs TAB, LF, FF
+// and CR.
+func checkRepertoire(v string, text bool) string {
+	for _, r := range v {
+		if r >= 0x20 && r != 0x7F || r == 0x1B {
+			continue
+		}
+		if text && (r == '\t' || r == '\n' || r == '\f' || r == '\r') {
+			continue
+		}
+		return fmt.Sprintf("value contains control character %q", r)
+	}
+	return ""
+}
+
+// isSingleValuedVR returns true for VRs whose VM is always 1 because a
+// backslash is not a value delimiter for them.
+func isSingleValuedVR(vr tag.VR) bool {
+	switch vr {
+	case tag.LT, tag.ST, tag.UT, tag.UR:
+		return true
+	}
+	return false
+}
+
+// intRange returns the range of the integers that can be encoded with vr.
+// Integers of SV are held in Int64s, which cannot hold values out of its range,
+// and those of UV in Uint64s, which are not checked against it.
+func intRange(vr tag.VR) (int64, int64) {
+	switch vr {
+	case tag.US, tag.AT:
+		return 0, math.MaxUint16
+	case tag.SS:
+		return math.MinInt16, math.MaxInt16
+	case tag.UL:
+		return 0, math.MaxUint32
+	case tag.SL:
+		return math.MinInt32, math.MaxInt32
+	default:
+		return math.MinInt64, math.MaxInt64
+	}
+}
+
+// validationError returns an error wrapping ErrorInvalidDataset and each of
+// the violations, or nil if there are none.
+func validationError(violations []Violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	errs := make([]error, 0, len(violations))
+	for _, v := range violations {
+		errs = append(errs, v)
+	}
+	return fmt.Errorf("%w: %w", ErrorInvalidDataset, errors.Join(errs...))
+}
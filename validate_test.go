@@ -0,0 +1,273 @@
+package dicom
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/vrraw"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name     string
+		elem     *Element
+		wantPath string
+		wantMsg  string
+	}{
+		{
+			name:     "LO too long",
+			elem:     mustNewElement(tag.InstitutionName, []string{strings.Repeat("a", 65)}),
+			wantPath: "(0008,0080)",
+			wantMsg:  "length 65 exceeds maximum of 64",
+		},
+		{
+			name:    "LO max length in characters",
+			elem:    mustNewElement(tag.InstitutionName, []string{strings.Repeat("病", 64)}),
+			wantMsg: "",
+		},
+		{
+			name:     "SH too long",
+			elem:     mustNewElement(tag.StationName, []string{strings.Repeat("a", 17)}),
+			wantPath: "(0008,1010)",
+			wantMsg:  "length 17 exceeds maximum of 16",
+		},
+		{
+			name:     "CS lowercase",
+			elem:     mustNewElement(tag.Modality, []string{"ct"}),
+			wantPath: "(0008,0060)",
+			wantMsg:  "characters other than uppercase letters",
+		},
+		{
+			name:     "UI letters",
+			elem:     mustNewElement(tag.SOPInstanceUID, []string{"1.2.abc"}),
+			wantPath: "(0008,0018)",
+			wantMsg:  "invalid UID component",
+		},
+		{
+			name:     "UI leading zero",
+			elem:     mustNewElement(tag.SOPInstanceUID, []string{"1.02.3"}),
+			wantPath: "(0008,0018)",
+			wantMsg:  "leading zero",
+		},
+		{
+			name:     "UI space padded",
+			elem:     mustNewElement(tag.SOPInstanceUID, []string{"1.2.3 "}),
+			wantPath: "(0008,0018)",
+			wantMsg:  "padded with NULL",
+		},
+		{
+			name:     "DA format",
+			elem:     mustNewElement(tag.StudyDate, []string{"2020AB02"}),
+			wantPath: "(0008,0020)",
+			wantMsg:  "YYYYMMDD",
+		},
+		{
+			name:     "DA invalid date",
+			elem:     mustNewElement(tag.StudyDate, []string{"20201302"}),
+			wantPath: "(0008,0020)",
+			wantMsg:  "not a valid date",
+		},
+		{
+			name:    "DA valid",
+			elem:    mustNewElement(tag.StudyDate, []string{"20200102"}),
+			wantMsg: "",
+		},
+		{
+			name:     "TM format",
+			elem:     mustNewElement(tag.StudyTime, []string{"10:10:10"}),
+			wantPath: "(0008,0030)",
+			wantMsg:  "HHMMSS",
+		},
+		{
+			name:     "DS format",
+			elem:     mustNewElement(tag.SliceThickness, []string{"1,5"}),
+			wantPath: "(0018,0050)",
+			wantMsg:  "not a decimal number",
+		},
+		{
+			name:     "IS range",
+			elem:     mustNewElement(tag.SeriesNumber, []string{"2147483648"}),
+			wantPath: "(0020,0011)",
+			wantMsg:  "out of range",
+		},
+		{
+			name:     "IS sign separated",
+			elem:     mustNewElement(tag.SeriesNumber, []string{"+ 1"}),
+			wantPath: "(0020,0011)",
+			wantMsg:  "not an integer",
+		},
+		{
+			name:    "IS padded",
+			elem:    mustNewElement(tag.SeriesNumber, []string{" -12 "}),
+			wantMsg: "",
+		},
+		{
+			name:     "CS NULL padded",
+			elem:     mustNewElement(tag.Modality, []string{"CT\x00"}),
+			wantPath: "(0008,0060)",
+			wantMsg:  "padded with space, not NULL",
+		},
+		{
+			name:    "UI NULL padded to even length",
+			elem:    mustNewElement(tag.SOPInstanceUID, []string{"1.2.3\x00"}),
+			wantMsg: "",
+		},
+		{
+			name:     "UI NULL padded to odd length",
+			elem:     mustNewElement(tag.SOPInstanceUID, []string{"1.2.34\x00"}),
+			wantPath: "(0008,0018)",
+			wantMsg:  "not padded to an even length with a single NULL",
+		},
+		{
+			name:     "UI padded twice",
+			elem:     mustNewElement(tag.SOPInstanceUID, []string{"1.2.3\x00\x00"}),
+			wantPath: "(0008,0018)",
+			wantMsg:  "not padded to an even length with a single NULL",
+		},
+		{
+			name:     "UI padding before the last value",
+			elem:     mustNewElement(tag.RelatedGeneralSOPClassUID, []string{"1.2.3\x00", "1.2.4"}),
+			wantPath: "(0008,001a)",
+			wantMsg:  "only the last value may be padded",
+		},
+		{
+			name:     "SS range of Int64s",
+			elem:     mustNewPrivateElement(tag.SelectorSSValue, vrraw.SignedShort, []int64{40000}),
+			wantPath: "(0072,007e)",
+			wantMsg:  "out of range",
+		},
+		{
+			name:    "SV full range",
+			elem:    mustNewElement(tag.SelectorSVValue, []int64{math.MinInt64, math.MaxInt64}),
+			wantMsg: "",
+		},
+		{
+			name:    "UV full range",
+			elem:    mustNewElement(tag.SelectorUVValue, []uint64{0, math.MaxUint64}),
+			wantMsg: "",
+		},
+		{
+			name:     "AS format",
+			elem:     mustNewElement(tag.PatientAge, []string{"42Y"}),
+			wantPath: "(0010,1010)",
+			wantMsg:  "nnnD",
+		},
+		{
+			name:     "US range",
+			elem:     mustNewElement(tag.Rows, []int{70000}),
+			wantPath: "(0028,0010)",
+			wantMsg:  "out of range",
+		},
+		{
+			name:     "VM too many values",
+			elem:     mustNewPrivateElement(tag.Modality, vrraw.CodeString, []string{"CT", "MR"}),
+			wantPath: "(0008,0060)",
+			wantMsg:  "value multiplicity 2 does not match VM 1",
+		},
+		{
+			name:     "VM not a multiple",
+			elem:     mustNewPrivateElement(tag.ImagePositionPatient, vrraw.DecimalString, []string{"1", "2"}),
+			wantPath: "(0020,0032)",
+			wantMsg:  "value multiplicity 2 does not match VM 3",
+		},
+		{
+			name:    "VM 1-n",
+			elem:    mustNewElement(tag.ImageType, []string{"ORIGINAL", "PRIMARY", "AXIAL"}),
+			wantMsg: "",
+		},
+		{
+			name:    "empty value",
+			elem:    mustNewElement(tag.PatientName, []string{""}),
+			wantMsg: "",
+		},
+		{
+			name:     "PN too many groups",
+			elem:     mustNewElement(tag.PatientName, []string{"a=b=c=d"}),
+			wantPath: "(0010,0010)",
+			wantMsg:  "more than 3 component groups",
+		},
+		{
+			name:     "OW odd length",
+			elem:     mustNewElement(tag.RedPaletteColorLookupTableData, []byte{1, 2, 3}),
+			wantPath: "(0028,1201)",
+			wantMsg:  "not a multiple of 2",
+		},
+		{
+			name: "nested",
+			elem: makeSequenceElement(tag.ReferencedSeriesSequence, [][]*Element{
+				{mustNewElement(tag.SeriesInstanceUID, []string{"1.2.3"})},
+				{
+					makeSequenceElement(tag.ReferencedInstanceSequence, [][]*Element{
+						{mustNewElement(tag.ReferencedSOPInstanceUID, []string{"1.2.3"})},
+						{mustNewElement(tag.ReferencedSOPInstanceUID, []string{"1.2.x"})},
+					}),
+				},
+			}),
+			wantPath: "(0008,1115)[1].(0008,114a)[1].(0008,1155)",
+			wantMsg:  "invalid UID component",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			violations := Validate(Dataset{Elements: []*Element{tc.elem}})
+			if tc.wantMsg == "" {
+				if len(violations) != 0 {
+					t.Errorf("Validate() returned unexpected violations: %v", violations)
+				}
+				return
+			}
+			if len(violations) != 1 {
+				t.Fatalf("Validate() returned %d violations, want 1: %v", len(violations), violations)
+			}
+			if got := violations[0].Path.String(); got != tc.wantPath {
+				t.Errorf("Validate() violation path = %v, want %v", got, tc.wantPath)
+			}
+			if !strings.Contains(violations[0].Message, tc.wantMsg) {
+				t.Errorf("Validate() violation message = %q, want it to contain %q", violations[0].Message, tc.wantMsg)
+			}
+		})
+	}
+}
+
+func TestValidate_Testdata(t *testing.T) {
+	ds, err := ParseFile("./testdata/5.dcm", nil)
+	if err != nil {
+		t.Fatalf("ParseFile() unexpected error: %v", err)
+	}
+	if violations := Validate(ds); len(violations) != 0 {
+		t.Errorf("Validate() returned unexpected violations: %v", violations)
+	}
+}
+
+func TestWrite_StrictValidation(t *testing.T) {
+	ds := Dataset{Elements: []*Element{
+		mustNewElement(tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.1.2"}),
+		mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4.5.6.7"}),
+		mustNewElement(tag.TransferSyntaxUID, []string{"1.2.840.10008.1.2"}),
+		mustNewElement(tag.Modality, []string{"ct"}),
+	}}
+	if err := Write(&bytes.Buffer{}, ds); err != nil {
+		t.Fatalf("Write() without StrictValidation unexpected error: %v", err)
+	}
+
+	err := Write(&bytes.Buffer{}, ds, StrictValidation())
+	if !errors.Is(err, ErrorInvalidDataset) {
+		t.Fatalf("Write() with StrictValidation returned err %v, want %v", err, ErrorInvalidDataset)
+	}
+	var v Violation
+	if !errors.As(err, &v) || v.Path.Tag() != tag.Modality {
+		t.Errorf("Write() with StrictValidation returned err %v, want it to wrap a Violation for %v", err, tag.Modality)
+	}
+
+	w, err := NewWriter(&bytes.Buffer{}, StrictValidation())
+	if err != nil {
+		t.Fatalf("NewWriter() unexpected error: %v", err)
+	}
+	if err := w.WriteElement(ds.Elements[3]); !errors.Is(err, ErrorInvalidDataset) {
+		t.Errorf("WriteElement() with StrictValidation returned err %v, want %v", err, ErrorInvalidDataset)
+	}
+}
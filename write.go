@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"io"
 	"slices"
+	"strings"
 
+	"github.com/wybaby168/dicom/pkg/charset"
 	"github.com/wybaby168/dicom/pkg/vrraw"
 
 	"github.com/wybaby168/dicom/pkg/uid"
@@ -33,6 +35,7 @@ var (
 // Writer is a struct that allows element-by element writing to a DICOM writer.
 type Writer struct {
 	writer *dicomio.Writer
+	out    io.Writer
 	optSet *writeOptSet
 }
 
@@ -46,6 +49,7 @@ func NewWriter(out io.Writer, opts ...WriteOption) (*Writer, error) {
 
 	return &Writer{
 		writer: w,
+		out:    out,
 		optSet: optSet,
 	}, nil
 }
@@ -57,6 +61,16 @@ func (w *Writer) SetTransferSyntax(bo binary.ByteOrder, implicit bool) {
 
 // writeDataset writes the provided DICOM dataset to the Writer, including headers if available.
 func (w *Writer) writeDataset(ds Dataset) error {
+	if w.optSet.strictValidation {
+		if err := validationError(Validate(ds)); err != nil {
+			return err
+		}
+	}
+
+	if w.optSet.autoCharacterSet {
+		ds = withAutoCharacterSet(ds)
+	}
+
 	var metaElems []*Element
 	for _, elem := range ds.Elements {
 		if elem.Tag.Group == tag.MetadataGroup {
@@ -84,20 +98,99 @@ func (w *Writer) writeDataset(ds Dataset) error {
 
 	w.writer.SetTransferSyntax(bo, implicit)
 
+	var body []*Element
 	for _, elem := range ds.Elements {
 		if elem.Tag.Group != tag.MetadataGroup {
-			err = writeElement(w.writer, elem, *w.optSet)
-			if err != nil {
+			body = append(body, elem)
+		}
+	}
+	for i, elem := range body {
+		w.setCodingSystem(elem)
+		if w.optSet.preserveEncoding {
+			if elem, err = withGroupLength(w.writer, elem, body[i+1:], *w.optSet); err != nil {
 				return err
 			}
 		}
+		err = writeElement(w.writer, elem, *w.optSet)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// setCodingSystem sets the coding system of the following elements if elem
+// is the Specific Character Set. Unknown character sets are ignored, and
+// strings written as is, since they may have been read with
+// AllowUnknownSpecificCharacterSet.
+func (w *Writer) setCodingSystem(elem *Element) {
+	setCodingSystem(w.writer, elem)
+}
+
+func setCodingSystem(w *dicomio.Writer, elem *Element) {
+	if elem.Tag != tag.SpecificCharacterSet || elem.Value == nil || elem.Value.ValueType() != Strings {
+		return
+	}
+	cs, err := charset.ParseSpecificCharacterSet(MustGetStrings(elem.Value))
+	if err != nil {
+		cs = charset.CodingSystem{}
+	}
+	w.SetCodingSystem(cs)
+}
+
+// withAutoCharacterSet returns ds with a Specific Character Set element able
+// to encode all of its strings, see AutoCharacterSet.
+func withAutoCharacterSet(ds Dataset) Dataset {
+	var values []string
+	collectCharacterStrings(ds.Elements, &values)
+	if elem, err := ds.FindElementByTag(tag.SpecificCharacterSet); err == nil && elem.Value != nil && elem.Value.ValueType() == Strings {
+		if cs, err := charset.ParseSpecificCharacterSet(MustGetStrings(elem.Value)); err == nil && cs.CanEncode(values) {
+			return ds
+		}
+	} else if !slices.ContainsFunc(values, func(v string) bool { return strings.ContainsFunc(v, func(r rune) bool { return r >= 0x80 }) }) {
+		return ds
+	}
+
+	names := charset.SelectCharacterSet(values)
+	if names == nil {
+		names = []string{""}
+	}
+	out := Dataset{Elements: slices.Clone(ds.Elements)}
+	out.SetElement(mustNewElement(tag.SpecificCharacterSet, names))
+	return out
+}
+
+// collectCharacterStrings appends the strings of elements with VRs affected
+// by the Specific Character Set to values, including those in sequences.
+func collectCharacterStrings(elems []*Element, values *[]string) {
+	for _, elem := range elems {
+		if elem.Value == nil {
+			continue
+		}
+		switch elem.Value.ValueType() {
+		case Strings:
+			if vr, err := tag.ParseVR(elem.RawValueRepresentation); err == nil && vr.IsCharacterSetAffected() {
+				*values = append(*values, MustGetStrings(elem.Value)...)
+			}
+		case Sequences:
+			for _, item := range elem.Value.GetValue().([]*SequenceItemValue) {
+				collectCharacterStrings(item.elements, values)
+			}
+		}
+	}
+}
+
 // WriteElement writes a single DICOM element to a Writer.
 func (w *Writer) WriteElement(e *Element) error {
+	if w.optSet.strictValidation {
+		var violations []Violation
+		validateElement(e, nil, &violations)
+		if err := validationError(violations); err != nil {
+			return err
+		}
+	}
+	w.setCodingSystem(e)
 	return writeElement(w.writer, e, *w.optSet)
 }
 
@@ -156,6 +249,58 @@ func OverrideMissingTransferSyntax(transferSyntaxUID string) WriteOption {
 	}
 }
 
+// StrictValidation returns a WriteOption that validates the Dataset (or the
+// element passed to Writer.WriteElement) with Validate before writing
+// anything, and returns an error wrapping ErrorInvalidDataset and each
+// Violation if any are found.
+func StrictValidation() WriteOption {
+	return func(set *writeOptSet) {
+		set.strictValidation = true
+	}
+}
+
+// AutoCharacterSet returns a WriteOption that selects the Specific Character
+// Set (0008,0005) able to encode all strings of the Dataset, preferring the
+// default repertoire and single byte character sets over UTF-8, and writes it
+// instead of the Dataset's one if that can't encode them. The Dataset passed
+// to Write is not modified.
+func AutoCharacterSet() WriteOption {
+	return func(set *writeOptSet) {
+		set.autoCharacterSet = true
+	}
+}
+
+// PreserveEncoding returns a WriteOption that writes the elements of a Dataset
+// parsed with the RecordEncoding ParseOption the way they were read, so that
+// writing an unchanged Dataset reproduces the parsed file byte for byte:
+//
+//   - Values that were not changed are written as read, including their
+//     padding.
+//   - Sequences and items keep their defined or undefined length.
+//   - Group length elements keep their place, with their value updated if the
+//     length of their group changed.
+//   - File meta elements keep their order in the Dataset, including changed
+//     ones, and the preamble is written as read.
+//
+// Changed values, and elements and items without a recorded Position, are
+// encoded as Write does without this option, except that the VRs of elements
+// that were read are not verified.
+func PreserveEncoding() WriteOption {
+	return func(set *writeOptSet) {
+		set.preserveEncoding = true
+	}
+}
+
+// ExtendedOffsetTable returns a WriteOption that makes Writer.StartPixelData
+// write the Extended Offset Table (7FE0,0001) and its lengths for
+// encapsulated frames, instead of the Basic Offset Table, which cannot address
+// frames past 4 GiB.
+func ExtendedOffsetTable() WriteOption {
+	return func(set *writeOptSet) {
+		set.extendedOffsetTable = true
+	}
+}
+
 // skipWritingTransferSyntaxForTests is a test WriteOption that cause Write to skip
 // writing the transfer syntax uid element in the DICOM metadata. When used in
 // combination with OverrideMissingTransferSyntax, this can be used to set the
@@ -174,6 +319,10 @@ type writeOptSet struct {
 	defaultMissingTransferSyntax      bool
 	overrideMissingTransferSyntaxUID  string
 	skipWritingTransferSyntaxForTests bool
+	strictValidation                  bool
+	autoCharacterSet                  bool
+	preserveEncoding                  bool
+	extendedOffsetTable               bool
 }
 
 func (w *writeOptSet) validate() error {
@@ -202,6 +351,19 @@ func writeFileHeader(w *dicomio.Writer, ds *Dataset, metaElems []*Element, opts
 	tagsUsed := make(map[tag.Tag]bool)
 	tagsUsed[tag.FileMetaInformationGroupLength] = true
 
+	if opts.preserveEncoding && slices.ContainsFunc(metaElems, func(e *Element) bool { return e.Position != nil }) {
+		// Keep the order of the elements of a file that was read, including
+		// the elements that were changed.
+		for _, elem := range metaElems {
+			if tagsUsed[elem.Tag] {
+				continue
+			}
+			if err := writeMetaElem(subWriter, elem.Tag, ds, &tagsUsed, opts); err != nil {
+				return err
+			}
+		}
+	}
+
 	err := writeMetaElem(subWriter, tag.FileMetaInformationVersion, ds, &tagsUsed, opts)
 	if err != nil && !errors.Is(err, ErrorElementNotFound) {
 		return err
@@ -245,14 +407,27 @@ func writeFileHeader(w *dicomio.Writer, ds *Dataset, metaElems []*Element, opts
 		}
 	}
 
-	if err := w.WriteZeros(128); err != nil {
+	preamble := make([]byte, 128)
+	lengthElem, err := NewElement(tag.FileMetaInformationGroupLength, []int{len(metaBytes.Bytes())})
+	if err != nil {
 		return err
 	}
-	if err := w.WriteString(magicWord); err != nil {
+	if opts.preserveEncoding {
+		for _, elem := range metaElems {
+			if elem.Position != nil && elem.Position.preamble != nil {
+				preamble = elem.Position.preamble
+			}
+			if elem.Tag == tag.FileMetaInformationGroupLength && elem.Position != nil {
+				c := *elem
+				c.Value = lengthElem.Value
+				lengthElem = &c
+			}
+		}
+	}
+	if err := w.WriteBytes(preamble); err != nil {
 		return err
 	}
-	lengthElem, err := NewElement(tag.FileMetaInformationGroupLength, []int{len(metaBytes.Bytes())})
-	if err != nil {
+	if err := w.WriteString(magicWord); err != nil {
 		return err
 	}
 
@@ -271,31 +446,55 @@ func writeFileHeader(w *dicomio.Writer, ds *Dataset, metaElems []*Element, opts
 func writeElement(w *dicomio.Writer, elem *Element, opts writeOptSet) error {
 	vr := elem.RawValueRepresentation
 	var err error
-	vr, err = verifyVROrDefault(elem.Tag, elem.RawValueRepresentation, opts)
+	vrOpts := opts
+	if opts.preserveEncoding && elem.Position != nil {
+		// Keep the VR as read, even if it is not the one in the data dictionary.
+		vrOpts.skipVRVerification = true
+	}
+	vr, err = verifyVROrDefault(elem.Tag, elem.RawValueRepresentation, vrOpts)
 	if err != nil {
 		return err
 	}
+	// The header is written with the VR string, which may not be a valid VR
+	// if VR verification is skipped, and the value with the parsed VR.
+	parsed, _ := tag.ParseVR(vr)
 
 	if !opts.skipValueTypeVerification && elem.Value != nil {
-		err := verifyValueType(elem.Tag, elem.Value, vr)
+		err := verifyValueType(elem.Tag, elem.Value, parsed)
 		if err != nil {
 			return err
 		}
 	}
 
 	length := elem.ValueLength
-	var valueData = &bytes.Buffer{}
+	if parsed == tag.SQ {
+		// We are going to write these out with undefined length always.
+		length = tag.VLUndefinedLength
+	}
+	if opts.preserveEncoding && elem.Position != nil && elem.Value != nil && elem.Value.ValueType() == Sequences {
+		length = elem.Position.ValueLength
+	}
+	if !opts.preserveEncoding && elem.Value != nil && elem.Value.ValueType() == PixelData {
+		// Write the frames directly, rather than buffering the whole value
+		// to know its length.
+		return writePixelDataElement(w, elem, vr)
+	}
+	var valueData []byte
 	if elem.Value != nil {
-		bo, implicit := w.GetTransferSyntax()
-		subWriter := dicomio.NewWriter(valueData, bo, implicit)
-		err := writeValue(subWriter, elem.Tag, elem.Value, elem.Value.ValueType(), vr, elem.ValueLength, opts)
+		vl := elem.ValueLength
+		if elem.Value.ValueType() == Sequences {
+			vl = length
+		}
+		valueData, err = encodeValue(w, elem, parsed, vl, opts)
+		if opts.preserveEncoding {
+			valueData, err = elem.Position.preservedValue(valueData, err)
+		}
 		if err != nil {
 			return err
 		}
 
-		length = uint32(len(valueData.Bytes()))
-		if elem.ValueLength == tag.VLUndefinedLength {
-			length = tag.VLUndefinedLength
+		if vl != tag.VLUndefinedLength {
+			length = uint32(len(valueData))
 		}
 	}
 
@@ -306,7 +505,7 @@ func writeElement(w *dicomio.Writer, elem *Element, opts writeOptSet) error {
 
 	if elem.Value != nil {
 		// Write the bytes to the original writer
-		err = w.WriteBytes(valueData.Bytes())
+		err = w.WriteBytes(valueData)
 		if err != nil {
 			return err
 		}
@@ -314,7 +513,44 @@ func writeElement(w *dicomio.Writer, elem *Element, opts writeOptSet) error {
 	return nil
 }
 
+// writePixelDataElement writes the PixelData element elem, computing the
+// length of native PixelData from its frames.
+func writePixelDataElement(w *dicomio.Writer, elem *Element, vr string) error {
+	image := MustGetPixelDataInfo(elem.Value)
+	length := elem.ValueLength
+	if length != tag.VLUndefinedLength {
+		n, err := nativePixelDataLength(image)
+		if err != nil {
+			return err
+		}
+		if n >= int64(tag.VLUndefinedLength) {
+			return fmt.Errorf("native PixelData of %d bytes is too long", n)
+		}
+		length = uint32(n)
+	}
+	if err := encodeElementHeader(w, elem.Tag, vr, length); err != nil {
+		return err
+	}
+	return writePixelData(w, elem.Value, length)
+}
+
+// encodeValue returns the value of elem encoded with the transfer syntax and
+// coding system of w.
+func encodeValue(w *dicomio.Writer, elem *Element, vr tag.VR, vl uint32, opts writeOptSet) ([]byte, error) {
+	valueData := &bytes.Buffer{}
+	bo, implicit := w.GetTransferSyntax()
+	subWriter := dicomio.NewWriter(valueData, bo, implicit)
+	subWriter.SetCodingSystem(w.GetCodingSystem())
+	if err := writeValue(subWriter, elem.Tag, elem.Value, elem.Value.ValueType(), vr, vl, opts); err != nil {
+		return nil, err
+	}
+	return valueData.Bytes(), nil
+}
+
 func writeMetaElem(w *dicomio.Writer, t tag.Tag, ds *Dataset, tagsUsed *map[tag.Tag]bool, optSet writeOptSet) error {
+	if (*tagsUsed)[t] {
+		return nil
+	}
 	elem, err := ds.FindElementByTag(t)
 	if err != nil {
 		return err
@@ -365,25 +601,31 @@ func verifyVROrDefault(t tag.Tag, vr string, opts writeOptSet) (string, error) {
 	return vr, nil
 }
 
-func verifyValueType(t tag.Tag, value Value, vr string) error {
+func verifyValueType(t tag.Tag, value Value, vr tag.VR) error {
 	valueType := value.ValueType()
 	var ok bool
-	switch vr {
-	case vrraw.UnsignedShort, vrraw.UnsignedLong, vrraw.SignedLong, vrraw.SignedShort, vrraw.AttributeTag:
+	switch tag.VRKindOf(t, vr) {
+	case tag.VRUInt16List, tag.VRUInt32List, tag.VRInt16List, tag.VRInt32List:
 		ok = valueType == Ints
-	case vrraw.Sequence:
+	case tag.VRTagList:
+		// AT values used to be flat (group, element) ints, which are still
+		// accepted.
+		ok = valueType == Tags || valueType == Ints
+	case tag.VRInt64List:
+		ok = valueType == Int64s || valueType == Ints
+	case tag.VRUInt64List:
+		ok = valueType == Uint64s || valueType == Ints
+	case tag.VRSequence:
 		ok = valueType == Sequences
-	case "NA":
+	case tag.VRItem:
 		ok = valueType == SequenceItem
-	case vrraw.OtherWord, vrraw.OtherByte:
-		if t == tag.PixelData {
-			ok = valueType == PixelData
-		} else {
-			ok = valueType == Bytes
-		}
-	case vrraw.Unknown:
+	case tag.VRPixelData:
+		ok = valueType == PixelData
+	case tag.VRBytes:
+		ok = valueType == Bytes
+	case tag.VRUnknown:
 		ok = valueType == Bytes || valueType == Sequences
-	case vrraw.FloatingPointSingle, vrraw.FloatingPointDouble:
+	case tag.VRFloat32List, tag.VRFloat64List:
 		ok = valueType == Floats
 	default:
 		ok = valueType == Strings
@@ -412,11 +654,6 @@ func writeVRVL(w *dicomio.Writer, t tag.Tag, vr string, vl uint32) error {
 		vl = tag.VLUndefinedLength
 	}
 
-	if vr == vrraw.Sequence {
-		// We are going to write these out with undefined length always.
-		vl = tag.VLUndefinedLength
-	}
-
 	// We want to make sure there is any VR unless this is a Sequence delimiter.
 	if len(vr) != 2 && vl != tag.VLUndefinedLength && t != tag.SequenceDelimitationItem && t != tag.ItemDelimitationItem {
 		return fmt.Errorf("ERROR dicomio.writeVRVL: Value Representation must be of length 2, e.g. 'UN'. For tag=%v, it was RawValueRepresentation=%v",
@@ -432,21 +669,15 @@ func writeVRVL(w *dicomio.Writer, t tag.Tag, vr string, vl uint32) error {
 		if err := w.WriteString(vr); err != nil {
 			return err
 		}
-		switch vr {
-		case "NA", vrraw.OtherByte, vrraw.OtherDouble, vrraw.OtherFloat,
-			vrraw.OtherLong, vrraw.OtherWord, vrraw.Sequence, vrraw.Unknown,
-			vrraw.UnlimitedCharacters, vrraw.UniversalResourceIdentifier,
-			vrraw.UnlimitedText:
+		if parsed, err := tag.ParseVR(vr); err == nil && parsed.HeaderLength() == 12 {
 			if err := w.WriteZeros(2); err != nil {
 				return err
 			}
 			if err := w.WriteUInt32(vl); err != nil {
 				return err
 			}
-		default:
-			if err := w.WriteUInt16(uint16(vl)); err != nil {
-				return err
-			}
+		} else if err := w.WriteUInt16(uint16(vl)); err != nil {
+			return err
 		}
 	} else {
 		if err := w.WriteUInt32(vl); err != nil {
@@ -494,9 +725,14 @@ func encodeElementHeader(w *dicomio.Writer, t tag.Tag, vr string, vl uint32) err
 	return nil
 }
 
-func writeValue(w *dicomio.Writer, t tag.Tag, value Value, valueType ValueType, vr string, vl uint32, opts writeOptSet) error {
-	if vl == tag.VLUndefinedLength && valueType <= 2 { // strings, bytes or ints
-		return fmt.Errorf("encoding undefined-length element not yet supported: %v", t)
+func writeValue(w *dicomio.Writer, t tag.Tag, value Value, valueType ValueType, vr tag.VR, vl uint32, opts writeOptSet) error {
+	if vl == tag.VLUndefinedLength {
+		switch valueType {
+		case PixelData, SequenceItem, Sequences:
+			// Encapsulated pixel data, items and sequences have delimiters.
+		default:
+			return fmt.Errorf("encoding undefined-length element not yet supported: %v", t)
+		}
 	}
 
 	v := value.GetValue()
@@ -507,12 +743,18 @@ func writeValue(w *dicomio.Writer, t tag.Tag, value Value, valueType ValueType,
 		return writeBytes(w, v.([]byte), vr)
 	case Ints:
 		return writeInts(w, v.([]int), vr)
+	case Int64s:
+		return writeInt64s(w, v.([]int64), vr)
+	case Uint64s:
+		return writeUint64s(w, v.([]uint64), vr)
+	case Tags:
+		return writeTags(w, v.([]tag.Tag), vr)
 	case PixelData:
-		return writePixelData(w, t, value, vr, vl)
+		return writePixelData(w, value, vl)
 	case SequenceItem:
-		return writeSequenceItem(w, t, v.([]*Element), vr, vl, opts)
+		return writeSequenceItem(w, v.([]*Element), tag.VLUndefinedLength, opts)
 	case Sequences:
-		return writeSequence(w, t, v.([]*SequenceItemValue), vr, vl, opts)
+		return writeSequence(w, v.([]*SequenceItemValue), vl, opts)
 	case Floats:
 		return writeFloats(w, value, vr)
 	default:
@@ -520,7 +762,7 @@ func writeValue(w *dicomio.Writer, t tag.Tag, value Value, valueType ValueType,
 	}
 }
 
-func writeStrings(w *dicomio.Writer, values []string, vr string) error {
+func writeStrings(w *dicomio.Writer, values []string, vr tag.VR) error {
 	s := ""
 	for i, substr := range values {
 		if i > 0 {
@@ -528,55 +770,65 @@ func writeStrings(w *dicomio.Writer, values []string, vr string) error {
 		}
 		s += substr
 	}
-	if err := w.WriteString(s); err != nil {
+	data := []byte(s)
+	if vr.IsCharacterSetAffected() {
+		encode := w.GetCodingSystem().Encode
+		if vr == tag.PN {
+			encode = w.GetCodingSystem().EncodePersonName
+		}
+		var err error
+		if data, err = encode(s); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteBytes(data); err != nil {
 		return err
 	}
-	if len(s)%2 == 1 {
-		switch vr {
-		case vrraw.DateTime, vrraw.LongString, vrraw.LongText, vrraw.PersonName,
-			vrraw.ShortString, vrraw.ShortText, vrraw.UnlimitedText,
-			vrraw.DecimalString, vrraw.CodeString, vrraw.Time, vrraw.ApplicationEntity,
-			vrraw.IntegerString, vrraw.Unknown:
-			if err := w.WriteString(" "); err != nil { // https://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_6.2
-				return err
-			}
-		default:
-			if err := w.WriteByte(0); err != nil {
-				return err
-			}
+	if len(data)%2 == 1 {
+		// https://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_6.2
+		padding := vr.Padding()
+		if vr == tag.UN {
+			// Strings of unknown VR have always been padded with a space,
+			// as they are usually text.
+			padding = ' '
+		}
+		if err := w.WriteByte(padding); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func writeBytes(w *dicomio.Writer, values []byte, vr string) error {
-	var err error
-	switch vr {
-	case vrraw.OtherWord, vrraw.Unknown:
-		err = writeOtherWordString(w, values)
-	case vrraw.OtherByte:
-		err = writeOtherByteString(w, values)
-	default:
+func writeBytes(w *dicomio.Writer, values []byte, vr tag.VR) error {
+	if vr.Kind() != tag.VRBytes && vr.Kind() != tag.VRUnknown {
 		return ErrorMismatchValueTypeAndVR
 	}
-	if err != nil {
-		return err
+	switch vr.WordSize() {
+	case 1:
+		return writeOtherByteString(w, values)
+	case 2:
+		return writeOtherWordString(w, values)
+	default:
+		return writeOtherWords(w, values, vr.WordSize())
 	}
-	return nil
 }
 
-func writeInts(w *dicomio.Writer, values []int, vr string) error {
+func writeInts(w *dicomio.Writer, values []int, vr tag.VR) error {
 	for _, value := range values {
 		switch vr {
 		// TODO(suyashkumar): consider additional validation of VR=AT elements.
-		case vrraw.UnsignedShort, vrraw.SignedShort, vrraw.AttributeTag:
+		case tag.US, tag.SS, tag.AT:
 			if err := w.WriteUInt16(uint16(value)); err != nil {
 				return err
 			}
-		case vrraw.UnsignedLong, vrraw.SignedLong:
+		case tag.UL, tag.SL:
 			if err := w.WriteUInt32(uint32(value)); err != nil {
 				return err
 			}
+		case tag.UV, tag.SV:
+			if err := w.WriteUInt64(uint64(value)); err != nil {
+				return err
+			}
 		default:
 			return ErrorMismatchValueTypeAndVR
 		}
@@ -584,14 +836,53 @@ func writeInts(w *dicomio.Writer, values []int, vr string) error {
 	return nil
 }
 
-func writeFloats(w *dicomio.Writer, v Value, vr string) error {
+func writeTags(w *dicomio.Writer, values []tag.Tag, vr tag.VR) error {
+	if vr != tag.AT {
+		return ErrorMismatchValueTypeAndVR
+	}
+	for _, value := range values {
+		if err := w.WriteUInt16(value.Group); err != nil {
+			return err
+		}
+		if err := w.WriteUInt16(value.Element); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeInt64s(w *dicomio.Writer, values []int64, vr tag.VR) error {
+	if vr != tag.SV {
+		return ErrorMismatchValueTypeAndVR
+	}
+	for _, value := range values {
+		if err := w.WriteUInt64(uint64(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeUint64s(w *dicomio.Writer, values []uint64, vr tag.VR) error {
+	if vr != tag.UV {
+		return ErrorMismatchValueTypeAndVR
+	}
+	for _, value := range values {
+		if err := w.WriteUInt64(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFloats(w *dicomio.Writer, v Value, vr tag.VR) error {
 	if v.ValueType() != Floats {
 		return ErrorUnexpectedValueType
 	}
 	floats := MustGetFloats(v)
 	for _, fl := range floats {
 		switch vr {
-		case vrraw.FloatingPointSingle:
+		case tag.FL:
 			// NOTE: this is a conversion from float64 -> float32 which may lead to a loss in precision. The assumption
 			// is that the value sitting in the float64 was originally at float32 precision if the VR is FL for this
 			// element. We will need to revisit this. Maybe we can detect if there will be a loss of precision and if so
@@ -600,7 +891,7 @@ func writeFloats(w *dicomio.Writer, v Value, vr string) error {
 			if err != nil {
 				return err
 			}
-		case vrraw.FloatingPointDouble:
+		case tag.FD:
 			err := w.WriteFloat64(fl)
 			if err != nil {
 				return err
@@ -610,7 +901,7 @@ func writeFloats(w *dicomio.Writer, v Value, vr string) error {
 	return nil
 }
 
-func writePixelData(w *dicomio.Writer, t tag.Tag, value Value, vr string, vl uint32) error {
+func writePixelData(w *dicomio.Writer, value Value, vl uint32) error {
 	image := MustGetPixelDataInfo(value)
 
 	if vl == tag.VLUndefinedLength {
@@ -636,60 +927,28 @@ func writePixelData(w *dicomio.Writer, t tag.Tag, value Value, vr string, vl uin
 			w.WriteBytes(image.UnprocessedValueData)
 			return nil
 		}
-		numFrames := len(image.Frames)
-		numPixels := image.Frames[0].NativeData.Rows() * image.Frames[0].NativeData.Cols()
-		numValues := image.Frames[0].NativeData.SamplesPerPixel()
-		// Total required buffer length in bytes:
-		length := numFrames * numPixels * numValues * image.Frames[0].NativeData.BitsPerSample() / 8
-
-		buf := &bytes.Buffer{}
-		buf.Grow(length)
+		// Write the frames one at a time, reusing the buffer of the frames
+		// that need converting to the byte order of the transfer syntax.
 		bo, _ := w.GetTransferSyntax()
-		for frame := 0; frame < numFrames; frame++ {
-			currFrameData := image.Frames[frame].NativeData
-			for pixel := 0; pixel < numPixels; pixel++ {
-				for sampleIdx := 0; sampleIdx < currFrameData.SamplesPerPixel(); sampleIdx++ {
-					switch image.Frames[frame].NativeData.BitsPerSample() {
-					case 8:
-						rawSlice, ok := currFrameData.RawDataSlice().([]uint8)
-						if !ok {
-							return fmt.Errorf("got frame with bitsAllocated=8 but can't assert RawDataSlice to []uint8")
-						}
-						if err := binary.Write(buf, bo, rawSlice[(pixel*currFrameData.SamplesPerPixel())+sampleIdx]); err != nil {
-							return err
-						}
-					case 16:
-						rawSlice, ok := currFrameData.RawDataSlice().([]uint16)
-						if !ok {
-							return fmt.Errorf("got frame with bitsAllocated=16 but can't assert RawDataSlice to []uint16")
-						}
-						if err := binary.Write(buf, bo, rawSlice[(pixel*currFrameData.SamplesPerPixel())+sampleIdx]); err != nil {
-							return err
-						}
-					case 32:
-						rawSlice, ok := currFrameData.RawDataSlice().([]uint32)
-						if !ok {
-							return fmt.Errorf("got frame with bitsAllocated=32 but can't assert RawDataSlice to []uint32")
-						}
-						if err := binary.Write(buf, bo, rawSlice[(pixel*currFrameData.SamplesPerPixel())+sampleIdx]); err != nil {
-							return err
-						}
-					default:
-						return ErrorUnsupportedBitsPerSample
-					}
-				}
+		var scratch []byte
+		length := 0
+		for _, f := range image.Frames {
+			data, err := nativeFrameBytes(f.NativeData, bo, &scratch)
+			if err != nil {
+				return err
 			}
+			if err := w.WriteBytes(data); err != nil {
+				return err
+			}
+			length += len(data)
 		}
 		// If the byte length is not even, append 1 padding byte to make it even.
 		// https://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_8.1.1
-		if buf.Len()%2 != 0 {
-			if err := buf.WriteByte(0); err != nil {
+		if length%2 != 0 {
+			if err := w.WriteByte(0); err != nil {
 				return err
 			}
 		}
-		if err := w.WriteBytes(buf.Bytes()); err != nil {
-			return err
-		}
 	}
 	return nil
 }
@@ -699,8 +958,9 @@ var sequenceDelimitationItem = &Element{
 	ValueLength: 0, // This should be 00000000H in base32
 }
 
-func writeSequence(w *dicomio.Writer, t tag.Tag, values []*SequenceItemValue, vr string, vl uint32, opts writeOptSet) error {
-	// We always write out sequences using the undefined length encoding.
+func writeSequence(w *dicomio.Writer, values []*SequenceItemValue, vl uint32, opts writeOptSet) error {
+	// We always write out sequences using the undefined length encoding,
+	// unless preserving the encoding of a sequence of defined length.
 	// Note: we currently don't validate that the length of the sequence matches
 	// the VL if it's not undefined VL.
 	// More details about the sequence structure can be found at:
@@ -708,10 +968,17 @@ func writeSequence(w *dicomio.Writer, t tag.Tag, values []*SequenceItemValue, vr
 
 	// Write out the items.
 	for _, seqItem := range values {
-		if err := writeSequenceItem(w, t, seqItem.elements, vr, vl, opts); err != nil {
+		itemVL := tag.VLUndefinedLength
+		if opts.preserveEncoding && seqItem.position != nil {
+			itemVL = seqItem.position.ValueLength
+		}
+		if err := writeSequenceItem(w, seqItem.elements, itemVL, opts); err != nil {
 			return err
 		}
 	}
+	if opts.preserveEncoding && vl != tag.VLUndefinedLength {
+		return nil
+	}
 
 	// Write Sequence Delimitation Item as implicit VR
 	oldBO, oldImplicit := w.GetTransferSyntax()
@@ -734,23 +1001,53 @@ var item = &Element{
 	ValueLength: tag.VLUndefinedLength,
 }
 
-func writeSequenceItem(w *dicomio.Writer, t tag.Tag, values []*Element, vr string, vl uint32, opts writeOptSet) error {
+func writeSequenceItem(w *dicomio.Writer, values []*Element, vl uint32, opts writeOptSet) error {
+	if opts.preserveEncoding && vl != tag.VLUndefinedLength {
+		// Preserve the defined length of the item.
+		data := &bytes.Buffer{}
+		bo, implicit := w.GetTransferSyntax()
+		subWriter := dicomio.NewWriter(data, bo, implicit)
+		subWriter.SetCodingSystem(w.GetCodingSystem())
+		if err := writeItemElements(subWriter, values, opts); err != nil {
+			return err
+		}
+		return writeRawItem(w, data.Bytes())
+	}
+
 	// Write out item header.
 	if err := writeElement(w, item, opts); err != nil {
 		return err
 	}
 
 	// Write out nested Dataset elements.
-	for _, elem := range values {
-		if err := writeElement(w, elem, opts); err != nil {
-			return err
-		}
+	if err := writeItemElements(w, values, opts); err != nil {
+		return err
 	}
 
 	// Write ItemDelimitationItem.
 	return writeElement(w, sequenceItemDelimitationItem, opts)
 }
 
+// writeItemElements writes the elements of a sequence item. A Specific
+// Character Set in the item applies to the rest of the item only, so the
+// coding system of w is restored afterwards.
+func writeItemElements(w *dicomio.Writer, values []*Element, opts writeOptSet) error {
+	defer w.SetCodingSystem(w.GetCodingSystem())
+	for i, elem := range values {
+		setCodingSystem(w, elem)
+		if opts.preserveEncoding {
+			var err error
+			if elem, err = withGroupLength(w, elem, values[i+1:], opts); err != nil {
+				return err
+			}
+		}
+		if err := writeElement(w, elem, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func writeOtherWordString(w *dicomio.Writer, data []byte) error {
 	if len(data)%2 != 0 {
 		return ErrorOWRequiresEvenVL
@@ -770,6 +1067,37 @@ func writeOtherWordString(w *dicomio.Writer, data []byte) error {
 	return nil
 }
 
+// writeOtherWords writes data as a stream of 32 or 64 bit words, e.g. for OF
+// or OV.
+func writeOtherWords(w *dicomio.Writer, data []byte, wordSize int) error {
+	if len(data)%wordSize != 0 {
+		return fmt.Errorf("value length %d is not a multiple of %d: %w", len(data), wordSize, ErrorMismatchValueTypeAndVR)
+	}
+	bo, _ := w.GetTransferSyntax()
+	r := dicomio.NewReader(bufio.NewReader(bytes.NewBuffer(data)), bo, int64(len(data)))
+
+	for i := 0; i < len(data)/wordSize; i++ {
+		if wordSize == 4 {
+			v, err := r.ReadUInt32()
+			if err != nil {
+				return err
+			}
+			if err := w.WriteUInt32(v); err != nil {
+				return err
+			}
+			continue
+		}
+		v, err := r.ReadUInt64()
+		if err != nil {
+			return err
+		}
+		if err := w.WriteUInt64(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func writeOtherByteString(w *dicomio.Writer, data []byte) error {
 	if err := w.WriteBytes(data); err != nil {
 		return err
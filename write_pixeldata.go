@@ -0,0 +1,352 @@
+package dicom
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/wybaby168/dicom/pkg/dicomio"
+	"github.com/wybaby168/dicom/pkg/frame"
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/uid"
+	"github.com/wybaby168/dicom/pkg/vrraw"
+)
+
+var (
+	// ErrorUnexpectedFrame indicates that a frame written to a PixelDataWriter
+	// does not match the PixelData described by the Dataset it was started
+	// with, or that there are more frames than its NumberOfFrames.
+	ErrorUnexpectedFrame = errors.New("frame does not match the PixelData being written")
+	// ErrorMissingFrames indicates that a PixelDataWriter was closed before
+	// all the frames of its NumberOfFrames were written.
+	ErrorMissingFrames = errors.New("fewer frames were written than the NumberOfFrames")
+)
+
+// PixelDataWriter writes the frames of a PixelData element one at a time, so
+// that only one frame needs to be in memory. Get one with
+// Writer.StartPixelData, write NumberOfFrames frames with WriteFrame,
+// WriteFrameData or WriteFrames, then call Close.
+type PixelDataWriter struct {
+	w *dicomio.Writer
+	// seeker is set if the offset table of encapsulated PixelData is written
+	// when closing.
+	seeker       io.WriteSeeker
+	encapsulated bool
+	numFrames    int
+	written      int
+	closed       bool
+
+	// Native PixelData.
+	frameLength int64
+	// scratch is reused to convert frames to the byte order of the transfer
+	// syntax.
+	scratch []byte
+
+	// Encapsulated PixelData.
+	extended bool
+	// tableOffset is the position in seeker of the value of the Basic Offset
+	// Table, or of the Extended Offset Table, followed by the header and
+	// value of the Extended Offset Table Lengths.
+	tableOffset int64
+	offsets     []uint64
+	lengths     []uint64
+	nextOffset  uint64
+}
+
+// StartPixelData writes ds, which must not contain the PixelData or elements
+// following it, then the header of the PixelData, and returns a
+// PixelDataWriter for its frames.
+//
+// The frames are native unless the transfer syntax is not one of
+// uid.StandardTransferSyntaxes. Native frames must match the Rows, Columns,
+// SamplesPerPixel and BitsAllocated of ds. Encapsulated frames are written as
+// one fragment each, and if the output of the Writer is an io.WriteSeeker,
+// their Basic Offset Table, or with the ExtendedOffsetTable WriteOption their
+// Extended Offset Table, is written when closing. Otherwise, the Basic Offset
+// Table is empty.
+func (w *Writer) StartPixelData(ds Dataset) (*PixelDataWriter, error) {
+	for _, elem := range ds.Elements {
+		if elem.Tag.Compare(tag.PixelData) >= 0 {
+			return nil, fmt.Errorf("StartPixelData: element %v must be written with the PixelData", elem.Tag)
+		}
+	}
+	numFrames := 1
+	if nof, err := ds.FindElementByTag(tag.NumberOfFrames); err == nil {
+		strs, ok := nof.Value.GetValue().([]string)
+		if !ok || len(strs) == 0 {
+			return nil, fmt.Errorf("StartPixelData: invalid NumberOfFrames: %v", nof.Value)
+		}
+		var err error
+		if numFrames, err = strconv.Atoi(strs[0]); err != nil {
+			return nil, fmt.Errorf("StartPixelData: error converting NumberOfFrames from string to int: %w", err)
+		}
+	}
+	p := &PixelDataWriter{
+		w:            w.writer,
+		numFrames:    numFrames,
+		encapsulated: !slices.Contains(uid.StandardTransferSyntaxes, w.transferSyntaxUID(ds)),
+		tableOffset:  -1,
+	}
+	if seeker, ok := w.out.(io.WriteSeeker); ok && p.encapsulated {
+		p.seeker = seeker
+		p.extended = w.optSet.extendedOffsetTable
+	}
+
+	vr := vrraw.OtherByte
+	if !p.encapsulated {
+		var bitsAllocated int
+		var err error
+		if p.frameLength, bitsAllocated, err = nativeFrameLength(ds); err != nil {
+			return nil, err
+		}
+		if bitsAllocated > 8 {
+			vr = vrraw.OtherWord
+		}
+	}
+	if p.extended {
+		// The Extended Offset Table replaces any given one.
+		ds.Elements = slices.DeleteFunc(slices.Clone(ds.Elements), func(e *Element) bool {
+			return e.Tag == tag.ExtendedOffsetTable || e.Tag == tag.ExtendedOffsetTableLengths
+		})
+	}
+	if err := w.writeDataset(ds); err != nil {
+		return nil, err
+	}
+
+	if !p.encapsulated {
+		length := p.frameLength * int64(numFrames)
+		if length%2 != 0 {
+			length++
+		}
+		if length >= int64(tag.VLUndefinedLength) {
+			return nil, fmt.Errorf("StartPixelData: native PixelData of %d bytes is too long", length)
+		}
+		return p, encodeElementHeader(p.w, tag.PixelData, vr, uint32(length))
+	}
+
+	tableLength := 0
+	if p.extended {
+		tableLength = 8 * numFrames
+		if err := encodeElementHeader(p.w, tag.ExtendedOffsetTable, vrraw.OtherVeryLong, uint32(tableLength)); err != nil {
+			return nil, err
+		}
+		if err := p.reserveTable(tableLength); err != nil {
+			return nil, err
+		}
+		if err := encodeElementHeader(p.w, tag.ExtendedOffsetTableLengths, vrraw.OtherVeryLong, uint32(tableLength)); err != nil {
+			return nil, err
+		}
+		if err := p.w.WriteZeros(tableLength); err != nil {
+			return nil, err
+		}
+		tableLength = 0
+	} else if p.seeker != nil {
+		tableLength = 4 * numFrames
+	}
+	if err := encodeElementHeader(p.w, tag.PixelData, vr, tag.VLUndefinedLength); err != nil {
+		return nil, err
+	}
+	if err := encodeElementHeader(p.w, tag.Item, "NA", uint32(tableLength)); err != nil {
+		return nil, err
+	}
+	if p.seeker != nil && !p.extended {
+		if err := p.reserveTable(tableLength); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// transferSyntaxUID returns the transfer syntax writeDataset writes ds with.
+func (w *Writer) transferSyntaxUID(ds Dataset) string {
+	if ts, err := ds.FindElementByTag(tag.TransferSyntaxUID); err == nil {
+		if strs, ok := ts.Value.GetValue().([]string); ok && len(strs) > 0 {
+			return strs[0]
+		}
+	}
+	if w.optSet.overrideMissingTransferSyntaxUID != "" {
+		return w.optSet.overrideMissingTransferSyntaxUID
+	}
+	return uid.ImplicitVRLittleEndian
+}
+
+// nativeFrameLength returns the length in bytes of a native frame described
+// by ds, and its BitsAllocated.
+func nativeFrameLength(ds Dataset) (int64, int, error) {
+	values := make(map[tag.Tag]int64)
+	for _, t := range []tag.Tag{tag.Rows, tag.Columns, tag.SamplesPerPixel, tag.BitsAllocated} {
+		elem, err := ds.FindElementByTag(t)
+		if err != nil {
+			return 0, 0, fmt.Errorf("StartPixelData: error finding %v: %w", t, err)
+		}
+		ints, ok := elem.Value.GetValue().([]int)
+		if !ok || len(ints) == 0 {
+			return 0, 0, fmt.Errorf("StartPixelData: invalid %v: %v", t, elem.Value)
+		}
+		values[t] = int64(ints[0])
+	}
+	bits := values[tag.Rows] * values[tag.Columns] * values[tag.SamplesPerPixel] * values[tag.BitsAllocated]
+	return (bits + 7) / 8, int(values[tag.BitsAllocated]), nil
+}
+
+// reserveTable records the current position as the offset of the table, and
+// writes length zeros for it.
+func (p *PixelDataWriter) reserveTable(length int) error {
+	offset, err := p.seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	p.tableOffset = offset
+	return p.w.WriteZeros(length)
+}
+
+// WriteFrame writes the next frame.
+func (p *PixelDataWriter) WriteFrame(f *frame.Frame) error {
+	if f.Encapsulated != p.encapsulated {
+		return fmt.Errorf("%w: got Encapsulated=%v", ErrorUnexpectedFrame, f.Encapsulated)
+	}
+	if p.encapsulated {
+		data := f.EncapsulatedData.Data
+		return p.WriteFrameData(bytes.NewReader(data), int64(len(data)))
+	}
+
+	if f.NativeData == nil {
+		return fmt.Errorf("%w: native frame without NativeData", ErrorUnexpectedFrame)
+	}
+	bo, _ := p.w.GetTransferSyntax()
+	data, err := nativeFrameBytes(f.NativeData, bo, &p.scratch)
+	if err != nil {
+		return err
+	}
+	return p.WriteFrameData(bytes.NewReader(data), int64(len(data)))
+}
+
+// WriteFrameData writes the next frame from the n bytes of r, which for
+// native PixelData are its samples encoded with the transfer syntax.
+func (p *PixelDataWriter) WriteFrameData(r io.Reader, n int64) error {
+	if p.closed {
+		return errors.New("PixelDataWriter: write after Close")
+	}
+	if p.written == p.numFrames {
+		return fmt.Errorf("%w: more than NumberOfFrames=%d frames", ErrorUnexpectedFrame, p.numFrames)
+	}
+	if !p.encapsulated && n != p.frameLength {
+		return fmt.Errorf("%w: got %d bytes, want %d bytes per frame", ErrorUnexpectedFrame, n, p.frameLength)
+	}
+
+	length := n + n%2
+	if p.encapsulated {
+		if length >= int64(tag.VLUndefinedLength) {
+			return fmt.Errorf("%w: frame of %d bytes is too long", ErrorUnexpectedFrame, n)
+		}
+		if p.seeker != nil && !p.extended && p.nextOffset > math.MaxUint32 {
+			return fmt.Errorf("%w: frames exceed the 4 GiB of a Basic Offset Table, use ExtendedOffsetTable", ErrorUnexpectedFrame)
+		}
+		if err := encodeElementHeader(p.w, tag.Item, "NA", uint32(length)); err != nil {
+			return err
+		}
+	}
+	if err := p.w.WriteFrom(r, n); err != nil {
+		return err
+	}
+	if p.encapsulated {
+		if length != n {
+			if err := p.w.WriteByte(0); err != nil {
+				return err
+			}
+		}
+		if p.seeker != nil {
+			p.offsets = append(p.offsets, p.nextOffset)
+			p.lengths = append(p.lengths, uint64(length))
+		}
+		p.nextOffset += 8 + uint64(length)
+	}
+	p.written++
+	return nil
+}
+
+// WriteFrames writes the frames received from frameChan until it is closed,
+// like the frame channel of NewParser. If a frame cannot be written, the
+// following ones are discarded until frameChan is closed, so that its sender
+// does not block.
+func (p *PixelDataWriter) WriteFrames(frameChan <-chan *frame.Frame) error {
+	for f := range frameChan {
+		if err := p.WriteFrame(f); err != nil {
+			for range frameChan {
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Close ends the PixelData, after checking that all frames were written,
+// and writes the offset table of encapsulated frames if needed. It does not
+// close the output of the Writer.
+func (p *PixelDataWriter) Close() error {
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	if p.written < p.numFrames {
+		return fmt.Errorf("%w: wrote %d of %d frames", ErrorMissingFrames, p.written, p.numFrames)
+	}
+	if !p.encapsulated {
+		if p.frameLength*int64(p.numFrames)%2 != 0 {
+			return p.w.WriteByte(0)
+		}
+		return nil
+	}
+	if err := encodeElementHeader(p.w, tag.SequenceDelimitationItem, "", 0); err != nil {
+		return err
+	}
+	if p.tableOffset < 0 {
+		return nil
+	}
+	return p.writeTable()
+}
+
+// writeTable writes the offset table reserved at tableOffset, and seeks back
+// to the end.
+func (p *PixelDataWriter) writeTable() error {
+	end, err := p.seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	var table bytes.Buffer
+	bo, implicit := p.w.GetTransferSyntax()
+	w := dicomio.NewWriter(&table, bo, implicit)
+	if p.extended {
+		for _, offset := range p.offsets {
+			if err := w.WriteUInt64(offset); err != nil {
+				return err
+			}
+		}
+		if err := encodeElementHeader(w, tag.ExtendedOffsetTableLengths, vrraw.OtherVeryLong, uint32(8*len(p.lengths))); err != nil {
+			return err
+		}
+		for _, length := range p.lengths {
+			if err := w.WriteUInt64(length); err != nil {
+				return err
+			}
+		}
+	} else {
+		for _, offset := range p.offsets {
+			if err := w.WriteUInt32(uint32(offset)); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := p.seeker.Seek(p.tableOffset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := p.seeker.Write(table.Bytes()); err != nil {
+		return err
+	}
+	_, err = p.seeker.Seek(end, io.SeekStart)
+	return err
+}
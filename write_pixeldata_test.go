@@ -0,0 +1,305 @@
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wybaby168/dicom/pkg/frame"
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/uid"
+)
+
+// jpegBaseline is the JPEG Baseline (Process 1) transfer syntax.
+const jpegBaseline = "1.2.840.10008.1.2.4.50"
+
+func nativeTestFrames(n, rows, cols int) []*frame.Frame {
+	var frames []*frame.Frame
+	for i := 0; i < n; i++ {
+		nativeFrame := frame.NewNativeFrame[uint16](16, rows, cols, rows*cols, 1)
+		for j := range nativeFrame.RawData {
+			nativeFrame.RawData[j] = uint16(i*1000 + j)
+		}
+		frames = append(frames, &frame.Frame{NativeData: nativeFrame})
+	}
+	return frames
+}
+
+func encapsulatedTestFrames(sizes ...int) []*frame.Frame {
+	var frames []*frame.Frame
+	for i, size := range sizes {
+		frames = append(frames, &frame.Frame{
+			Encapsulated:     true,
+			EncapsulatedData: frame.EncapsulatedFrame{Data: bytes.Repeat([]byte{byte(i + 1)}, size)},
+		})
+	}
+	return frames
+}
+
+func pixelDataTestHeader(ts string, numFrames int) Dataset {
+	return Dataset{Elements: []*Element{
+		mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3"}),
+		mustNewElement(tag.TransferSyntaxUID, []string{ts}),
+		mustNewElement(tag.PatientName, []string{"Doe^John"}),
+		mustNewElement(tag.SamplesPerPixel, []int{1}),
+		mustNewElement(tag.NumberOfFrames, []string{strconv.Itoa(numFrames)}),
+		mustNewElement(tag.Rows, []int{3}),
+		mustNewElement(tag.Columns, []int{5}),
+		mustNewElement(tag.BitsAllocated, []int{16}),
+	}}
+}
+
+func TestWriter_StartPixelData_Native(t *testing.T) {
+	for _, ts := range []string{uid.ImplicitVRLittleEndian, uid.ExplicitVRLittleEndian, uid.ExplicitVRBigEndian} {
+		t.Run(ts, func(t *testing.T) {
+			frames := nativeTestFrames(3, 3, 5)
+			header := pixelDataTestHeader(ts, len(frames))
+			var got bytes.Buffer
+			w, err := NewWriter(&got)
+			if err != nil {
+				t.Fatalf("NewWriter() unexpected error: %v", err)
+			}
+			p, err := w.StartPixelData(header)
+			if err != nil {
+				t.Fatalf("StartPixelData() unexpected error: %v", err)
+			}
+			for _, f := range frames {
+				if err := p.WriteFrame(f); err != nil {
+					t.Fatalf("WriteFrame() unexpected error: %v", err)
+				}
+			}
+			if err := p.Close(); err != nil {
+				t.Fatalf("Close() unexpected error: %v", err)
+			}
+
+			ds := Dataset{Elements: append(header.Elements, mustNewElement(tag.PixelData, PixelDataInfo{Frames: frames}))}
+			var want bytes.Buffer
+			if err := Write(&want, ds); err != nil {
+				t.Fatalf("Write() unexpected error: %v", err)
+			}
+			if !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Errorf("StartPixelData() wrote %d bytes differing from the %d bytes of Write()", got.Len(), want.Len())
+			}
+		})
+	}
+}
+
+func TestWriter_StartPixelData_Encapsulated(t *testing.T) {
+	frames := encapsulatedTestFrames(10, 7, 4)
+	// Offsets of the items of the frames, with odd lengths padded.
+	wantOffsets := []uint32{0, 18, 34}
+	cases := []struct {
+		name     string
+		seekable bool
+		opts     []WriteOption
+		// wantOffsets are the offsets of the Basic Offset Table.
+		wantOffsets []uint32
+		extended    bool
+	}{
+		{name: "basic offset table", seekable: true, wantOffsets: wantOffsets},
+		{name: "extended offset table", seekable: true, opts: []WriteOption{ExtendedOffsetTable()}, extended: true},
+		{name: "not seekable", seekable: false},
+		{name: "not seekable with extended offset table", seekable: false, opts: []WriteOption{ExtendedOffsetTable()}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := pixelDataTestHeader(jpegBaseline, len(frames))
+			path := filepath.Join(t.TempDir(), "test.dcm")
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatalf("Create() unexpected error: %v", err)
+			}
+			defer f.Close()
+			var buf bytes.Buffer
+			var out io.Writer = f
+			if !tc.seekable {
+				out = &buf
+			}
+			w, err := NewWriter(out, tc.opts...)
+			if err != nil {
+				t.Fatalf("NewWriter() unexpected error: %v", err)
+			}
+			p, err := w.StartPixelData(header)
+			if err != nil {
+				t.Fatalf("StartPixelData() unexpected error: %v", err)
+			}
+			ch := make(chan *frame.Frame)
+			go func() {
+				for _, fr := range frames {
+					ch <- fr
+				}
+				close(ch)
+			}()
+			if err := p.WriteFrames(ch); err != nil {
+				t.Fatalf("WriteFrames() unexpected error: %v", err)
+			}
+			if err := p.Close(); err != nil {
+				t.Fatalf("Close() unexpected error: %v", err)
+			}
+			data := buf.Bytes()
+			if tc.seekable {
+				if data, err = os.ReadFile(path); err != nil {
+					t.Fatalf("ReadFile() unexpected error: %v", err)
+				}
+			}
+
+			ds, err := Parse(bytes.NewReader(data), int64(len(data)), nil, RecordEncoding())
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			pixelData, err := ds.FindElementByTag(tag.PixelData)
+			if err != nil {
+				t.Fatalf("FindElementByTag(PixelData) unexpected error: %v", err)
+			}
+			info := MustGetPixelDataInfo(pixelData.Value)
+			if diff := cmp.Diff(tc.wantOffsets, info.Offsets); diff != "" {
+				t.Errorf("Basic Offset Table unexpected diff (-want +got): %v", diff)
+			}
+			for i, fr := range info.Frames {
+				want := frames[i].EncapsulatedData.Data
+				if got := fr.EncapsulatedData.Data; !bytes.Equal(got[:len(want)], want) || len(got)-len(want) > 1 {
+					t.Errorf("frame %d = %v, want %v", i, got, want)
+				}
+			}
+			if len(info.Frames) != len(frames) {
+				t.Errorf("got %d frames, want %d", len(info.Frames), len(frames))
+			}
+
+			eot, err := ds.FindElementByTag(tag.ExtendedOffsetTable)
+			if !tc.extended {
+				if err == nil {
+					t.Errorf("unexpected Extended Offset Table %v", eot)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindElementByTag(ExtendedOffsetTable) unexpected error: %v", err)
+			}
+			lengths, err := ds.FindElementByTag(tag.ExtendedOffsetTableLengths)
+			if err != nil {
+				t.Fatalf("FindElementByTag(ExtendedOffsetTableLengths) unexpected error: %v", err)
+			}
+			if diff := cmp.Diff([]uint64{0, 18, 34}, uint64s(MustGetBytes(eot.Value))); diff != "" {
+				t.Errorf("Extended Offset Table unexpected diff (-want +got): %v", diff)
+			}
+			if diff := cmp.Diff([]uint64{10, 8, 4}, uint64s(MustGetBytes(lengths.Value))); diff != "" {
+				t.Errorf("Extended Offset Table Lengths unexpected diff (-want +got): %v", diff)
+			}
+		})
+	}
+}
+
+func uint64s(data []byte) []uint64 {
+	var values []uint64
+	for i := 0; i+8 <= len(data); i += 8 {
+		values = append(values, binary.LittleEndian.Uint64(data[i:]))
+	}
+	return values
+}
+
+func TestPixelDataWriter_WriteFrameData(t *testing.T) {
+	frames := nativeTestFrames(2, 3, 5)
+	header := pixelDataTestHeader(uid.ExplicitVRLittleEndian, len(frames))
+	var got bytes.Buffer
+	w, err := NewWriter(&got)
+	if err != nil {
+		t.Fatalf("NewWriter() unexpected error: %v", err)
+	}
+	p, err := w.StartPixelData(header)
+	if err != nil {
+		t.Fatalf("StartPixelData() unexpected error: %v", err)
+	}
+	for _, f := range frames {
+		var scratch []byte
+		data, err := nativeFrameBytes(f.NativeData, binary.LittleEndian, &scratch)
+		if err != nil {
+			t.Fatalf("nativeFrameBytes() unexpected error: %v", err)
+		}
+		if err := p.WriteFrameData(bytes.NewReader(data), int64(len(data))); err != nil {
+			t.Fatalf("WriteFrameData() unexpected error: %v", err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+
+	ds, err := Parse(bytes.NewReader(got.Bytes()), int64(got.Len()), nil)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	want := Dataset{Elements: append(header.Elements, mustNewElement(tag.PixelData, PixelDataInfo{Frames: frames}))}
+	// Parse adds the group length.
+	ds.Elements = ds.Elements[1:]
+	if changes := Diff(want, ds); len(changes) > 0 {
+		t.Errorf("WriteFrameData() unexpected changes: %v", changes)
+	}
+}
+
+func TestPixelDataWriter_Errors(t *testing.T) {
+	cases := []struct {
+		name   string
+		ts     string
+		frames []*frame.Frame
+		want   error
+	}{
+		{
+			name:   "too many frames",
+			ts:     uid.ExplicitVRLittleEndian,
+			frames: nativeTestFrames(3, 3, 5),
+			want:   ErrorUnexpectedFrame,
+		},
+		{
+			name:   "wrong frame size",
+			ts:     uid.ExplicitVRLittleEndian,
+			frames: nativeTestFrames(1, 5, 5),
+			want:   ErrorUnexpectedFrame,
+		},
+		{
+			name:   "encapsulated frame in native PixelData",
+			ts:     uid.ExplicitVRLittleEndian,
+			frames: encapsulatedTestFrames(4),
+			want:   ErrorUnexpectedFrame,
+		},
+		{
+			name:   "native frame in encapsulated PixelData",
+			ts:     jpegBaseline,
+			frames: nativeTestFrames(1, 3, 5),
+			want:   ErrorUnexpectedFrame,
+		},
+		{
+			name:   "missing frames",
+			ts:     jpegBaseline,
+			frames: encapsulatedTestFrames(4),
+			want:   ErrorMissingFrames,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w, err := NewWriter(&bytes.Buffer{})
+			if err != nil {
+				t.Fatalf("NewWriter() unexpected error: %v", err)
+			}
+			p, err := w.StartPixelData(pixelDataTestHeader(tc.ts, 2))
+			if err != nil {
+				t.Fatalf("StartPixelData() unexpected error: %v", err)
+			}
+			for _, f := range tc.frames {
+				if err = p.WriteFrame(f); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = p.Close()
+			}
+			if !errors.Is(err, tc.want) {
+				t.Errorf("PixelDataWriter error = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
@@ -4,9 +4,15 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"io"
+	"math"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/wybaby168/dicom/pkg/charset"
 	"github.com/wybaby168/dicom/pkg/frame"
 	"github.com/wybaby168/dicom/pkg/vrraw"
 
@@ -42,10 +48,10 @@ func TestWrite(t *testing.T) {
 				mustNewElement(tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.1.2"}),
 				mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4.5.6.7"}),
 				mustNewElement(tag.TransferSyntaxUID, []string{uid.ImplicitVRLittleEndian}),
-				mustNewElement(tag.PatientName, []string{"Bob", "Jones"}),
+				mustNewElement(tag.PatientName, []string{"Bob^Jones"}),
 				mustNewElement(tag.Rows, []int{128}),
 				mustNewElement(tag.FloatingPointValue, []float64{128.10}),
-				mustNewElement(tag.DimensionIndexPointer, []int{32, 36950}),
+				mustNewElement(tag.DimensionIndexPointer, []tag.Tag{tag.StackID}),
 				mustNewElement(tag.RedPaletteColorLookupTableData, []byte{0x1, 0x2, 0x3, 0x4}),
 				mustNewElement(tag.SelectorSLValue, []int{-20}),
 				// Some tag with an unknown VR.
@@ -61,6 +67,18 @@ func TestWrite(t *testing.T) {
 			}},
 			wantError: nil,
 		},
+		{
+			name: "64-bit VRs",
+			dataset: Dataset{Elements: []*Element{
+				mustNewElement(tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.1.2"}),
+				mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4.5.6.7"}),
+				mustNewElement(tag.TransferSyntaxUID, []string{uid.ExplicitVRLittleEndian}),
+				mustNewElement(tag.SelectorSVValue, []int64{math.MinInt64, 1<<53 + 1}),
+				mustNewElement(tag.SelectorUVValue, []uint64{math.MaxUint64, 7}),
+				mustNewElement(tag.ExtendedOffsetTable, []byte{0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8}),
+			}},
+			wantError: nil,
+		},
 		{
 			name: "private tag",
 			dataset: Dataset{Elements: []*Element{
@@ -79,7 +97,7 @@ func TestWrite(t *testing.T) {
 				mustNewElement(tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.1.2"}),
 				mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4.5.6.7"}),
 				mustNewElement(tag.TransferSyntaxUID, []string{uid.ImplicitVRLittleEndian}),
-				mustNewElement(tag.PatientName, []string{"Bob", "Jones"}),
+				mustNewElement(tag.PatientName, []string{"Bob^Jones"}),
 				makeSequenceElement(tag.AddOtherSequence, [][]*Element{
 					// Item 1.
 					{
@@ -129,7 +147,7 @@ func TestWrite(t *testing.T) {
 				mustNewElement(tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.1.2"}),
 				mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4.5.6.7"}),
 				mustNewElement(tag.TransferSyntaxUID, []string{uid.ImplicitVRLittleEndian}),
-				mustNewElement(tag.PatientName, []string{"Bob", "Jones"}),
+				mustNewElement(tag.PatientName, []string{"Bob^Jones"}),
 				makeSequenceElement(tag.AddOtherSequence, [][]*Element{
 					// Item 1.
 					{
@@ -180,7 +198,7 @@ func TestWrite(t *testing.T) {
 				mustNewElement(tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.1.2"}),
 				mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4.5.6.7"}),
 				mustNewElement(tag.TransferSyntaxUID, []string{uid.ImplicitVRLittleEndian}),
-				mustNewElement(tag.PatientName, []string{"Bob", "Jones"}),
+				mustNewElement(tag.PatientName, []string{"Bob^Jones"}),
 				makeSequenceElement(tag.AddOtherSequence, [][]*Element{
 					// Item 1.
 					{
@@ -216,7 +234,7 @@ func TestWrite(t *testing.T) {
 				mustNewElement(tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.1.2"}),
 				mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4.5.6.7"}),
 				mustNewElement(tag.TransferSyntaxUID, []string{uid.ImplicitVRLittleEndian}),
-				mustNewElement(tag.PatientName, []string{"Bob", "Jones"}),
+				mustNewElement(tag.PatientName, []string{"Bob^Jones"}),
 				makeSequenceElement(tag.AddOtherSequence, [][]*Element{
 					// Item 1.
 					{
@@ -252,7 +270,7 @@ func TestWrite(t *testing.T) {
 			dataset: Dataset{Elements: []*Element{
 				mustNewElement(tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.1.2"}),
 				mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4.5.6.7"}),
-				mustNewElement(tag.PatientName, []string{"Bob", "Jones"}),
+				mustNewElement(tag.PatientName, []string{"Bob^Jones"}),
 				mustNewElement(tag.Rows, []int{128}),
 				mustNewElement(tag.FloatingPointValue, []float64{128.10}),
 			}},
@@ -263,7 +281,7 @@ func TestWrite(t *testing.T) {
 			dataset: Dataset{Elements: []*Element{
 				mustNewElement(tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.1.2"}),
 				mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4.5.6.7"}),
-				mustNewElement(tag.PatientName, []string{"Bob", "Jones"}),
+				mustNewElement(tag.PatientName, []string{"Bob^Jones"}),
 				mustNewElement(tag.Rows, []int{128}),
 				mustNewElement(tag.FloatingPointValue, []float64{128.10}),
 			}},
@@ -299,7 +317,7 @@ func TestWrite(t *testing.T) {
 					},
 				}),
 				mustNewElement(tag.FloatingPointValue, []float64{128.10}),
-				mustNewElement(tag.DimensionIndexPointer, []int{32, 36950}),
+				mustNewElement(tag.DimensionIndexPointer, []tag.Tag{tag.StackID}),
 			}},
 			wantError: nil,
 		},
@@ -423,7 +441,7 @@ func TestWrite(t *testing.T) {
 					}),
 				}),
 				mustNewElement(tag.FloatingPointValue, []float64{128.10}),
-				mustNewElement(tag.DimensionIndexPointer, []int{32, 36950}),
+				mustNewElement(tag.DimensionIndexPointer, []tag.Tag{tag.StackID}),
 			}},
 			wantError: nil,
 		},
@@ -448,7 +466,7 @@ func TestWrite(t *testing.T) {
 					},
 				})),
 				mustNewElement(tag.FloatingPointValue, []float64{128.10}),
-				mustNewElement(tag.DimensionIndexPointer, []int{32, 36950}),
+				mustNewElement(tag.DimensionIndexPointer, []tag.Tag{tag.StackID}),
 			}},
 			wantError: nil,
 		},
@@ -491,6 +509,35 @@ func TestWrite(t *testing.T) {
 			}},
 			wantError: nil,
 		},
+		{
+			name: "native_PixelData_16bit_BigEndian",
+			dataset: Dataset{Elements: []*Element{
+				mustNewElement(tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.1.2"}),
+				mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4.5.6.7"}),
+				mustNewElement(tag.TransferSyntaxUID, []string{uid.ExplicitVRBigEndian}),
+				mustNewElement(tag.Rows, []int{1}),
+				mustNewElement(tag.Columns, []int{3}),
+				mustNewElement(tag.BitsAllocated, []int{16}),
+				mustNewElement(tag.NumberOfFrames, []string{"1"}),
+				mustNewElement(tag.SamplesPerPixel, []int{1}),
+				mustNewElement(tag.PixelData, PixelDataInfo{
+					IsEncapsulated: false,
+					Frames: []*frame.Frame{
+						{
+							Encapsulated: false,
+							NativeData: &frame.NativeFrame[uint16]{
+								InternalBitsPerSample:   16,
+								InternalRows:            1,
+								InternalCols:            3,
+								InternalSamplesPerPixel: 1,
+								RawData:                 []uint16{1, 0x0102, 0xfffe},
+							},
+						},
+					},
+				}),
+			}},
+			wantError: nil,
+		},
 		{
 			name: "native_PixelData_odd_bytes",
 			dataset: Dataset{Elements: []*Element{
@@ -528,7 +575,7 @@ func TestWrite(t *testing.T) {
 				mustNewElement(tag.TransferSyntaxUID, []string{uid.ImplicitVRLittleEndian}),
 				mustNewElement(tag.BitsAllocated, []int{8}),
 				mustNewElement(tag.FloatingPointValue, []float64{128.10}),
-				mustNewElement(tag.DimensionIndexPointer, []int{32, 36950}),
+				mustNewElement(tag.DimensionIndexPointer, []tag.Tag{tag.StackID}),
 				mustNewElement(tag.PixelData, PixelDataInfo{
 					IntentionallyUnprocessed: true,
 					UnprocessedValueData:     []byte{1, 2, 3, 4},
@@ -591,7 +638,7 @@ func TestWrite(t *testing.T) {
 				mustNewElement(tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.1.13"}),
 				mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4.5.6.7"}),
 				mustNewElement(tag.TransferSyntaxUID, []string{uid.ImplicitVRLittleEndian}),
-				mustNewElement(tag.PatientName, []string{"Bob", "Jones"}),
+				mustNewElement(tag.PatientName, []string{"Bob^Jones"}),
 				makeUNSequenceElement(tag.Tag{0x0019, 0x1027}, [][]*Element{
 					// Item 1.
 					{
@@ -743,28 +790,42 @@ func TestVerifyValueType(t *testing.T) {
 		name      string
 		tg        tag.Tag
 		value     Value
-		vr        string
+		vr        tag.VR
 		wantError bool
 	}{
 		{
 			name:      "valid",
 			tg:        tag.FileMetaInformationGroupLength,
 			value:     mustNewValue([]int{128}),
-			vr:        "UL",
+			vr:        tag.UL,
+			wantError: false,
+		},
+		{
+			name:      "AT tags",
+			tg:        tag.FrameIncrementPointer,
+			value:     mustNewValue([]tag.Tag{tag.StackID}),
+			vr:        tag.AT,
+			wantError: false,
+		},
+		{
+			name:      "AT flat ints",
+			tg:        tag.FrameIncrementPointer,
+			value:     mustNewValue([]int{0x0020, 0x9056}),
+			vr:        tag.AT,
 			wantError: false,
 		},
 		{
 			name:      "invalid vr",
 			tg:        tag.FileMetaInformationGroupLength,
 			value:     mustNewValue([]int{128}),
-			vr:        "NA",
+			vr:        tag.NA,
 			wantError: true,
 		},
 		{
 			name:      "wrong valueType",
 			tg:        tag.FileMetaInformationGroupLength,
 			value:     mustNewValue([]string{"str"}),
-			vr:        "UL",
+			vr:        tag.UL,
 			wantError: true,
 		},
 	}
@@ -784,14 +845,14 @@ func TestWriteFloats(t *testing.T) {
 	cases := []struct {
 		name         string
 		value        Value
-		vr           string
+		vr           tag.VR
 		expectedData []byte
 		expectedErr  error
 	}{
 		{
 			name:  "float64s",
 			value: &floatsValue{value: []float64{20.1019, 21.212}},
-			vr:    "FD",
+			vr:    tag.FD,
 			// TODO: improve test expectation
 			expectedData: []byte{0x60, 0x76, 0x4f, 0x1e, 0x16, 0x1a, 0x34, 0x40, 0x83, 0xc0, 0xca, 0xa1, 0x45, 0x36, 0x35, 0x40},
 			expectedErr:  nil,
@@ -814,29 +875,110 @@ func TestWriteFloats(t *testing.T) {
 
 }
 
+func TestWriteStrings(t *testing.T) {
+	cases := []struct {
+		name         string
+		values       []string
+		vr           tag.VR
+		expectedData []byte
+	}{
+		{name: "even length", values: []string{"AB"}, vr: tag.CS, expectedData: []byte("AB")},
+		{name: "multiple values", values: []string{"AB", "CD"}, vr: tag.CS, expectedData: []byte("AB\\CD ")},
+		{name: "character string padded with space", values: []string{"ABC"}, vr: tag.LO, expectedData: []byte("ABC ")},
+		{name: "date padded with space", values: []string{"2024010"}, vr: tag.DA, expectedData: []byte("2024010 ")},
+		{name: "UI padded with NULL", values: []string{"1.2.3"}, vr: tag.UI, expectedData: []byte("1.2.3\x00")},
+		// Strings of unknown VR have always been padded with a space, unlike
+		// binary values of VR UN.
+		{name: "UN padded with space", values: []string{"ABC"}, vr: tag.UN, expectedData: []byte("ABC ")},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			w := dicomio.NewWriter(&buf, binary.LittleEndian, false)
+			if err := writeStrings(w, tc.values, tc.vr); err != nil {
+				t.Fatalf("writeStrings(%v, %v) unexpected error: %v", tc.values, tc.vr, err)
+			}
+			if diff := cmp.Diff(tc.expectedData, buf.Bytes()); diff != "" {
+				t.Errorf("writeStrings(%v, %v) wrote unexpected data. diff: %s", tc.values, tc.vr, diff)
+			}
+		})
+	}
+}
+
+func TestWriteValue_UndefinedLength(t *testing.T) {
+	cases := []struct {
+		name    string
+		data    any
+		vr      tag.VR
+		wantErr bool
+	}{
+		{name: "strings", data: []string{"AB"}, vr: tag.LO, wantErr: true},
+		{name: "bytes", data: []byte{1, 2}, vr: tag.OB, wantErr: true},
+		{name: "ints", data: []int{1}, vr: tag.US, wantErr: true},
+		{name: "floats", data: []float64{1}, vr: tag.FD, wantErr: true},
+		{name: "int64s", data: []int64{1}, vr: tag.SV, wantErr: true},
+		{name: "uint64s", data: []uint64{1}, vr: tag.UV, wantErr: true},
+		{name: "tags", data: []tag.Tag{tag.PatientName}, vr: tag.AT, wantErr: true},
+		{name: "sequences", data: [][]*Element{{mustNewElement(tag.PatientName, []string{"Bob"})}}, vr: tag.SQ, wantErr: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			value, err := NewValue(tc.data)
+			if err != nil {
+				t.Fatalf("NewValue(%v) unexpected error: %v", tc.data, err)
+			}
+			w := dicomio.NewWriter(&bytes.Buffer{}, binary.LittleEndian, false)
+			err = writeValue(w, tag.Tag{Group: 0x0009, Element: 0x1001}, value, value.ValueType(), tc.vr, tag.VLUndefinedLength, writeOptSet{})
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("writeValue() with undefined length returned err %v, want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
 func TestWriteOtherWord(t *testing.T) {
 	// TODO: add additional cases
 	cases := []struct {
 		name         string
 		value        []byte
-		vr           string
+		vr           tag.VR
 		expectedData []byte
 		expectedErr  error
 	}{
 		{
 			name:         "OtherWord",
 			value:        []byte{0x1, 0x2, 0x3, 0x4},
-			vr:           "OW",
+			vr:           tag.OW,
 			expectedData: []byte{0x1, 0x2, 0x3, 0x4},
 			expectedErr:  nil,
 		},
 		{
 			name:         "OtherBytes",
 			value:        []byte{0x1, 0x2, 0x3, 0x4},
-			vr:           "OB",
+			vr:           tag.OB,
 			expectedData: []byte{0x1, 0x2, 0x3, 0x4},
 			expectedErr:  nil,
 		},
+		{
+			name:         "OtherFloat",
+			value:        []byte{0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8},
+			vr:           tag.OF,
+			expectedData: []byte{0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8},
+			expectedErr:  nil,
+		},
+		{
+			name:         "OtherDouble",
+			value:        []byte{0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8},
+			vr:           tag.OD,
+			expectedData: []byte{0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8},
+			expectedErr:  nil,
+		},
+		{
+			name:        "not a binary VR",
+			value:       []byte{0x1, 0x2},
+			vr:          tag.LO,
+			expectedErr: ErrorMismatchValueTypeAndVR,
+		},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -866,10 +1008,10 @@ func TestWriteElement(t *testing.T) {
 		mustNewElement(tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.1.2"}),
 		mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4.5.6.7"}),
 		mustNewElement(tag.TransferSyntaxUID, []string{uid.ImplicitVRLittleEndian}),
-		mustNewElement(tag.PatientName, []string{"Bob", "Jones"}),
+		mustNewElement(tag.PatientName, []string{"Bob^Jones"}),
 		mustNewElement(tag.Rows, []int{128}),
 		mustNewElement(tag.FloatingPointValue, []float64{128.10}),
-		mustNewElement(tag.DimensionIndexPointer, []int{32, 36950}),
+		mustNewElement(tag.DimensionIndexPointer, []tag.Tag{tag.StackID}),
 		mustNewElement(tag.RedPaletteColorLookupTableData, []byte{0x1, 0x2, 0x3, 0x4}),
 	}}
 
@@ -908,10 +1050,10 @@ func TestWrite_OverrideMissingTransferSyntax(t *testing.T) {
 	dsWithMissingTS := Dataset{Elements: []*Element{
 		mustNewElement(tag.MediaStorageSOPClassUID, []string{"1.2.840.10008.5.1.4.1.1.1.2"}),
 		mustNewElement(tag.MediaStorageSOPInstanceUID, []string{"1.2.3.4.5.6.7"}),
-		mustNewElement(tag.PatientName, []string{"Bob", "Jones"}),
+		mustNewElement(tag.PatientName, []string{"Bob^Jones"}),
 		mustNewElement(tag.Rows, []int{128}),
 		mustNewElement(tag.FloatingPointValue, []float64{128.10}),
-		mustNewElement(tag.DimensionIndexPointer, []int{32, 36950}),
+		mustNewElement(tag.DimensionIndexPointer, []tag.Tag{tag.StackID}),
 		mustNewElement(tag.RedPaletteColorLookupTableData, []byte{0x1, 0x2, 0x3, 0x4}),
 	}}
 
@@ -964,3 +1106,372 @@ func TestWrite_OverrideMissingTransferSyntax(t *testing.T) {
 		})
 	}
 }
+
+func TestWrite_CharacterSetEncoding(t *testing.T) {
+	cases := []struct {
+		name    string
+		charset []string
+		value   string
+		want    []byte
+		wantErr error
+	}{
+		{
+			name:    "Latin-1",
+			charset: []string{"ISO_IR 100"},
+			value:   "Müller^José",
+			want:    []byte("M\xfcller^Jos\xe9"),
+		},
+		{
+			name:    "GB18030",
+			charset: []string{"GB18030"},
+			value:   "张^小东",
+			want:    []byte("\xd5\xc5^\xd0\xa1\xb6\xab"),
+		},
+		{
+			name:    "UTF-8",
+			charset: []string{"ISO_IR 192"},
+			value:   "山田^太郎",
+			want:    []byte("山田^太郎"),
+		},
+		{
+			name:    "unrepresentable",
+			charset: []string{"ISO_IR 100"},
+			value:   "山田^太郎",
+			wantErr: charset.ErrorUnrepresentableCharacter,
+		},
+		{
+			name:    "unrepresentable in default repertoire",
+			charset: []string{""},
+			value:   "Müller",
+			wantErr: charset.ErrorUnrepresentableCharacter,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ds := Dataset{Elements: []*Element{
+				mustNewElement(tag.TransferSyntaxUID, []string{uid.ExplicitVRLittleEndian}),
+				mustNewElement(tag.SpecificCharacterSet, tc.charset),
+				mustNewElement(tag.PatientName, []string{tc.value}),
+			}}
+			var buf bytes.Buffer
+			err := Write(&buf, ds)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Write() returned err %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			if !bytes.Contains(buf.Bytes(), tc.want) {
+				t.Errorf("Write() did not encode %q as % x", tc.value, tc.want)
+			}
+			parsed, err := Parse(&buf, int64(buf.Len()), nil)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			e, err := parsed.FindElementByTag(tag.PatientName)
+			if err != nil {
+				t.Fatalf("FindElementByTag() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff([]string{tc.value}, MustGetStrings(e.Value)); diff != "" {
+				t.Errorf("Parse() unexpected PatientName diff: %v", diff)
+			}
+		})
+	}
+}
+
+func TestWrite_CharacterSetInSequenceItem(t *testing.T) {
+	ds := Dataset{Elements: []*Element{
+		mustNewElement(tag.TransferSyntaxUID, []string{uid.ExplicitVRLittleEndian}),
+		mustNewElement(tag.SpecificCharacterSet, []string{"ISO_IR 100"}),
+		makeSequenceElement(tag.ReferencedPatientSequence, [][]*Element{
+			{
+				mustNewElement(tag.SpecificCharacterSet, []string{"ISO_IR 192"}),
+				mustNewElement(tag.PatientName, []string{"山田^太郎"}),
+			},
+			{mustNewElement(tag.PatientName, []string{"José"})},
+		}),
+		mustNewElement(tag.PatientName, []string{"Müller"}),
+	}}
+	var buf bytes.Buffer
+	if err := Write(&buf, ds); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	// The item's character set applies within the item only, and the
+	// following item and elements are encoded with the character set of the
+	// Dataset again.
+	for _, want := range [][]byte{[]byte("山田^太郎"), []byte("Jos\xe9"), []byte("M\xfcller")} {
+		if !bytes.Contains(buf.Bytes(), want) {
+			t.Errorf("Write() did not encode % x", want)
+		}
+	}
+}
+
+func TestWrite_AutoCharacterSet(t *testing.T) {
+	cases := []struct {
+		name        string
+		charset     []string
+		nilCharset  bool
+		value       string
+		wantCharset []string
+	}{
+		{name: "ASCII", value: "Bob^Jones"},
+		{name: "nil charset value replaced", nilCharset: true, value: "Müller^José", wantCharset: []string{"ISO_IR 100"}},
+		{name: "existing charset kept", charset: []string{"ISO_IR 192"}, value: "Müller", wantCharset: []string{"ISO_IR 192"}},
+		{name: "Latin-1", value: "Müller^José", wantCharset: []string{"ISO_IR 100"}},
+		{name: "Cyrillic", charset: []string{"ISO_IR 100"}, value: "Иванов", wantCharset: []string{"ISO_IR 144"}},
+		{name: "UTF-8", value: "山田^太郎", wantCharset: []string{"ISO_IR 192"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ds := Dataset{Elements: []*Element{
+				mustNewElement(tag.TransferSyntaxUID, []string{uid.ExplicitVRLittleEndian}),
+			}}
+			if tc.charset != nil {
+				ds.Elements = append(ds.Elements, mustNewElement(tag.SpecificCharacterSet, tc.charset))
+			}
+			if tc.nilCharset {
+				ds.Elements = append(ds.Elements, &Element{Tag: tag.SpecificCharacterSet, ValueRepresentation: tag.VRStringList, RawValueRepresentation: "CS"})
+			}
+			ds.Elements = append(ds.Elements, makeSequenceElement(tag.ReferencedPatientSequence, [][]*Element{{
+				mustNewElement(tag.PatientName, []string{tc.value}),
+			}}))
+			elems := slices.Clone(ds.Elements)
+
+			var buf bytes.Buffer
+			if err := Write(&buf, ds, AutoCharacterSet()); err != nil {
+				t.Fatalf("Write() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(elems, ds.Elements, cmp.AllowUnexported(allValues...)); diff != "" {
+				t.Errorf("Write() modified the Dataset: %v", diff)
+			}
+			parsed, err := Parse(&buf, int64(buf.Len()), nil)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			var gotCharset []string
+			if e, err := parsed.FindElementByTag(tag.SpecificCharacterSet); err == nil {
+				gotCharset = MustGetStrings(e.Value)
+			}
+			if diff := cmp.Diff(tc.wantCharset, gotCharset); diff != "" {
+				t.Errorf("Write() unexpected SpecificCharacterSet diff: %v", diff)
+			}
+			e, err := parsed.FindElementByTagNested(tag.PatientName)
+			if err != nil {
+				t.Fatalf("FindElementByTagNested() unexpected error: %v", err)
+			}
+			if diff := cmp.Diff([]string{tc.value}, MustGetStrings(e.Value)); diff != "" {
+				t.Errorf("Parse() unexpected PatientName diff: %v", diff)
+			}
+		})
+	}
+}
+
+// preserveEncodingTestFile is a DICOM file encoded the way Write doesn't
+// encode it, with the given raw values of some elements.
+type preserveEncodingTestFile struct {
+	studyDescription, seriesInstanceUID, patientName []byte
+}
+
+func (f preserveEncodingTestFile) bytes() []byte {
+	// el encodes an explicit VR little endian element, with undefined length
+	// if value is nil.
+	el := func(t tag.Tag, vr string, value []byte) []byte {
+		b := binary.LittleEndian.AppendUint16(nil, t.Group)
+		b = binary.LittleEndian.AppendUint16(b, t.Element)
+		length := uint32(len(value))
+		if value == nil {
+			length = tag.VLUndefinedLength
+		}
+		switch {
+		case t.Group == tag.GroupSeqItem:
+			b = binary.LittleEndian.AppendUint32(b, length)
+		case vr == "SQ" || vr == "OB" || vr == "UN":
+			b = append(b, vr...)
+			b = append(b, 0, 0)
+			b = binary.LittleEndian.AppendUint32(b, length)
+		default:
+			b = append(b, vr...)
+			b = binary.LittleEndian.AppendUint16(b, uint16(length))
+		}
+		return append(b, value...)
+	}
+	ul := func(v int) []byte { return binary.LittleEndian.AppendUint32(nil, uint32(v)) }
+
+	meta := slices.Concat(
+		// Write puts the version before the transfer syntax.
+		el(tag.TransferSyntaxUID, "UI", []byte(uid.ExplicitVRLittleEndian+"\x00")),
+		el(tag.FileMetaInformationVersion, "OB", []byte{0, 1}),
+		el(tag.MediaStorageSOPClassUID, "UI", []byte(uid.CTImageStorage+"\x00")),
+	)
+	definedItem := el(tag.SeriesInstanceUID, "UI", f.seriesInstanceUID)
+	sequence := slices.Concat(
+		el(tag.Item, "", definedItem),
+		el(tag.Item, "", nil), el(tag.Rows, "US", []byte{2, 0}), el(tag.ItemDelimitationItem, "", []byte{}),
+	)
+	group8 := slices.Concat(
+		el(tag.StudyDate, "DA", []byte("20200101")),
+		el(tag.StudyDescription, "LO", f.studyDescription),
+		el(tag.ReferencedSeriesSequence, "SQ", sequence),
+	)
+	return slices.Concat(
+		bytes.Repeat([]byte{'P'}, 128), []byte("DICM"),
+		el(tag.FileMetaInformationGroupLength, "UL", ul(len(meta))), meta,
+		el(tag.Tag{Group: 0x0008, Element: 0x0000}, "UL", ul(len(group8))), group8,
+		el(tag.PatientName, "PN", f.patientName),
+		el(tag.PixelData, "OB", nil),
+		el(tag.Item, "", ul(0)), el(tag.Item, "", []byte{0xff, 0xd8}),
+		el(tag.SequenceDelimitationItem, "", []byte{}),
+	)
+}
+
+func TestWrite_PreserveEncoding(t *testing.T) {
+	original := preserveEncodingTestFile{
+		studyDescription:  []byte("Study\x00"),
+		seriesInstanceUID: []byte("1.2.3\x00"),
+		patientName:       []byte(" Bob"),
+	}
+	data := original.bytes()
+	parse := func(data []byte) Dataset {
+		t.Helper()
+		ds, err := Parse(bytes.NewReader(data), int64(len(data)), nil, RecordEncoding())
+		if err != nil {
+			t.Fatalf("Parse() unexpected error: %v", err)
+		}
+		return ds
+	}
+	write := func(ds Dataset, opts ...WriteOption) []byte {
+		t.Helper()
+		var out bytes.Buffer
+		if err := Write(&out, ds, opts...); err != nil {
+			t.Fatalf("Write() unexpected error: %v", err)
+		}
+		return out.Bytes()
+	}
+
+	ds := parse(data)
+	if got := write(ds, PreserveEncoding()); !bytes.Equal(got, data) {
+		t.Errorf("Write() with PreserveEncoding = %q, want the parsed file %q", got, data)
+	}
+	if got := write(ds); bytes.Equal(got, data) {
+		t.Errorf("Write() without PreserveEncoding reproduced the parsed file, want it re-encoded")
+	}
+
+	// Changed elements are re-encoded, and the lengths of their group,
+	// sequence and item updated.
+	ds = parse(data)
+	for _, change := range []struct {
+		path  Path
+		value []string
+	}{
+		{path: Path{{Tag: tag.StudyDescription}}, value: []string{"Changed"}},
+		{path: Path{{Tag: tag.ReferencedSeriesSequence}, {Tag: tag.SeriesInstanceUID}}, value: []string{"1.2.34"}},
+		{path: Path{{Tag: tag.PatientName}}, value: []string{"Alice"}},
+	} {
+		elem := findPath(ds, change.path)
+		if elem == nil {
+			t.Fatalf("Parse() result has no element at %v", change.path)
+		}
+		elem.Value = &stringsValue{value: change.value}
+	}
+	want := preserveEncodingTestFile{
+		studyDescription:  []byte("Changed "),
+		seriesInstanceUID: []byte("1.2.34"),
+		patientName:       []byte("Alice "),
+	}.bytes()
+	if got := write(ds, PreserveEncoding()); !bytes.Equal(got, want) {
+		t.Errorf("Write() with PreserveEncoding of changed Dataset = %q, want %q", got, want)
+	}
+}
+
+// findPath returns the element at path, following the first item of
+// sequences.
+func findPath(ds Dataset, path Path) *Element {
+	elems := ds.Elements
+	var elem *Element
+	for _, step := range path {
+		if elem != nil {
+			items := elem.Value.GetValue().([]*SequenceItemValue)
+			elems = items[step.Item].elements
+		}
+		if elem = findElement(elems, step.Tag); elem == nil {
+			return nil
+		}
+	}
+	return elem
+}
+
+func TestWrite_PreserveEncodingTestdata(t *testing.T) {
+	files, err := os.ReadDir("./testdata")
+	if err != nil {
+		t.Fatalf("unable to read testdata/: %v", err)
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".dcm") {
+			continue
+		}
+		t.Run(f.Name(), func(t *testing.T) {
+			data, err := os.ReadFile("./testdata/" + f.Name())
+			if err != nil {
+				t.Fatalf("unable to read %s: %v", f.Name(), err)
+			}
+			ds, err := Parse(bytes.NewReader(data), int64(len(data)), nil, RecordEncoding())
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			if e, err := ds.FindElementByTag(tag.PixelData); err == nil && e.Position.encoding != nil {
+				t.Errorf("Parse() recorded the encoding of PixelData, want it written from its frames")
+			}
+			var out bytes.Buffer
+			err = Write(&out, ds, PreserveEncoding())
+			if errors.Is(err, errorDeflatedTransferSyntaxUnsupported) {
+				t.Skip("deflated transfer syntax is not supported by Write")
+			}
+			if err != nil {
+				t.Fatalf("Write() unexpected error: %v", err)
+			}
+			if !bytes.Equal(out.Bytes(), data) {
+				t.Errorf("Write() with PreserveEncoding did not reproduce %s", f.Name())
+			}
+		})
+	}
+}
+
+func BenchmarkWritePixelData(b *testing.B) {
+	cases := []struct {
+		name      string
+		ts        string
+		numFrames int
+	}{
+		{name: "512x512, 100 frames, little endian", ts: uid.ExplicitVRLittleEndian, numFrames: 100},
+		{name: "512x512, 100 frames, big endian", ts: uid.ExplicitVRBigEndian, numFrames: 100},
+	}
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			rows, cols := 512, 512
+			var frames []*frame.Frame
+			for i := 0; i < tc.numFrames; i++ {
+				nativeFrame := frame.NewNativeFrame[uint16](16, rows, cols, rows*cols, 1)
+				for j := range nativeFrame.RawData {
+					nativeFrame.RawData[j] = uint16(j)
+				}
+				frames = append(frames, &frame.Frame{NativeData: nativeFrame})
+			}
+			ds := Dataset{Elements: []*Element{
+				mustNewElement(tag.TransferSyntaxUID, []string{tc.ts}),
+				mustNewElement(tag.Rows, []int{rows}),
+				mustNewElement(tag.Columns, []int{cols}),
+				mustNewElement(tag.BitsAllocated, []int{16}),
+				mustNewElement(tag.NumberOfFrames, []string{strconv.Itoa(tc.numFrames)}),
+				mustNewElement(tag.SamplesPerPixel, []int{1}),
+				mustNewElement(tag.PixelData, PixelDataInfo{Frames: frames}),
+			}}
+			b.SetBytes(int64(2 * rows * cols * tc.numFrames))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := Write(io.Discard, ds); err != nil {
+					b.Fatalf("Write() unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,545 @@
+package dicom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wybaby168/dicom/pkg/dicomio"
+	"github.com/wybaby168/dicom/pkg/tag"
+	"github.com/wybaby168/dicom/pkg/vrraw"
+)
+
+// nativeDICOMModelNamespace is the XML namespace of the Native DICOM Model, see
+// https://dicom.nema.org/medical/dicom/current/output/html/part19.html#sect_A.1.
+const nativeDICOMModelNamespace = "http://dicom.nema.org/PS3.19/models/NativeDICOM"
+
+var (
+	// ErrorXMLBulkDataUnresolved indicates that a BulkData reference was found
+	// while decoding Native DICOM Model XML, but no XMLBulkDataLoader was
+	// provided to resolve it.
+	ErrorXMLBulkDataUnresolved = errors.New("unable to resolve BulkData uri, no XMLBulkDataLoader provided")
+	// ErrorXMLMalformedAttribute indicates that a DicomAttribute in the Native
+	// DICOM Model XML could not be converted into an Element.
+	ErrorXMLMalformedAttribute = errors.New("malformed DicomAttribute")
+)
+
+// XMLOption represents an option that can be passed to EncodeXML or DecodeXML.
+type XMLOption func(*xmlOptSet)
+
+// xmlOptSet represents the flattened option set after all XMLOptions have been
+// applied.
+type xmlOptSet struct {
+	bulkDataURI    func(e *Element) (string, bool)
+	bulkDataLoader func(uri string) ([]byte, error)
+}
+
+func toXMLOptSet(opts ...XMLOption) xmlOptSet {
+	optSet := xmlOptSet{}
+	for _, opt := range opts {
+		opt(&optSet)
+	}
+	return optSet
+}
+
+// XMLBulkDataURI returns an XMLOption that lets the caller move binary
+// element values (e.g. PixelData) out of the XML document. For every element
+// whose value would otherwise be written as InlineBinary, uriFunc is called;
+// if it returns true, a BulkData reference with the returned uri is written
+// instead of the value. Storing the value at that uri is up to the caller.
+func XMLBulkDataURI(uriFunc func(e *Element) (uri string, ok bool)) XMLOption {
+	return func(set *xmlOptSet) {
+		set.bulkDataURI = uriFunc
+	}
+}
+
+// XMLBulkDataLoader returns an XMLOption used by DecodeXML to fetch the value
+// bytes of BulkData references. Without it, decoding a document that contains
+// BulkData returns ErrorXMLBulkDataUnresolved.
+func XMLBulkDataLoader(loader func(uri string) ([]byte, error)) XMLOption {
+	return func(set *xmlOptSet) {
+		set.bulkDataLoader = loader
+	}
+}
+
+// The xml* structs below mirror the Native DICOM Model defined in PS3.19
+// Annex A, and are only used as an intermediate representation during
+// encoding and decoding.
+
+type xmlNativeDicomModel struct {
+	XMLName    xml.Name            `xml:"NativeDicomModel"`
+	Xmlns      string              `xml:"xmlns,attr,omitempty"`
+	Attributes []xmlDicomAttribute `xml:"DicomAttribute"`
+}
+
+type xmlDicomAttribute struct {
+	Tag            string          `xml:"tag,attr"`
+	VR             string          `xml:"vr,attr"`
+	Keyword        string          `xml:"keyword,attr,omitempty"`
+	PrivateCreator string          `xml:"privateCreator,attr,omitempty"`
+	Values         []xmlValue      `xml:"Value"`
+	PersonNames    []xmlPersonName `xml:"PersonName"`
+	Items          []xmlItem       `xml:"Item"`
+	InlineBinary   string          `xml:"InlineBinary,omitempty"`
+	BulkData       *xmlBulkData    `xml:"BulkData"`
+}
+
+type xmlValue struct {
+	Number int    `xml:"number,attr"`
+	Text   string `xml:",chardata"`
+}
+
+type xmlPersonName struct {
+	Number      int                 `xml:"number,attr"`
+	Alphabetic  *xmlPersonNameGroup `xml:"Alphabetic"`
+	Ideographic *xmlPersonNameGroup `xml:"Ideographic"`
+	Phonetic    *xmlPersonNameGroup `xml:"Phonetic"`
+}
+
+type xmlPersonNameGroup struct {
+	FamilyName string `xml:"FamilyName,omitempty"`
+	GivenName  string `xml:"GivenName,omitempty"`
+	MiddleName string `xml:"MiddleName,omitempty"`
+	NamePrefix string `xml:"NamePrefix,omitempty"`
+	NameSuffix string `xml:"NameSuffix,omitempty"`
+}
+
+type xmlItem struct {
+	Number     int                 `xml:"number,attr"`
+	Attributes []xmlDicomAttribute `xml:"DicomAttribute"`
+}
+
+type xmlBulkData struct {
+	URI string `xml:"uri,attr"`
+}
+
+// EncodeXML writes the provided Dataset to out as a Native DICOM Model XML
+// document (see PS3.19 Annex A).
+func EncodeXML(out io.Writer, ds Dataset, opts ...XMLOption) error {
+	optSet := toXMLOptSet(opts...)
+	attrs, err := elementsToXML(ds.Elements, optSet)
+	if err != nil {
+		return err
+	}
+	model := xmlNativeDicomModel{
+		Xmlns:      nativeDICOMModelNamespace,
+		Attributes: attrs,
+	}
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(model); err != nil {
+		return err
+	}
+	_, err = io.WriteString(out, "\n")
+	return err
+}
+
+// DecodeXML reads a Native DICOM Model XML document (see PS3.19 Annex A) from
+// in and returns the Dataset it represents.
+func DecodeXML(in io.Reader, opts ...XMLOption) (Dataset, error) {
+	var model xmlNativeDicomModel
+	if err := xml.NewDecoder(in).Decode(&model); err != nil {
+		return Dataset{}, err
+	}
+	elems, err := elementsFromXML(model.Attributes, toXMLOptSet(opts...))
+	if err != nil {
+		return Dataset{}, err
+	}
+	return Dataset{Elements: elems}, nil
+}
+
+// MarshalXML encodes this Dataset as a Native DICOM Model XML document, which
+// means that Dataset is XML serializable out of the box (implements
+// xml.Marshaler). Use EncodeXML to pass XMLOptions.
+//
+// The attributes are wrapped in the element named by start, e.g. by the tag
+// of a struct field holding the Dataset. When start only carries the default
+// name of the type, they are wrapped in a NativeDicomModel element instead.
+func (d *Dataset) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	attrs, err := elementsToXML(d.Elements, xmlOptSet{})
+	if err != nil {
+		return err
+	}
+	model := xmlNativeDicomModel{Attributes: attrs}
+	if start.Name.Local == "" || start.Name == (xml.Name{Local: "Dataset"}) {
+		start = xml.StartElement{Name: xml.Name{Local: "NativeDicomModel"}}
+		model.Xmlns = nativeDICOMModelNamespace
+	}
+	return e.EncodeElement(model, start)
+}
+
+// UnmarshalXML decodes a Native DICOM Model XML document into this Dataset
+// (implements xml.Unmarshaler). Use DecodeXML to pass XMLOptions. Like
+// MarshalXML, it accepts the attributes wrapped in any element.
+func (d *Dataset) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	var model struct {
+		Attributes []xmlDicomAttribute `xml:"DicomAttribute"`
+	}
+	if err := dec.DecodeElement(&model, &start); err != nil {
+		return err
+	}
+	elems, err := elementsFromXML(model.Attributes, xmlOptSet{})
+	if err != nil {
+		return err
+	}
+	d.Elements = elems
+	return nil
+}
+
+func elementsToXML(elems []*Element, opts xmlOptSet) ([]xmlDicomAttribute, error) {
+	attrs := make([]xmlDicomAttribute, 0, len(elems))
+	for _, elem := range elems {
+		attr, err := elementToXML(elem, elems, opts)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs, nil
+}
+
+func elementToXML(elem *Element, siblings []*Element, opts xmlOptSet) (xmlDicomAttribute, error) {
+	vr := elem.RawValueRepresentation
+	if vr == "" {
+		if info, err := tag.Find(elem.Tag); err == nil {
+			vr = info.VRs[0]
+		} else {
+			vr = vrraw.Unknown
+		}
+	}
+	attr := xmlDicomAttribute{
+		Tag: fmt.Sprintf("%04X%04X", elem.Tag.Group, elem.Tag.Element),
+		VR:  vr,
+	}
+	// PS3.19 uses the dictionary keyword for every group of a repeating group.
+	dictTag := elem.Tag
+	if base, isRepeating := tag.RepeatingGroupBase(elem.Tag); isRepeating {
+		dictTag = base
+	}
+	if info, err := tag.Find(dictTag); err == nil {
+		attr.Keyword = info.Keyword
+	}
+	if tag.IsPrivate(elem.Tag.Group) {
+		attr.PrivateCreator, _ = (&Dataset{Elements: siblings}).PrivateCreator(elem.Tag)
+	}
+	if elem.Value == nil {
+		return attr, nil
+	}
+
+	switch elem.Value.ValueType() {
+	case Strings:
+		values := MustGetStrings(elem.Value)
+		if vr == vrraw.PersonName {
+			for i, v := range values {
+				attr.PersonNames = append(attr.PersonNames, personNameToXML(i+1, v))
+			}
+			break
+		}
+		for i, v := range values {
+			attr.Values = append(attr.Values, xmlValue{Number: i + 1, Text: v})
+		}
+	case Ints:
+		values := MustGetInts(elem.Value)
+		if vr == vrraw.AttributeTag {
+			// AT values are stored as (group, element) pairs.
+			for i := 0; i+1 < len(values); i += 2 {
+				attr.Values = append(attr.Values, xmlValue{Number: i/2 + 1, Text: fmt.Sprintf("%04X%04X", values[i], values[i+1])})
+			}
+			break
+		}
+		for i, v := range values {
+			attr.Values = append(attr.Values, xmlValue{Number: i + 1, Text: strconv.Itoa(v)})
+		}
+	case Tags:
+		for i, v := range MustGetTags(elem.Value) {
+			attr.Values = append(attr.Values, xmlValue{Number: i + 1, Text: fmt.Sprintf("%04X%04X", v.Group, v.Element)})
+		}
+	case Int64s:
+		for i, v := range MustGetInt64s(elem.Value) {
+			attr.Values = append(attr.Values, xmlValue{Number: i + 1, Text: strconv.FormatInt(v, 10)})
+		}
+	case Uint64s:
+		for i, v := range MustGetUint64s(elem.Value) {
+			attr.Values = append(attr.Values, xmlValue{Number: i + 1, Text: strconv.FormatUint(v, 10)})
+		}
+	case Floats:
+		for i, v := range MustGetFloats(elem.Value) {
+			attr.Values = append(attr.Values, xmlValue{Number: i + 1, Text: strconv.FormatFloat(v, 'g', -1, 64)})
+		}
+	case Sequences:
+		for i, item := range elem.Value.GetValue().([]*SequenceItemValue) {
+			itemAttrs, err := elementsToXML(item.elements, opts)
+			if err != nil {
+				return xmlDicomAttribute{}, err
+			}
+			attr.Items = append(attr.Items, xmlItem{Number: i + 1, Attributes: itemAttrs})
+		}
+	case Bytes, PixelData:
+		if opts.bulkDataURI != nil {
+			if uri, ok := opts.bulkDataURI(elem); ok {
+				attr.BulkData = &xmlBulkData{URI: uri}
+				break
+			}
+		}
+		data, err := binaryValueBytes(elem)
+		if err != nil {
+			return xmlDicomAttribute{}, fmt.Errorf("unable to encode value of %v as InlineBinary: %w", elem.Tag, err)
+		}
+		attr.InlineBinary = base64.StdEncoding.EncodeToString(data)
+	default:
+		return xmlDicomAttribute{}, fmt.Errorf("unable to encode value of %v to XML: %w", elem.Tag, ErrorUnexpectedValueType)
+	}
+	return attr, nil
+}
+
+// binaryValueBytes returns the little endian encoded value field of a binary
+// element. Encapsulated PixelData is returned as its full undefined length
+// value field (offset table, fragments and sequence delimiter).
+func binaryValueBytes(elem *Element) ([]byte, error) {
+	if elem.Value.ValueType() == Bytes {
+		return MustGetBytes(elem.Value), nil
+	}
+	vl := elem.ValueLength
+	if MustGetPixelDataInfo(elem.Value).IsEncapsulated {
+		vl = tag.VLUndefinedLength
+	} else if vl == tag.VLUndefinedLength {
+		vl = 0
+	}
+	buf := &bytes.Buffer{}
+	w := dicomio.NewWriter(buf, binary.LittleEndian, false)
+	if err := writePixelData(w, elem.Value, vl); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func personNameToXML(number int, name string) xmlPersonName {
+	pn := xmlPersonName{Number: number}
+	groups := strings.Split(name, "=")
+	for i, group := range groups {
+		if group == "" {
+			continue
+		}
+		var components [5]string
+		copy(components[:], strings.SplitN(group, "^", 5))
+		g := &xmlPersonNameGroup{
+			FamilyName: components[0],
+			GivenName:  components[1],
+			MiddleName: components[2],
+			NamePrefix: components[3],
+			NameSuffix: components[4],
+		}
+		switch i {
+		case 0:
+			pn.Alphabetic = g
+		case 1:
+			pn.Ideographic = g
+		case 2:
+			pn.Phonetic = g
+		}
+	}
+	return pn
+}
+
+func personNameFromXML(pn xmlPersonName) string {
+	groups := []*xmlPersonNameGroup{pn.Alphabetic, pn.Ideographic, pn.Phonetic}
+	out := make([]string, len(groups))
+	for i, g := range groups {
+		if g == nil {
+			continue
+		}
+		out[i] = strings.TrimRight(strings.Join([]string{g.FamilyName, g.GivenName, g.MiddleName, g.NamePrefix, g.NameSuffix}, "^"), "^")
+	}
+	return strings.TrimRight(strings.Join(out, "="), "=")
+}
+
+func elementsFromXML(attrs []xmlDicomAttribute, opts xmlOptSet) ([]*Element, error) {
+	elems := make([]*Element, 0, len(attrs))
+	for _, attr := range attrs {
+		elem, err := elementFromXML(attr, opts)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+	}
+	return elems, nil
+}
+
+func elementFromXML(attr xmlDicomAttribute, opts xmlOptSet) (*Element, error) {
+	t, err := parseXMLTag(attr.Tag)
+	if err != nil {
+		return nil, err
+	}
+	vr := attr.VR
+	if vr == "" {
+		if info, err := tag.Find(t); err == nil {
+			vr = info.VRs[0]
+		} else {
+			vr = vrraw.Unknown
+		}
+	}
+	elem := &Element{
+		Tag:                    t,
+		ValueRepresentation:    tag.GetVRKind(t, vr),
+		RawValueRepresentation: vr,
+	}
+
+	switch {
+	case attr.BulkData != nil || attr.InlineBinary != "":
+		var data []byte
+		if attr.BulkData != nil {
+			if opts.bulkDataLoader == nil {
+				return nil, fmt.Errorf("element %v references %q: %w", t, attr.BulkData.URI, ErrorXMLBulkDataUnresolved)
+			}
+			data, err = opts.bulkDataLoader(attr.BulkData.URI)
+		} else {
+			data, err = base64.StdEncoding.DecodeString(strings.TrimSpace(attr.InlineBinary))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read binary value of %v: %w", t, err)
+		}
+		if t == tag.PixelData {
+			return pixelDataFromXML(elem, data)
+		}
+		elem.Value = &bytesValue{value: data}
+	case len(attr.Items) > 0 || vr == vrraw.Sequence:
+		items := append([]xmlItem(nil), attr.Items...)
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Number < items[j].Number })
+		seq := &sequencesValue{value: make([]*SequenceItemValue, 0, len(items))}
+		for _, item := range items {
+			itemElems, err := elementsFromXML(item.Attributes, opts)
+			if err != nil {
+				return nil, err
+			}
+			seq.value = append(seq.value, &SequenceItemValue{elements: itemElems})
+		}
+		elem.Value = seq
+	case len(attr.PersonNames) > 0:
+		names := append([]xmlPersonName(nil), attr.PersonNames...)
+		sort.SliceStable(names, func(i, j int) bool { return names[i].Number < names[j].Number })
+		values := make([]string, 0, len(names))
+		for _, pn := range names {
+			values = append(values, personNameFromXML(pn))
+		}
+		elem.Value = &stringsValue{value: values}
+	default:
+		value, err := valueFromXML(t, vr, attr.Values)
+		if err != nil {
+			return nil, err
+		}
+		elem.Value = value
+	}
+	return elem, nil
+}
+
+func valueFromXML(t tag.Tag, vr string, xmlValues []xmlValue) (Value, error) {
+	values := append([]xmlValue(nil), xmlValues...)
+	sort.SliceStable(values, func(i, j int) bool { return values[i].Number < values[j].Number })
+
+	switch tag.GetVRKind(t, vr) {
+	case tag.VRUInt16List, tag.VRUInt32List, tag.VRInt16List, tag.VRInt32List:
+		ints := make([]int, 0, len(values))
+		for _, v := range values {
+			i, err := strconv.Atoi(strings.TrimSpace(v.Text))
+			if err != nil {
+				return nil, fmt.Errorf("element %v has non integer value %q: %w", t, v.Text, ErrorXMLMalformedAttribute)
+			}
+			ints = append(ints, i)
+		}
+		return &intsValue{value: ints}, nil
+	case tag.VRInt64List:
+		ints := make([]int64, 0, len(values))
+		for _, v := range values {
+			i, err := strconv.ParseInt(strings.TrimSpace(v.Text), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("element %v has non integer value %q: %w", t, v.Text, ErrorXMLMalformedAttribute)
+			}
+			ints = append(ints, i)
+		}
+		return &int64sValue{value: ints}, nil
+	case tag.VRUInt64List:
+		ints := make([]uint64, 0, len(values))
+		for _, v := range values {
+			i, err := strconv.ParseUint(strings.TrimSpace(v.Text), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("element %v has non integer value %q: %w", t, v.Text, ErrorXMLMalformedAttribute)
+			}
+			ints = append(ints, i)
+		}
+		return &uint64sValue{value: ints}, nil
+	case tag.VRTagList:
+		tags := make([]tag.Tag, 0, len(values))
+		for _, v := range values {
+			at, err := parseXMLTag(v.Text)
+			if err != nil {
+				return nil, err
+			}
+			tags = append(tags, at)
+		}
+		return &tagsValue{value: tags}, nil
+	case tag.VRFloat32List, tag.VRFloat64List:
+		floats := make([]float64, 0, len(values))
+		for _, v := range values {
+			f, err := strconv.ParseFloat(strings.TrimSpace(v.Text), 64)
+			if err != nil {
+				return nil, fmt.Errorf("element %v has non floating point value %q: %w", t, v.Text, ErrorXMLMalformedAttribute)
+			}
+			floats = append(floats, f)
+		}
+		return &floatsValue{value: floats}, nil
+	case tag.VRBytes, tag.VRUnknown, tag.VRPixelData:
+		if len(values) > 0 {
+			return nil, fmt.Errorf("element %v with VR %s must use InlineBinary or BulkData: %w", t, vr, ErrorXMLMalformedAttribute)
+		}
+		return &bytesValue{value: []byte{}}, nil
+	default:
+		strs := make([]string, 0, len(values))
+		for _, v := range values {
+			strs = append(strs, v.Text)
+		}
+		return &stringsValue{value: strs}, nil
+	}
+}
+
+// pixelDataFromXML builds the PixelData value from its encoded value field.
+// Encapsulated PixelData is split back into frames, while native PixelData is
+// kept as unprocessed bytes, since the attributes needed to unpack it may not
+// have been decoded yet.
+func pixelDataFromXML(elem *Element, data []byte) (*Element, error) {
+	if len(data) >= 4 && binary.LittleEndian.Uint16(data) == tag.Item.Group && binary.LittleEndian.Uint16(data[2:]) == tag.Item.Element {
+		r := &reader{rawReader: dicomio.NewReader(bufio.NewReader(bytes.NewReader(data)), binary.LittleEndian, int64(len(data)))}
+		value, err := r.readPixelData(tag.VLUndefinedLength, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read encapsulated PixelData from InlineBinary: %w", err)
+		}
+		elem.ValueLength = tag.VLUndefinedLength
+		elem.Value = value
+		return elem, nil
+	}
+	elem.ValueLength = uint32(len(data))
+	elem.Value = &pixelDataValue{PixelDataInfo{IntentionallyUnprocessed: true, UnprocessedValueData: data}}
+	return elem, nil
+}
+
+func parseXMLTag(s string) (tag.Tag, error) {
+	s = strings.TrimSpace(s)
+	if len(s) != 8 {
+		return tag.Tag{}, fmt.Errorf("tag %q is not of form ggggeeee: %w", s, ErrorXMLMalformedAttribute)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return tag.Tag{}, fmt.Errorf("tag %q is not of form ggggeeee: %w", s, ErrorXMLMalformedAttribute)
+	}
+	return tag.Tag{Group: uint16(v >> 16), Element: uint16(v)}, nil
+}
@@ -0,0 +1,232 @@
+package dicom
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/wybaby168/dicom/pkg/frame"
+	"github.com/wybaby168/dicom/pkg/tag"
+)
+
+func TestXML_RoundTrip(t *testing.T) {
+	cases := []struct {
+		name    string
+		dataset Dataset
+	}{
+		{
+			name: "basic types",
+			dataset: Dataset{Elements: []*Element{
+				mustNewElement(tag.SOPInstanceUID, []string{"1.2.3.4"}),
+				mustNewElement(tag.ImageType, []string{"ORIGINAL", "PRIMARY"}),
+				mustNewElement(tag.Rows, []int{128}),
+				mustNewElement(tag.FloatingPointValue, []float64{128.1}),
+				mustNewElement(tag.SelectorSLValue, []int{-20}),
+				mustNewElement(tag.FrameIncrementPointer, []tag.Tag{tag.StackID, tag.InStackPositionNumber}),
+				mustNewElement(tag.RedPaletteColorLookupTableData, []byte{0x1, 0x2, 0x3, 0x4}),
+			}},
+		},
+		{
+			name: "person names",
+			dataset: Dataset{Elements: []*Element{
+				mustNewElement(tag.PatientName, []string{"Yamada^Tarou=山田^太郎=やまだ^たろう"}),
+				mustNewElement(tag.OtherPatientNames, []string{"Doe^John^^Dr^Jr", "=Only^Ideographic"}),
+			}},
+		},
+		{
+			name: "nested sequences",
+			dataset: Dataset{Elements: []*Element{
+				makeSequenceElement(tag.ReferencedSeriesSequence, [][]*Element{
+					{
+						mustNewElement(tag.SeriesInstanceUID, []string{"1.2.3"}),
+						makeSequenceElement(tag.ReferencedInstanceSequence, [][]*Element{
+							{mustNewElement(tag.ReferencedSOPInstanceUID, []string{"1.2.3.1"})},
+							{mustNewElement(tag.ReferencedSOPInstanceUID, []string{"1.2.3.2"})},
+						}),
+					},
+				}),
+			}},
+		},
+		{
+			name: "encapsulated pixel data",
+			dataset: Dataset{Elements: []*Element{
+				{
+					Tag:                    tag.PixelData,
+					ValueRepresentation:    tag.VRPixelData,
+					RawValueRepresentation: "OB",
+					ValueLength:            tag.VLUndefinedLength,
+					Value: &pixelDataValue{PixelDataInfo{
+						IsEncapsulated: true,
+						Frames: []*frame.Frame{
+							{Encapsulated: true, EncapsulatedData: frame.EncapsulatedFrame{Data: []byte{1, 2, 3, 4}}},
+							{Encapsulated: true, EncapsulatedData: frame.EncapsulatedFrame{Data: []byte{5, 6}}},
+						},
+					}},
+				},
+			}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeXML(&buf, tc.dataset); err != nil {
+				t.Fatalf("EncodeXML() unexpected error: %v", err)
+			}
+			got, err := DecodeXML(&buf)
+			if err != nil {
+				t.Fatalf("DecodeXML() unexpected error: %v", err)
+			}
+			if len(got.Elements) != len(tc.dataset.Elements) {
+				t.Fatalf("DecodeXML() returned %d elements, want %d", len(got.Elements), len(tc.dataset.Elements))
+			}
+			for i, want := range tc.dataset.Elements {
+				if got.Elements[i].Tag != want.Tag || got.Elements[i].RawValueRepresentation != want.RawValueRepresentation {
+					t.Errorf("DecodeXML() element %d = %v %s, want %v %s", i, got.Elements[i].Tag, got.Elements[i].RawValueRepresentation, want.Tag, want.RawValueRepresentation)
+				}
+				if !got.Elements[i].Value.Equals(want.Value) {
+					t.Errorf("DecodeXML() element %v value = %v, want %v", want.Tag, got.Elements[i].Value, want.Value)
+				}
+			}
+		})
+	}
+}
+
+func TestXML_Marshaler(t *testing.T) {
+	ds := Dataset{Elements: []*Element{mustNewElement(tag.SOPInstanceUID, []string{"1.2.3.4"})}}
+
+	out, err := xml.Marshal(&ds)
+	if err != nil {
+		t.Fatalf("xml.Marshal() unexpected error: %v", err)
+	}
+	if want := `<NativeDicomModel xmlns="` + nativeDICOMModelNamespace + `">`; !strings.HasPrefix(string(out), want) {
+		t.Errorf("xml.Marshal() = %s, want prefix %s", out, want)
+	}
+
+	type report struct {
+		XMLName xml.Name `xml:"report"`
+		Study   *Dataset `xml:"study"`
+	}
+	out, err = xml.Marshal(report{Study: &ds})
+	if err != nil {
+		t.Fatalf("xml.Marshal() unexpected error: %v", err)
+	}
+	if want := `<report><study><DicomAttribute tag="00080018" vr="UI" keyword="SOPInstanceUID">`; !strings.HasPrefix(string(out), want) {
+		t.Errorf("xml.Marshal() = %s, want prefix %s", out, want)
+	}
+
+	var got report
+	if err := xml.Unmarshal(out, &got); err != nil {
+		t.Fatalf("xml.Unmarshal() unexpected error: %v", err)
+	}
+	if got.Study == nil || len(got.Study.Elements) != 1 || !got.Study.Elements[0].Value.Equals(ds.Elements[0].Value) {
+		t.Errorf("xml.Unmarshal() = %v, want %v", got.Study, ds)
+	}
+}
+
+func TestEncodeXML_PersonNameComponents(t *testing.T) {
+	ds := Dataset{Elements: []*Element{
+		mustNewElement(tag.PatientName, []string{"Doe^John^^Dr=ド^ジョン"}),
+	}}
+	var buf bytes.Buffer
+	if err := EncodeXML(&buf, ds); err != nil {
+		t.Fatalf("EncodeXML() unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`<DicomAttribute tag="00100010" vr="PN" keyword="PatientName">`,
+		`<PersonName number="1">`,
+		`<Alphabetic>`,
+		`<FamilyName>Doe</FamilyName>`,
+		`<GivenName>John</GivenName>`,
+		`<NamePrefix>Dr</NamePrefix>`,
+		`<Ideographic>`,
+		`<FamilyName>ド</FamilyName>`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("EncodeXML() output missing %q, got:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "<MiddleName>") || strings.Contains(buf.String(), "<Phonetic>") {
+		t.Errorf("EncodeXML() output contains empty name components, got:\n%s", buf.String())
+	}
+}
+
+func TestXML_BulkData(t *testing.T) {
+	data := []byte{0x1, 0x2, 0x3, 0x4}
+	ds := Dataset{Elements: []*Element{
+		mustNewElement(tag.RedPaletteColorLookupTableData, data),
+	}}
+	var buf bytes.Buffer
+	err := EncodeXML(&buf, ds, XMLBulkDataURI(func(e *Element) (string, bool) {
+		return "bulk/" + e.Tag.String(), true
+	}))
+	if err != nil {
+		t.Fatalf("EncodeXML() unexpected error: %v", err)
+	}
+	if want := `<BulkData uri="bulk/(0028,1201)"></BulkData>`; !strings.Contains(buf.String(), want) {
+		t.Fatalf("EncodeXML() output missing %q, got:\n%s", want, buf.String())
+	}
+
+	if _, err := DecodeXML(bytes.NewReader(buf.Bytes())); !errors.Is(err, ErrorXMLBulkDataUnresolved) {
+		t.Errorf("DecodeXML() without loader returned err %v, want %v", err, ErrorXMLBulkDataUnresolved)
+	}
+
+	got, err := DecodeXML(bytes.NewReader(buf.Bytes()), XMLBulkDataLoader(func(uri string) ([]byte, error) {
+		if uri != "bulk/(0028,1201)" {
+			t.Errorf("XMLBulkDataLoader called with unexpected uri %q", uri)
+		}
+		return data, nil
+	}))
+	if err != nil {
+		t.Fatalf("DecodeXML() unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(data, MustGetBytes(got.Elements[0].Value)); diff != "" {
+		t.Errorf("DecodeXML() unexpected BulkData value diff: %v", diff)
+	}
+}
+
+func TestXML_PrivateCreator(t *testing.T) {
+	ds := Dataset{Elements: []*Element{
+		mustNewPrivateElement(tag.Tag{Group: 0x0019, Element: 0x0010}, "LO", []string{"ACME 1.0"}),
+		mustNewPrivateElement(tag.Tag{Group: 0x0019, Element: 0x1001}, "SH", []string{"secret"}),
+	}}
+	var buf bytes.Buffer
+	if err := EncodeXML(&buf, ds); err != nil {
+		t.Fatalf("EncodeXML() unexpected error: %v", err)
+	}
+	if want := `<DicomAttribute tag="00191001" vr="SH" privateCreator="ACME 1.0">`; !strings.Contains(buf.String(), want) {
+		t.Errorf("EncodeXML() output missing %q, got:\n%s", want, buf.String())
+	}
+}
+
+func TestXML_Testdata(t *testing.T) {
+	ds, err := ParseFile("./testdata/1.dcm", nil)
+	if err != nil {
+		t.Fatalf("ParseFile() unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := EncodeXML(&buf, ds); err != nil {
+		t.Fatalf("EncodeXML() unexpected error: %v", err)
+	}
+	got, err := DecodeXML(&buf)
+	if err != nil {
+		t.Fatalf("DecodeXML() unexpected error: %v", err)
+	}
+	if len(got.Elements) != len(ds.Elements) {
+		t.Fatalf("DecodeXML() returned %d elements, want %d", len(got.Elements), len(ds.Elements))
+	}
+
+	// Native PixelData comes back unprocessed, so compare the encoded bytes.
+	var want, roundTripped bytes.Buffer
+	if err := Write(&want, ds, SkipVRVerification()); err != nil {
+		t.Fatalf("Write(original) unexpected error: %v", err)
+	}
+	if err := Write(&roundTripped, got, SkipVRVerification()); err != nil {
+		t.Fatalf("Write(round tripped) unexpected error: %v", err)
+	}
+	if !bytes.Equal(want.Bytes(), roundTripped.Bytes()) {
+		t.Errorf("writing the XML round tripped dataset produced different bytes than the original dataset")
+	}
+}